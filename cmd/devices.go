@@ -0,0 +1,167 @@
+/*
+Copyright © 2020 Gal Amiram <galamiram1@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/galamiram/nadctl/nadapi"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// devicesCmd represents the devices command
+var devicesCmd = &cobra.Command{
+	Use:   "devices [list|alias DEVICE [NAME]|forget DEVICE]",
+	Short: "List and manage the devices to choose from",
+	Long: `List the devices --device chooses from, and name and forget known devices.
+
+The list holds the devices named in the devices section of the config
+file, followed by the devices nadctl has seen before. Every device found
+by discovery is remembered in the registry of known devices, together
+with the address it was last seen at. Devices are recognized by their MAC
+address, so a device that DHCP moves to another address keeps its entry.
+
+--device selects a device by its number in the list, its name or alias,
+MAC address, host name or address, or a unique part of its name. The
+groups of the config file, which --group selects, are listed last.
+
+Examples:
+  nadctl devices                          # List the devices to choose from
+  nadctl devices alias 10.0.0.5 office    # Name the device at 10.0.0.5
+  nadctl devices alias office             # Remove the alias
+  nadctl devices forget office            # Remove the device
+  nadctl power --device office            # Control a device by its alias
+  nadctl power --device 2                 # Control the second device of the list`,
+	Args: cobra.RangeArgs(0, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			args = []string{"list"}
+		}
+
+		switch args[0] {
+		case "list":
+			entries, err := deviceEntries()
+			if err != nil {
+				log.WithError(err).Fatal("failed to load devices")
+			}
+			if len(entries) == 0 {
+				fmt.Println("No known devices yet. Run 'nadctl discover' to find some.")
+				return
+			}
+			for i, e := range entries {
+				printDeviceEntry(i+1, e)
+			}
+			printGroups()
+
+		case "alias":
+			if len(args) < 2 {
+				log.Fatal("usage: nadctl devices alias DEVICE [NAME]")
+			}
+			alias := ""
+			if len(args) == 3 {
+				alias = args[2]
+			}
+			if err := nadapi.SetKnownDeviceAlias(args[1], alias); err != nil {
+				log.WithError(err).Fatal("failed to set alias")
+			}
+			if alias == "" {
+				fmt.Printf("Removed the alias of %s\n", args[1])
+			} else {
+				fmt.Printf("%s is now known as %s\n", args[1], alias)
+			}
+
+		case "forget":
+			if len(args) != 2 {
+				log.Fatal("usage: nadctl devices forget DEVICE")
+			}
+			if err := nadapi.ForgetKnownDevice(args[1]); err != nil {
+				log.WithError(err).Fatal("failed to forget device")
+			}
+			fmt.Printf("Forgot %s\n", args[1])
+
+		default:
+			log.Fatalf("unknown devices command %q (want list, alias or forget)", args[0])
+		}
+	},
+}
+
+func printDeviceEntry(n int, e nadapi.DeviceEntry) {
+	k := e.Known
+	if k == nil {
+		address := e.Address
+		if e.Port != "" {
+			address = net.JoinHostPort(e.Address, e.Port)
+		}
+		fmt.Printf("%d. %s (configured, not seen yet)\n", n, e.Name)
+		fmt.Printf("   Address: %s\n", address)
+		fmt.Println()
+		return
+	}
+
+	name := e.String()
+	if e.Name == "" {
+		name = k.Name()
+	} else if k.Alias != "" && k.Alias != e.Name {
+		name += " (alias " + k.Alias + ")"
+	}
+	fmt.Printf("%d. %s - %s\n", n, name, k.Model)
+	fmt.Printf("   Address: %s\n", net.JoinHostPort(k.IP, k.Port))
+	if k.MAC != "" {
+		fmt.Printf("   MAC: %s\n", k.MAC)
+	}
+	if k.Firmware != "" {
+		fmt.Printf("   Firmware: %s\n", k.Firmware)
+	}
+	if k.Hostname != "" {
+		fmt.Printf("   Host name: %s\n", k.Hostname)
+	}
+	fmt.Printf("   Last seen: %s\n", k.LastSeen.Local().Format("2006-01-02 15:04:05"))
+	fmt.Println()
+}
+
+// printGroups lists the groups of the config file with their members
+func printGroups() {
+	groups := configuredGroups()
+	if len(groups) == 0 {
+		return
+	}
+	fmt.Println("Groups (--group):")
+	for _, name := range nadapi.GroupNames(groups) {
+		group, err := nadapi.ParseGroup(name, groups[name]...)
+		if err != nil {
+			fmt.Printf("  %s: invalid: %v\n", name, err)
+			continue
+		}
+		members := make([]string, len(group.Members))
+		for i, m := range group.Members {
+			members[i] = m.String()
+		}
+		fmt.Printf("  %s: %s\n", name, strings.Join(members, ", "))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(devicesCmd)
+}
@@ -23,6 +23,7 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 
@@ -37,7 +38,7 @@ var dimCmd = &cobra.Command{
 	Short: "Set or get display brightness",
 	Long: `Set the display brightness to a specific level or adjust it relatively.
 
-Brightness levels are discrete values, typically 0-3:
+Brightness levels are discrete values, typically 0-3 depending on the model:
   0 = Display off/darkest
   1 = Low brightness  
   2 = Medium brightness
@@ -52,7 +53,8 @@ Examples:
   nadctl dim list         # List all available levels`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := connectToDevice()
+		ctx := cmd.Context()
+		client, err := connectToDevice(ctx)
 		if err != nil {
 			log.WithError(err).Fatal("could not connect to device")
 		}
@@ -60,7 +62,7 @@ Examples:
 
 		// No arguments - show current brightness
 		if len(args) == 0 {
-			currentBrightness, err := client.GetBrightnessInt()
+			currentBrightness, err := client.GetBrightnessIntContext(ctx)
 			if err != nil {
 				log.WithError(err).Fatal("failed to get current brightness")
 			}
@@ -70,10 +72,15 @@ Examples:
 
 		arg := strings.ToLower(args[0])
 
+		caps, err := client.CapabilitiesContext(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("failed to get device capabilities")
+		}
+
 		// Handle special commands
 		switch arg {
 		case "list":
-			levels := nadapi.GetAvailableBrightnessLevels()
+			levels := caps.BrightnessLevels()
 			fmt.Println("Available brightness levels:")
 			for _, level := range levels {
 				description := getBrightnessDescription(level)
@@ -82,11 +89,11 @@ Examples:
 			return
 
 		case "up":
-			err = client.ToggleBrightness(nadapi.DirectionUp)
+			err = client.ToggleBrightnessContext(ctx, nadapi.DirectionUp)
 			if err != nil {
 				log.WithError(err).Fatal("failed to increase brightness")
 			}
-			newBrightness, err := client.GetBrightnessInt()
+			newBrightness, err := client.GetBrightnessIntContext(ctx)
 			if err == nil {
 				fmt.Printf("Brightness increased to: %d\n", newBrightness)
 			} else {
@@ -95,11 +102,11 @@ Examples:
 			return
 
 		case "down":
-			err = client.ToggleBrightness(nadapi.DirectionDown)
+			err = client.ToggleBrightnessContext(ctx, nadapi.DirectionDown)
 			if err != nil {
 				log.WithError(err).Fatal("failed to decrease brightness")
 			}
-			newBrightness, err := client.GetBrightnessInt()
+			newBrightness, err := client.GetBrightnessIntContext(ctx)
 			if err == nil {
 				fmt.Printf("Brightness decreased to: %d\n", newBrightness)
 			} else {
@@ -119,22 +126,22 @@ Examples:
 				fmt.Println("  nadctl dim up           # Increase brightness")
 				fmt.Println("  nadctl dim down         # Decrease brightness")
 				fmt.Println("  nadctl dim list         # List all available levels")
-				fmt.Printf("\nAvailable levels: %v\n", nadapi.GetAvailableBrightnessLevels())
-				return
+				fmt.Printf("\nAvailable levels: %v\n", caps.BrightnessLevels())
+				os.Exit(exitInvalidValue)
 			}
 
-			if !nadapi.IsValidBrightnessLevel(level) {
+			if !caps.IsValidBrightnessLevel(level) {
 				fmt.Printf("Error: Brightness level %d is not valid.\n\n", level)
 				fmt.Println("Available brightness levels:")
-				levels := nadapi.GetAvailableBrightnessLevels()
+				levels := caps.BrightnessLevels()
 				for _, l := range levels {
 					description := getBrightnessDescription(l)
 					fmt.Printf("  %d - %s\n", l, description)
 				}
-				return
+				os.Exit(exitInvalidValue)
 			}
 
-			err = client.SetBrightness(level)
+			err = client.SetBrightnessContext(ctx, level)
 			if err != nil {
 				log.WithError(err).Fatal("failed to set brightness")
 			}
@@ -22,13 +22,16 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"time"
 
 	"github.com/galamiram/nadctl/nadapi"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // discoverCmd represents the discover command
@@ -36,9 +39,25 @@ var discoverCmd = &cobra.Command{
 	Use:   "discover",
 	Short: "Discover NAD devices on the network",
 	Long: `Scan the local network for NAD devices and display their information.
-This command will search all local network interfaces for devices
-listening on the standard NAD port (30001) and verify they are
-NAD devices by querying their model information.
+This command first checks the addresses in the kernel neighbor table and
+the devices found before, which usually finds an amplifier at once. If
+none answers, it listens for devices announcing themselves over mDNS and
+SSDP, and otherwise sweeps the subnets of all local network interfaces
+for devices listening on the NAD port (30001), verifying they are NAD
+devices by querying their model information.
+
+Large networks can restrict the sweep with --subnet, --exclude and
+--interface, and bound its load with --workers and --rate. The same
+settings can be kept in the discovery section of the config file.
+
+Devices are listed as soon as they are found, and --limit stops the
+search once enough have been found. Each is shown with its firmware
+version, MAC address, host name, response time and how it was found, as
+far as these are known; --output json prints the list as JSON instead.
+
+By default only the known NAD models and devices naming themselves NAD
+are listed. --match selects other models by pattern, e.g. --match "C3*",
+or "any" to list every device answering on the NAD port.
 
 The discovery results are cached for faster subsequent operations.
 Use --no-cache to bypass the cache or --clear-cache to reset it.`,
@@ -46,6 +65,10 @@ Use --no-cache to bypass the cache or --clear-cache to reset it.`,
 		timeout, _ := cmd.Flags().GetDuration("timeout")
 		forceRefresh, _ := cmd.Flags().GetBool("refresh")
 		showCache, _ := cmd.Flags().GetBool("show-cache")
+		output, _ := cmd.Flags().GetString("output")
+		if output != "text" && output != "json" {
+			log.Fatalf("Invalid output format %q (want text or json)", output)
+		}
 
 		// Handle cache status display
 		if showCache {
@@ -56,28 +79,61 @@ Use --no-cache to bypass the cache or --clear-cache to reset it.`,
 		useCache := !noCache && !forceRefresh
 		cacheTTL := nadapi.DefaultCacheTTL
 
+		opts, err := nadapi.DiscoveryOptionsFromSettings(viper.GetViper())
+		if err != nil {
+			log.WithError(err).Fatal("Invalid discovery settings")
+		}
+		// A search of other subnets must not be answered from the cache
+		for _, flag := range []string{"subnet", "exclude", "interface", "port", "match", "full"} {
+			if cmd.Flags().Changed(flag) {
+				useCache = false
+			}
+		}
+
+		opts.MaxDevices, _ = cmd.Flags().GetInt("limit")
+		opts.SkipPrepass, _ = cmd.Flags().GetBool("full")
+
+		// Devices are printed as they are found; the list is only printed
+		// afterwards when it comes from the cache
+		live := &liveDiscovery{progress: isTerminal(os.Stderr)}
+		if output == "text" {
+			opts.Events = nadapi.DiscoveryEvents{
+				OnDevice:   live.device,
+				OnProgress: live.showProgress,
+			}
+		}
+
 		log.Info("Scanning network for NAD devices...")
-		devices, fromCache, err := nadapi.DiscoverDevicesWithCache(timeout, useCache, cacheTTL)
+		devices, fromCache, err := nadapi.DiscoverDevicesWithCacheOptions(timeout, useCache, cacheTTL, opts)
+		live.clearProgress()
 		if err != nil {
 			log.WithError(err).Fatal("Failed to discover devices")
 		}
 
+		if output == "json" {
+			if devices == nil {
+				devices = []nadapi.DiscoveredDevice{}
+			}
+			data, err := json.MarshalIndent(devices, "", "  ")
+			if err != nil {
+				log.WithError(err).Fatal("Failed to encode devices")
+			}
+			fmt.Println(string(data))
+			return
+		}
+
 		if len(devices) == 0 {
 			fmt.Println("No NAD devices found on the network")
 			return
 		}
 
-		// Show cache status
-		cacheStatus := "from network scan"
 		if fromCache {
-			cacheStatus = "from cache"
-		}
-
-		fmt.Printf("Found %d NAD device(s) (%s):\n\n", len(devices), cacheStatus)
-		for i, device := range devices {
-			fmt.Printf("%d. %s\n", i+1, device.Model)
-			fmt.Printf("   IP: %s:%s\n", device.IP, device.Port)
-			fmt.Println()
+			fmt.Printf("Found %d NAD device(s) (from cache):\n\n", len(devices))
+			for i, device := range devices {
+				printDiscoveredDevice(i+1, device)
+			}
+		} else {
+			fmt.Printf("Found %d NAD device(s) (from network scan)\n\n", len(devices))
 		}
 
 		fmt.Println("To use a specific device, set the IP in your config file or use:")
@@ -89,6 +145,67 @@ Use --no-cache to bypass the cache or --clear-cache to reset it.`,
 	},
 }
 
+// liveDiscovery prints discovered devices as they come in, with a progress
+// line on the terminal while the search goes on
+type liveDiscovery struct {
+	progress bool // whether to draw the progress line
+	found    int
+	drawn    bool // whether a progress line is on screen
+}
+
+func (l *liveDiscovery) device(device nadapi.DiscoveredDevice) {
+	l.clearProgress()
+	l.found++
+	printDiscoveredDevice(l.found, device)
+}
+
+func (l *liveDiscovery) showProgress(p nadapi.DiscoveryProgress) {
+	if !l.progress {
+		return
+	}
+	line := fmt.Sprintf("Listening for %s announcements... %d found", p.Provider, p.Found)
+	if p.Total > 0 {
+		line = fmt.Sprintf("Sweeping %s: %d/%d addresses (%d%%), %d found",
+			p.Subnet, p.Probed, p.Total, p.Probed*100/p.Total, p.Found)
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K%s", line)
+	l.drawn = true
+}
+
+func (l *liveDiscovery) clearProgress() {
+	if l.drawn {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		l.drawn = false
+	}
+}
+
+func printDiscoveredDevice(n int, device nadapi.DiscoveredDevice) {
+	fmt.Printf("%d. %s\n", n, device.Model)
+	fmt.Printf("   Address: %s\n", net.JoinHostPort(device.IP, device.Port))
+	if device.Firmware != "" {
+		fmt.Printf("   Firmware: %s\n", device.Firmware)
+	}
+	if device.MAC != "" {
+		fmt.Printf("   MAC: %s\n", device.MAC)
+	}
+	if device.Hostname != "" {
+		fmt.Printf("   Host name: %s\n", device.Hostname)
+	}
+	if device.Latency > 0 {
+		fmt.Printf("   Latency: %s\n", device.Latency.Round(time.Millisecond/10))
+	}
+	if device.Method != "" {
+		fmt.Printf("   Found by: %s\n", device.Method)
+	}
+	fmt.Println()
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 func displayCacheStatus() {
 	valid, err := nadapi.IsCacheValid()
 	if err != nil {
@@ -109,7 +226,7 @@ func displayCacheStatus() {
 
 	fmt.Printf("Cache status: %d device(s) cached\n", len(devices))
 	for i, device := range devices {
-		fmt.Printf("  %d. %s at %s:%s\n", i+1, device.Model, device.IP, device.Port)
+		fmt.Printf("  %d. %s at %s\n", i+1, device.Model, net.JoinHostPort(device.IP, device.Port))
 	}
 
 	// Try to read cache file for timestamp info
@@ -127,4 +244,23 @@ func init() {
 	discoverCmd.Flags().DurationP("timeout", "t", 30*time.Second, "Discovery timeout")
 	discoverCmd.Flags().BoolP("refresh", "r", false, "Force refresh by bypassing cache")
 	discoverCmd.Flags().Bool("show-cache", false, "Show current cache status")
+	discoverCmd.Flags().StringSlice("subnet", nil, "Sweep only these subnets or ranges, e.g. 10.2.30.0/24 or 10.2.30.10-10.2.30.50")
+	discoverCmd.Flags().StringSlice("exclude", nil, "Never sweep these subnets or ranges")
+	discoverCmd.Flags().StringSlice("interface", nil, "Sweep only the subnets of these network interfaces")
+	discoverCmd.Flags().String("port", "", "NAD control port (default 30001)")
+	discoverCmd.Flags().Int("workers", 0, "Concurrent sweep probes (default 64)")
+	discoverCmd.Flags().Int("rate", 0, "Sweep probes started per second (default unlimited)")
+	discoverCmd.Flags().IntP("limit", "n", 0, "Stop once this many devices are found (default all)")
+	discoverCmd.Flags().Bool("full", false, "Skip the quick check of known addresses and search the whole network")
+	discoverCmd.Flags().StringSlice("oui", nil, "Only check neighbors whose MAC address starts with these prefixes, e.g. 00:1a:2b")
+	discoverCmd.Flags().StringSlice("match", nil, `Only list these models: "known" (default), "any" or patterns such as "C3*"`)
+	discoverCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+	viper.BindPFlag("discovery.subnets", discoverCmd.Flags().Lookup("subnet"))
+	viper.BindPFlag("discovery.exclude", discoverCmd.Flags().Lookup("exclude"))
+	viper.BindPFlag("discovery.interfaces", discoverCmd.Flags().Lookup("interface"))
+	viper.BindPFlag("discovery.port", discoverCmd.Flags().Lookup("port"))
+	viper.BindPFlag("discovery.workers", discoverCmd.Flags().Lookup("workers"))
+	viper.BindPFlag("discovery.rate", discoverCmd.Flags().Lookup("rate"))
+	viper.BindPFlag("discovery.oui", discoverCmd.Flags().Lookup("oui"))
+	viper.BindPFlag("discovery.models", discoverCmd.Flags().Lookup("match"))
 }
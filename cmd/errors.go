@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/galamiram/nadctl/nadapi"
+	log "github.com/sirupsen/logrus"
+)
+
+// Exit codes of the CLI, telling the kinds of device errors apart
+const (
+	exitFailure      = 1 // Any other error
+	exitInvalidValue = 2
+	exitNotConnected = 3
+	exitTimeout      = 4
+	exitStandby      = 5
+	exitUnsupported  = 6
+	exitVolumeLimit  = 7
+)
+
+// errorKinds maps the kinds of device errors to exit codes and to the codes
+// of MCP tool error results. A device in standby also times out, so standby
+// is tested first.
+var errorKinds = []struct {
+	err  error
+	exit int
+	code string
+}{
+	{nadapi.ErrStandby, exitStandby, "standby"},
+	{nadapi.ErrTimeout, exitTimeout, "timeout"},
+	{nadapi.ErrNotConnected, exitNotConnected, "not_connected"},
+	{nadapi.ErrUnsupported, exitUnsupported, "unsupported"},
+	{nadapi.ErrVolumeLimit, exitVolumeLimit, "volume_limit"},
+	{nadapi.ErrSuperseded, exitFailure, "superseded"},
+	{nadapi.ErrInvalidValue, exitInvalidValue, "invalid_value"},
+}
+
+// exitCode returns the exit code for err
+func exitCode(err error) int {
+	for _, k := range errorKinds {
+		if errors.Is(err, k.err) {
+			return k.exit
+		}
+	}
+	return exitFailure
+}
+
+// errorCode returns the code of the kind of err, or "" for other errors
+func errorCode(err error) string {
+	for _, k := range errorKinds {
+		if errors.Is(err, k.err) {
+			return k.code
+		}
+	}
+	return ""
+}
+
+// exitCodeHook remembers the exit code for the error of a fatal log entry,
+// so that the commands can keep ending with log.WithError(err).Fatal
+type exitCodeHook struct {
+	code int
+}
+
+func (h *exitCodeHook) Levels() []log.Level {
+	return []log.Level{log.FatalLevel}
+}
+
+func (h *exitCodeHook) Fire(entry *log.Entry) error {
+	h.code = exitFailure
+	if err, ok := entry.Data[log.ErrorKey].(error); ok {
+		h.code = exitCode(err)
+	}
+	return nil
+}
+
+func init() {
+	hook := &exitCodeHook{}
+	log.AddHook(hook)
+	log.StandardLogger().ExitFunc = func(code int) {
+		if code == exitFailure && hook.code != 0 {
+			code = hook.code
+		}
+		os.Exit(code)
+	}
+}
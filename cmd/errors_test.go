@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/galamiram/nadctl/nadapi"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("boom"), exitFailure},
+		{fmt.Errorf("get volume: %w", nadapi.ErrTimeout), exitTimeout},
+		{fmt.Errorf("get volume: %w", nadapi.ErrNotConnected), exitNotConnected},
+		{fmt.Errorf("zone 2: %w", nadapi.ErrUnsupported), exitUnsupported},
+		{fmt.Errorf("brightness 9: %w", nadapi.ErrInvalidValue), exitInvalidValue},
+		// Standby wins over the timeout it comes with
+		{fmt.Errorf("%w: %w", nadapi.ErrTimeout, nadapi.ErrStandby), exitStandby},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestToolError(t *testing.T) {
+	result := toolError("Failed to set volume", fmt.Errorf("set volume: %w", nadapi.ErrStandby))
+	if !result.IsError || result.Meta["errorCode"] != "standby" {
+		t.Errorf("toolError() = %+v, want an error result coded standby", result)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.HasPrefix(text, "[standby] Failed to set volume: ") {
+		t.Errorf("toolError() text = %q, want it led by the code", text)
+	}
+
+	result = toolError("Failed to get playback state", errors.New("401"))
+	if result.Meta != nil || result.Content[0].(mcp.TextContent).Text != "Failed to get playback state: 401" {
+		t.Errorf("toolError() of another error = %+v, want it uncoded", result)
+	}
+}
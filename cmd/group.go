@@ -0,0 +1,127 @@
+/*
+Copyright © 2020 Gal Amiram <galamiram1@gmail.com>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/galamiram/nadctl/nadapi"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// groupRef holds the --group flag of the group-aware commands
+var groupRef string
+
+// addGroupFlag adds the --group flag to a command that can act on several
+// devices at once
+func addGroupFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&groupRef, "group", "", "group of devices to control at once: a group of the config file, all, or devices joined by + (e.g. office+kitchen)")
+}
+
+// configuredGroups returns the groups of the config file, name to member
+// specs
+func configuredGroups() map[string][]string {
+	return viper.GetStringMapStringSlice("groups")
+}
+
+// resolveGroup returns the group ref selects, see nadapi.ResolveGroup
+func resolveGroup(ref string) (nadapi.Group, error) {
+	entries, err := deviceEntries()
+	if err != nil {
+		return nadapi.Group{}, fmt.Errorf("failed to load devices: %v", err)
+	}
+	group, err := nadapi.ResolveGroup(configuredGroups(), entries, ref)
+	if err != nil {
+		return nadapi.Group{}, fmt.Errorf("%w (see the groups section of the config file)", err)
+	}
+	return group, nil
+}
+
+// runOnGroup runs run on the group chosen with --group, in the zone chosen
+// with --zone, prints the outcome on each device and exits with an error if
+// any device failed
+func runOnGroup(ctx context.Context, run func(*nadapi.GroupExecutor) []nadapi.GroupResult) {
+	group, err := resolveGroup(groupRef)
+	if err != nil {
+		log.WithError(err).Fatal("invalid group")
+	}
+	zone, err := nadapi.ParseZone(zoneName)
+	if err != nil {
+		log.WithError(err).Fatal("invalid zone")
+	}
+
+	exec := nadapi.NewGroupExecutor(group, func(ctx context.Context, m nadapi.GroupMember) (*nadapi.Device, error) {
+		return connectToSelectedDevice(ctx, m.Device)
+	})
+	exec.SetZone(zone)
+	results := run(exec)
+	exec.Close()
+
+	fmt.Print(formatGroupResults(group, results))
+	if nadapi.GroupError(results) != nil {
+		log.Fatalf("command failed on some devices of group %s", group.Name)
+	}
+}
+
+// formatGroupResults renders the outcome of a group command, one device per
+// line
+func formatGroupResults(group nadapi.Group, results []nadapi.GroupResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Group %s:\n", group.Name)
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(&b, "  ✗ %s: %v\n", r.Member.Device, r.Err)
+			continue
+		}
+		fmt.Fprintf(&b, "  ✓ %s: %s\n", r.Member.Device, r.Value)
+	}
+	return b.String()
+}
+
+// requireOnOff parses the on/off argument the group forms of toggling
+// commands need, as toggling devices in different states would leave them
+// apart
+func requireOnOff(args []string, command string) bool {
+	if len(args) == 0 {
+		log.Fatalf("use '%s on' or '%s off' with --group", command, command)
+	}
+	on, err := parseOnOffArg(args[0])
+	if err != nil {
+		log.WithError(err).Fatal("invalid argument")
+	}
+	return on
+}
+
+// parseOnOffArg parses an on/off command argument
+func parseOnOffArg(arg string) (bool, error) {
+	switch strings.ToLower(arg) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("%w: %q is not on or off", nadapi.ErrInvalidValue, arg)
+}
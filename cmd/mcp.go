@@ -2,10 +2,15 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/galamiram/nadctl/nadapi"
@@ -14,10 +19,15 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/sync/singleflight"
 )
 
 const mcpDiscoverTimeout = 5 * time.Second
 
+// maxToolFade bounds the fades of nad_volume_set, which answers once the
+// fade is over
+const maxToolFade = time.Minute
+
 // mcpCmd represents the mcp command
 var mcpCmd = &cobra.Command{
 	Use:   "mcp",
@@ -29,14 +39,26 @@ The MCP server provides tools for:
 - Volume control (set/adjust/mute)
 - Source selection and navigation
 - Display brightness adjustment
+- Tone controls (bass, treble, balance, tone defeat)
+- Zone 2 power, volume, mute and source on multi-zone receivers
 - Device discovery and status
+- Device groups (power, mute, volume and source of several devices at once)
 - Spotify device casting and playback control
 
 Example usage with Cursor or other MCP-compatible AI tools:
   nadctl mcp
 
+Every device tool takes an optional device argument selecting one of the
+devices listed by nad_device_list, so several amplifiers can be controlled
+from one server. The nad_group tools act on every device of a group listed
+by nad_group_list at once.
+
+The nad_raw tool, which sends any protocol line to the device, is only offered
+when the config file sets mcp.allow_raw to true.
+
 Environment variables:
   NAD_IP: IP address of the NAD device (default: auto-discover)
+  NAD_ADDRESS: Address URI of the NAD device, e.g. serial:///dev/ttyUSB0 (overrides NAD_IP)
   NAD_PORT: Port of the NAD device (default: 30001)
   SPOTIFY_CLIENT_ID: Spotify client ID for device casting (optional)`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -46,7 +68,7 @@ Environment variables:
 
 func init() {
 	rootCmd.AddCommand(mcpCmd)
-	mcpCmd.Flags().String("device-ip", "", "IP address of the NAD device")
+	mcpCmd.Flags().String("device-ip", "", "IP address or address URI (tcp://, serial://) of the NAD device")
 	mcpCmd.Flags().String("device-port", "30001", "Port of the NAD device")
 	viper.BindPFlag("mcp.device_ip", mcpCmd.Flags().Lookup("device-ip"))
 	viper.BindPFlag("mcp.device_port", mcpCmd.Flags().Lookup("device-port"))
@@ -79,11 +101,33 @@ func runMCPServer() {
 	}
 }
 
-func getDevice() (*nadapi.Device, error) {
+// mcpDevices are the connections shared by all requests, by device address.
+// The server is long running, so they are kept open and supervised rather
+// than dialed per call.
+var (
+	mcpDeviceMu sync.Mutex // Held only to read or store mcpDevices
+	mcpDevices  = make(map[string]*nadapi.Device)
+	mcpOpening  singleflight.Group // Shares a discovery or dial between concurrent calls
+)
+
+// getDevice returns the connection to the device ref selects, or to the
+// device of the --device flag, the MCP flags or the environment when ref is
+// empty, discovering one if none is configured
+func getDevice(ctx context.Context, ref string) (*nadapi.Device, error) {
+	if ref == "" {
+		ref = viper.GetString("device")
+	}
+	if ref != "" {
+		return connectSelected(ctx, ref, mcpConnect)
+	}
+
 	deviceIP := viper.GetString("mcp.device_ip")
 	devicePort := viper.GetString("mcp.device_port")
 
 	// Use environment variables if flags not set
+	if deviceIP == "" {
+		deviceIP = os.Getenv("NAD_ADDRESS")
+	}
 	if deviceIP == "" {
 		deviceIP = os.Getenv("NAD_IP")
 	}
@@ -95,20 +139,81 @@ func getDevice() (*nadapi.Device, error) {
 
 	// Auto-discover if no IP provided
 	if deviceIP == "" {
-		devices, err := nadapi.DiscoverDevices(mcpDiscoverTimeout)
+		return mcpShared(ctx, "", func(ctx context.Context) (*nadapi.Device, error) {
+			opts, err := nadapi.DiscoveryOptionsFromSettings(viper.GetViper())
+			if err != nil {
+				return nil, fmt.Errorf("invalid discovery settings: %v", err)
+			}
+			discoverCtx, cancel := context.WithTimeout(ctx, mcpDiscoverTimeout)
+			defer cancel()
+			devices, err := nadapi.DiscoverDevicesWithOptions(discoverCtx, opts)
+			if err != nil {
+				return nil, fmt.Errorf("device discovery failed: %v", err)
+			}
+			// Guessing among several devices could act on the wrong one
+			chosen, err := pickDiscovered(devices, false)
+			if err != nil {
+				return nil, err
+			}
+			return mcpConnect(ctx, chosen.IP, chosen.Port)
+		})
+	}
+
+	return mcpConnect(ctx, deviceIP, devicePort)
+}
+
+// mcpConnect returns the shared connection to a device, opening it on first
+// use
+func mcpConnect(ctx context.Context, addr, port string) (*nadapi.Device, error) {
+	return mcpShared(ctx, addr+"|"+port, func(ctx context.Context) (*nadapi.Device, error) {
+		device, err := nadapi.NewFromSettings(ctx, addr, port, viper.GetViper())
 		if err != nil {
-			return nil, fmt.Errorf("device discovery failed: %v", err)
+			return nil, err
 		}
-		if len(devices) == 0 {
-			return nil, fmt.Errorf("no NAD devices found on network")
+		device.Supervise(context.Background(), nadapi.SuperviseOptions{})
+		return device, nil
+	})
+}
+
+// mcpShared returns the device of mcpDevices under key, calling open to get
+// it on first use. Concurrent calls for a key share a single open, which
+// goes on for the others when one caller gives up.
+func mcpShared(ctx context.Context, key string, open func(context.Context) (*nadapi.Device, error)) (*nadapi.Device, error) {
+	mcpDeviceMu.Lock()
+	device := mcpDevices[key]
+	mcpDeviceMu.Unlock()
+	if device != nil {
+		return device, nil
+	}
+
+	openCtx := context.WithoutCancel(ctx)
+	opened := mcpOpening.DoChan(key, func() (interface{}, error) {
+		mcpDeviceMu.Lock()
+		device := mcpDevices[key] // Stored by an open that just ended
+		mcpDeviceMu.Unlock()
+		if device != nil {
+			return device, nil
 		}
-		deviceIP = devices[0].IP
-		if devices[0].Port != "" {
-			devicePort = devices[0].Port
+
+		device, err := open(openCtx)
+		if err != nil {
+			return nil, err
+		}
+		mcpDeviceMu.Lock()
+		mcpDevices[key] = device
+		mcpDeviceMu.Unlock()
+		return device, nil
+	})
+
+	select {
+	case res := <-opened:
+		if res.Err != nil {
+			return nil, res.Err
 		}
+		return res.Val.(*nadapi.Device), nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("connecting to the device: %w", ctx.Err())
 	}
-
-	return nadapi.New(deviceIP, devicePort)
 }
 
 func getMCPSpotifyClient() (*spotify.Client, error) {
@@ -135,62 +240,131 @@ func getMCPSpotifyClient() (*spotify.Client, error) {
 	return client, nil
 }
 
+// deviceOption adds the optional device argument to the device tools
+func deviceOption() mcp.ToolOption {
+	return mcp.WithString("device",
+		mcp.Description("Device to control by name, alias, index, MAC or IP address as listed by nad_device_list (default: the configured device, or the only discovered one)"),
+	)
+}
+
+// deviceArg returns the device selector of the device argument, empty when
+// it is absent
+func deviceArg(request mcp.CallToolRequest) string {
+	return request.GetString("device", "")
+}
+
+// zoneOption adds the optional zone argument to the zone-aware tools
+func zoneOption() mcp.ToolOption {
+	return mcp.WithString("zone",
+		mcp.Description("Zone to control: main (default) or zone2 on multi-zone receivers"),
+	)
+}
+
+// zoneArg returns the zone named by the zone argument, the main zone when it
+// is absent
+func zoneArg(request mcp.CallToolRequest) (nadapi.ZoneID, error) {
+	return nadapi.ParseZone(request.GetString("zone", ""))
+}
+
+// toolError returns the error result of a tool for text and err, see
+// codedToolError
+func toolError(text string, err error) *mcp.CallToolResult {
+	return codedToolError(errorCode(err), fmt.Sprintf("%s: %v", text, err))
+}
+
+// invalidParameter returns the error result of a tool for a missing or
+// invalid argument
+func invalidParameter(name string, err error) *mcp.CallToolResult {
+	return codedToolError(errorCode(nadapi.ErrInvalidValue), fmt.Sprintf("Invalid %s parameter: %v", name, err))
+}
+
+// codedToolError returns an error result led by code, the kind of the error
+// such as "standby" or "timeout" (see errorKinds), which is also set as
+// errorCode in the _meta of the result
+func codedToolError(code, text string) *mcp.CallToolResult {
+	if code == "" {
+		return mcp.NewToolResultError(text)
+	}
+	result := mcp.NewToolResultError(fmt.Sprintf("[%s] %s", code, text))
+	result.Meta = map[string]any{"errorCode": code}
+	return result
+}
+
+// groupOption adds the required group argument to the group tools
+func groupOption() mcp.ToolOption {
+	return mcp.WithString("group",
+		mcp.Required(),
+		mcp.Description("Group as listed by nad_group_list, all for every device, or devices joined by + (e.g. office+kitchen)"),
+	)
+}
+
 func registerNADTools(s *server.MCPServer) {
 	// Power Control Tools
 	s.AddTool(
-		mcp.NewTool("nad_power_on", mcp.WithDescription("Turn on the NAD audio device")),
+		mcp.NewTool("nad_power_on", mcp.WithDescription("Turn on the NAD audio device"), zoneOption(), deviceOption()),
 		handlePowerOn,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_power_off", mcp.WithDescription("Turn off the NAD audio device")),
+		mcp.NewTool("nad_power_off", mcp.WithDescription("Turn off the NAD audio device"), zoneOption(), deviceOption()),
 		handlePowerOff,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_power_toggle", mcp.WithDescription("Toggle power state of the NAD audio device")),
+		mcp.NewTool("nad_power_toggle", mcp.WithDescription("Toggle power state of the NAD audio device"), zoneOption(), deviceOption()),
 		handlePowerToggle,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_power_status", mcp.WithDescription("Get current power state of the NAD audio device")),
+		mcp.NewTool("nad_power_status", mcp.WithDescription("Get current power state of the NAD audio device"), zoneOption(), deviceOption()),
 		handlePowerStatus,
 	)
 
 	// Volume Control Tools
 	s.AddTool(
 		mcp.NewTool("nad_volume_set",
-			mcp.WithDescription("Set NAD device volume to a specific level"),
+			mcp.WithDescription("Set NAD device volume to a specific level, jumping to it or fading to it over time"),
 			mcp.WithNumber("volume",
 				mcp.Required(),
 				mcp.Description("Volume level in dB (typically -80 to +10)"),
 			),
+			mcp.WithNumber("fade_seconds",
+				mcp.Description("Fade to the level over this many seconds instead of jumping to it. Prefer a fade of a few seconds for changes of more than a few dB"),
+				mcp.Min(0),
+				mcp.Max(maxToolFade.Seconds()),
+			),
+			mcp.WithString("curve",
+				mcp.Description("Shape of the fade: linear steps evenly, logarithmic changes quickly first and eases into the level"),
+				mcp.Enum("linear", "logarithmic"),
+			),
+			zoneOption(),
+			deviceOption(),
 		),
 		handleVolumeSet,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_volume_up", mcp.WithDescription("Increase NAD device volume")),
+		mcp.NewTool("nad_volume_up", mcp.WithDescription("Increase NAD device volume"), zoneOption(), deviceOption()),
 		handleVolumeUp,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_volume_down", mcp.WithDescription("Decrease NAD device volume")),
+		mcp.NewTool("nad_volume_down", mcp.WithDescription("Decrease NAD device volume"), zoneOption(), deviceOption()),
 		handleVolumeDown,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_volume_status", mcp.WithDescription("Get current volume level")),
+		mcp.NewTool("nad_volume_status", mcp.WithDescription("Get current volume level"), zoneOption(), deviceOption()),
 		handleVolumeStatus,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_mute_toggle", mcp.WithDescription("Toggle mute state of the NAD device")),
+		mcp.NewTool("nad_mute_toggle", mcp.WithDescription("Toggle mute state of the NAD device"), zoneOption(), deviceOption()),
 		handleMuteToggle,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_mute_status", mcp.WithDescription("Get current mute status")),
+		mcp.NewTool("nad_mute_status", mcp.WithDescription("Get current mute status"), zoneOption(), deviceOption()),
 		handleMuteStatus,
 	)
 
@@ -200,30 +374,31 @@ func registerNADTools(s *server.MCPServer) {
 			mcp.WithDescription("Set NAD device input source"),
 			mcp.WithString("source",
 				mcp.Required(),
-				mcp.Description("Input source name"),
-				mcp.Enum("Stream", "Wireless", "TV", "Phono", "Coax1", "Coax2", "Opt1", "Opt2"),
+				mcp.Description("Input source name, factory or custom name as set on the amplifier (nad_source_list shows the inputs of the connected model)"),
 			),
+			zoneOption(),
+			deviceOption(),
 		),
 		handleSourceSet,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_source_next", mcp.WithDescription("Switch to next input source")),
+		mcp.NewTool("nad_source_next", mcp.WithDescription("Switch to next input source"), zoneOption(), deviceOption()),
 		handleSourceNext,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_source_previous", mcp.WithDescription("Switch to previous input source")),
+		mcp.NewTool("nad_source_previous", mcp.WithDescription("Switch to previous input source"), zoneOption(), deviceOption()),
 		handleSourcePrevious,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_source_status", mcp.WithDescription("Get current input source")),
+		mcp.NewTool("nad_source_status", mcp.WithDescription("Get current input source"), zoneOption(), deviceOption()),
 		handleSourceStatus,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_source_list", mcp.WithDescription("List all available input sources")),
+		mcp.NewTool("nad_source_list", mcp.WithDescription("List all available input sources"), deviceOption()),
 		handleSourceList,
 	)
 
@@ -233,27 +408,82 @@ func registerNADTools(s *server.MCPServer) {
 			mcp.WithDescription("Set NAD device display brightness"),
 			mcp.WithNumber("level",
 				mcp.Required(),
-				mcp.Description("Brightness level (0-3, where 0 is dimmest, 3 is brightest)"),
+				mcp.Description("Brightness level (0 is dimmest; the maximum depends on the model, usually 3)"),
 			),
+			deviceOption(),
 		),
 		handleBrightnessSet,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_brightness_up", mcp.WithDescription("Increase display brightness")),
+		mcp.NewTool("nad_brightness_up", mcp.WithDescription("Increase display brightness"), deviceOption()),
 		handleBrightnessUp,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_brightness_down", mcp.WithDescription("Decrease display brightness")),
+		mcp.NewTool("nad_brightness_down", mcp.WithDescription("Decrease display brightness"), deviceOption()),
 		handleBrightnessDown,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_brightness_status", mcp.WithDescription("Get current brightness level")),
+		mcp.NewTool("nad_brightness_status", mcp.WithDescription("Get current brightness level"), deviceOption()),
 		handleBrightnessStatus,
 	)
 
+	// Tone Control Tools
+	s.AddTool(
+		mcp.NewTool("nad_tone_status", mcp.WithDescription("Get bass, treble, balance and tone defeat"), deviceOption()),
+		handleToneStatus,
+	)
+
+	s.AddTool(
+		mcp.NewTool("nad_bass_set",
+			mcp.WithDescription("Set bass level"),
+			mcp.WithNumber("level",
+				mcp.Required(),
+				mcp.Description(fmt.Sprintf("Bass in dB (%d to %+d)", nadapi.MinTone, nadapi.MaxTone)),
+			),
+			deviceOption(),
+		),
+		handleBassSet,
+	)
+
+	s.AddTool(
+		mcp.NewTool("nad_treble_set",
+			mcp.WithDescription("Set treble level"),
+			mcp.WithNumber("level",
+				mcp.Required(),
+				mcp.Description(fmt.Sprintf("Treble in dB (%d to %+d)", nadapi.MinTone, nadapi.MaxTone)),
+			),
+			deviceOption(),
+		),
+		handleTrebleSet,
+	)
+
+	s.AddTool(
+		mcp.NewTool("nad_balance_set",
+			mcp.WithDescription("Set left/right balance"),
+			mcp.WithNumber("level",
+				mcp.Required(),
+				mcp.Description(fmt.Sprintf("Balance in dB (%d is fully left, 0 center, %+d fully right)", nadapi.MinBalance, nadapi.MaxBalance)),
+			),
+			deviceOption(),
+		),
+		handleBalanceSet,
+	)
+
+	s.AddTool(
+		mcp.NewTool("nad_tone_defeat_set",
+			mcp.WithDescription("Turn tone defeat on or off; on bypasses bass and treble"),
+			mcp.WithBoolean("enabled",
+				mcp.Required(),
+				mcp.Description("true to bypass the tone controls"),
+			),
+			deviceOption(),
+		),
+		handleToneDefeatSet,
+	)
+
 	// Device Discovery and Info Tools
 	s.AddTool(
 		mcp.NewTool("nad_discover", mcp.WithDescription("Discover NAD devices on the network")),
@@ -261,14 +491,97 @@ func registerNADTools(s *server.MCPServer) {
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_device_info", mcp.WithDescription("Get information about the connected NAD device")),
+		mcp.NewTool("nad_device_list", mcp.WithDescription("List the NAD devices the device argument of the other tools can select: the devices named in the config file and the devices seen before")),
+		handleDeviceList,
+	)
+
+	// Group Tools
+	s.AddTool(
+		mcp.NewTool("nad_group_list", mcp.WithDescription("List the device groups the group argument of the group tools can select")),
+		handleGroupList,
+	)
+
+	s.AddTool(
+		mcp.NewTool("nad_group_power",
+			mcp.WithDescription("Turn every device of a group on or off at once"),
+			groupOption(),
+			mcp.WithString("state",
+				mcp.Required(),
+				mcp.Enum("on", "off"),
+				mcp.Description("Power state to set"),
+			),
+			zoneOption(),
+		),
+		handleGroupPower,
+	)
+
+	s.AddTool(
+		mcp.NewTool("nad_group_mute",
+			mcp.WithDescription("Mute or unmute every device of a group at once"),
+			groupOption(),
+			mcp.WithBoolean("muted",
+				mcp.Required(),
+				mcp.Description("true to mute, false to unmute"),
+			),
+			zoneOption(),
+		),
+		handleGroupMute,
+	)
+
+	s.AddTool(
+		mcp.NewTool("nad_group_volume",
+			mcp.WithDescription("Set or adjust the volume of every device of a group at once, keeping the volume offsets of its devices"),
+			groupOption(),
+			mcp.WithNumber("volume",
+				mcp.Description("Volume level in dB to set (typically -80 to +10)"),
+			),
+			mcp.WithString("direction",
+				mcp.Enum("up", "down"),
+				mcp.Description("Raise or lower the volume by one step instead of setting a level"),
+			),
+			zoneOption(),
+		),
+		handleGroupVolume,
+	)
+
+	s.AddTool(
+		mcp.NewTool("nad_group_source",
+			mcp.WithDescription("Select the same input source on every device of a group"),
+			groupOption(),
+			mcp.WithString("source",
+				mcp.Required(),
+				mcp.Description("Source by factory or custom name, e.g. Stream"),
+			),
+			zoneOption(),
+		),
+		handleGroupSource,
+	)
+
+	s.AddTool(
+		mcp.NewTool("nad_device_info", mcp.WithDescription("Get information about the connected NAD device"), deviceOption()),
 		handleDeviceInfo,
 	)
 
 	s.AddTool(
-		mcp.NewTool("nad_device_status", mcp.WithDescription("Get comprehensive status of the NAD device")),
+		mcp.NewTool("nad_device_status", mcp.WithDescription("Get comprehensive status of the NAD device"), deviceOption()),
 		handleDeviceStatus,
 	)
+
+	// Raw protocol access reaches every setting of the device, so it is
+	// offered only when the config allows it
+	if viper.GetBool("mcp.allow_raw") {
+		s.AddTool(
+			mcp.NewTool("nad_raw",
+				mcp.WithDescription("Send raw NAD protocol lines and return the replies, for settings the other tools do not cover. Each line is a key followed by ? to query it, = and a value to set it, or + or - to step it, e.g. Main.Bass? or Main.Dimmer=On"),
+				mcp.WithString("lines",
+					mcp.Required(),
+					mcp.Description("One protocol line per line of text; sending stops at the first line the device does not answer"),
+				),
+				deviceOption(),
+			),
+			handleRaw,
+		)
+	}
 }
 
 func registerSpotifyTools(s *server.MCPServer) {
@@ -337,46 +650,61 @@ func registerSpotifyTools(s *server.MCPServer) {
 
 // Power Control Handlers
 func handlePowerOn(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
+	if err != nil {
+		return invalidParameter("zone", err), nil
+	}
+
+	device, err := getDevice(ctx, deviceArg(request))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return toolError("Failed to connect to device", err), nil
 	}
-	defer device.Disconnect()
+	zone := device.Zone(zoneID)
 
-	if err := device.PowerOn(); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to power on: %v", err)), nil
+	if err := zone.PowerOnContext(ctx); err != nil {
+		return toolError("Failed to power on", err), nil
 	}
 
 	return mcp.NewToolResultText("NAD device powered on successfully"), nil
 }
 
 func handlePowerOff(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return invalidParameter("zone", err), nil
 	}
-	defer device.Disconnect()
 
-	if err := device.PowerOff(); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to power off: %v", err)), nil
+	device, err := getDevice(ctx, deviceArg(request))
+	if err != nil {
+		return toolError("Failed to connect to device", err), nil
+	}
+	zone := device.Zone(zoneID)
+
+	if err := zone.PowerOffContext(ctx); err != nil {
+		return toolError("Failed to power off", err), nil
 	}
 
 	return mcp.NewToolResultText("NAD device powered off successfully"), nil
 }
 
 func handlePowerToggle(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return invalidParameter("zone", err), nil
 	}
-	defer device.Disconnect()
 
-	if err := device.PowerToggle(); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to toggle power: %v", err)), nil
+	device, err := getDevice(ctx, deviceArg(request))
+	if err != nil {
+		return toolError("Failed to connect to device", err), nil
+	}
+	zone := device.Zone(zoneID)
+
+	if err := zone.PowerToggleContext(ctx); err != nil {
+		return toolError("Failed to toggle power", err), nil
 	}
 
 	// Get new state
-	state, err := device.GetPowerState()
+	state, err := zone.GetPowerContext(ctx)
 	if err != nil {
 		return mcp.NewToolResultText("Power toggled successfully"), nil
 	}
@@ -385,15 +713,20 @@ func handlePowerToggle(ctx context.Context, request mcp.CallToolRequest) (*mcp.C
 }
 
 func handlePowerStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
+	if err != nil {
+		return invalidParameter("zone", err), nil
+	}
+
+	device, err := getDevice(ctx, deviceArg(request))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return toolError("Failed to connect to device", err), nil
 	}
-	defer device.Disconnect()
+	zone := device.Zone(zoneID)
 
-	state, err := device.GetPowerState()
+	state, err := zone.GetPowerContext(ctx)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get power state: %v", err)), nil
+		return toolError("Failed to get power state", err), nil
 	}
 
 	return mcp.NewToolResultText(fmt.Sprintf("Power state: %s", state)), nil
@@ -401,37 +734,73 @@ func handlePowerStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.C
 
 // Volume Control Handlers
 func handleVolumeSet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return invalidParameter("zone", err), nil
 	}
-	defer device.Disconnect()
+
+	device, err := getDevice(ctx, deviceArg(request))
+	if err != nil {
+		return toolError("Failed to connect to device", err), nil
+	}
+	zone := device.Zone(zoneID)
 
 	volume, err := request.RequireFloat("volume")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Invalid volume parameter: %v", err)), nil
+		return invalidParameter("volume", err), nil
 	}
 
-	if err := device.SetVolume(volume); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to set volume: %v", err)), nil
+	if seconds := request.GetFloat("fade_seconds", 0); seconds > 0 {
+		over := time.Duration(seconds * float64(time.Second))
+		if over > maxToolFade {
+			return invalidParameter("fade_seconds", fmt.Errorf("fades last at most %v", maxToolFade)), nil
+		}
+		curve, err := nadapi.ParseFadeCurve(request.GetString("curve", ""))
+		if err != nil {
+			return invalidParameter("curve", err), nil
+		}
+		fade, err := zone.FadeVolume(ctx, volume, over, curve)
+		if err != nil {
+			return toolError("Failed to fade volume", err), nil
+		}
+		var last nadapi.FadeProgress
+		for p := range fade.Progress() {
+			last = p
+		}
+		if err := fade.Wait(); err != nil {
+			if last.Step == 0 {
+				return toolError("Fade did not start", err), nil
+			}
+			return toolError(fmt.Sprintf("Fade stopped at %.1f dB", last.Volume), err), nil
+		}
+		return mcp.NewToolResultText(fmt.Sprintf("Volume faded to %.1f dB over %v", last.Volume, over)), nil
+	}
+
+	if err := zone.SetVolumeContext(ctx, volume); err != nil {
+		return toolError("Failed to set volume", err), nil
 	}
 
 	return mcp.NewToolResultText(fmt.Sprintf("Volume set to %.1f dB", volume)), nil
 }
 
 func handleVolumeUp(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
+	if err != nil {
+		return invalidParameter("zone", err), nil
+	}
+
+	device, err := getDevice(ctx, deviceArg(request))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return toolError("Failed to connect to device", err), nil
 	}
-	defer device.Disconnect()
+	zone := device.Zone(zoneID)
 
-	if err := device.TuneVolume(nadapi.DirectionUp); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to increase volume: %v", err)), nil
+	if err := zone.TuneVolumeContext(ctx, nadapi.DirectionUp); err != nil {
+		return toolError("Failed to increase volume", err), nil
 	}
 
 	// Get new volume
-	vol, err := device.GetVolume()
+	vol, err := zone.GetVolumeContext(ctx)
 	if err != nil {
 		return mcp.NewToolResultText("Volume increased"), nil
 	}
@@ -440,18 +809,23 @@ func handleVolumeUp(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 }
 
 func handleVolumeDown(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
+	if err != nil {
+		return invalidParameter("zone", err), nil
+	}
+
+	device, err := getDevice(ctx, deviceArg(request))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return toolError("Failed to connect to device", err), nil
 	}
-	defer device.Disconnect()
+	zone := device.Zone(zoneID)
 
-	if err := device.TuneVolume(nadapi.DirectionDown); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to decrease volume: %v", err)), nil
+	if err := zone.TuneVolumeContext(ctx, nadapi.DirectionDown); err != nil {
+		return toolError("Failed to decrease volume", err), nil
 	}
 
 	// Get new volume
-	vol, err := device.GetVolume()
+	vol, err := zone.GetVolumeContext(ctx)
 	if err != nil {
 		return mcp.NewToolResultText("Volume decreased"), nil
 	}
@@ -460,163 +834,217 @@ func handleVolumeDown(ctx context.Context, request mcp.CallToolRequest) (*mcp.Ca
 }
 
 func handleVolumeStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return invalidParameter("zone", err), nil
 	}
-	defer device.Disconnect()
 
-	vol, err := device.GetVolume()
+	device, err := getDevice(ctx, deviceArg(request))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get volume: %v", err)), nil
+		return toolError("Failed to connect to device", err), nil
+	}
+	zone := device.Zone(zoneID)
+
+	vol, err := zone.GetVolumeContext(ctx)
+	if err != nil {
+		return toolError("Failed to get volume", err), nil
 	}
 
 	return mcp.NewToolResultText(fmt.Sprintf("Current volume: %s dB", vol)), nil
 }
 
 func handleMuteToggle(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return invalidParameter("zone", err), nil
 	}
-	defer device.Disconnect()
 
-	if err := device.ToggleMute(); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to toggle mute: %v", err)), nil
+	device, err := getDevice(ctx, deviceArg(request))
+	if err != nil {
+		return toolError("Failed to connect to device", err), nil
+	}
+	zone := device.Zone(zoneID)
+
+	if err := zone.ToggleMuteContext(ctx); err != nil {
+		return toolError("Failed to toggle mute", err), nil
 	}
 
 	// Get new mute status
-	status, err := device.GetMuteStatus()
+	muted, err := zone.IsMutedContext(ctx)
 	if err != nil {
 		return mcp.NewToolResultText("Mute toggled"), nil
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Mute toggled. Current status: %s", status)), nil
+	return mcp.NewToolResultText(fmt.Sprintf("Mute toggled. Current status: %s", onOff(muted))), nil
 }
 
 func handleMuteStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return invalidParameter("zone", err), nil
 	}
-	defer device.Disconnect()
 
-	status, err := device.GetMuteStatus()
+	device, err := getDevice(ctx, deviceArg(request))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get mute status: %v", err)), nil
+		return toolError("Failed to connect to device", err), nil
 	}
+	zone := device.Zone(zoneID)
 
-	return mcp.NewToolResultText(fmt.Sprintf("Mute status: %s", status)), nil
+	muted, err := zone.IsMutedContext(ctx)
+	if err != nil {
+		return toolError("Failed to get mute status", err), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Mute status: %s", onOff(muted))), nil
 }
 
 // Source Control Handlers
 func handleSourceSet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return invalidParameter("zone", err), nil
 	}
-	defer device.Disconnect()
+
+	device, err := getDevice(ctx, deviceArg(request))
+	if err != nil {
+		return toolError("Failed to connect to device", err), nil
+	}
+	zone := device.Zone(zoneID)
 
 	source, err := request.RequireString("source")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Invalid source parameter: %v", err)), nil
+		return invalidParameter("source", err), nil
 	}
 
-	if err := device.SetSource(source); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to set source: %v", err)), nil
+	if err := zone.SetSourceContext(ctx, source); err != nil {
+		return toolError("Failed to set source", err), nil
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Input source set to %s", source)), nil
+	current, err := zone.CurrentInputContext(ctx)
+	if err != nil {
+		return mcp.NewToolResultText(fmt.Sprintf("Input source set to %s", source)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Input source set to %s", current.DisplayName())), nil
 }
 
 func handleSourceNext(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return invalidParameter("zone", err), nil
 	}
-	defer device.Disconnect()
 
-	newSource, err := device.ToggleSource(nadapi.DirectionUp)
+	device, err := getDevice(ctx, deviceArg(request))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to switch to next source: %v", err)), nil
+		return toolError("Failed to connect to device", err), nil
+	}
+	zone := device.Zone(zoneID)
+
+	newSource, err := zone.ToggleSourceContext(ctx, nadapi.DirectionUp)
+	if err != nil {
+		return toolError("Failed to switch to next source", err), nil
 	}
 
 	return mcp.NewToolResultText(fmt.Sprintf("Switched to next source: %s", newSource)), nil
 }
 
 func handleSourcePrevious(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
+	if err != nil {
+		return invalidParameter("zone", err), nil
+	}
+
+	device, err := getDevice(ctx, deviceArg(request))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return toolError("Failed to connect to device", err), nil
 	}
-	defer device.Disconnect()
+	zone := device.Zone(zoneID)
 
-	newSource, err := device.ToggleSource(nadapi.DirectionDown)
+	newSource, err := zone.ToggleSourceContext(ctx, nadapi.DirectionDown)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to switch to previous source: %v", err)), nil
+		return toolError("Failed to switch to previous source", err), nil
 	}
 
 	return mcp.NewToolResultText(fmt.Sprintf("Switched to previous source: %s", newSource)), nil
 }
 
 func handleSourceStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	zoneID, err := zoneArg(request)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return invalidParameter("zone", err), nil
 	}
-	defer device.Disconnect()
 
-	source, err := device.GetSource()
+	device, err := getDevice(ctx, deviceArg(request))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get source: %v", err)), nil
+		return toolError("Failed to connect to device", err), nil
 	}
+	zone := device.Zone(zoneID)
 
-	return mcp.NewToolResultText(fmt.Sprintf("Current source: %s", source)), nil
+	current, err := zone.CurrentInputContext(ctx)
+	if err != nil {
+		return toolError("Failed to get source", err), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Current source: %s", current.DisplayName())), nil
 }
 
 func handleSourceList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	sources := nadapi.GetAvailableSources()
-	return mcp.NewToolResultText(fmt.Sprintf("Available sources: %s", strings.Join(sources, ", "))), nil
+	device, err := getDevice(ctx, deviceArg(request))
+	if err != nil {
+		return toolError("Failed to connect to device", err), nil
+	}
+
+	inputs, err := device.InputsContext(ctx)
+	if err != nil {
+		return toolError("Failed to get device inputs", err), nil
+	}
+
+	var result strings.Builder
+	result.WriteString("Available sources:\n")
+	for _, in := range inputs {
+		result.WriteString(fmt.Sprintf("%d. %s", in.Index, in.DisplayName()))
+		if in.Custom != "" {
+			result.WriteString(fmt.Sprintf(" (factory name %s)", in.Name))
+		}
+		if !in.Enabled {
+			result.WriteString(" [disabled]")
+		}
+		result.WriteString("\n")
+	}
+	return mcp.NewToolResultText(strings.TrimRight(result.String(), "\n")), nil
 }
 
 // Brightness Control Handlers
 func handleBrightnessSet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	device, err := getDevice(ctx, deviceArg(request))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return toolError("Failed to connect to device", err), nil
 	}
-	defer device.Disconnect()
 
 	level, err := request.RequireFloat("level")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Invalid level parameter: %v", err)), nil
+		return invalidParameter("level", err), nil
 	}
 
 	levelInt := int(level)
-	if !nadapi.IsValidBrightnessLevel(levelInt) {
-		return mcp.NewToolResultError("Brightness level must be between 0 and 3"), nil
-	}
-
-	if err := device.SetBrightness(levelInt); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to set brightness: %v", err)), nil
+	if err := device.SetBrightnessContext(ctx, levelInt); err != nil {
+		return toolError("Failed to set brightness", err), nil
 	}
 
 	return mcp.NewToolResultText(fmt.Sprintf("Brightness set to level %d", levelInt)), nil
 }
 
 func handleBrightnessUp(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	device, err := getDevice(ctx, deviceArg(request))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return toolError("Failed to connect to device", err), nil
 	}
-	defer device.Disconnect()
 
-	if err := device.ToggleBrightness(nadapi.DirectionUp); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to increase brightness: %v", err)), nil
+	if err := device.ToggleBrightnessContext(ctx, nadapi.DirectionUp); err != nil {
+		return toolError("Failed to increase brightness", err), nil
 	}
 
 	// Get new brightness
-	brightness, err := device.GetBrightness()
+	brightness, err := device.GetBrightnessContext(ctx)
 	if err != nil {
 		return mcp.NewToolResultText("Brightness increased"), nil
 	}
@@ -625,18 +1053,17 @@ func handleBrightnessUp(ctx context.Context, request mcp.CallToolRequest) (*mcp.
 }
 
 func handleBrightnessDown(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	device, err := getDevice(ctx, deviceArg(request))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return toolError("Failed to connect to device", err), nil
 	}
-	defer device.Disconnect()
 
-	if err := device.ToggleBrightness(nadapi.DirectionDown); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to decrease brightness: %v", err)), nil
+	if err := device.ToggleBrightnessContext(ctx, nadapi.DirectionDown); err != nil {
+		return toolError("Failed to decrease brightness", err), nil
 	}
 
 	// Get new brightness
-	brightness, err := device.GetBrightness()
+	brightness, err := device.GetBrightnessContext(ctx)
 	if err != nil {
 		return mcp.NewToolResultText("Brightness decreased"), nil
 	}
@@ -645,25 +1072,97 @@ func handleBrightnessDown(ctx context.Context, request mcp.CallToolRequest) (*mc
 }
 
 func handleBrightnessStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+	device, err := getDevice(ctx, deviceArg(request))
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return toolError("Failed to connect to device", err), nil
 	}
-	defer device.Disconnect()
 
-	brightness, err := device.GetBrightness()
+	brightness, err := device.GetBrightnessContext(ctx)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get brightness: %v", err)), nil
+		return toolError("Failed to get brightness", err), nil
 	}
 
 	return mcp.NewToolResultText(fmt.Sprintf("Current brightness: level %s", brightness)), nil
 }
 
+// Tone Control Handlers
+func handleToneStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	device, err := getDevice(ctx, deviceArg(request))
+	if err != nil {
+		return toolError("Failed to connect to device", err), nil
+	}
+
+	tone, err := device.ToneContext(ctx)
+	if err != nil {
+		return toolError("Failed to get tone settings", err), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Bass: %s\nTreble: %s\nBalance: %s\nTone defeat: %s",
+		dbString(tone.Bass), dbString(tone.Treble), balanceString(tone.Balance), onOff(tone.Defeat))), nil
+}
+
+func handleBassSet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return setToneLevel(ctx, request, "bass", (*nadapi.Device).SetBassContext, dbString)
+}
+
+func handleTrebleSet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return setToneLevel(ctx, request, "treble", (*nadapi.Device).SetTrebleContext, dbString)
+}
+
+func handleBalanceSet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return setToneLevel(ctx, request, "balance", (*nadapi.Device).SetBalanceContext, balanceString)
+}
+
+// setToneLevel handles the tone level tools, which differ only in the setter
+func setToneLevel(ctx context.Context, request mcp.CallToolRequest, name string,
+	set func(*nadapi.Device, context.Context, int) error, format func(int) string) (*mcp.CallToolResult, error) {
+	device, err := getDevice(ctx, deviceArg(request))
+	if err != nil {
+		return toolError("Failed to connect to device", err), nil
+	}
+
+	level, err := request.RequireFloat("level")
+	if err != nil {
+		return invalidParameter("level", err), nil
+	}
+
+	levelInt := int(math.Round(level))
+	if err := set(device, ctx, levelInt); err != nil {
+		return toolError("Failed to set "+name, err), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("%s set to %s", strings.ToUpper(name[:1])+name[1:], format(levelInt))), nil
+}
+
+func handleToneDefeatSet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	device, err := getDevice(ctx, deviceArg(request))
+	if err != nil {
+		return toolError("Failed to connect to device", err), nil
+	}
+
+	enabled, err := request.RequireBool("enabled")
+	if err != nil {
+		return invalidParameter("enabled", err), nil
+	}
+
+	if err := device.SetToneDefeatContext(ctx, enabled); err != nil {
+		return toolError("Failed to set tone defeat", err), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("Tone defeat %s", onOff(enabled))), nil
+}
+
 // Device Discovery and Info Handlers
 func handleDiscover(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	devices, err := nadapi.DiscoverDevices(mcpDiscoverTimeout)
+	opts, err := nadapi.DiscoveryOptionsFromSettings(viper.GetViper())
+	if err != nil {
+		return toolError("Invalid discovery settings", err), nil
+	}
+	discoverCtx, cancel := context.WithTimeout(ctx, mcpDiscoverTimeout)
+	defer cancel()
+	devices, err := nadapi.DiscoverDevicesWithOptions(discoverCtx, opts)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Device discovery failed: %v", err)), nil
+		return toolError("Device discovery failed", err), nil
 	}
 
 	if len(devices) == 0 {
@@ -680,66 +1179,217 @@ func handleDiscover(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 	return mcp.NewToolResultText(result.String()), nil
 }
 
-func handleDeviceInfo(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+func handleDeviceList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	entries, err := deviceEntries()
+	if err != nil {
+		return toolError("Failed to load devices", err), nil
+	}
+	if len(entries) == 0 {
+		return mcp.NewToolResultText("No known devices yet. Use nad_discover to find devices on the network."), nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("%d device(s), select one with the device argument:\n", len(entries)))
+	for i, e := range entries {
+		model := "not seen yet"
+		address := e.Address
+		if e.Known != nil {
+			model = e.Known.Model
+			address = net.JoinHostPort(e.Known.IP, e.Known.Port)
+		}
+		result.WriteString(fmt.Sprintf("%d. %s: %s at %s\n", i+1, e.String(), model, address))
+	}
+	return mcp.NewToolResultText(result.String()), nil
+}
+
+func handleGroupList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	groups := configuredGroups()
+	var result strings.Builder
+	for _, name := range nadapi.GroupNames(groups) {
+		group, err := nadapi.ParseGroup(name, groups[name]...)
+		if err != nil {
+			result.WriteString(fmt.Sprintf("- %s: invalid: %v\n", name, err))
+			continue
+		}
+		members := make([]string, len(group.Members))
+		for i, m := range group.Members {
+			members[i] = m.String()
+		}
+		result.WriteString(fmt.Sprintf("- %s: %s\n", name, strings.Join(members, ", ")))
+	}
+	if _, ok := groups[nadapi.AllDevicesGroup]; !ok {
+		result.WriteString("- all: every device listed by nad_device_list\n")
+	}
+	result.WriteString("Members with @ and a number keep that volume offset in dB to the group volume.")
+	return mcp.NewToolResultText(result.String()), nil
+}
+
+func handleGroupPower(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	state, err := request.RequireString("state")
+	if err != nil {
+		return invalidParameter("state", err), nil
+	}
+	on, err := parseOnOffArg(state)
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return invalidParameter("state", err), nil
 	}
-	defer device.Disconnect()
+	return runMCPGroup(ctx, request, func(exec *nadapi.GroupExecutor) []nadapi.GroupResult {
+		if on {
+			return exec.PowerOn(ctx)
+		}
+		return exec.PowerOff(ctx)
+	})
+}
 
-	model, err := device.GetModel()
+func handleGroupMute(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	muted, err := request.RequireBool("muted")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get device model: %v", err)), nil
+		return invalidParameter("muted", err), nil
 	}
+	return runMCPGroup(ctx, request, func(exec *nadapi.GroupExecutor) []nadapi.GroupResult {
+		return exec.SetMute(ctx, muted)
+	})
+}
 
-	result := fmt.Sprintf("Device Info:\nIP: %s\nPort: %s\nModel: %s",
-		device.IP.String(), device.Port, model)
+func handleGroupVolume(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	switch request.GetString("direction", "") {
+	case "up":
+		return runMCPGroup(ctx, request, func(exec *nadapi.GroupExecutor) []nadapi.GroupResult {
+			return exec.TuneVolume(ctx, nadapi.DirectionUp)
+		})
+	case "down":
+		return runMCPGroup(ctx, request, func(exec *nadapi.GroupExecutor) []nadapi.GroupResult {
+			return exec.TuneVolume(ctx, nadapi.DirectionDown)
+		})
+	case "":
+	default:
+		return invalidParameter("direction", errors.New("want up or down")), nil
+	}
 
-	return mcp.NewToolResultText(result), nil
+	volume, err := request.RequireFloat("volume")
+	if err != nil {
+		return codedToolError(errorCode(nadapi.ErrInvalidValue), "Either volume or direction is required"), nil
+	}
+	return runMCPGroup(ctx, request, func(exec *nadapi.GroupExecutor) []nadapi.GroupResult {
+		return exec.SetVolume(ctx, volume)
+	})
 }
 
-func handleDeviceStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	device, err := getDevice()
+func handleGroupSource(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	source, err := request.RequireString("source")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to connect to device: %v", err)), nil
+		return invalidParameter("source", err), nil
 	}
-	defer device.Disconnect()
+	return runMCPGroup(ctx, request, func(exec *nadapi.GroupExecutor) []nadapi.GroupResult {
+		return exec.SetSource(ctx, source)
+	})
+}
 
-	// Gather all status information
-	var result strings.Builder
-	result.WriteString("NAD Device Status:\n")
+// runMCPGroup runs run on the group of the group argument and reports the
+// outcome on each device. Members use the shared connections of getDevice,
+// so the executor is not closed.
+func runMCPGroup(ctx context.Context, request mcp.CallToolRequest, run func(*nadapi.GroupExecutor) []nadapi.GroupResult) (*mcp.CallToolResult, error) {
+	zoneID, err := zoneArg(request)
+	if err != nil {
+		return invalidParameter("zone", err), nil
+	}
+	ref, err := request.RequireString("group")
+	if err != nil {
+		return invalidParameter("group", err), nil
+	}
+	group, err := resolveGroup(ref)
+	if err != nil {
+		return invalidParameter("group", err), nil
+	}
 
-	// Power
-	if power, err := device.GetPowerState(); err == nil {
-		result.WriteString(fmt.Sprintf("Power: %s\n", power))
+	exec := nadapi.NewGroupExecutor(group, func(ctx context.Context, m nadapi.GroupMember) (*nadapi.Device, error) {
+		return getDevice(ctx, m.Device)
+	})
+	exec.SetZone(zoneID)
+	results := run(exec)
+
+	text := formatGroupResults(group, results)
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
 	}
+	if failed == len(results) {
+		return codedToolError(errorCode(nadapi.GroupError(results)), text), nil
+	}
+	return mcp.NewToolResultText(text), nil
+}
 
-	// Volume
-	if volume, err := device.GetVolume(); err == nil {
-		result.WriteString(fmt.Sprintf("Volume: %s dB\n", volume))
+func handleRaw(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	text, err := request.RequireString("lines")
+	if err != nil {
+		return invalidParameter("lines", err), nil
+	}
+	lines, _ := readRawLines(strings.NewReader(text))
+	if len(lines) == 0 {
+		return invalidParameter("lines", errors.New("no protocol lines")), nil
 	}
 
-	// Mute
-	if mute, err := device.GetMuteStatus(); err == nil {
-		result.WriteString(fmt.Sprintf("Mute: %s\n", mute))
+	device, err := getDevice(ctx, deviceArg(request))
+	if err != nil {
+		return toolError("Failed to connect to device", err), nil
 	}
 
-	// Source
-	if source, err := device.GetSource(); err == nil {
-		result.WriteString(fmt.Sprintf("Source: %s\n", source))
+	replies, err := device.RawLines(ctx, lines)
+	var b strings.Builder
+	for _, reply := range replies {
+		fmt.Fprintln(&b, reply)
 	}
+	if err != nil {
+		// Keep the replies to the lines before the failure
+		return codedToolError(errorCode(err), strings.TrimSuffix(fmt.Sprintf("Failed to send raw command: %v\n%s", err, b.String()), "\n")), nil
+	}
+	return mcp.NewToolResultText(strings.TrimSuffix(b.String(), "\n")), nil
+}
 
-	// Brightness
-	if brightness, err := device.GetBrightness(); err == nil {
-		result.WriteString(fmt.Sprintf("Brightness: level %s\n", brightness))
+func handleDeviceInfo(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	device, err := getDevice(ctx, deviceArg(request))
+	if err != nil {
+		return toolError("Failed to connect to device", err), nil
 	}
 
-	// Model
-	if model, err := device.GetModel(); err == nil {
-		result.WriteString(fmt.Sprintf("Model: %s\n", model))
+	model, err := device.GetModelContext(ctx)
+	if err != nil {
+		return toolError("Failed to get device model", err), nil
 	}
 
-	result.WriteString(fmt.Sprintf("IP: %s, Port: %s", device.IP.String(), device.Port))
+	result := fmt.Sprintf("Device Info:\nAddress: %s\nModel: %s",
+		device.String(), model)
+
+	return mcp.NewToolResultText(result), nil
+}
+
+func handleDeviceStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	device, err := getDevice(ctx, deviceArg(request))
+	if err != nil {
+		return toolError("Failed to connect to device", err), nil
+	}
+
+	state, err := device.StateContext(ctx)
+	if err != nil {
+		return toolError("Failed to get device status", err), nil
+	}
+
+	var result strings.Builder
+	result.WriteString("NAD Device Status:\n")
+	result.WriteString(fmt.Sprintf("Power: %s\n", state.Power))
+	result.WriteString(fmt.Sprintf("Volume: %s\n", state.VolumeString()))
+	result.WriteString(fmt.Sprintf("Mute: %s\n", state.MuteString()))
+	result.WriteString(fmt.Sprintf("Source: %s\n", state.SourceName))
+	result.WriteString(fmt.Sprintf("Brightness: level %d\n", state.Brightness))
+	result.WriteString(fmt.Sprintf("Model: %s\n", state.Model))
+	if caps, _ := nadapi.LookupCapabilities(state.Model); caps.HasFeature(nadapi.FeatureZone2) {
+		if zs, err := device.Zone(nadapi.Zone2).StateContext(ctx); err == nil {
+			result.WriteString(fmt.Sprintf("Zone 2: %s, %.1f dB, mute %s, source %s\n", zs.Power, zs.Volume, onOff(zs.Muted), zs.SourceName))
+		}
+	}
+	result.WriteString(fmt.Sprintf("Address: %s", device.String()))
 
 	return mcp.NewToolResultText(result.String()), nil
 }
@@ -780,91 +1430,110 @@ func registerNADResources(s *server.MCPServer) {
 }
 
 func handleDeviceStatusResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-	device, err := getDevice()
+	device, err := getDevice(ctx, "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to device: %v", err)
 	}
-	defer device.Disconnect()
 
-	// Gather status
-	status := map[string]interface{}{
-		"ip":   device.IP.String(),
-		"port": device.Port,
+	state, err := device.StateContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device status: %w", err)
 	}
 
-	if power, err := device.GetPowerState(); err == nil {
-		status["power"] = power
-	}
-	if volume, err := device.GetVolumeFloat(); err == nil {
-		status["volume_db"] = volume
-	}
-	if mute, err := device.GetMuteStatus(); err == nil {
-		status["mute"] = mute
-	}
-	if source, err := device.GetSource(); err == nil {
-		status["source"] = source
-	}
-	if brightness, err := device.GetBrightnessInt(); err == nil {
-		status["brightness"] = brightness
-	}
-	if model, err := device.GetModel(); err == nil {
-		status["model"] = model
+	data, err := json.Marshal(struct {
+		nadapi.State
+		Address string `json:"address"`
+		IP      string `json:"ip,omitempty"`
+		Port    string `json:"port,omitempty"`
+	}{state, device.String(), device.Host, device.Port})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode device status: %w", err)
 	}
 
 	return []mcp.ResourceContents{
 		mcp.TextResourceContents{
 			URI:      "nad://device/status",
 			MIMEType: "application/json",
-			Text:     fmt.Sprintf("%+v", status),
+			Text:     string(data),
 		},
 	}, nil
 }
 
 func handleSourcesResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-	sources := nadapi.GetAvailableSources()
-	data := map[string]interface{}{
-		"sources": sources,
-		"count":   len(sources),
+	device, err := getDevice(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to device: %v", err)
+	}
+
+	inputs, err := device.InputsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device inputs: %w", err)
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"sources": inputs,
+		"count":   len(inputs),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode sources: %w", err)
 	}
 
 	return []mcp.ResourceContents{
 		mcp.TextResourceContents{
 			URI:      "nad://device/sources",
 			MIMEType: "application/json",
-			Text:     fmt.Sprintf("%+v", data),
+			Text:     string(data),
 		},
 	}, nil
 }
 
 func handleCapabilitiesResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-	capabilities := map[string]interface{}{
-		"volume_range": map[string]interface{}{
-			"min":  -80,
-			"max":  10,
-			"unit": "dB",
-		},
-		"brightness_range": map[string]interface{}{
-			"min": 0,
-			"max": 3,
-		},
-		"sources":      nadapi.GetAvailableSources(),
-		"power_states": []string{"On", "Off"},
-		"mute_states":  []string{"On", "Off"},
-		"operations": []string{
+	caps, err := getCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(struct {
+		nadapi.Capabilities
+		PowerStates []string `json:"power_states"`
+		MuteStates  []string `json:"mute_states"`
+		Operations  []string `json:"operations"`
+	}{
+		Capabilities: caps,
+		PowerStates:  []string{"On", "Off"},
+		MuteStates:   []string{"On", "Off"},
+		Operations: []string{
 			"power_control", "volume_control", "source_selection",
 			"mute_control", "brightness_control", "device_discovery",
 		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode capabilities: %w", err)
 	}
 
 	return []mcp.ResourceContents{
 		mcp.TextResourceContents{
 			URI:      "nad://device/capabilities",
 			MIMEType: "application/json",
-			Text:     fmt.Sprintf("%+v", capabilities),
+			Text:     string(data),
 		},
 	}, nil
 }
 
+// getCapabilities connects to the device and returns its model profile
+func getCapabilities(ctx context.Context) (nadapi.Capabilities, error) {
+	device, err := getDevice(ctx, "")
+	if err != nil {
+		return nadapi.Capabilities{}, fmt.Errorf("failed to connect to device: %v", err)
+	}
+
+	caps, err := device.CapabilitiesContext(ctx)
+	if err != nil {
+		return nadapi.Capabilities{}, fmt.Errorf("failed to get device capabilities: %w", err)
+	}
+	return caps, nil
+}
+
 func registerNADPrompts(s *server.MCPServer) {
 	// Audio setup prompt
 	s.AddPrompt(
@@ -998,6 +1667,7 @@ Volume: nad_volume_up, nad_volume_down, nad_volume_set
 Sources: nad_source_next, nad_source_previous, nad_source_set
 Mute: nad_mute_toggle
 Brightness: nad_brightness_up, nad_brightness_down, nad_brightness_set
+Tone: nad_tone_status, nad_bass_set, nad_treble_set, nad_balance_set, nad_tone_defeat_set
 Status: nad_device_status
 
 Just tell me what you'd like to do and I'll use the appropriate tool!`),
@@ -1009,12 +1679,12 @@ Just tell me what you'd like to do and I'll use the appropriate tool!`),
 func handleSpotifyDevicesList(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	client, err := getMCPSpotifyClient()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get Spotify client: %v", err)), nil
+		return toolError("Failed to get Spotify client", err), nil
 	}
 
 	devices, err := client.GetAvailableDevices()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get devices: %v", err)), nil
+		return toolError("Failed to get devices", err), nil
 	}
 
 	if len(devices) == 0 {
@@ -1045,12 +1715,12 @@ func handleSpotifyDevicesList(ctx context.Context, request mcp.CallToolRequest)
 func handleSpotifyTransferPlayback(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	client, err := getMCPSpotifyClient()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get Spotify client: %v", err)), nil
+		return toolError("Failed to get Spotify client", err), nil
 	}
 
 	deviceIdentifier, err := request.RequireString("device_identifier")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Invalid device_identifier parameter: %v", err)), nil
+		return invalidParameter("device_identifier", err), nil
 	}
 
 	// The play parameter is optional, defaults to false
@@ -1061,7 +1731,7 @@ func handleSpotifyTransferPlayback(ctx context.Context, request mcp.CallToolRequ
 	// Get available devices to find the target device
 	devices, err := client.GetAvailableDevices()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get available devices: %v", err)), nil
+		return toolError("Failed to get available devices", err), nil
 	}
 
 	if len(devices) == 0 {
@@ -1118,11 +1788,11 @@ func handleSpotifyTransferPlayback(ctx context.Context, request mcp.CallToolRequ
 func handleSpotifyPlay(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	client, err := getMCPSpotifyClient()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get Spotify client: %v", err)), nil
+		return toolError("Failed to get Spotify client", err), nil
 	}
 
 	if err := client.Play(); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to start playback: %v", err)), nil
+		return toolError("Failed to start playback", err), nil
 	}
 
 	return mcp.NewToolResultText("Spotify playback started"), nil
@@ -1131,11 +1801,11 @@ func handleSpotifyPlay(ctx context.Context, request mcp.CallToolRequest) (*mcp.C
 func handleSpotifyPause(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	client, err := getMCPSpotifyClient()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get Spotify client: %v", err)), nil
+		return toolError("Failed to get Spotify client", err), nil
 	}
 
 	if err := client.Pause(); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to pause playback: %v", err)), nil
+		return toolError("Failed to pause playback", err), nil
 	}
 
 	return mcp.NewToolResultText("Spotify playback paused"), nil
@@ -1144,11 +1814,11 @@ func handleSpotifyPause(ctx context.Context, request mcp.CallToolRequest) (*mcp.
 func handleSpotifyNext(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	client, err := getMCPSpotifyClient()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get Spotify client: %v", err)), nil
+		return toolError("Failed to get Spotify client", err), nil
 	}
 
 	if err := client.Next(); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to skip to next track: %v", err)), nil
+		return toolError("Failed to skip to next track", err), nil
 	}
 
 	return mcp.NewToolResultText("Skipped to next track"), nil
@@ -1157,11 +1827,11 @@ func handleSpotifyNext(ctx context.Context, request mcp.CallToolRequest) (*mcp.C
 func handleSpotifyPrevious(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	client, err := getMCPSpotifyClient()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get Spotify client: %v", err)), nil
+		return toolError("Failed to get Spotify client", err), nil
 	}
 
 	if err := client.Previous(); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to skip to previous track: %v", err)), nil
+		return toolError("Failed to skip to previous track", err), nil
 	}
 
 	return mcp.NewToolResultText("Skipped to previous track"), nil
@@ -1170,12 +1840,12 @@ func handleSpotifyPrevious(ctx context.Context, request mcp.CallToolRequest) (*m
 func handleSpotifyVolumeSet(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	client, err := getMCPSpotifyClient()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get Spotify client: %v", err)), nil
+		return toolError("Failed to get Spotify client", err), nil
 	}
 
 	volume, err := request.RequireFloat("volume")
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Invalid volume parameter: %v", err)), nil
+		return invalidParameter("volume", err), nil
 	}
 
 	if volume < 0 || volume > 100 {
@@ -1183,7 +1853,7 @@ func handleSpotifyVolumeSet(ctx context.Context, request mcp.CallToolRequest) (*
 	}
 
 	if err := client.SetVolume(int(volume)); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to set volume: %v", err)), nil
+		return toolError("Failed to set volume", err), nil
 	}
 
 	return mcp.NewToolResultText(fmt.Sprintf("Spotify volume set to %.0f%%", volume)), nil
@@ -1192,11 +1862,11 @@ func handleSpotifyVolumeSet(ctx context.Context, request mcp.CallToolRequest) (*
 func handleSpotifyShuffleToggle(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	client, err := getMCPSpotifyClient()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get Spotify client: %v", err)), nil
+		return toolError("Failed to get Spotify client", err), nil
 	}
 
 	if err := client.ToggleShuffle(); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to toggle shuffle: %v", err)), nil
+		return toolError("Failed to toggle shuffle", err), nil
 	}
 
 	return mcp.NewToolResultText("Spotify shuffle mode toggled"), nil
@@ -1205,12 +1875,12 @@ func handleSpotifyShuffleToggle(ctx context.Context, request mcp.CallToolRequest
 func handleSpotifyStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	client, err := getMCPSpotifyClient()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get Spotify client: %v", err)), nil
+		return toolError("Failed to get Spotify client", err), nil
 	}
 
 	state, err := client.GetPlaybackState()
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to get Spotify status: %v", err)), nil
+		return toolError("Failed to get Spotify status", err), nil
 	}
 
 	var result strings.Builder
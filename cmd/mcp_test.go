@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/galamiram/nadctl/nadapi"
+)
+
+func TestMCPSharedOpensOnce(t *testing.T) {
+	const key = "test|30001"
+	t.Cleanup(func() {
+		mcpDeviceMu.Lock()
+		delete(mcpDevices, key)
+		mcpDeviceMu.Unlock()
+	})
+
+	var opens atomic.Int32
+	release := make(chan struct{})
+	open := func(ctx context.Context) (*nadapi.Device, error) {
+		opens.Add(1)
+		<-release
+		return &nadapi.Device{}, nil
+	}
+
+	// A caller giving up does not fail the open for the others
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := mcpShared(cancelled, key, open); !errors.Is(err, context.Canceled) {
+		t.Errorf("mcpShared() with a cancelled context error = %v, want context.Canceled", err)
+	}
+
+	var wg sync.WaitGroup
+	devices := make([]*nadapi.Device, 4)
+	for i := range devices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			device, err := mcpShared(context.Background(), key, open)
+			if err != nil {
+				t.Errorf("mcpShared() unexpected error: %v", err)
+			}
+			devices[i] = device
+		}()
+	}
+	close(release)
+	wg.Wait()
+
+	if n := opens.Load(); n != 1 {
+		t.Errorf("concurrent mcpShared() opened the device %d times, want once", n)
+	}
+	for i, device := range devices {
+		if device == nil || device != devices[0] {
+			t.Errorf("mcpShared() call %d returned %p, want the shared %p", i, device, devices[0])
+		}
+	}
+}
@@ -24,47 +24,87 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/galamiram/nadctl/nadapi"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 // muteCmd represents the mute command
 var muteCmd = &cobra.Command{
-	Use:   "mute",
+	Use:   "mute [on|off]",
 	Short: "Toggle mute on and off",
-	Long: `Toggle the mute state of the NAD device.
+	Long: `Toggle the mute state of the NAD device, or mute or unmute it.
 
-This command will automatically detect the current mute state
-and switch it to the opposite state (muted->unmuted or unmuted->muted).
+Without an argument this command will automatically detect the current
+mute state and switch it to the opposite state (muted->unmuted or
+unmuted->muted). With --group it mutes or unmutes every device of the
+group at once.
 
 Examples:
-  nadctl mute               # Toggle mute state`,
+  nadctl mute               # Toggle mute state
+  nadctl mute on            # Mute the device
+  nadctl mute --zone zone2  # Toggle Zone 2 mute
+  nadctl mute on --group downstairs    # Mute the devices of a group`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"on", "off"},
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := connectToDevice()
+		ctx := cmd.Context()
+		if groupRef != "" {
+			muted := requireOnOff(args, "mute")
+			runOnGroup(ctx, func(exec *nadapi.GroupExecutor) []nadapi.GroupResult {
+				return exec.SetMute(ctx, muted)
+			})
+			return
+		}
+
+		client, err := connectToDevice(ctx)
 		if err != nil {
 			log.WithError(err).Fatal("could not connect to device")
 		}
 		defer client.Disconnect()
 
+		zone, err := selectedZone(client)
+		if err != nil {
+			log.WithError(err).Fatal("invalid zone")
+		}
+
+		if len(args) == 1 {
+			muted, err := parseOnOffArg(args[0])
+			if err != nil {
+				log.WithError(err).Fatal("invalid argument")
+			}
+			if err := zone.SetMuteContext(ctx, muted); err != nil {
+				log.WithError(err).Fatal("failed to set mute")
+			}
+			fmt.Printf("Mute: %s\n", onOff(muted))
+			return
+		}
+
 		// Get current state to show what we're doing
-		currentState, err := client.GetMuteStatus()
+		muted, err := zone.IsMutedContext(ctx)
 		if err != nil {
 			log.WithError(err).Fatal("failed to get current mute state")
 		}
 
-		err = client.ToggleMute()
+		err = zone.ToggleMuteContext(ctx)
 		if err != nil {
 			log.WithError(err).Fatal("failed to toggle mute")
 		}
 
-		newState := "On"
-		if currentState == "On" {
-			newState = "Off"
-		}
-		fmt.Printf("Mute toggled: %s -> %s\n", currentState, newState)
+		fmt.Printf("Mute toggled: %s -> %s\n", onOff(muted), onOff(!muted))
 	},
 }
 
+// onOff renders a boolean setting the way the device spells it
+func onOff(b bool) string {
+	if b {
+		return "On"
+	}
+	return "Off"
+}
+
 func init() {
 	rootCmd.AddCommand(muteCmd)
+	addZoneFlag(muteCmd)
+	addGroupFlag(muteCmd)
 }
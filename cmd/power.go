@@ -26,40 +26,83 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+
+	"github.com/galamiram/nadctl/nadapi"
 )
 
 // powerCmd represents the power command
 var powerCmd = &cobra.Command{
-	Use:   "power",
+	Use:   "power [on|off]",
 	Short: "Toggle power on and off",
-	Long: `Toggle the power state of the NAD device.
+	Long: `Toggle the power state of the NAD device, or turn it on or off.
 
-This command will automatically detect the current power state
-and switch it to the opposite state (on->off or off->on).
+Without an argument this command will automatically detect the current
+power state and switch it to the opposite state (on->off or off->on).
+With --group it turns every device of the group on or off at once.
 
 Examples:
-  nadctl power              # Toggle power state`,
+  nadctl power              # Toggle power state
+  nadctl power on           # Turn the device on
+  nadctl power --zone zone2 # Toggle Zone 2 power
+  nadctl power off --group all         # Turn every device off
+  nadctl power on --group downstairs   # Turn on the devices of a group`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"on", "off"},
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := connectToDevice()
+		ctx := cmd.Context()
+		if groupRef != "" {
+			on := requireOnOff(args, "power")
+			runOnGroup(ctx, func(exec *nadapi.GroupExecutor) []nadapi.GroupResult {
+				if on {
+					return exec.PowerOn(ctx)
+				}
+				return exec.PowerOff(ctx)
+			})
+			return
+		}
+
+		client, err := connectToDevice(ctx)
 		if err != nil {
 			log.WithError(err).Fatal("could not connect to device")
 		}
 		defer client.Disconnect()
 
+		zone, err := selectedZone(client)
+		if err != nil {
+			log.WithError(err).Fatal("invalid zone")
+		}
+
+		if len(args) == 1 {
+			on, err := parseOnOffArg(args[0])
+			if err != nil {
+				log.WithError(err).Fatal("invalid argument")
+			}
+			if on {
+				err = zone.PowerOnContext(ctx)
+			} else {
+				err = zone.PowerOffContext(ctx)
+			}
+			if err != nil {
+				log.WithError(err).Fatal("failed to set power")
+			}
+			fmt.Printf("Power: %s\n", onOff(on))
+			return
+		}
+
 		// Get current state to show what we're doing
-		currentState, err := client.GetPowerState()
+		currentState, err := zone.GetPowerContext(ctx)
 		if err != nil {
 			log.WithError(err).Fatal("failed to get current power state")
 		}
 
-		err = client.PowerToggle()
+		err = zone.PowerToggleContext(ctx)
 		if err != nil {
 			log.WithError(err).Fatal("failed to toggle power")
 		}
 
-		newState := "On"
-		if currentState == "On" {
-			newState = "Off"
+		newState := nadapi.PowerOn
+		if currentState == nadapi.PowerOn {
+			newState = nadapi.PowerOff
 		}
 		fmt.Printf("Power toggled: %s -> %s\n", currentState, newState)
 	},
@@ -67,4 +110,6 @@ Examples:
 
 func init() {
 	rootCmd.AddCommand(powerCmd)
+	addZoneFlag(powerCmd)
+	addGroupFlag(powerCmd)
 }
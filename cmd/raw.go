@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// rawCmd represents the raw command
+var rawCmd = &cobra.Command{
+	Use:   "raw [LINE...]",
+	Short: "Send raw NAD protocol commands",
+	Long: `Send NAD protocol lines to the device as they are and print its replies,
+for the keys the other commands do not cover.
+
+Each line is a key followed by ? to query it, = and a value to set it, or
++ or - to step it. Without lines, or with -, the lines are read from stdin,
+one per line, skipping blank lines and lines starting with #. Sending stops at
+the first line the device does not answer.
+
+Quote the lines, as the shell treats ? and = specially.
+
+Examples:
+  nadctl raw 'Main.Bass?'                  # Query the bass level
+  nadctl raw 'Main.Dimmer=On' 'Main.Dimmer?'  # Set and read back
+  nadctl raw < commands.txt                # Send a batch of commands`,
+	Run: func(cmd *cobra.Command, args []string) {
+		lines := args
+		if len(args) == 0 || (len(args) == 1 && args[0] == "-") {
+			var err error
+			if lines, err = readRawLines(cmd.InOrStdin()); err != nil {
+				log.WithError(err).Fatal("failed to read commands")
+			}
+		}
+		if len(lines) == 0 {
+			log.Fatal("no commands to send")
+		}
+
+		ctx := cmd.Context()
+		client, err := connectToDevice(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("could not connect to device")
+		}
+		defer client.Disconnect()
+
+		replies, err := client.RawLines(ctx, lines)
+		for _, reply := range replies {
+			fmt.Println(reply)
+		}
+		if err != nil {
+			log.WithError(err).Fatal("command failed")
+		}
+	},
+}
+
+// readRawLines reads the protocol lines of a batch, skipping blank lines and
+// # comments
+func readRawLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func init() {
+	rootCmd.AddCommand(rawCmd)
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadRawLines(t *testing.T) {
+	input := "# Bass up\nMain.Bass=2\n\n  Main.Bass?  \r\n#Main.Treble?\n"
+	lines, err := readRawLines(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readRawLines() error = %v", err)
+	}
+	if want := []string{"Main.Bass=2", "Main.Bass?"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("readRawLines() = %q, want %q", lines, want)
+	}
+}
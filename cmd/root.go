@@ -22,15 +22,22 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mitchellh/go-homedir"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
 	"github.com/galamiram/nadctl/nadapi"
@@ -67,23 +74,90 @@ var rootCmd = &cobra.Command{
 			}
 		}
 
-		device, err := connectToDevice()
+		device, err := connectToDevice(cmd.Context())
 		if err != nil {
 			log.WithError(err).Fatal("Failed to connect to device")
 		}
-		log.WithField("ip", device.IP).Info("Successfully connected to NAD device")
+		log.WithField("device", device.String()).Info("Successfully connected to NAD device")
 	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	rootCmd.SetArgs(negativeNumberArgs(rootCmd, os.Args[1:]))
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
+// negativeNumberArgs moves negative numbers given as arguments, e.g. the -20
+// of "volume set -20", behind a "--" so that they are not taken for flags.
+// The flags after the first such number are kept before the "--".
+func negativeNumberArgs(root *cobra.Command, args []string) []string {
+	cmd, _, err := root.Find(args)
+	if err != nil {
+		return args
+	}
+	takesValue := func(arg string) bool {
+		var flag *pflag.Flag
+		switch {
+		case strings.Contains(arg, "="):
+			return false
+		case strings.HasPrefix(arg, "--"):
+			flag = cmd.Flags().Lookup(arg[2:])
+			if flag == nil {
+				flag = cmd.InheritedFlags().Lookup(arg[2:])
+			}
+		case len(arg) == 2 && arg[0] == '-':
+			flag = cmd.Flags().ShorthandLookup(arg[1:])
+			if flag == nil {
+				flag = cmd.InheritedFlags().ShorthandLookup(arg[1:])
+			}
+		}
+		return flag != nil && flag.NoOptDefVal == ""
+	}
+	isNumber := func(arg string) bool {
+		_, err := strconv.ParseFloat(arg, 64)
+		return strings.HasPrefix(arg, "-") && err == nil
+	}
+
+	first := -1
+	for i, arg := range args {
+		if arg == "--" {
+			return args
+		}
+		if isNumber(arg) && (i == 0 || !takesValue(args[i-1])) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return args
+	}
+
+	flags := append([]string{}, args[:first]...)
+	var positional []string
+	for i := first; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			positional = append(positional, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "-") && len(arg) > 1 && !isNumber(arg):
+			flags = append(flags, arg)
+			if takesValue(arg) && i+1 < len(args) {
+				i++
+				flags = append(flags, args[i])
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	return append(append(flags, "--"), positional...)
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.nadctl.yaml)")
@@ -93,6 +167,8 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&debugMode, "debug-mode", false, "enable debug mode")
 	rootCmd.PersistentFlags().BoolVar(&demoMode, "demo", false, "enable demo mode (TUI without NAD device)")
 	rootCmd.PersistentFlags().BoolVar(&logToFile, "log-to-file", false, "enable logging to file")
+	rootCmd.PersistentFlags().String("device", "", "device to control, by name, alias, index, MAC or address (see 'nadctl devices')")
+	viper.BindPFlag("device", rootCmd.PersistentFlags().Lookup("device"))
 
 	// Handle clear cache flag
 	cobra.OnInitialize(func() {
@@ -151,17 +227,19 @@ func initConfig() {
 
 	// Log some key configuration values in debug mode
 	if debug {
-		ip := viper.GetString("ip")
-		if ip != "" {
-			log.WithField("ip", ip).Debug("IP address configured")
+		if addr := nadapi.AddressFromSettings(viper.GetViper()); addr != "" {
+			log.WithField("address", addr).Debug("Device address configured")
 		} else {
-			log.Debug("No IP address configured")
+			log.Debug("No device address configured")
 		}
 
 		// Check environment variables
 		if nadIP := os.Getenv("NAD_IP"); nadIP != "" {
 			log.WithField("NAD_IP", nadIP).Debug("NAD_IP environment variable set")
 		}
+		if nadAddress := os.Getenv("NAD_ADDRESS"); nadAddress != "" {
+			log.WithField("NAD_ADDRESS", nadAddress).Debug("NAD_ADDRESS environment variable set")
+		}
 		if nadDebug := os.Getenv("NAD_DEBUG"); nadDebug != "" {
 			log.WithField("NAD_DEBUG", nadDebug).Debug("NAD_DEBUG environment variable set")
 		}
@@ -170,10 +248,14 @@ func initConfig() {
 	log.Debug("Configuration initialization completed")
 }
 
-// connectToDevice establishes a connection to a NAD device, with automatic discovery if no IP is configured
-func connectToDevice() (*nadapi.Device, error) {
-	ip := viper.GetString("ip")
-	log.WithField("configuredIP", ip).Debug("Checking for configured IP address")
+// connectToDevice establishes a connection to a NAD device, with automatic discovery if no address is configured
+func connectToDevice(ctx context.Context) (*nadapi.Device, error) {
+	if ref := viper.GetString("device"); ref != "" {
+		return connectToSelectedDevice(ctx, ref)
+	}
+
+	ip, port := nadapi.AddressFromSettings(viper.GetViper()), ""
+	log.WithField("configuredAddress", ip).Debug("Checking for configured device address")
 
 	if ip == "" {
 		log.Debug("No IP address configured, proceeding with device discovery")
@@ -193,8 +275,13 @@ func connectToDevice() (*nadapi.Device, error) {
 			}
 		}
 
+		opts, err := nadapi.DiscoveryOptionsFromSettings(viper.GetViper())
+		if err != nil {
+			return nil, fmt.Errorf("invalid discovery settings: %v", err)
+		}
+
 		log.Debug("Starting device discovery with cache")
-		devices, fromCache, err := nadapi.DiscoverDevicesWithCache(30*time.Second, useCache, cacheTTL)
+		devices, fromCache, err := nadapi.DiscoverDevicesWithCacheOptions(30*time.Second, useCache, cacheTTL, opts)
 		if err != nil {
 			log.WithError(err).Debug("Device discovery failed")
 			return nil, fmt.Errorf("failed to discover devices: %v", err)
@@ -205,13 +292,12 @@ func connectToDevice() (*nadapi.Device, error) {
 			"fromCache":   fromCache,
 		}).Debug("Device discovery completed")
 
-		if len(devices) == 0 {
-			log.Debug("No NAD devices found during discovery")
-			return nil, fmt.Errorf("no NAD devices found on the network. Please specify an IP address manually")
+		chosen, err := pickDiscovered(devices, true)
+		if err != nil {
+			return nil, err
 		}
-
-		ip = devices[0].IP
-		log.WithField("selectedIP", ip).Debug("Selected first discovered device")
+		ip, port = chosen.IP, chosen.Port
+		log.WithField("selectedIP", ip).Debug("Selected discovered device")
 
 		if debug {
 			cacheStatus := "from network scan"
@@ -222,24 +308,19 @@ func connectToDevice() (*nadapi.Device, error) {
 			if len(devices) == 1 {
 				log.WithFields(log.Fields{
 					"ip":     ip,
-					"model":  devices[0].Model,
+					"model":  chosen.Model,
 					"source": cacheStatus,
 				}).Info("Automatically discovered and using NAD device")
 			} else {
-				log.WithFields(log.Fields{
-					"count":  len(devices),
-					"source": cacheStatus,
-				}).Info("Multiple NAD devices found, using first one")
-
 				log.WithField("devices", devices).Debug("All discovered devices")
 			}
 		}
 	} else {
-		log.WithField("ip", ip).Debug("Using configured IP address")
+		log.WithField("address", ip).Debug("Using configured device address")
 	}
 
 	log.WithField("ip", ip).Debug("Establishing connection to NAD device")
-	device, err := nadapi.New(ip, "")
+	device, err := nadapi.NewFromSettings(ctx, ip, port, viper.GetViper())
 	if err != nil {
 		log.WithError(err).WithField("ip", ip).Debug("Failed to connect to NAD device")
 		return nil, err
@@ -249,6 +330,116 @@ func connectToDevice() (*nadapi.Device, error) {
 	return device, nil
 }
 
+// pickDiscovered returns the device to use of those discovery found. Several
+// devices are ambiguous: with firstOfMany the first one is used with a
+// warning, otherwise the error lists them to choose from.
+func pickDiscovered(devices []nadapi.DiscoveredDevice, firstOfMany bool) (nadapi.DiscoveredDevice, error) {
+	switch {
+	case len(devices) == 0:
+		log.Debug("No NAD devices found during discovery")
+		return nadapi.DiscoveredDevice{}, fmt.Errorf("%w: no NAD devices found on the network. Please specify an IP address manually", nadapi.ErrNotConnected)
+	case len(devices) == 1:
+		return devices[0], nil
+	case firstOfMany:
+		log.WithFields(log.Fields{
+			"count": len(devices),
+			"using": devices[0].IP,
+		}).Warn("Multiple NAD devices found, using the first one; choose another with --device (see 'nadctl devices')")
+		return devices[0], nil
+	}
+	candidates := make([]string, len(devices))
+	for i, dev := range devices {
+		candidates[i] = fmt.Sprintf("%s at %s", dev.Model, net.JoinHostPort(dev.IP, dev.Port))
+	}
+	return nadapi.DiscoveredDevice{}, fmt.Errorf("found %d NAD devices, choose one: %s", len(devices), strings.Join(candidates, ", "))
+}
+
+// deviceEntries returns the devices --device selects from: the devices
+// named in the config file and the known devices
+func deviceEntries() ([]nadapi.DeviceEntry, error) {
+	return nadapi.DeviceEntries(viper.GetStringMapString("devices"))
+}
+
+// selectDevice returns the address of the device ref selects, see
+// nadapi.SelectDevice. An IP address or address URI that selects no device
+// is used as it is.
+func selectDevice(ref string) (nadapi.DeviceEntry, error) {
+	entries, err := deviceEntries()
+	if err != nil {
+		return nadapi.DeviceEntry{}, fmt.Errorf("failed to load devices: %v", err)
+	}
+	entry, err := nadapi.SelectDevice(entries, ref)
+	if errors.Is(err, nadapi.ErrUnknownDevice) {
+		if _, addrErr := netip.ParseAddr(ref); addrErr == nil || strings.Contains(ref, "://") {
+			log.WithField("address", ref).Debug("Device is not known, using it as an address")
+			return nadapi.DeviceEntry{Address: ref}, nil
+		}
+	}
+	if err != nil {
+		return nadapi.DeviceEntry{}, fmt.Errorf("%w (see 'nadctl devices' for the devices to choose from)", err)
+	}
+	return entry, nil
+}
+
+// connectToSelectedDevice connects to the device ref selects, searching the
+// network for a known device that no longer answers at its last address
+func connectToSelectedDevice(ctx context.Context, ref string) (*nadapi.Device, error) {
+	return connectSelected(ctx, ref, func(ctx context.Context, addr, port string) (*nadapi.Device, error) {
+		return nadapi.NewFromSettings(ctx, addr, port, viper.GetViper())
+	})
+}
+
+// connectSelected is connectToSelectedDevice connecting with connect
+func connectSelected(ctx context.Context, ref string, connect func(ctx context.Context, addr, port string) (*nadapi.Device, error)) (*nadapi.Device, error) {
+	entry, err := selectDevice(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"device":  entry.String(),
+		"address": entry.Address,
+	}).Debug("Connecting to selected device")
+	device, err := connect(ctx, entry.Address, entry.Port)
+	if err == nil || entry.Known == nil {
+		return device, err
+	}
+	log.WithError(err).WithField("device", entry.String()).Debug("Known device did not answer at its last address")
+
+	opts, optsErr := nadapi.DiscoveryOptionsFromSettings(viper.GetViper())
+	if optsErr != nil {
+		return nil, fmt.Errorf("invalid discovery settings: %v", optsErr)
+	}
+	moved, relocateErr := nadapi.RelocateKnownDevice(30*time.Second, entry.Known.ID, opts)
+	if relocateErr != nil {
+		return nil, fmt.Errorf("%w; %v", err, relocateErr)
+	}
+	log.WithFields(log.Fields{
+		"device":  entry.String(),
+		"from":    entry.Address,
+		"address": moved.IP,
+	}).Info("Known device moved to a new address")
+	return connect(ctx, moved.IP, moved.Port)
+}
+
+// zoneName holds the --zone flag of the zone-aware commands
+var zoneName string
+
+// addZoneFlag adds the --zone flag to a command that acts on one zone
+func addZoneFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&zoneName, "zone", "main", "zone to control (main, zone2)")
+}
+
+// selectedZone returns the zone of client chosen with --zone
+func selectedZone(client *nadapi.Device) (*nadapi.Zone, error) {
+	id, err := nadapi.ParseZone(zoneName)
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("zone", id).Debug("Using zone")
+	return client.Zone(id), nil
+}
+
 // setupFileLogging configures file logging in addition to console logging
 func setupFileLogging() error {
 	return setupFileLoggingWithConsole(true)
@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -38,6 +41,25 @@ func TestRootCmdMetadata(t *testing.T) {
 	}
 }
 
+func TestNegativeNumberArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"volume", "set", "-20"}, []string{"volume", "set", "--", "-20"}},
+		{[]string{"volume", "fade", "-40", "--over", "10s"}, []string{"volume", "fade", "--over", "10s", "--", "-40"}},
+		{[]string{"volume", "fade", "--over", "10s", "-40", "--curve=log"}, []string{"volume", "fade", "--over", "10s", "--curve=log", "--", "-40"}},
+		{[]string{"volume", "fade", "-40", "--debug", "--device", "office"}, []string{"volume", "fade", "--debug", "--device", "office", "--", "-40"}},
+		{[]string{"volume", "--", "-20"}, []string{"volume", "--", "-20"}},
+		{[]string{"power", "on", "--device", "office"}, []string{"power", "on", "--device", "office"}},
+	}
+	for _, tt := range tests {
+		if got := negativeNumberArgs(rootCmd, tt.args); !slices.Equal(got, tt.want) {
+			t.Errorf("negativeNumberArgs(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
 // Test environment variable handling
 func TestEnvironmentVariables(t *testing.T) {
 	// Save original environment
@@ -139,6 +161,27 @@ func TestConnectToDeviceDiscoveryLogic(t *testing.T) {
 	t.Log("Discovery logic validation works correctly - skipping actual network connection to avoid timeout")
 }
 
+func TestPickDiscovered(t *testing.T) {
+	first := nadapi.DiscoveredDevice{IP: "192.168.1.100", Model: "C338", Port: "30001"}
+	second := nadapi.DiscoveredDevice{IP: "192.168.1.101", Model: "M10", Port: "30001"}
+
+	if _, err := pickDiscovered(nil, true); !errors.Is(err, nadapi.ErrNotConnected) {
+		t.Errorf("pickDiscovered(none) error = %v, want ErrNotConnected", err)
+	}
+	if dev, err := pickDiscovered([]nadapi.DiscoveredDevice{first, second}, true); err != nil || dev != first {
+		t.Errorf("pickDiscovered(two, firstOfMany) = %+v, %v, want the first", dev, err)
+	}
+	if dev, err := pickDiscovered([]nadapi.DiscoveredDevice{second}, false); err != nil || dev != second {
+		t.Errorf("pickDiscovered(one) = %+v, %v, want it", dev, err)
+	}
+
+	// Without a choice, the candidates are listed
+	_, err := pickDiscovered([]nadapi.DiscoveredDevice{first, second}, false)
+	if err == nil || !strings.Contains(err.Error(), "C338 at 192.168.1.100:30001, M10 at 192.168.1.101:30001") {
+		t.Errorf("pickDiscovered(two) error = %v, want both devices listed", err)
+	}
+}
+
 // Test command structure
 func TestCommandStructure(t *testing.T) {
 	// Verify expected subcommands exist
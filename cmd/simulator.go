@@ -25,14 +25,18 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
+	"github.com/galamiram/nadctl/nadapi"
 	"github.com/galamiram/nadctl/simulator"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var simulatorPort string
+var simulatorModel string
+var simulatorPTY bool
 
 // simulatorCmd represents the simulator command
 var simulatorCmd = &cobra.Command{
@@ -46,8 +50,8 @@ and responds to all supported commands with realistic behavior.
 
 The simulator maintains state for:
 - Power (On/Off)
-- Volume (-80 to +10 dB)
-- Source (Stream, Wireless, TV, Phono, Coax1, Coax2, Opt1, Opt2)
+- Volume (within the range of the simulated model)
+- Source (the inputs of the simulated model)
 - Mute (On/Off)  
 - Brightness (0-3)
 - Device model
@@ -55,6 +59,8 @@ The simulator maintains state for:
 Examples:
   nadctl simulator                    # Start simulator on port 30001
   nadctl simulator --port 30002       # Start on custom port
+  nadctl simulator --model C658       # Simulate a C658 instead of a C338
+  nadctl simulator --pty              # Serve on a pseudo terminal like an RS-232 port (Linux)
   
 Then in another terminal:
   NAD_IP=127.0.0.1 nadctl tui         # Connect TUI to simulator
@@ -67,9 +73,16 @@ Then in another terminal:
 		log.Info("🎵 Starting NAD Device Simulator...")
 
 		// Create and start simulator
-		sim := simulator.NewNADSimulator()
-
-		if err := sim.Start(simulatorPort); err != nil {
+		sim := simulator.NewNADSimulatorForModel(simulatorModel)
+
+		env := "NAD_IP=127.0.0.1"
+		if simulatorPTY {
+			path, err := sim.StartPTY()
+			if err != nil {
+				log.WithError(err).Fatal("Failed to start simulator")
+			}
+			env = "NAD_ADDRESS=serial://" + path
+		} else if err := sim.Start(simulatorPort); err != nil {
 			log.WithError(err).Fatal("Failed to start simulator")
 		}
 
@@ -78,12 +91,12 @@ Then in another terminal:
 		fmt.Println("📱 NAD Device Simulator is running!")
 		fmt.Println()
 		fmt.Println("🔗 To connect your TUI:")
-		fmt.Printf("   NAD_IP=127.0.0.1 %s tui\n", os.Args[0])
+		fmt.Printf("   %s %s tui\n", env, os.Args[0])
 		fmt.Println()
 		fmt.Println("🔧 To test CLI commands:")
-		fmt.Printf("   NAD_IP=127.0.0.1 %s power\n", os.Args[0])
-		fmt.Printf("   NAD_IP=127.0.0.1 %s volume up\n", os.Args[0])
-		fmt.Printf("   NAD_IP=127.0.0.1 %s source next\n", os.Args[0])
+		fmt.Printf("   %s %s power\n", env, os.Args[0])
+		fmt.Printf("   %s %s volume up\n", env, os.Args[0])
+		fmt.Printf("   %s %s source next\n", env, os.Args[0])
 		fmt.Println()
 		fmt.Println("⏹️  Press Ctrl+C to stop the simulator")
 		fmt.Println()
@@ -106,4 +119,6 @@ Then in another terminal:
 func init() {
 	rootCmd.AddCommand(simulatorCmd)
 	simulatorCmd.Flags().StringVar(&simulatorPort, "port", "30001", "Port to listen on")
+	simulatorCmd.Flags().BoolVar(&simulatorPTY, "pty", false, "Serve on a pseudo terminal instead of TCP to test the serial transport")
+	simulatorCmd.Flags().StringVar(&simulatorModel, "model", "C338", fmt.Sprintf("Model to simulate (%s)", strings.Join(nadapi.KnownModels(), ", ")))
 }
@@ -22,10 +22,13 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/galamiram/nadctl/nadapi"
+	"github.com/galamiram/nadctl/nadapi/protocol"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -36,37 +39,58 @@ var sourceCmd = &cobra.Command{
 	Short: "Set or get input source",
 	Long: `Set the input source to a specific source or cycle through sources.
 
-Available sources: Stream, Wireless, TV, Phono, Coax1, Coax2, Opt1, Opt2
+Available sources depend on the model; use 'nadctl source list' to see them.
+Inputs renamed on the amplifier can be selected by their custom name, and
+inputs disabled on the amplifier are skipped by next and prev.
 
 Examples:
   nadctl source              # Show current source
   nadctl source Stream       # Set source to Stream
   nadctl source tv           # Set source to TV (case-insensitive)  
+  nadctl source Turntable    # Set source by the name given on the device
   nadctl source next         # Switch to next source
   nadctl source prev         # Switch to previous source
-  nadctl source list         # List all available sources`,
+  nadctl source list         # List all available sources
+  nadctl source TV --zone zone2  # Play TV in Zone 2
+  nadctl source Stream --group all  # Select Stream on every device`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := connectToDevice()
+		ctx := cmd.Context()
+		if groupRef != "" {
+			runSourceOnGroup(ctx, args)
+			return
+		}
+
+		client, err := connectToDevice(ctx)
 		if err != nil {
 			log.WithError(err).Fatal("could not connect to device")
 		}
 		defer client.Disconnect()
 
-		log.WithField("device", client.IP.String()).Debug("Connected to device for source command")
+		zone, err := selectedZone(client)
+		if err != nil {
+			log.WithError(err).Fatal("invalid zone")
+		}
+
+		log.WithField("device", client.String()).Debug("Connected to device for source command")
 
 		// No arguments - show current source
 		if len(args) == 0 {
 			log.Debug("No arguments provided, getting current source")
-			currentSource, err := client.GetSource()
+			current, err := zone.CurrentInputContext(ctx)
 			if err != nil {
 				log.WithError(err).Fatal("failed to get current source")
 			}
-			fmt.Printf("Current source: %s\n", currentSource)
+			fmt.Printf("Current source: %s\n", current.DisplayName())
 			return
 		}
 
 		arg := strings.ToLower(args[0])
+
+		inputs, err := client.InputsContext(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("failed to get device inputs")
+		}
 		log.WithFields(log.Fields{
 			"argument": arg,
 			"original": args[0],
@@ -75,23 +99,20 @@ Examples:
 		switch arg {
 		case "list":
 			log.Debug("Listing available sources")
-			sources := nadapi.GetAvailableSources()
 			fmt.Println("Available sources:")
-			for i, source := range sources {
-				fmt.Printf("  %d. %s\n", i+1, source)
-			}
+			printInputs(inputs)
 			return
 
 		case "next":
 			log.Debug("Changing to next source")
-			newSource, err := client.ToggleSource(nadapi.DirectionUp)
+			newSource, err := zone.ToggleSourceContext(ctx, nadapi.DirectionUp)
 			if err != nil {
 				log.WithError(err).Fatal("failed to change source")
 			}
 			// Extract the source name from response
 			if val, extractErr := extractValue(newSource); extractErr == nil {
 				log.WithField("newSource", val).Debug("Successfully changed to next source")
-				fmt.Printf("Source changed to: %s\n", val)
+				fmt.Printf("Source changed to: %s\n", inputDisplayName(inputs, val))
 			} else {
 				log.WithError(extractErr).Debug("Failed to extract source name from response")
 				fmt.Println("Source changed to next")
@@ -100,14 +121,14 @@ Examples:
 
 		case "prev", "previous":
 			log.Debug("Changing to previous source")
-			newSource, err := client.ToggleSource(nadapi.DirectionDown)
+			newSource, err := zone.ToggleSourceContext(ctx, nadapi.DirectionDown)
 			if err != nil {
 				log.WithError(err).Fatal("failed to change source")
 			}
 			// Extract the source name from response
 			if val, extractErr := extractValue(newSource); extractErr == nil {
 				log.WithField("newSource", val).Debug("Successfully changed to previous source")
-				fmt.Printf("Source changed to: %s\n", val)
+				fmt.Printf("Source changed to: %s\n", inputDisplayName(inputs, val))
 			} else {
 				log.WithError(extractErr).Debug("Failed to extract source name from response")
 				fmt.Println("Source changed to previous")
@@ -115,60 +136,102 @@ Examples:
 			return
 
 		default:
-			// Try to set to specific source
+			// Try to set to specific source, by custom or factory name
 			log.WithField("sourceName", arg).Debug("Attempting to set specific source")
 
-			if !nadapi.IsValidSource(arg) {
-				log.WithFields(log.Fields{
-					"invalidSource":    arg,
-					"availableSources": nadapi.GetAvailableSources(),
-				}).Debug("Invalid source name provided")
+			input, ok := nadapi.FindInput(inputs, args[0])
+			if !ok {
+				log.WithField("invalidSource", arg).Debug("Invalid source name provided")
 				fmt.Printf("Error: '%s' is not a valid source name.\n\n", arg)
 				fmt.Println("Available sources:")
-				sources := nadapi.GetAvailableSources()
-				for i, source := range sources {
-					fmt.Printf("  %d. %s\n", i+1, source)
-				}
+				printInputs(inputs)
 				fmt.Println("\nYou can also use: next, prev, list")
-				return
+				os.Exit(exitInvalidValue)
+			}
+			if !input.Enabled {
+				fmt.Printf("Error: source '%s' is disabled on the device.\n", input.DisplayName())
+				os.Exit(exitInvalidValue)
 			}
 
 			log.WithField("sourceName", arg).Debug("Source name validated, setting source")
-			err = client.SetSource(arg)
+			err = zone.SetSourceContext(ctx, input.Name)
 			if err != nil {
 				log.WithError(err).Fatal("failed to set source")
 			}
 
-			// Find the proper case for the source name
-			sources := nadapi.GetAvailableSources()
-			var properName string
-			for _, s := range sources {
-				if strings.EqualFold(s, arg) {
-					properName = s
-					break
-				}
-			}
-
 			log.WithFields(log.Fields{
 				"requestedSource": arg,
-				"actualSource":    properName,
+				"actualSource":    input.Name,
 			}).Debug("Successfully set source")
-			fmt.Printf("Source set to: %s\n", properName)
+			fmt.Printf("Source set to: %s\n", input.DisplayName())
 		}
 	},
 }
 
-// Helper function to extract value from response (copied from nadapi package)
+// printInputs lists inputs with their number on the device, showing the
+// factory name next to renamed inputs
+func printInputs(inputs []nadapi.Input) {
+	for _, in := range inputs {
+		line := fmt.Sprintf("  %d. %s", in.Index, in.DisplayName())
+		if in.Custom != "" {
+			line += fmt.Sprintf(" (%s)", in.Name)
+		}
+		if !in.Enabled {
+			line += " [disabled]"
+		}
+		fmt.Println(line)
+	}
+}
+
+// inputDisplayName returns the display name for a Main.Source value
+func inputDisplayName(inputs []nadapi.Input, source string) string {
+	for _, in := range inputs {
+		if strings.EqualFold(in.Name, source) {
+			return in.DisplayName()
+		}
+	}
+	return source
+}
+
+// extractValue returns the value a reply line of the device reports
 func extractValue(raw string) (string, error) {
-	s := strings.Split(raw, "=")
-	if len(s) > 1 {
-		// Simple trim of newline and carriage return
-		result := strings.TrimRight(s[1], "\r\n")
-		return result, nil
+	msg, err := protocol.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if msg.Op != protocol.OpSet {
+		return "", fmt.Errorf("failed to extract value from %q", msg.String())
+	}
+	return msg.Value, nil
+}
+
+// runSourceOnGroup is the source command for --group: it shows the source
+// of every device of the group, or selects the same source on all of them
+func runSourceOnGroup(ctx context.Context, args []string) {
+	if len(args) == 0 {
+		runOnGroup(ctx, func(exec *nadapi.GroupExecutor) []nadapi.GroupResult {
+			return exec.Run(ctx, func(ctx context.Context, z *nadapi.Zone, _ nadapi.GroupMember) (string, error) {
+				current, err := z.CurrentInputContext(ctx)
+				if err != nil {
+					return "", err
+				}
+				return current.DisplayName(), nil
+			})
+		})
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "list", "next", "prev", "previous":
+		log.Fatalf("'source %s' is not supported with --group; name the source to select", args[0])
 	}
-	return "", fmt.Errorf("failed to extract value")
+	runOnGroup(ctx, func(exec *nadapi.GroupExecutor) []nadapi.GroupResult {
+		return exec.SetSource(ctx, args[0])
+	})
 }
 
 func init() {
 	rootCmd.AddCommand(sourceCmd)
+	addZoneFlag(sourceCmd)
+	addGroupFlag(sourceCmd)
 }
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/galamiram/nadctl/nadapi"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the current device state",
+	Long: `Show power, volume, source, mute, brightness and model of the NAD device.
+
+Examples:
+  nadctl status             # Print the device state
+  nadctl status --zone zone2  # Print the Zone 2 state`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		client, err := connectToDevice(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("could not connect to device")
+		}
+		defer client.Disconnect()
+
+		zone, err := selectedZone(client)
+		if err != nil {
+			log.WithError(err).Fatal("invalid zone")
+		}
+		if zone.ID() != nadapi.MainZone {
+			zs, err := zone.StateContext(ctx)
+			if err != nil {
+				log.WithError(err).Fatal("failed to get zone state")
+			}
+			fmt.Printf("Zone:       %s\n", zs.Zone)
+			fmt.Printf("Power:      %s\n", zs.Power)
+			fmt.Printf("Volume:     %.1f dB\n", zs.Volume)
+			fmt.Printf("Mute:       %s\n", onOff(zs.Muted))
+			printSource(zs.Source, zs.SourceName)
+			return
+		}
+
+		state, err := client.StateContext(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("failed to get device state")
+		}
+
+		fmt.Printf("Model:      %s\n", state.Model)
+		fmt.Printf("Power:      %s\n", state.Power)
+		fmt.Printf("Volume:     %s\n", state.VolumeString())
+		fmt.Printf("Mute:       %s\n", state.MuteString())
+		printSource(state.Source, state.SourceName)
+		fmt.Printf("Brightness: %d\n", state.Brightness)
+	},
+}
+
+// printSource prints the source line, showing the factory name of a renamed input
+func printSource(source, name string) {
+	if name != source {
+		fmt.Printf("Source:     %s (%s)\n", name, source)
+	} else {
+		fmt.Printf("Source:     %s\n", source)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	addZoneFlag(statusCmd)
+}
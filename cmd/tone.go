@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/galamiram/nadctl/nadapi"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// toneCmd represents the tone command
+var toneCmd = &cobra.Command{
+	Use:   "tone",
+	Short: "Show or adjust bass, treble, balance and tone defeat",
+	Long: `Show the tone control settings or adjust them with a subcommand.
+
+Bass and treble range from -6 to +6 dB, balance from -18 (left) to +18 (right).
+Tone controls are only available on models that have them (C368, C658, T 758).
+
+Examples:
+  nadctl tone                # Show bass, treble, balance and tone defeat
+  nadctl tone bass           # Show bass
+  nadctl tone bass 3         # Set bass to +3 dB
+  nadctl tone bass -- -2     # Set bass to -2 dB (-- before negative values)
+  nadctl tone treble up      # Increase treble by 1 dB
+  nadctl tone balance 0      # Center the balance
+  nadctl tone defeat on      # Bypass bass and treble`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		client, err := connectToDevice(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("could not connect to device")
+		}
+		defer client.Disconnect()
+
+		tone, err := client.ToneContext(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("failed to get tone settings")
+		}
+
+		fmt.Printf("Bass:        %+d dB\n", tone.Bass)
+		fmt.Printf("Treble:      %+d dB\n", tone.Treble)
+		fmt.Printf("Balance:     %s\n", balanceString(tone.Balance))
+		fmt.Printf("Tone defeat: %s\n", onOff(tone.Defeat))
+	},
+}
+
+// toneLevel describes one of the dB tone levels for the tone subcommands
+type toneLevel struct {
+	name     string
+	min, max int
+	get      func(*nadapi.Device, context.Context) (int, error)
+	set      func(*nadapi.Device, context.Context, int) error
+	format   func(int) string
+}
+
+var toneLevels = []toneLevel{
+	{"bass", nadapi.MinTone, nadapi.MaxTone, (*nadapi.Device).GetBassContext, (*nadapi.Device).SetBassContext, dbString},
+	{"treble", nadapi.MinTone, nadapi.MaxTone, (*nadapi.Device).GetTrebleContext, (*nadapi.Device).SetTrebleContext, dbString},
+	{"balance", nadapi.MinBalance, nadapi.MaxBalance, (*nadapi.Device).GetBalanceContext, (*nadapi.Device).SetBalanceContext, balanceString},
+}
+
+// newToneLevelCmd builds the subcommand for a tone level
+func newToneLevelCmd(l toneLevel) *cobra.Command {
+	return &cobra.Command{
+		Use:   fmt.Sprintf("%s [LEVEL|up|down]", l.name),
+		Short: fmt.Sprintf("Set or get %s (%d to %+d dB)", l.name, l.min, l.max),
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := cmd.Context()
+			client, err := connectToDevice(ctx)
+			if err != nil {
+				log.WithError(err).Fatal("could not connect to device")
+			}
+			defer client.Disconnect()
+
+			current, err := l.get(client, ctx)
+			if err != nil {
+				log.WithError(err).Fatalf("failed to get %s", l.name)
+			}
+
+			if len(args) == 0 {
+				fmt.Printf("Current %s: %s\n", l.name, l.format(current))
+				return
+			}
+
+			var level int
+			switch strings.ToLower(args[0]) {
+			case "up":
+				level = current + 1
+			case "down":
+				level = current - 1
+			default:
+				level, err = strconv.Atoi(args[0])
+				if err != nil {
+					fmt.Printf("Error: '%s' is not a valid %s level.\n", args[0], l.name)
+					os.Exit(exitInvalidValue)
+				}
+			}
+
+			if level < l.min || level > l.max {
+				fmt.Printf("Error: %s must be between %d and %+d dB.\n", l.name, l.min, l.max)
+				os.Exit(exitInvalidValue)
+			}
+
+			if err := l.set(client, ctx, level); err != nil {
+				log.WithError(err).Fatalf("failed to set %s", l.name)
+			}
+			fmt.Printf("%s set to: %s\n", strings.ToUpper(l.name[:1])+l.name[1:], l.format(level))
+		},
+	}
+}
+
+// toneDefeatCmd represents the tone defeat subcommand
+var toneDefeatCmd = &cobra.Command{
+	Use:   "defeat [on|off|toggle]",
+	Short: "Set or get tone defeat, which bypasses bass and treble",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		client, err := connectToDevice(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("could not connect to device")
+		}
+		defer client.Disconnect()
+
+		if len(args) == 0 {
+			on, err := client.GetToneDefeatContext(ctx)
+			if err != nil {
+				log.WithError(err).Fatal("failed to get tone defeat")
+			}
+			fmt.Printf("Tone defeat: %s\n", onOff(on))
+			return
+		}
+
+		switch strings.ToLower(args[0]) {
+		case "on":
+			err = client.SetToneDefeatContext(ctx, true)
+		case "off":
+			err = client.SetToneDefeatContext(ctx, false)
+		case "toggle":
+			err = client.ToggleToneDefeatContext(ctx)
+		default:
+			fmt.Printf("Error: '%s' is not valid. Use on, off or toggle.\n", args[0])
+			os.Exit(exitInvalidValue)
+		}
+		if err != nil {
+			log.WithError(err).Fatal("failed to set tone defeat")
+		}
+
+		on, err := client.GetToneDefeatContext(ctx)
+		if err != nil {
+			fmt.Println("Tone defeat changed")
+			return
+		}
+		fmt.Printf("Tone defeat: %s\n", onOff(on))
+	},
+}
+
+// dbString formats a tone level, e.g. "+3 dB"
+func dbString(db int) string {
+	return fmt.Sprintf("%+d dB", db)
+}
+
+// balanceString formats a balance, e.g. "3 dB left" or "center"
+func balanceString(db int) string {
+	switch {
+	case db < 0:
+		return fmt.Sprintf("%d dB left", -db)
+	case db > 0:
+		return fmt.Sprintf("%d dB right", db)
+	default:
+		return "center"
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(toneCmd)
+	for _, l := range toneLevels {
+		toneCmd.AddCommand(newToneLevelCmd(l))
+	}
+	toneCmd.AddCommand(toneDefeatCmd)
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/galamiram/nadctl/tui"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // tuiCmd represents the tui command
@@ -52,10 +53,15 @@ Keyboard shortcuts:
   ↑/↓       - Brightness up/down
   r         - Refresh status
   d         - Discover devices
+  c         - Choose the device or group to control
+
+With a group chosen, power, mute, volume and the source picker act on every
+device of the group, and the first device of the group is shown.
 
 Examples:
   nadctl tui               # Launch the TUI interface
-  nadctl tui --demo        # Launch TUI in demo mode (no NAD device required)`,
+  nadctl tui --demo        # Launch TUI in demo mode (no NAD device required)
+  nadctl tui --group downstairs  # Control the devices of a group together`,
 	Run: func(cmd *cobra.Command, args []string) {
 		log.Debug("Launching TUI interface")
 
@@ -137,4 +143,6 @@ func init() {
 	rootCmd.AddCommand(tuiCmd)
 	// Add demo flag to the TUI command
 	tuiCmd.Flags().BoolP("demo", "d", false, "run in demo mode without NAD device connection")
+	addGroupFlag(tuiCmd)
+	viper.BindPFlag("group", tuiCmd.Flags().Lookup("group"))
 }
@@ -22,9 +22,12 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/galamiram/nadctl/nadapi"
 	log "github.com/sirupsen/logrus"
@@ -46,24 +49,47 @@ Examples:
   nadctl volume 0            # Set volume to 0 dB (reference level)
   nadctl volume up           # Increase volume by 1 dB
   nadctl volume down         # Decrease volume by 1 dB
+  nadctl volume up --zone zone2  # Increase Zone 2 volume by 1 dB
+  nadctl volume set -30 --group office+kitchen  # Set the volume of two devices
+  nadctl volume up --group downstairs           # Raise the volume of a group
+  nadctl volume fade -40 --over 10s             # Fade to -40 dB over 10 seconds
+
+With --group the volume of every device of the group is shown or changed
+at once. Devices listed in a group with a volume offset, such as
+kitchen@-3, are set that many dB away from the level given.
 
 Note: For negative volume levels, you can use:
   nadctl volume set -10      # Easiest way (recommended)
   nadctl volume -- -10       # Alternative using -- separator`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := connectToDevice()
+		ctx := cmd.Context()
+		if groupRef != "" {
+			arg := ""
+			if len(args) == 1 {
+				arg = args[0]
+			}
+			runVolumeOnGroup(ctx, arg)
+			return
+		}
+
+		client, err := connectToDevice(ctx)
 		if err != nil {
 			log.WithError(err).Fatal("could not connect to device")
 		}
 		defer client.Disconnect()
 
-		log.WithField("device", client.IP.String()).Debug("Connected to device for volume command")
+		zone, err := selectedZone(client)
+		if err != nil {
+			log.WithError(err).Fatal("invalid zone")
+		}
+
+		log.WithField("device", client.String()).Debug("Connected to device for volume command")
 
 		// No arguments - show current volume
 		if len(args) == 0 {
 			log.Debug("No arguments provided, getting current volume")
-			currentVolume, err := client.GetVolumeFloat()
+			currentVolume, err := zone.GetVolumeFloatContext(ctx)
 			if err != nil {
 				log.WithError(err).Fatal("failed to get current volume")
 			}
@@ -82,11 +108,11 @@ Note: For negative volume levels, you can use:
 		switch arg {
 		case "up":
 			log.Debug("Increasing volume")
-			err = client.TuneVolume(nadapi.DirectionUp)
+			err = zone.TuneVolumeContext(ctx, nadapi.DirectionUp)
 			if err != nil {
 				log.WithError(err).Fatal("failed to increase volume")
 			}
-			newVolume, err := client.GetVolumeFloat()
+			newVolume, err := zone.GetVolumeFloatContext(ctx)
 			if err == nil {
 				log.WithField("newVolume", newVolume).Debug("Successfully increased volume")
 				fmt.Printf("Volume increased to: %.1f dB\n", newVolume)
@@ -98,11 +124,11 @@ Note: For negative volume levels, you can use:
 
 		case "down":
 			log.Debug("Decreasing volume")
-			err = client.TuneVolume(nadapi.DirectionDown)
+			err = zone.TuneVolumeContext(ctx, nadapi.DirectionDown)
 			if err != nil {
 				log.WithError(err).Fatal("failed to decrease volume")
 			}
-			newVolume, err := client.GetVolumeFloat()
+			newVolume, err := zone.GetVolumeFloatContext(ctx)
 			if err == nil {
 				log.WithField("newVolume", newVolume).Debug("Successfully decreased volume")
 				fmt.Printf("Volume decreased to: %.1f dB\n", newVolume)
@@ -128,28 +154,17 @@ Note: For negative volume levels, you can use:
 				fmt.Println("  nadctl volume down         # Decrease volume")
 				fmt.Println("\nVolume range is typically -80 to +10 dB")
 				fmt.Println("Note: Use -- before negative numbers to prevent flag parsing")
-				return
+				os.Exit(exitInvalidValue)
 			}
 
 			log.WithField("parsedVolume", volume).Debug("Successfully parsed volume level")
 
-			// Warn about potentially dangerous volume levels
-			if volume > 5 {
-				log.WithField("volume", volume).Debug("High volume level detected, requesting confirmation")
-				fmt.Printf("Warning: Volume level %.1f dB is quite high. Continue? (y/N): ", volume)
-				var response string
-				fmt.Scanln(&response)
-				log.WithField("userResponse", response).Debug("User response to volume warning")
-				if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
-					log.Debug("User cancelled high volume operation")
-					fmt.Println("Volume change cancelled")
-					return
-				}
-				log.Debug("User confirmed high volume operation")
+			if !confirmHighVolume(volume) {
+				return
 			}
 
 			log.WithField("volume", volume).Debug("Setting volume to specific level")
-			err = client.SetVolume(volume)
+			err = zone.SetVolumeContext(ctx, volume)
 			if err != nil {
 				log.WithError(err).Fatal("failed to set volume")
 			}
@@ -162,9 +177,10 @@ Note: For negative volume levels, you can use:
 
 func init() {
 	rootCmd.AddCommand(volumeCmd)
+	addZoneFlag(volumeCmd)
 
 	// Add convenient aliases for common volume levels
-	volumeCmd.AddCommand(&cobra.Command{
+	volumeSetCmd := &cobra.Command{
 		Use:   "set LEVEL",
 		Short: "Set volume to a specific level",
 		Args:  cobra.ExactArgs(1),
@@ -172,32 +188,179 @@ func init() {
 			volume, err := strconv.ParseFloat(args[0], 64)
 			if err != nil {
 				fmt.Printf("Error: '%s' is not a valid volume level.\n", args[0])
+				os.Exit(exitInvalidValue)
+			}
+
+			ctx := cmd.Context()
+			if groupRef != "" {
+				runVolumeOnGroup(ctx, args[0])
 				return
 			}
 
-			client, err := connectToDevice()
+			client, err := connectToDevice(ctx)
 			if err != nil {
 				log.WithError(err).Fatal("could not connect to device")
 			}
 			defer client.Disconnect()
 
-			// Warn about potentially dangerous volume levels
-			if volume > 5 {
-				fmt.Printf("Warning: Volume level %.1f dB is quite high. Continue? (y/N): ", volume)
-				var response string
-				fmt.Scanln(&response)
-				if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
-					fmt.Println("Volume change cancelled")
-					return
-				}
+			zone, err := selectedZone(client)
+			if err != nil {
+				log.WithError(err).Fatal("invalid zone")
+			}
+
+			if !confirmHighVolume(volume) {
+				return
 			}
 
-			err = client.SetVolume(volume)
+			err = zone.SetVolumeContext(ctx, volume)
 			if err != nil {
 				log.WithError(err).Fatal("failed to set volume")
 			}
 
 			fmt.Printf("Volume set to: %.1f dB\n", volume)
 		},
-	})
+	}
+	addZoneFlag(volumeSetCmd)
+	addGroupFlag(volumeSetCmd)
+	volumeCmd.AddCommand(volumeSetCmd)
+	addGroupFlag(volumeCmd)
+
+	volumeFadeCmd.Flags().DurationVar(&fadeOver, "over", 5*time.Second, "how long the fade takes")
+	volumeFadeCmd.Flags().StringVar(&fadeCurve, "curve", "linear", "how the fade spreads the change: linear or logarithmic")
+	addZoneFlag(volumeFadeCmd)
+	volumeCmd.AddCommand(volumeFadeCmd)
+}
+
+var (
+	fadeOver  time.Duration
+	fadeCurve string
+)
+
+// volumeFadeCmd steps the volume to a level instead of jumping to it
+var volumeFadeCmd = &cobra.Command{
+	Use:   "fade LEVEL",
+	Short: "Fade the volume to a level over time",
+	Long: `Step the volume from its current level to LEVEL over the time given with
+--over, instead of jumping to it.
+
+A linear fade changes the volume by the same amount at every step; a
+logarithmic one makes most of the change early and slows down near the
+target. Changing the volume meanwhile, here or on the device, or starting
+another fade stops the fade where it is.
+
+Examples:
+  nadctl volume fade -40 --over 10s        # Fade to -40 dB over 10 seconds
+  nadctl volume fade -20 --curve log       # Rise quickly, then ease in
+  nadctl volume fade -60 --over 1m --zone zone2`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		volume, err := strconv.ParseFloat(args[0], 64)
+		if err != nil {
+			fmt.Printf("Error: '%s' is not a valid volume level.\n", args[0])
+			os.Exit(exitInvalidValue)
+		}
+		curve, err := nadapi.ParseFadeCurve(fadeCurve)
+		if err != nil {
+			log.WithError(err).Fatal("invalid curve")
+		}
+
+		ctx := cmd.Context()
+		client, err := connectToDevice(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("could not connect to device")
+		}
+		defer client.Disconnect()
+
+		zone, err := selectedZone(client)
+		if err != nil {
+			log.WithError(err).Fatal("invalid zone")
+		}
+
+		if !confirmHighVolume(volume) {
+			return
+		}
+
+		fade, err := zone.FadeVolume(ctx, volume, fadeOver, curve)
+		if err != nil {
+			log.WithError(err).Fatal("failed to start fade")
+		}
+		reached := volume
+		for p := range fade.Progress() {
+			fmt.Printf("\rFading volume: %6.1f dB (%d/%d)", p.Volume, p.Step, p.Steps)
+			reached = p.Volume
+		}
+		fmt.Println()
+		if err := fade.Wait(); err != nil {
+			log.WithError(err).Fatal("fade did not finish")
+		}
+		fmt.Printf("Volume faded to: %.1f dB\n", reached)
+	},
+}
+
+// confirmHighVolume asks before setting a potentially dangerous volume level
+// and reports whether to go ahead
+func confirmHighVolume(volume float64) bool {
+	if volume <= 5 {
+		return true
+	}
+	log.WithField("volume", volume).Debug("High volume level detected, requesting confirmation")
+	fmt.Printf("Warning: Volume level %.1f dB is quite high. Continue? (y/N): ", volume)
+	var response string
+	fmt.Scanln(&response)
+	log.WithField("userResponse", response).Debug("User response to volume warning")
+	if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+		log.Debug("User cancelled high volume operation")
+		fmt.Println("Volume change cancelled")
+		return false
+	}
+	log.Debug("User confirmed high volume operation")
+	return true
+}
+
+// runVolumeOnGroup is the volume command for --group: it shows, tunes or
+// sets the volume of every device of the group, the latter keeping the
+// volume offsets of the members
+func runVolumeOnGroup(ctx context.Context, arg string) {
+	switch strings.ToLower(arg) {
+	case "":
+		runOnGroup(ctx, func(exec *nadapi.GroupExecutor) []nadapi.GroupResult {
+			return exec.Run(ctx, func(ctx context.Context, z *nadapi.Zone, _ nadapi.GroupMember) (string, error) {
+				volume, err := z.GetVolumeFloatContext(ctx)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%.1f dB", volume), nil
+			})
+		})
+
+	case "up", "down":
+		direction := nadapi.DirectionUp
+		if strings.ToLower(arg) == "down" {
+			direction = nadapi.DirectionDown
+		}
+		runOnGroup(ctx, func(exec *nadapi.GroupExecutor) []nadapi.GroupResult {
+			return exec.TuneVolume(ctx, direction)
+		})
+
+	default:
+		volume, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			log.WithError(fmt.Errorf("%w: '%s' is not a volume level", nadapi.ErrInvalidValue, arg)).Fatal("invalid argument")
+		}
+		group, err := resolveGroup(groupRef)
+		if err != nil {
+			log.WithError(err).Fatal("invalid group")
+		}
+		// Confirm for the loudest member
+		loudest := volume + group.Members[0].VolumeOffset
+		for _, m := range group.Members {
+			loudest = max(loudest, volume+m.VolumeOffset)
+		}
+		if !confirmHighVolume(loudest) {
+			return
+		}
+		runOnGroup(ctx, func(exec *nadapi.GroupExecutor) []nadapi.GroupResult {
+			return exec.SetVolume(ctx, volume)
+		})
+	}
 }
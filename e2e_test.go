@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/galamiram/nadctl/nadapi"
 	"github.com/galamiram/nadctl/simulator"
 )
 
@@ -65,6 +66,10 @@ func TestE2EWithSimulator(t *testing.T) {
 	t.Run("BrightnessLimit", func(t *testing.T) {
 		testBrightnessLimit(t, simulatorIP)
 	})
+
+	t.Run("Status", func(t *testing.T) {
+		testStatus(t, simulatorIP)
+	})
 }
 
 func testPowerControl(t *testing.T, ip string) {
@@ -342,6 +347,19 @@ func testBrightnessLimit(t *testing.T, ip string) {
 	}
 }
 
+func testStatus(t *testing.T, ip string) {
+	output, err := runNadctlCommand(ip, "status")
+	if err != nil {
+		t.Fatalf("Status command failed: %v, output: %s", err, output)
+	}
+
+	for _, want := range []string{"Model:", "Power:", "Volume:", "Mute:", "Source:", "Brightness:"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in status output, got: %s", want, output)
+		}
+	}
+}
+
 // runNadctlCommand executes a nadctl command against the simulator
 func runNadctlCommand(ip string, args ...string) (string, error) {
 	// Check if binary exists, if not build it
@@ -429,4 +447,31 @@ func TestSimulatorUnitTests(t *testing.T) {
 			t.Errorf("Expected brightness 3, got %d", currentState.Brightness)
 		}
 	})
+	t.Run("SimulatorPTY", func(t *testing.T) {
+		sim := simulator.NewNADSimulator()
+		path, err := sim.StartPTY()
+		if err != nil {
+			t.Skipf("Pseudo terminal unavailable: %v", err)
+		}
+		defer sim.Stop()
+
+		// Connect twice to check the port stays usable after a client leaves
+		for i := 0; i < 2; i++ {
+			device, err := nadapi.New("serial://"+path, "")
+			if err != nil {
+				t.Fatalf("Failed to connect over serial: %v", err)
+			}
+			if err := device.PowerOn(); err != nil {
+				t.Fatalf("PowerOn over serial failed: %v", err)
+			}
+			power, err := device.GetPowerState()
+			if err != nil {
+				t.Fatalf("GetPowerState over serial failed: %v", err)
+			}
+			if power != "On" {
+				t.Errorf("Expected power On, got %s", power)
+			}
+			device.Disconnect()
+		}
+	})
 }
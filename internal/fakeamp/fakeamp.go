@@ -0,0 +1,261 @@
+// Package fakeamp is a fake NAD amplifier for tests. It answers the line
+// protocol on a TCP port from a table of values, keeping the values set on
+// it, and can be made silent, slow or chatty, or dropped and brought back.
+package fakeamp
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galamiram/nadctl/nadapi/protocol"
+)
+
+// Amp is a fake amplifier listening on Host:Port
+type Amp struct {
+	Host string
+	Port string
+
+	t    testing.TB
+	addr string // Address listened on, kept to listen again after Stop
+
+	mu       sync.Mutex // Guards the fields below and serializes writes
+	ln       net.Listener
+	conns    []net.Conn
+	values   map[string]string
+	queried  []string
+	inFlight int
+	peak     int
+
+	silent      bool
+	inputs      bool
+	unsolicited []string
+	fixed       []string
+	delay       time.Duration
+	hold        int
+}
+
+// Option configures an Amp
+type Option func(*Amp)
+
+// Values sets the values the amp answers, by key, e.g. "Main.Volume": "-40".
+// Keys missing from values go unanswered, as on a model without them.
+func Values(values map[string]string) Option {
+	return func(a *Amp) {
+		for key, value := range values {
+			a.values[key] = value
+		}
+	}
+}
+
+// Silent makes the amp accept connections but never answer
+func Silent() Option {
+	return func(a *Amp) { a.silent = true }
+}
+
+// FactoryInputs makes the amp answer SourceN.Name and SourceN.Enabled
+// missing from its values as inputs that keep their factory names
+func FactoryInputs() Option {
+	return func(a *Amp) { a.inputs = true }
+}
+
+// Unsolicited makes the amp write lines before every reply, as a device
+// reporting changes of its own
+func Unsolicited(lines ...string) Option {
+	return func(a *Amp) { a.unsolicited = lines }
+}
+
+// Reply makes the amp answer every command with lines, whatever it asks
+func Reply(lines ...string) Option {
+	return func(a *Amp) { a.fixed = lines }
+}
+
+// Delay makes the amp take d to answer each command
+func Delay(d time.Duration) Option {
+	return func(a *Amp) { a.delay = d }
+}
+
+// Pipelined makes the amp read n commands before answering them, in
+// reverse order
+func Pipelined(n int) Option {
+	return func(a *Amp) { a.hold = n }
+}
+
+// AllAddresses makes the amp listen on every local address, e.g. the whole
+// loopback range for sweeps
+func AllAddresses() Option {
+	return func(a *Amp) { a.addr = "0.0.0.0:0" }
+}
+
+// Start starts an amp configured by opts, stopped when the test ends
+func Start(t testing.TB, opts ...Option) *Amp {
+	t.Helper()
+	a := &Amp{t: t, addr: "127.0.0.1:0", values: make(map[string]string)}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.Restart()
+	_, a.Port, _ = net.SplitHostPort(a.addr)
+	a.Host = "127.0.0.1"
+	t.Cleanup(a.Stop)
+	return a
+}
+
+// Addr returns the address of the amp as host:port
+func (a *Amp) Addr() string {
+	return net.JoinHostPort(a.Host, a.Port)
+}
+
+// Restart listens again after Stop, on the same address
+func (a *Amp) Restart() {
+	a.t.Helper()
+	ln, err := net.Listen("tcp", a.addr)
+	if err != nil {
+		a.t.Fatalf("failed to listen: %v", err)
+	}
+	a.mu.Lock()
+	a.ln = ln
+	a.addr = ln.Addr().String()
+	a.mu.Unlock()
+	go a.accept(ln)
+}
+
+// Stop closes the listener and every connection
+func (a *Amp) Stop() {
+	a.mu.Lock()
+	if a.ln != nil {
+		a.ln.Close()
+		a.ln = nil
+	}
+	a.mu.Unlock()
+	a.DropConns()
+}
+
+// DropConns closes every open connection, keeping the listener
+func (a *Amp) DropConns() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, conn := range a.conns {
+		conn.Close()
+	}
+	a.conns = nil
+}
+
+// Value returns the value of key
+func (a *Amp) Value(key string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.values[key]
+}
+
+// Notify sets key to value and reports it unasked on every connection, as
+// when the front panel changes it
+func (a *Amp) Notify(key, value string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.values[key] = value
+	for _, conn := range a.conns {
+		protocol.Encode(conn, protocol.Set(key, value))
+	}
+}
+
+// TakeQueried returns the keys queried since the last call
+func (a *Amp) TakeQueried() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	keys := a.queried
+	a.queried = nil
+	return keys
+}
+
+// PeakInFlight returns the most commands the amp had yet to answer at once
+func (a *Amp) PeakInFlight() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.peak
+}
+
+func (a *Amp) accept(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		a.mu.Lock()
+		a.conns = append(a.conns, conn)
+		a.mu.Unlock()
+		go a.serve(conn)
+	}
+}
+
+// serve answers the commands of conn until it is closed
+func (a *Amp) serve(conn net.Conn) {
+	defer conn.Close()
+	var held []protocol.Message
+	scanner := protocol.NewScanner(conn)
+	for scanner.Scan() {
+		msg, err := protocol.Parse(scanner.Text())
+		if err != nil {
+			continue
+		}
+		a.mu.Lock()
+		if msg.Op == protocol.OpQuery {
+			a.queried = append(a.queried, msg.Key)
+		}
+		a.inFlight++
+		a.peak = max(a.peak, a.inFlight)
+		a.mu.Unlock()
+
+		if held = append(held, msg); len(held) < a.hold {
+			continue
+		}
+		slices.Reverse(held)
+		for _, msg := range held {
+			time.Sleep(a.delay)
+			a.answer(conn, msg)
+		}
+		held = nil
+	}
+}
+
+// answer writes the reply to msg, if any
+func (a *Amp) answer(conn net.Conn, msg protocol.Message) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inFlight--
+	if a.silent {
+		return
+	}
+
+	lines := slices.Clone(a.unsolicited)
+	if a.fixed != nil {
+		lines = append(lines, a.fixed...)
+	} else if value, ok := a.lookup(msg); ok {
+		lines = append(lines, protocol.Set(msg.Key, value).String())
+	}
+	if len(lines) > 0 {
+		conn.Write([]byte(strings.Join(lines, "\r\n") + "\r\n"))
+	}
+}
+
+// lookup returns the value msg reports, storing the value of a set. Keys
+// without a value are left unanswered, and so are sets of them.
+func (a *Amp) lookup(msg protocol.Message) (string, bool) {
+	value, ok := a.values[msg.Key]
+	switch {
+	case ok:
+		if msg.Op == protocol.OpSet {
+			a.values[msg.Key] = msg.Value
+			value = msg.Value
+		}
+	case !a.inputs || msg.Op == protocol.OpSet:
+	case strings.HasSuffix(msg.Key, ".Name"):
+		value, ok = "", true // Inputs keep their factory names
+	case strings.HasSuffix(msg.Key, ".Enabled"):
+		value, ok = "Yes", true
+	}
+	return value, ok
+}
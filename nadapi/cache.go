@@ -1,6 +1,7 @@
 package nadapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -31,11 +32,20 @@ type SpotifyTokenCache struct {
 type AppCache struct {
 	Discovery *CachedDiscovery   `json:"discovery,omitempty"`
 	Spotify   *SpotifyTokenCache `json:"spotify,omitempty"`
+	// History holds every device discovered before, most recent first. It
+	// does not expire and seeds the fast discovery pre-pass.
+	History []DiscoveredDevice `json:"history,omitempty"`
+	// Known is the registry of devices seen before with their aliases. It
+	// survives clearing the cache.
+	Known []KnownDevice `json:"known,omitempty"`
 }
 
 // DefaultCacheTTL is the default time-to-live for cached discovery results
 const DefaultCacheTTL = 5 * time.Minute
 
+// maxHistory bounds the number of devices kept in the discovery history
+const maxHistory = 16
+
 // getCacheFilePathFunc is a variable that can be overridden for testing
 var getCacheFilePathFunc = defaultGetCacheFilePath
 
@@ -43,7 +53,7 @@ var getCacheFilePathFunc = defaultGetCacheFilePath
 func defaultGetCacheFilePath() (string, error) {
 	home, err := homedir.Dir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %v", err)
+		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
 	return filepath.Join(home, ".nadctl_cache.json"), nil
 }
@@ -114,23 +124,59 @@ func SaveCachedDevices(devices []DiscoveredDevice, ttl time.Duration) error {
 		"ttl":         ttl,
 	}).Debug("Saving devices to cache")
 
+	return saveDevices(devices, &CachedDiscovery{
+		Devices:   devices,
+		Timestamp: time.Now(),
+		TTL:       ttl,
+	})
+}
+
+// saveDevices adds devices to the history and the registry of known devices
+// and, unless discovery is nil, replaces the cached discovery results with
+// it. Discoveries that may have missed devices pass nil, so they are not
+// taken for a complete one later.
+func saveDevices(devices []DiscoveredDevice, discovery *CachedDiscovery) error {
 	// Load existing cache to preserve Spotify tokens
 	cache, err := LoadAppCache()
 	if err != nil {
 		cache = &AppCache{} // Start with empty cache if load fails
 	}
 
-	// Update discovery data
-	cache.Discovery = &CachedDiscovery{
-		Devices:   devices,
-		Timestamp: time.Now(),
-		TTL:       ttl,
+	if discovery != nil {
+		cache.Discovery = discovery
+	}
+	cache.History = mergeDevices(devices, cache.History)
+	if len(cache.History) > maxHistory {
+		cache.History = cache.History[:maxHistory]
 	}
+	cache.Known = recordKnownDevices(cache.Known, devices, time.Now())
 
 	return SaveAppCache(cache)
 }
 
-// ClearCache removes the cached discovery results
+// loadDeviceHistory returns the devices discovered before, most recent
+// first, whether or not the discovery cache has expired
+func loadDeviceHistory() []DiscoveredDevice {
+	cache, err := LoadAppCache()
+	if err != nil {
+		log.WithError(err).Debug("Failed to load device history")
+		return nil
+	}
+	history := cache.History
+	// Caches written before the history existed still list their devices
+	if len(history) == 0 && cache.Discovery != nil {
+		history = cache.Discovery.Devices
+	}
+	// Known devices are probed at the address they were last seen at
+	known := make([]DiscoveredDevice, 0, len(cache.Known))
+	for _, k := range cache.Known {
+		known = append(known, k.Device())
+	}
+	return mergeDevices(history, known)
+}
+
+// ClearCache removes the cached discovery results. The registry of known
+// devices is kept.
 func ClearCache() error {
 	log.Debug("Clearing cache")
 
@@ -140,11 +186,16 @@ func ClearCache() error {
 		return err
 	}
 
+	if cache, err := LoadAppCache(); err == nil && len(cache.Known) > 0 {
+		log.WithField("knownDevices", len(cache.Known)).Debug("Keeping the registry of known devices")
+		return SaveAppCache(&AppCache{Known: cache.Known})
+	}
+
 	log.WithField("cachePath", cachePath).Debug("Attempting to clear cache file")
 
 	if err := os.Remove(cachePath); err != nil && !os.IsNotExist(err) {
 		log.WithError(err).WithField("cachePath", cachePath).Debug("Failed to remove cache file")
-		return fmt.Errorf("failed to clear cache: %v", err)
+		return fmt.Errorf("failed to clear cache: %w", err)
 	}
 
 	if os.IsNotExist(err) {
@@ -177,6 +228,12 @@ func IsCacheValid() (bool, error) {
 
 // DiscoverDevicesWithCache attempts to load from cache first, then discovers if needed
 func DiscoverDevicesWithCache(timeout time.Duration, useCache bool, cacheTTL time.Duration) ([]DiscoveredDevice, bool, error) {
+	return DiscoverDevicesWithCacheOptions(timeout, useCache, cacheTTL, DiscoveryOptions{})
+}
+
+// DiscoverDevicesWithCacheOptions is DiscoverDevicesWithCache with discovery
+// configured by opts
+func DiscoverDevicesWithCacheOptions(timeout time.Duration, useCache bool, cacheTTL time.Duration, opts DiscoveryOptions) ([]DiscoveredDevice, bool, error) {
 	log.WithFields(log.Fields{
 		"timeout":  timeout,
 		"useCache": useCache,
@@ -191,6 +248,9 @@ func DiscoverDevicesWithCache(timeout time.Duration, useCache bool, cacheTTL tim
 		cachedDevices, err := LoadCachedDevices()
 		if err == nil && len(cachedDevices) > 0 {
 			log.WithField("deviceCount", len(cachedDevices)).Debug("Successfully loaded devices from cache")
+			if opts.MaxDevices > 0 && len(cachedDevices) > opts.MaxDevices {
+				cachedDevices = cachedDevices[:opts.MaxDevices]
+			}
 			return cachedDevices, true, nil
 		}
 		if err != nil {
@@ -202,24 +262,51 @@ func DiscoverDevicesWithCache(timeout time.Duration, useCache bool, cacheTTL tim
 		log.Debug("Cache disabled, performing fresh discovery")
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Known addresses usually answer within milliseconds. Devices found
+	// this way may not be all of them, so the result is partial.
+	var devices []DiscoveredDevice
+	if !opts.SkipPrepass {
+		log.Debug("Probing neighbor table and device history")
+		devices = discoverPrepass(ctx, opts)
+	}
+	partial := len(devices) > 0
+
 	// Perform fresh discovery
-	log.WithField("timeout", timeout).Debug("Performing fresh device discovery")
-	devices, err := DiscoverDevices(timeout)
-	if err != nil {
-		log.WithError(err).Debug("Fresh device discovery failed")
-		return nil, false, err
+	if len(devices) == 0 {
+		log.WithField("timeout", timeout).Debug("Performing fresh device discovery")
+		var err error
+		devices, err = DiscoverDevicesWithOptions(ctx, opts)
+		if err != nil {
+			log.WithError(err).Debug("Fresh device discovery failed")
+			return nil, false, err
+		}
 	}
 
 	log.WithField("deviceCount", len(devices)).Debug("Fresh device discovery completed")
 
-	// Save to cache if discovery was successful and we have devices
+	// Save to cache if discovery was successful and we have devices. A
+	// discovery stopped at MaxDevices may have missed some too, so partial
+	// results only go to the history.
+	if opts.MaxDevices > 0 && len(devices) >= opts.MaxDevices {
+		partial = true
+	}
 	if len(devices) > 0 {
 		log.WithFields(log.Fields{
 			"deviceCount": len(devices),
 			"cacheTTL":    cacheTTL,
+			"partial":     partial,
 		}).Debug("Saving discovered devices to cache")
 
-		if err := SaveCachedDevices(devices, cacheTTL); err != nil {
+		var err error
+		if partial {
+			err = saveDevices(devices, nil)
+		} else {
+			err = SaveCachedDevices(devices, cacheTTL)
+		}
+		if err != nil {
 			// Log error but don't fail the discovery
 			log.WithError(err).Debug("Failed to save devices to cache (continuing anyway)")
 			fmt.Fprintf(os.Stderr, "Warning: failed to save cache: %v\n", err)
@@ -258,12 +345,12 @@ func LoadAppCache() (*AppCache, error) {
 	data, err := os.ReadFile(cachePath)
 	if err != nil {
 		log.WithError(err).WithField("cachePath", cachePath).Debug("Failed to read cache file")
-		return nil, fmt.Errorf("failed to read cache file: %v", err)
+		return nil, fmt.Errorf("failed to read cache file: %w", err)
 	}
 
 	// Try to parse as new AppCache format first
 	var appCache AppCache
-	if err := json.Unmarshal(data, &appCache); err == nil && (appCache.Discovery != nil || appCache.Spotify != nil) {
+	if err := json.Unmarshal(data, &appCache); err == nil && (appCache.Discovery != nil || appCache.Spotify != nil || len(appCache.History) > 0 || len(appCache.Known) > 0) {
 		log.Debug("Successfully loaded new format app cache")
 		return &appCache, nil
 	}
@@ -272,7 +359,7 @@ func LoadAppCache() (*AppCache, error) {
 	var legacyCache CachedDiscovery
 	if err := json.Unmarshal(data, &legacyCache); err != nil {
 		log.WithError(err).WithField("cachePath", cachePath).Debug("Failed to parse cache file")
-		return nil, fmt.Errorf("failed to parse cache file: %v", err)
+		return nil, fmt.Errorf("failed to parse cache file: %w", err)
 	}
 
 	log.Debug("Loaded legacy cache format, converting to new format")
@@ -292,12 +379,12 @@ func SaveAppCache(cache *AppCache) error {
 	data, err := json.MarshalIndent(cache, "", "  ")
 	if err != nil {
 		log.WithError(err).Debug("Failed to marshal cache data to JSON")
-		return fmt.Errorf("failed to marshal cache data: %v", err)
+		return fmt.Errorf("failed to marshal cache data: %w", err)
 	}
 
 	if err := os.WriteFile(cachePath, data, 0644); err != nil {
 		log.WithError(err).WithField("cachePath", cachePath).Debug("Failed to write cache file")
-		return fmt.Errorf("failed to write cache file: %v", err)
+		return fmt.Errorf("failed to write cache file: %w", err)
 	}
 
 	log.Debug("Successfully saved application cache")
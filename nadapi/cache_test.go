@@ -32,6 +32,8 @@ func TestSaveAndLoadCachedDevices(t *testing.T) {
 	testDevices := []DiscoveredDevice{
 		{IP: "192.168.1.100", Model: "NAD C338", Port: "30001"},
 		{IP: "192.168.1.101", Model: "NAD C368", Port: "30001"},
+		{IP: "fe80::1%eth0", Model: "NAD C658", Port: "30001"},
+		{IP: "amp.lan", Model: "NAD T758", Port: "30001"},
 	}
 
 	// Create a temporary cache file
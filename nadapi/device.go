@@ -1,18 +1,21 @@
 package nadapi
 
 import (
-	"bufio"
 	"context"
-	"errors"
 	"fmt"
 	"net"
+	"net/netip"
+	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-	"unicode/utf8"
 
 	log "github.com/sirupsen/logrus"
+
+	"github.com/galamiram/nadctl/nadapi/protocol"
 )
 
 // Direction -
@@ -21,6 +24,14 @@ type Direction int
 const (
 	defaultPort   = "30001"
 	maxBrightness = 3
+	// commandTimeout bounds a single command round trip when the caller's
+	// context carries no earlier deadline
+	commandTimeout = 5 * time.Second
+	// dialTimeout bounds establishing a new TCP connection
+	dialTimeout = 5 * time.Second
+	// standbyCheckTimeout bounds the power query sent after a command went
+	// unanswered
+	standbyCheckTimeout = time.Second
 	// DirectionUp -
 	DirectionUp Direction = 1
 	// DirectionDown -
@@ -31,158 +42,188 @@ var sources = []string{"Stream", "Wireless", "TV", "Phono", "Coax1", "Coax2", "O
 
 // Device is a generic nad receiver
 type Device struct {
-	IP   net.IP
-	Port string
-	conn net.Conn
-	mu   sync.Mutex // Protects concurrent access to the connection
+	IP        net.IP // Address of a device configured by IP, nil otherwise
+	Host      string // Host name or IP as configured, empty for serial devices
+	Port      string
+	transport Transport
+	conn      net.Conn
+	reader    *connReader // Routes lines read from conn
+	mu        sync.Mutex  // Protects concurrent access to the connection
+
+	subsMu sync.Mutex
+	subs   map[chan Event]struct{}
+
+	supMu sync.Mutex
+	sup   *supervisor // Keeps the connection alive, set by Supervise
+
+	capsMu sync.Mutex
+	caps   *Capabilities // Profile of the connected model, loaded on first use
+
+	inputsMu    sync.Mutex
+	inputs      []Input     // Input names and enabled flags, loaded on first use
+	inputsStale atomic.Bool // Set when the device reports an input change
+
+	fadesMu sync.Mutex
+	fades   map[ZoneID]*Fade // Volume fades running, by zone
+
+	limitsMu sync.Mutex
+	limits   *VolumeLimits // Volume safety policy, set by SetVolumeLimits
+
+	pacer pacer // Spaces and coalesces commands, see SetCommandInterval
 }
 
 // DiscoveredDevice represents a NAD device found on the network
 type DiscoveredDevice struct {
-	IP    string
-	Model string
-	Port  string
+	IP       string        `json:"ip"` // IPv4 or IPv6 address, or host name
+	Model    string        `json:"model"`
+	Port     string        `json:"port"`
+	Firmware string        `json:"firmware,omitempty"` // Main.Version reply, if the model answers it
+	MAC      string        `json:"mac,omitempty"`      // From the neighbor table, if on the local link
+	Hostname string        `json:"hostname,omitempty"` // Reverse DNS name
+	Latency  time.Duration `json:"latency_ns,omitempty"`
+	Method   string        `json:"method,omitempty"` // Name of the provider that found the device
 }
 
 // New - create a new device object with an open connection
 func New(addr, port string) (*Device, error) {
+	return NewContext(context.Background(), addr, port)
+}
+
+// NewContext is like New but uses ctx to bound establishing the connection.
+// addr may also be an address URI such as serial:///dev/ttyUSB0, see
+// ParseAddress.
+func NewContext(ctx context.Context, addr, port string) (*Device, error) {
 	log.WithFields(log.Fields{
 		"address": addr,
 		"port":    port,
 	}).Debug("Creating new NAD device connection")
 
-	ip := net.ParseIP(addr)
-	if ip == nil {
-		log.WithField("address", addr).Debug("Failed to parse IP address")
-		return nil, errors.New("failed to parse ip address")
-	}
-	if port == "" {
-		port = defaultPort
-		log.WithField("port", port).Debug("Using default port")
+	t, err := ParseAddress(addr, port)
+	if err != nil {
+		log.WithError(err).WithField("address", addr).Debug("Failed to parse device address")
+		return nil, err
 	}
-	d := &Device{
-		IP:   ip,
-		Port: port,
+	return NewWithTransport(ctx, t)
+}
+
+// NewWithTransport creates a device object that connects through t and
+// opens its first connection
+func NewWithTransport(ctx context.Context, t Transport) (*Device, error) {
+	d := &Device{transport: t}
+	if tcp, ok := t.(*TCPTransport); ok {
+		d.Host = tcp.Host
+		d.Port = tcp.Port
+		if ip, err := netip.ParseAddr(tcp.Host); err == nil {
+			d.IP = net.IP(ip.AsSlice())
+		}
 	}
 
-	log.WithFields(log.Fields{
-		"ip":   d.IP.String(),
-		"port": d.Port,
-	}).Debug("Attempting to establish connection to NAD device")
+	log.WithField("device", d.String()).Debug("Attempting to establish connection to NAD device")
 
-	conn, err := d.newConn()
+	conn, err := d.newConn(ctx)
 	if err != nil {
-		log.WithError(err).WithFields(log.Fields{
-			"ip":   d.IP.String(),
-			"port": d.Port,
-		}).Debug("Failed to establish connection")
+		log.WithError(err).WithField("device", d.String()).Debug("Failed to establish connection")
 		return nil, err
 	}
-	d.conn = conn
+	d.setConn(conn)
 
-	log.WithFields(log.Fields{
-		"ip":   d.IP.String(),
-		"port": d.Port,
-	}).Debug("Successfully connected to NAD device")
+	log.WithField("device", d.String()).Debug("Successfully connected to NAD device")
 
 	return d, nil
 }
 
+// Transport returns how the device is reached, e.g. to open a second
+// device object on the same amplifier
+func (d *Device) Transport() Transport {
+	if d.transport == nil {
+		host := d.Host
+		if host == "" {
+			host = d.IP.String()
+		}
+		return &TCPTransport{Host: host, Port: d.Port}
+	}
+	return d.transport
+}
+
+// String returns the address of the device as a URI
+func (d *Device) String() string {
+	return d.Transport().String()
+}
+
 // PowerOn powers on the device
 func (d *Device) PowerOn() error {
-	log.WithField("device", d.IP.String()).Debug("Powering on device")
-	if _, err := d.send("Main.Power=On"); err != nil {
-		return err
-	}
-	return d.reconnect()
+	return d.PowerOnContext(context.Background())
+}
+
+// PowerOnContext is like PowerOn but takes a context
+func (d *Device) PowerOnContext(ctx context.Context) error {
+	return d.main().PowerOnContext(ctx)
 }
 
 // PowerOff powers off the device
 func (d *Device) PowerOff() error {
-	log.WithField("device", d.IP.String()).Debug("Powering off device")
-	if _, err := d.send("Main.Power=Off"); err != nil {
-		return err
-	}
-	return d.reconnect()
+	return d.PowerOffContext(context.Background())
+}
+
+// PowerOffContext is like PowerOff but takes a context
+func (d *Device) PowerOffContext(ctx context.Context) error {
+	return d.main().PowerOffContext(ctx)
 }
 
 // GetPowerState retrieves the current power state
 func (d *Device) GetPowerState() (string, error) {
-	log.WithField("device", d.IP.String()).Debug("Getting power state")
-	res, err := d.send("Main.Power?")
-	if err != nil {
-		return "", fmt.Errorf("get power state: %v", err)
-	}
-	val, err := extractValue(res)
-	if err != nil {
-		return "", fmt.Errorf("get power state: %v", err)
-	}
-	log.WithFields(log.Fields{
-		"device": d.IP.String(),
-		"state":  val,
-	}).Debug("Retrieved power state")
-	return val, nil
+	return d.GetPowerStateContext(context.Background())
+}
+
+// GetPowerStateContext is like GetPowerState but takes a context
+func (d *Device) GetPowerStateContext(ctx context.Context) (string, error) {
+	return d.main().GetPowerStateContext(ctx)
 }
 
 // PowerToggle power on/off
 func (d *Device) PowerToggle() error {
-	log.WithField("device", d.IP.String()).Debug("Toggling power state")
-	state, err := d.GetPowerState()
-	if err != nil {
-		return err
-	}
-	log.WithFields(log.Fields{
-		"device":       d.IP.String(),
-		"currentState": state,
-	}).Debug("Current power state retrieved for toggle")
+	return d.PowerToggleContext(context.Background())
+}
 
-	if state == "On" {
-		return d.PowerOff()
-	}
-	return d.PowerOn()
+// PowerToggleContext is like PowerToggle but takes a context
+func (d *Device) PowerToggleContext(ctx context.Context) error {
+	return d.main().PowerToggleContext(ctx)
 }
 
 // GetSource retrieves the current source
 func (d *Device) GetSource() (string, error) {
-	log.WithField("device", d.IP.String()).Debug("Getting current source")
-	res, err := d.send("Main.Source?")
-	if err != nil {
-		return "", fmt.Errorf("get source: %v", err)
-	}
-	val, err := extractValue(res)
-	if err != nil {
-		return "", fmt.Errorf("get source: %v", err)
-	}
-	log.WithFields(log.Fields{
-		"device": d.IP.String(),
-		"source": val,
-	}).Debug("Retrieved current source")
-	return val, nil
+	return d.GetSourceContext(context.Background())
+}
+
+// GetSourceContext is like GetSource but takes a context
+func (d *Device) GetSourceContext(ctx context.Context) (string, error) {
+	return d.main().GetSourceContext(ctx)
 }
 
-// GetAvailableSources returns the list of available input sources
+// GetAvailableSources returns the input sources of models without a
+// capability profile; use Capabilities for the connected model
 func GetAvailableSources() []string {
-	return sources
+	return defaultCapabilities.Sources
 }
 
-// GetAvailableBrightnessLevels returns the list of available brightness levels
+// GetAvailableBrightnessLevels returns the brightness levels of models
+// without a capability profile
 func GetAvailableBrightnessLevels() []int {
-	levels := make([]int, maxBrightness+1)
-	for i := 0; i <= maxBrightness; i++ {
-		levels[i] = i
-	}
-	return levels
+	return defaultCapabilities.BrightnessLevels()
 }
 
-// IsValidBrightnessLevel checks if the given brightness level is valid
+// IsValidBrightnessLevel checks the level against the default profile
 func IsValidBrightnessLevel(level int) bool {
-	return level >= 0 && level <= maxBrightness
+	return defaultCapabilities.IsValidBrightnessLevel(level)
 }
 
-// IsValidSource checks if the given source name is valid
+// IsValidSource checks if any known model has the given source
 func IsValidSource(source string) bool {
-	for _, s := range sources {
-		if strings.EqualFold(s, source) {
+	if defaultCapabilities.IsValidSource(source) {
+		return true
+	}
+	for _, p := range profiles {
+		if p.IsValidSource(source) {
 			return true
 		}
 	}
@@ -191,108 +232,42 @@ func IsValidSource(source string) bool {
 
 // SetSource sets the input source to a specific source name
 func (d *Device) SetSource(sourceName string) error {
-	log.WithFields(log.Fields{
-		"device":     d.IP.String(),
-		"sourceName": sourceName,
-	}).Debug("Setting source")
-
-	// Validate source name (case-insensitive)
-	var validSource string
-	for _, s := range sources {
-		if strings.EqualFold(s, sourceName) {
-			validSource = s
-			break
-		}
-	}
-
-	if validSource == "" {
-		log.WithFields(log.Fields{
-			"device":           d.IP.String(),
-			"invalidSource":    sourceName,
-			"availableSources": sources,
-		}).Debug("Invalid source name provided")
-		return fmt.Errorf("invalid source '%s'. Available sources: %v", sourceName, sources)
-	}
-
-	log.WithFields(log.Fields{
-		"device":      d.IP.String(),
-		"sourceName":  sourceName,
-		"validSource": validSource,
-	}).Debug("Validated source name")
-
-	cmd := fmt.Sprintf("Main.Source=%s", validSource)
-	_, err := d.send(cmd)
-	if err != nil {
-		log.WithError(err).WithFields(log.Fields{
-			"device": d.IP.String(),
-			"source": validSource,
-		}).Debug("Failed to set source")
-		return err
-	}
+	return d.SetSourceContext(context.Background(), sourceName)
+}
 
-	log.WithFields(log.Fields{
-		"device": d.IP.String(),
-		"source": validSource,
-	}).Debug("Successfully set source")
-	return nil
+// SetSourceContext is like SetSource but takes a context
+func (d *Device) SetSourceContext(ctx context.Context, sourceName string) error {
+	return d.main().SetSourceContext(ctx, sourceName)
 }
 
 // ToggleSource changes the input source
 func (d *Device) ToggleSource(direction Direction) (string, error) {
-	log.WithFields(log.Fields{
-		"device":    d.IP.String(),
-		"direction": direction,
-	}).Debug("Toggling source")
-
-	src, err := d.GetSource()
-	if err != nil {
-		return "", err
-	}
-
-	log.WithFields(log.Fields{
-		"device":        d.IP.String(),
-		"currentSource": src,
-		"direction":     direction,
-	}).Debug("Current source retrieved for toggle")
-
-	for i, s := range sources {
-		if s == src {
-			pos := i + int(direction)
-			if pos > len(sources)-1 {
-				pos = 0
-			}
-			if pos < 0 {
-				pos = len(sources) - 1
-			}
-			newSource := sources[pos]
-
-			log.WithFields(log.Fields{
-				"device":   d.IP.String(),
-				"from":     src,
-				"to":       newSource,
-				"position": pos,
-			}).Debug("Calculated new source position")
+	return d.ToggleSourceContext(context.Background(), direction)
+}
 
-			cmd := fmt.Sprintf("Main.Source=%s", newSource)
-			return d.send(cmd)
-		}
-	}
-	return "", errors.New("undefined source name")
+// ToggleSourceContext is like ToggleSource but takes a context
+func (d *Device) ToggleSourceContext(ctx context.Context, direction Direction) (string, error) {
+	return d.main().ToggleSourceContext(ctx, direction)
 }
 
 // GetModel retrieves the model of the device
 func (d *Device) GetModel() (string, error) {
-	log.WithField("device", d.IP.String()).Debug("Getting device model")
-	res, err := d.send("Main.Model?")
+	return d.GetModelContext(context.Background())
+}
+
+// GetModelContext is like GetModel but takes a context
+func (d *Device) GetModelContext(ctx context.Context) (string, error) {
+	log.WithField("device", d.String()).Debug("Getting device model")
+	res, err := d.send(ctx, "Main.Model?")
 	if err != nil {
 		return "", err
 	}
 	val, err := extractValue(res)
 	if err != nil {
-		return "", fmt.Errorf("get device model: %v", err)
+		return "", fmt.Errorf("get device model: %w", err)
 	}
 	log.WithFields(log.Fields{
-		"device": d.IP.String(),
+		"device": d.String(),
 		"model":  val,
 	}).Debug("Retrieved device model")
 	return val, nil
@@ -300,170 +275,92 @@ func (d *Device) GetModel() (string, error) {
 
 // TuneVolume increases or decreases the device volume
 func (d *Device) TuneVolume(direction Direction) error {
-	log.WithFields(log.Fields{
-		"device":    d.IP.String(),
-		"direction": direction,
-	}).Debug("Tuning volume")
-
-	vol, err := d.GetVolume()
-	if err != nil {
-		return err
-	}
-	v, err := strconv.ParseFloat(vol, 64)
-	if err != nil {
-		return fmt.Errorf("tune volume: %v", err)
-	}
+	return d.TuneVolumeContext(context.Background(), direction)
+}
 
-	newVolume := v + float64(direction)
-	log.WithFields(log.Fields{
-		"device":     d.IP.String(),
-		"currentVol": v,
-		"direction":  direction,
-		"newVolume":  newVolume,
-	}).Debug("Calculated new volume level")
-
-	cmd := fmt.Sprintf("Main.Volume=%f", newVolume)
-	_, err = d.send(cmd)
-	return err
+// TuneVolumeContext is like TuneVolume but takes a context
+func (d *Device) TuneVolumeContext(ctx context.Context, direction Direction) error {
+	return d.main().TuneVolumeContext(ctx, direction)
 }
 
 // SetVolume sets the volume to a specific level
 func (d *Device) SetVolume(volume float64) error {
-	log.WithFields(log.Fields{
-		"device": d.IP.String(),
-		"volume": volume,
-	}).Debug("Setting volume")
-
-	// NAD devices typically support volume ranges, but let's be safe with bounds
-	originalVolume := volume
-	if volume < -80 {
-		volume = -80 // Typical minimum volume
-		log.WithFields(log.Fields{
-			"device":       d.IP.String(),
-			"requestedVol": originalVolume,
-			"adjustedVol":  volume,
-		}).Debug("Volume clamped to minimum (-80 dB)")
-	}
-	if volume > 10 {
-		volume = 10 // Typical maximum volume to prevent damage
-		log.WithFields(log.Fields{
-			"device":       d.IP.String(),
-			"requestedVol": originalVolume,
-			"adjustedVol":  volume,
-		}).Debug("Volume clamped to maximum (10 dB)")
-	}
+	return d.SetVolumeContext(context.Background(), volume)
+}
 
-	cmd := fmt.Sprintf("Main.Volume=%f", volume)
-	_, err := d.send(cmd)
-	if err != nil {
-		log.WithError(err).WithFields(log.Fields{
-			"device": d.IP.String(),
-			"volume": volume,
-		}).Debug("Failed to set volume")
-	} else {
-		log.WithFields(log.Fields{
-			"device": d.IP.String(),
-			"volume": volume,
-		}).Debug("Successfully set volume")
-	}
-	return err
+// SetVolumeContext is like SetVolume but takes a context
+func (d *Device) SetVolumeContext(ctx context.Context, volume float64) error {
+	return d.main().SetVolumeContext(ctx, volume)
 }
 
 // GetVolume retrieves the volume from the device
 func (d *Device) GetVolume() (string, error) {
-	log.WithField("device", d.IP.String()).Debug("Getting volume")
-	res, err := d.send("Main.Volume?")
-	if err != nil {
-		return "", fmt.Errorf("get volume: %v", err)
-	}
-	val, err := extractValue(res)
-	if err != nil {
-		return "", fmt.Errorf("get volume: %v", err)
-	}
-	log.WithFields(log.Fields{
-		"device": d.IP.String(),
-		"volume": val,
-	}).Debug("Retrieved volume")
-	return val, nil
+	return d.GetVolumeContext(context.Background())
+}
+
+// GetVolumeContext is like GetVolume but takes a context
+func (d *Device) GetVolumeContext(ctx context.Context) (string, error) {
+	return d.main().GetVolumeContext(ctx)
 }
 
 // GetVolumeFloat retrieves the volume as a float64 value
 func (d *Device) GetVolumeFloat() (float64, error) {
-	volStr, err := d.GetVolume()
-	if err != nil {
-		return 0, err
-	}
-
-	vol, err := strconv.ParseFloat(volStr, 64)
-	if err != nil {
-		log.WithError(err).WithFields(log.Fields{
-			"device":    d.IP.String(),
-			"volumeStr": volStr,
-		}).Debug("Failed to parse volume as float")
-		return 0, fmt.Errorf("failed to parse volume: %v", err)
-	}
+	return d.GetVolumeFloatContext(context.Background())
+}
 
-	log.WithFields(log.Fields{
-		"device":      d.IP.String(),
-		"volumeFloat": vol,
-	}).Debug("Retrieved volume as float")
-	return vol, nil
+// GetVolumeFloatContext is like GetVolumeFloat but takes a context
+func (d *Device) GetVolumeFloatContext(ctx context.Context) (float64, error) {
+	return d.main().GetVolumeFloatContext(ctx)
 }
 
 // GetMuteStatus -
 func (d *Device) GetMuteStatus() (string, error) {
-	log.WithField("device", d.IP.String()).Debug("Getting mute status")
-	res, err := d.send("Main.Mute?")
-	if err != nil {
-		return "", fmt.Errorf("get mute status: %v", err)
-	}
-	val, err := extractValue(res)
-	if err != nil {
-		return "", fmt.Errorf("get mute status: %v", err)
-	}
-	log.WithFields(log.Fields{
-		"device":     d.IP.String(),
-		"muteStatus": val,
-	}).Debug("Retrieved mute status")
-	return val, nil
+	return d.GetMuteStatusContext(context.Background())
+}
+
+// GetMuteStatusContext is like GetMuteStatus but takes a context
+func (d *Device) GetMuteStatusContext(ctx context.Context) (string, error) {
+	return d.main().GetMuteStatusContext(ctx)
 }
 
 // ToggleMute -
 func (d *Device) ToggleMute() error {
-	log.WithField("device", d.IP.String()).Debug("Toggling mute")
-	res, err := d.GetMuteStatus()
-	if err != nil {
-		return fmt.Errorf("get mute: %v", err)
-	}
+	return d.ToggleMuteContext(context.Background())
+}
 
-	log.WithFields(log.Fields{
-		"device":      d.IP.String(),
-		"currentMute": res,
-	}).Debug("Current mute status retrieved for toggle")
+// ToggleMuteContext is like ToggleMute but takes a context
+func (d *Device) ToggleMuteContext(ctx context.Context) error {
+	return d.main().ToggleMuteContext(ctx)
+}
 
-	if res == "Off" {
-		log.WithField("device", d.IP.String()).Debug("Muting device (turning mute On)")
-		_, err = d.send("Main.Mute=On")
-		return err
-	}
-	log.WithField("device", d.IP.String()).Debug("Unmuting device (turning mute Off)")
-	_, err = d.send("Main.Mute=Off")
-	return err
+// SetMute mutes or unmutes the device
+func (d *Device) SetMute(muted bool) error {
+	return d.SetMuteContext(context.Background(), muted)
+}
+
+// SetMuteContext is like SetMute but takes a context
+func (d *Device) SetMuteContext(ctx context.Context, muted bool) error {
+	return d.main().SetMuteContext(ctx, muted)
 }
 
 // GetBrightness retrieve the brightness level from the device
 func (d *Device) GetBrightness() (string, error) {
-	log.WithField("device", d.IP.String()).Debug("Getting brightness")
-	res, err := d.send("Main.Brightness?")
+	return d.GetBrightnessContext(context.Background())
+}
+
+// GetBrightnessContext is like GetBrightness but takes a context
+func (d *Device) GetBrightnessContext(ctx context.Context) (string, error) {
+	log.WithField("device", d.String()).Debug("Getting brightness")
+	res, err := d.send(ctx, "Main.Brightness?")
 	if err != nil {
-		return "", fmt.Errorf("get brightness: %s", err)
+		return "", fmt.Errorf("get brightness: %w", err)
 	}
 	val, err := extractValue(res)
 	if err != nil {
-		return "", fmt.Errorf("get brightness: %v", err)
+		return "", fmt.Errorf("get brightness: %w", err)
 	}
 	log.WithFields(log.Fields{
-		"device":     d.IP.String(),
+		"device":     d.String(),
 		"brightness": val,
 	}).Debug("Retrieved brightness")
 	return val, nil
@@ -471,7 +368,12 @@ func (d *Device) GetBrightness() (string, error) {
 
 // GetBrightnessInt retrieves the brightness level as an integer
 func (d *Device) GetBrightnessInt() (int, error) {
-	brightnessStr, err := d.GetBrightness()
+	return d.GetBrightnessIntContext(context.Background())
+}
+
+// GetBrightnessIntContext is like GetBrightnessInt but takes a context
+func (d *Device) GetBrightnessIntContext(ctx context.Context) (int, error) {
+	brightnessStr, err := d.GetBrightnessContext(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -479,45 +381,55 @@ func (d *Device) GetBrightnessInt() (int, error) {
 	brightness, err := strconv.Atoi(brightnessStr)
 	if err != nil {
 		log.WithError(err).WithFields(log.Fields{
-			"device":        d.IP.String(),
+			"device":        d.String(),
 			"brightnessStr": brightnessStr,
 		}).Debug("Failed to parse brightness as integer")
-		return 0, fmt.Errorf("failed to parse brightness: %v", err)
+		return 0, fmt.Errorf("failed to parse brightness: %w", err)
 	}
 
 	log.WithFields(log.Fields{
-		"device":        d.IP.String(),
+		"device":        d.String(),
 		"brightnessInt": brightness,
 	}).Debug("Retrieved brightness as integer")
 	return brightness, nil
 }
 
-// SetBrightness sets the brightness to a specific level (0-3)
+// SetBrightness sets the brightness to a specific level supported by the model
 func (d *Device) SetBrightness(level int) error {
+	return d.SetBrightnessContext(context.Background(), level)
+}
+
+// SetBrightnessContext is like SetBrightness but takes a context
+func (d *Device) SetBrightnessContext(ctx context.Context, level int) error {
 	log.WithFields(log.Fields{
-		"device": d.IP.String(),
+		"device": d.String(),
 		"level":  level,
 	}).Debug("Setting brightness")
 
-	if !IsValidBrightnessLevel(level) {
+	caps, err := d.CapabilitiesContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !caps.IsValidBrightnessLevel(level) {
 		log.WithFields(log.Fields{
-			"device":       d.IP.String(),
+			"device":       d.String(),
 			"invalidLevel": level,
-			"validLevels":  GetAvailableBrightnessLevels(),
+			"validLevels":  caps.BrightnessLevels(),
 		}).Debug("Invalid brightness level provided")
-		return fmt.Errorf("invalid brightness level %d. Valid levels: %v", level, GetAvailableBrightnessLevels())
+		return withKind(ErrInvalidValue, fmt.Errorf("invalid brightness level %d. Valid levels: %v", level, caps.BrightnessLevels()))
 	}
 
 	cmd := fmt.Sprintf("Main.Brightness=%d", level)
-	_, err := d.send(cmd)
+	_, err = d.send(ctx, cmd)
 	if err != nil {
 		log.WithError(err).WithFields(log.Fields{
-			"device": d.IP.String(),
+			"device": d.String(),
 			"level":  level,
 		}).Debug("Failed to set brightness")
 	} else {
 		log.WithFields(log.Fields{
-			"device": d.IP.String(),
+			"device": d.String(),
 			"level":  level,
 		}).Debug("Successfully set brightness")
 	}
@@ -526,481 +438,380 @@ func (d *Device) SetBrightness(level int) error {
 
 // ToggleBrightness change the screen brightness of the device
 func (d *Device) ToggleBrightness(direction Direction) error {
+	return d.ToggleBrightnessContext(context.Background(), direction)
+}
+
+// ToggleBrightnessContext is like ToggleBrightness but takes a context
+func (d *Device) ToggleBrightnessContext(ctx context.Context, direction Direction) error {
 	log.WithFields(log.Fields{
-		"device":    d.IP.String(),
+		"device":    d.String(),
 		"direction": direction,
 	}).Debug("Toggling brightness")
 
-	val, err := d.GetBrightness()
+	caps, err := d.CapabilitiesContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	val, err := d.GetBrightnessContext(ctx)
 	if err != nil {
 		return err
 	}
 	intVal, err := strconv.Atoi(val)
 	if err != nil {
-		return fmt.Errorf("toggle brightness: %s ", err)
+		return fmt.Errorf("toggle brightness: %w", err)
 	}
 
 	brightness := intVal + int(direction)
-	if brightness > maxBrightness {
+	if brightness > caps.MaxBrightness {
 		brightness = 0
 	}
 	if brightness < 0 {
-		brightness = maxBrightness
+		brightness = caps.MaxBrightness
 	}
 
 	log.WithFields(log.Fields{
-		"device":       d.IP.String(),
+		"device":       d.String(),
 		"currentLevel": intVal,
 		"direction":    direction,
 		"newLevel":     brightness,
 	}).Debug("Calculated new brightness level")
 
 	cmd := fmt.Sprintf("Main.Brightness=%d", brightness)
-	_, err = d.send(cmd)
+	_, err = d.send(ctx, cmd)
 	return err
 }
 
-// GetRead return bufio reader for reading device messages
-func (d *Device) GetRead() (*bufio.Reader, error) {
-	conn, err := d.newConn()
-	if err != nil {
-		return nil, err
-	}
-	// Note: Caller is responsible for closing the connection
-	r := bufio.NewReader(conn)
-	return r, nil
-}
-
-// Disconnect from device
+// Disconnect from device, ending any supervision
 func (d *Device) Disconnect() error {
+	d.stopSupervising()
+	d.stopFades()
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	if d.conn == nil {
-		log.WithField("device", d.IP.String()).Debug("Connection already nil, nothing to disconnect")
+		log.WithField("device", d.String()).Debug("Connection already nil, nothing to disconnect")
 		return nil
 	}
 
-	log.WithField("device", d.IP.String()).Debug("Disconnecting from device")
-	err := d.conn.Close()
-	d.conn = nil // Always set to nil after close attempt
+	log.WithField("device", d.String()).Debug("Disconnecting from device")
+	err := d.closeConn()
 
 	if err != nil {
-		log.WithError(err).WithField("device", d.IP.String()).Debug("Error during disconnect")
+		log.WithError(err).WithField("device", d.String()).Debug("Error during disconnect")
 	} else {
-		log.WithField("device", d.IP.String()).Debug("Successfully disconnected from device")
+		log.WithField("device", d.String()).Debug("Successfully disconnected from device")
 	}
 
 	return err
 }
 
-func (d *Device) reconnect() error {
+func (d *Device) reconnect(ctx context.Context) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	log.WithField("device", d.IP.String()).Debug("Reconnecting to device")
+	log.WithField("device", d.String()).Debug("Reconnecting to device")
 
 	// Close existing connection if it exists
 	if d.conn != nil {
-		if err := d.conn.Close(); err != nil {
-			log.WithError(err).WithField("device", d.IP.String()).Debug("Error during disconnect for reconnect")
+		if err := d.closeConn(); err != nil {
+			log.WithError(err).WithField("device", d.String()).Debug("Error during disconnect for reconnect")
 			// Continue anyway - the connection might already be closed
 		}
-		d.conn = nil
 	}
 
-	conn, err := d.newConn()
+	conn, err := d.newConn(ctx)
 	if err != nil {
-		log.WithError(err).WithField("device", d.IP.String()).Debug("Failed to establish new connection during reconnect")
+		log.WithError(err).WithField("device", d.String()).Debug("Failed to establish new connection during reconnect")
 		return err
 	}
-	d.conn = conn
-	log.WithField("device", d.IP.String()).Debug("Successfully reconnected")
+	d.setConn(conn)
+	log.WithField("device", d.String()).Debug("Successfully reconnected")
 	return nil
 }
 
-func (d *Device) newConn() (net.Conn, error) {
-	connString := fmt.Sprintf("%s:%s", d.IP.String(), d.Port)
-	log.WithFields(log.Fields{
-		"device":     d.IP.String(),
-		"connString": connString,
-	}).Debug("Creating new TCP connection")
+func (d *Device) newConn(ctx context.Context) (net.Conn, error) {
+	conn, err := d.Transport().Dial(ctx)
+	return conn, withKind(ErrNotConnected, err)
+}
 
-	conn, err := net.Dial("tcp", connString)
+// commandDeadline returns the deadline for a single command round trip:
+// commandTimeout from now, or the context deadline if that comes first
+func commandDeadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(commandTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		return ctxDeadline
+	}
+	return deadline
+}
+
+// setConn installs conn as the active connection and starts reading from it.
+// The caller must hold d.mu.
+func (d *Device) setConn(conn net.Conn) {
+	d.conn = conn
+	d.reader = d.startReader(conn)
+}
+
+// closeConn closes the active connection, which also stops its reader.
+// The caller must hold d.mu.
+func (d *Device) closeConn() error {
+	err := d.conn.Close()
+	d.conn = nil // Always set to nil after close attempt
+	d.reader = nil
+	return err
+}
+
+func (d *Device) send(ctx context.Context, cmd string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("command cancelled: %w", err)
+	}
+	msg, err := protocol.Parse(cmd)
 	if err != nil {
-		log.WithError(err).WithFields(log.Fields{
-			"device":     d.IP.String(),
-			"connString": connString,
-		}).Debug("Failed to create TCP connection")
-	} else {
-		log.WithFields(log.Fields{
-			"device":     d.IP.String(),
-			"connString": connString,
-		}).Debug("Successfully created TCP connection")
+		return "", fmt.Errorf("invalid command: %w", err)
 	}
-	return conn, err
+	replies, err := d.exchange(ctx, []protocol.Message{msg}, 0)
+	if err != nil {
+		return "", err
+	}
+	return replies[0], nil
 }
 
-func (d *Device) send(cmd string) (string, error) {
+// exchange writes msgs to the device at once and waits for the reply to
+// each, matched by key, so that a batch costs a single round trip. The
+// replies come back in the order of msgs, whose keys must differ. A
+// probeTimeout above zero bounds the wait for the replies, leaving those
+// missing by then empty, and a cancelled caller then leaves the connection
+// up.
+func (d *Device) exchange(ctx context.Context, msgs []protocol.Message, probeTimeout time.Duration) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("command cancelled: %w", err)
+	}
+	keys := make([]string, len(msgs))
+	lines := make([]string, len(msgs))
+	for i, msg := range msgs {
+		keys[i], lines[i] = msg.Key, msg.String()
+	}
+	cmd := strings.Join(lines, " ")
+
+	// Hold the command while a supervised connection is being re-established
+	if s := d.supervisor(); s != nil {
+		if err := s.waitReady(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// Wait for the turn of the command, which a newer one may take over
+	end, err := d.pacer.wait(ctx, msgs)
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"device":  d.String(),
+			"command": cmd,
+		}).Debug("Command not sent")
+		return nil, err
+	}
+	defer end()
+
 	// Lock to prevent concurrent access to the connection
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	log.WithFields(log.Fields{
-		"device":  d.IP.String(),
+		"device":  d.String(),
 		"command": cmd,
 	}).Debug("Sending command to device")
 
 	// Check if connection is valid, create new one if needed
 	if d.conn == nil {
-		log.WithField("device", d.IP.String()).Debug("Connection is nil, creating new connection")
-		conn, err := d.newConn()
+		log.WithField("device", d.String()).Debug("Connection is nil, creating new connection")
+		conn, err := d.newConn(ctx)
 		if err != nil {
-			return "", fmt.Errorf("failed to create connection: %w", err)
+			return nil, fmt.Errorf("failed to create connection: %w", err)
 		}
-		d.conn = conn
+		d.setConn(conn)
 	}
 
-	// Set a reasonable timeout for the entire operation
-	deadline := time.Now().Add(5 * time.Second)
-	d.conn.SetDeadline(deadline)
-	defer d.conn.SetDeadline(time.Time{}) // Clear deadline after operation
+	// Bound the whole operation by the command timeout or the caller's deadline
+	deadline := commandDeadline(ctx)
+	probing := probeTimeout > 0
+	if probeDeadline := time.Now().Add(probeTimeout); probing && probeDeadline.Before(deadline) {
+		deadline = probeDeadline
+	}
+	timeout := time.Until(deadline).Round(time.Millisecond)
+	ctxDeadline, ok := ctx.Deadline()
+	callerDeadline := ok && ctxDeadline.Equal(deadline)
 
-	// Send command
-	_, err := fmt.Fprintf(d.conn, "%s", cmd)
+	// Register for the replies before writing so a fast device cannot beat us
+	reply := d.reader.expect(keys...)
+	err = d.write(ctx, deadline, msgs...)
 	if err != nil {
 		log.WithError(err).WithFields(log.Fields{
-			"device":  d.IP.String(),
+			"device":  d.String(),
 			"command": cmd,
 		}).Debug("Failed to send command, attempting reconnection")
 
 		// Close the faulty connection and mark as invalid
-		if closeErr := d.conn.Close(); closeErr != nil {
-			log.WithError(closeErr).WithField("device", d.IP.String()).Debug("Error closing faulty connection")
-		}
-		d.conn = nil
-
-		// Try to reconnect and retry once
-		conn, reconnectErr := d.newConn()
-		if reconnectErr != nil {
-			return "", fmt.Errorf("failed to send command and reconnect failed: %w", err)
+		if closeErr := d.closeConn(); closeErr != nil {
+			log.WithError(closeErr).WithField("device", d.String()).Debug("Error closing faulty connection")
 		}
-		d.conn = conn
-
-		// Retry the command once
-		d.conn.SetDeadline(time.Now().Add(5 * time.Second))
-		defer d.conn.SetDeadline(time.Time{})
 
-		_, retryErr := fmt.Fprintf(d.conn, "%s", cmd)
-		if retryErr != nil {
-			return "", fmt.Errorf("failed to send command after reconnect: %w", retryErr)
+		// A cancelled caller does not get a retry
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("command cancelled: %w", ctxErr)
 		}
-	}
-
-	// Read response
-	status, err := bufio.NewReader(d.conn).ReadString('\n')
-	if err != nil {
-		log.WithError(err).WithFields(log.Fields{
-			"device":  d.IP.String(),
-			"command": cmd,
-		}).Debug("Failed to read response")
 
-		// Close the faulty connection and mark as invalid
-		if closeErr := d.conn.Close(); closeErr != nil {
-			log.WithError(closeErr).WithField("device", d.IP.String()).Debug("Error closing faulty connection after read error")
+		// Try to reconnect and retry once
+		conn, reconnectErr := d.newConn(ctx)
+		if reconnectErr != nil {
+			d.connLost()
+			return nil, fmt.Errorf("failed to send command and reconnect failed: %w", withKind(ErrNotConnected, err))
 		}
-		d.conn = nil
+		d.setConn(conn)
 
-		// Check if it's a timeout error
-		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-			return "", fmt.Errorf("command timeout after 5 seconds: %w", err)
+		reply = d.reader.expect(keys...)
+		if retryErr := d.write(ctx, deadline, msgs...); retryErr != nil {
+			d.closeConn()
+			d.connLost()
+			return nil, fmt.Errorf("failed to send command after reconnect: %w", withKind(ErrNotConnected, retryErr))
 		}
-		return "", fmt.Errorf("failed to read response: %w", err)
 	}
-
-	log.WithFields(log.Fields{
-		"device":   d.IP.String(),
-		"command":  cmd,
-		"response": strings.TrimSpace(status),
-	}).Debug("Received response from device")
-
-	return status, nil
-}
-
-func trimSuffix(s string) string {
-	_, size := utf8.DecodeLastRuneInString(s)
-	return s[:len(s)-size-1]
-}
-
-func extractValue(raw string) (string, error) {
-	s := strings.Split(raw, "=")
-	if len(s) > 1 {
-		return trimSuffix(s[1]), nil
-	}
-	return "", errors.New("failed to extract value")
-}
-
-// DiscoverDevices scans the local network for NAD devices
-func DiscoverDevices(timeout time.Duration) ([]DiscoveredDevice, error) {
-	log.WithField("timeout", timeout).Debug("Starting NAD device discovery")
-
-	// Get local network interfaces
-	interfaces, err := net.Interfaces()
-	if err != nil {
-		log.WithError(err).Debug("Failed to get network interfaces")
-		return nil, fmt.Errorf("failed to get network interfaces: %v", err)
-	}
-
-	log.WithField("interfaceCount", len(interfaces)).Debug("Retrieved network interfaces")
-
-	var devices []DiscoveredDevice
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	scannedSubnets := 0
-	// Scan each network interface
-	for _, iface := range interfaces {
-		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
-			log.WithFields(log.Fields{
-				"interface": iface.Name,
-				"flags":     iface.Flags,
-			}).Debug("Skipping interface (down or loopback)")
-			continue
-		}
-
-		log.WithField("interface", iface.Name).Debug("Processing network interface")
-
-		addrs, err := iface.Addrs()
-		if err != nil {
-			log.WithError(err).WithField("interface", iface.Name).Debug("Failed to get interface addresses")
-			continue
-		}
-
-		log.WithFields(log.Fields{
-			"interface":    iface.Name,
-			"addressCount": len(addrs),
-		}).Debug("Retrieved interface addresses")
-
-		for _, addr := range addrs {
-			ipNet, ok := addr.(*net.IPNet)
-			if !ok || ipNet.IP.To4() == nil {
-				log.WithFields(log.Fields{
-					"interface": iface.Name,
-					"address":   addr.String(),
-				}).Debug("Skipping non-IPv4 address")
-				continue
+	reader := d.reader
+	defer reader.forget()
+
+	// Wait for the replies; unrelated lines are routed to subscribers meanwhile
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	replies := make([]string, len(msgs))
+	var failure error
+	keepConn := false // Whether the connection survives the failure
+	for pending := len(msgs); pending > 0 && failure == nil; {
+		select {
+		case line := <-reply:
+			key := messageKey(line)
+			for i := range keys {
+				if strings.EqualFold(keys[i], key) {
+					replies[i] = line
+				}
 			}
-
-			log.WithFields(log.Fields{
-				"interface": iface.Name,
-				"subnet":    ipNet.String(),
-			}).Debug("Scanning subnet for NAD devices")
-
-			scannedSubnets++
-			// Scan this subnet
-			wg.Add(1)
-			go func(subnet *net.IPNet, ifaceName string) {
-				defer wg.Done()
-				found := scanSubnet(ctx, subnet)
-				mu.Lock()
-				devices = append(devices, found...)
+			pending--
+		case <-reader.done:
+			failure = fmt.Errorf("failed to read response: %w", withKind(ErrNotConnected, reader.err))
+			d.connLost()
+		case <-ctx.Done():
+			// The caller's context takes precedence over the I/O error it caused
+			failure = fmt.Errorf("command cancelled: %w", ctx.Err())
+			keepConn = probing
+		case <-timer.C:
+			// Check the standby state of the first key left unanswered
+			key := keys[slices.Index(replies, "")]
+			keepConn = probing
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				failure = fmt.Errorf("command cancelled: %w", ctxErr)
+			} else if callerDeadline {
+				// The timer may beat the context to its own deadline
+				failure = fmt.Errorf("%w after %v: %w", ErrTimeout, timeout, context.DeadlineExceeded)
+			} else if probing {
 				log.WithFields(log.Fields{
-					"interface":    ifaceName,
-					"subnet":       subnet.String(),
-					"devicesFound": len(found),
-				}).Debug("Subnet scan completed")
-				mu.Unlock()
-			}(ipNet, iface.Name)
-		}
-	}
-
-	log.WithField("scannedSubnets", scannedSubnets).Debug("Waiting for all subnet scans to complete")
-	wg.Wait()
-
-	log.WithFields(log.Fields{
-		"totalDevices": len(devices),
-		"timeout":      timeout,
-	}).Debug("Device discovery completed")
-
-	return devices, nil
-}
-
-// scanSubnet scans a subnet for NAD devices
-func scanSubnet(ctx context.Context, subnet *net.IPNet) []DiscoveredDevice {
-	log.WithField("subnet", subnet.String()).Debug("Starting subnet scan")
-
-	var devices []DiscoveredDevice
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-
-	scannedIPs := 0
-	foundDevices := 0
-
-	// Generate IPs in the subnet
-	for ip := subnet.IP.Mask(subnet.Mask); subnet.Contains(ip); inc(ip) {
-		if ctx.Err() != nil {
-			log.WithFields(log.Fields{
-				"subnet": subnet.String(),
-				"error":  ctx.Err(),
-			}).Debug("Subnet scan cancelled due to context")
-			break
-		}
-
-		ipStr := ip.String()
-		// Skip network and broadcast addresses
-		if ipStr == subnet.IP.String() || strings.HasSuffix(ipStr, ".0") || strings.HasSuffix(ipStr, ".255") {
-			log.WithFields(log.Fields{
-				"subnet": subnet.String(),
-				"ip":     ipStr,
-			}).Debug("Skipping network/broadcast address")
-			continue
-		}
-
-		scannedIPs++
-		wg.Add(1)
-		go func(target string) {
-			defer wg.Done()
-			if device := testNADDevice(ctx, target); device != nil {
-				mu.Lock()
-				devices = append(devices, *device)
-				foundDevices++
+					"device":  d.String(),
+					"command": cmd,
+					"key":     key,
+				}).Debug("Device left probed keys unanswered")
+				pending = 0
+			} else if !strings.HasSuffix(key, ".Power") && d.inStandby(ctx, key) {
+				// The connection is fine, the device just ignores the command
 				log.WithFields(log.Fields{
-					"subnet": subnet.String(),
-					"ip":     target,
-					"model":  device.Model,
-				}).Debug("Found NAD device")
-				mu.Unlock()
+					"device":  d.String(),
+					"command": cmd,
+				}).Debug("Device in standby ignored command")
+				return nil, fmt.Errorf("%w and ignored the command", ErrStandby)
+			} else {
+				failure = fmt.Errorf("%w after %v: %w", ErrTimeout, timeout, os.ErrDeadlineExceeded)
+				d.connLost()
 			}
-		}(ipStr)
+		}
 	}
-
-	log.WithFields(log.Fields{
-		"subnet":     subnet.String(),
-		"scannedIPs": scannedIPs,
-	}).Debug("Waiting for IP scans to complete in subnet")
-
-	wg.Wait()
-
-	log.WithFields(log.Fields{
-		"subnet":       subnet.String(),
-		"scannedIPs":   scannedIPs,
-		"foundDevices": foundDevices,
-	}).Debug("Subnet scan completed")
-
-	return devices
-}
-
-// testNADDevice tests if an IP address hosts a NAD device
-func testNADDevice(ctx context.Context, ip string) *DiscoveredDevice {
-	log.WithField("ip", ip).Debug("Testing IP for NAD device")
-
-	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, defaultPort), 2*time.Second)
-	if err != nil {
+	if failure == nil {
 		log.WithFields(log.Fields{
-			"ip":    ip,
-			"error": err.Error(),
-		}).Debug("Failed to connect to IP")
-		return nil
+			"device":   d.String(),
+			"command":  cmd,
+			"response": strings.Join(replies, " "),
+		}).Debug("Received response from device")
+		return replies, nil
 	}
 
-	// Ensure connection is always closed
-	defer func() {
-		if closeErr := conn.Close(); closeErr != nil {
-			log.WithFields(log.Fields{
-				"ip":    ip,
-				"error": closeErr.Error(),
-			}).Debug("Error closing test connection")
-		} else {
-			log.WithField("ip", ip).Debug("Successfully closed test connection")
-		}
-	}()
-
-	log.WithField("ip", ip).Debug("Successfully connected, testing for NAD device")
-
-	// Set deadline for the connection
-	deadline, ok := ctx.Deadline()
-	if ok {
-		conn.SetDeadline(deadline)
-	} else {
-		// Set a reasonable timeout if no context deadline
-		conn.SetDeadline(time.Now().Add(5 * time.Second))
+	log.WithError(failure).WithFields(log.Fields{
+		"device":  d.String(),
+		"command": cmd,
+	}).Debug("Failed to read response")
+	if keepConn {
+		return nil, failure
 	}
 
-	// Try to get the model to verify it's a NAD device
-	_, err = fmt.Fprintf(conn, "Main.Model?")
-	if err != nil {
-		log.WithFields(log.Fields{
-			"ip":      ip,
-			"command": "Main.Model?",
-			"error":   err.Error(),
-		}).Debug("Failed to send model query")
-		return nil
+	// Close the faulty connection and mark as invalid
+	if closeErr := d.closeConn(); closeErr != nil {
+		log.WithError(closeErr).WithField("device", d.String()).Debug("Error closing faulty connection after read error")
 	}
+	return nil, failure
+}
 
-	response, err := bufio.NewReader(conn).ReadString('\n')
-	if err != nil {
-		log.WithFields(log.Fields{
-			"ip":      ip,
-			"command": "Main.Model?",
-			"error":   err.Error(),
-		}).Debug("Failed to read model response")
-		return nil
+// inStandby asks for the power state of the zone of key after a command
+// on key went unanswered, telling a device in standby from one that stopped
+// answering. The caller must hold d.mu.
+func (d *Device) inStandby(ctx context.Context, key string) bool {
+	powerKey := string(MainZone) + ".Power"
+	if zone := zoneOfKey(key); zone != "" {
+		powerKey = string(zone) + ".Power"
 	}
 
-	log.WithFields(log.Fields{
-		"ip":       ip,
-		"response": strings.TrimSpace(response),
-	}).Debug("Received model response")
-
-	// Extract model from response
-	model, err := extractValue(response)
-	if err != nil {
-		log.WithFields(log.Fields{
-			"ip":       ip,
-			"response": strings.TrimSpace(response),
-			"error":    err.Error(),
-		}).Debug("Failed to extract model from response")
-		return nil
+	reader := d.reader
+	reply := reader.expect(powerKey)
+	deadline := time.Now().Add(standbyCheckTimeout)
+	if err := d.write(ctx, deadline, protocol.Query(powerKey)); err != nil {
+		return false
 	}
 
-	log.WithFields(log.Fields{
-		"ip":    ip,
-		"model": model,
-	}).Debug("Extracted model from response")
-
-	// Verify it looks like a NAD model
-	if strings.Contains(strings.ToUpper(model), "NAD") ||
-		strings.Contains(strings.ToUpper(model), "C338") {
-		log.WithFields(log.Fields{
-			"ip":    ip,
-			"model": model,
-		}).Debug("Confirmed NAD device")
-		return &DiscoveredDevice{
-			IP:    ip,
-			Model: model,
-			Port:  defaultPort,
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case line := <-reply:
+		value, err := extractValue(line)
+		if err != nil {
+			return false
 		}
+		power, err := ParsePowerState(value)
+		return err == nil && power == PowerOff
+	case <-reader.done:
+	case <-ctx.Done():
+	case <-timer.C:
 	}
+	return false
+}
 
-	log.WithFields(log.Fields{
-		"ip":    ip,
-		"model": model,
-	}).Debug("Device model does not appear to be NAD")
+// write sends msgs on the active connection, giving up at deadline or as
+// soon as ctx is done
+func (d *Device) write(ctx context.Context, deadline time.Time, msgs ...protocol.Message) error {
+	conn := d.conn
+	conn.SetWriteDeadline(deadline)
+	defer conn.SetWriteDeadline(time.Time{})
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetWriteDeadline(time.Unix(1, 0))
+	})
+	defer stop()
 
-	return nil
+	return protocol.EncodeAll(conn, msgs...)
 }
 
-// inc increments an IP address
-func inc(ip net.IP) {
-	for j := len(ip) - 1; j >= 0; j-- {
-		ip[j]++
-		if ip[j] > 0 {
-			break
-		}
+// extractValue returns the value a reply line reports
+func extractValue(raw string) (string, error) {
+	msg, err := protocol.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract value: %w", err)
+	}
+	if msg.Op != protocol.OpSet {
+		return "", fmt.Errorf("failed to extract value: %q reports no value", msg.String())
 	}
+	return msg.Value, nil
 }
 
 // IsConnected checks if the device has an active connection
@@ -1,9 +1,14 @@
 package nadapi
 
 import (
+	"context"
+	"errors"
 	"net"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
 )
 
 func TestGetAvailableSources(t *testing.T) {
@@ -86,10 +91,13 @@ func TestExtractValue(t *testing.T) {
 	}{
 		{"Valid response", "Main.Power=On\r\n", "On", false},
 		{"Valid response with spaces", "Main.Volume=-20.5\r\n", "-20.5", false},
-		{"Valid response simple", "Main.Source=Stream\n", "Strea", false},
+		{"Valid response simple", "Main.Source=Stream\n", "Stream", false},
+		{"Valid response without terminator", "Main.Source=Stream", "Stream", false},
+		{"Valid response with CR only", "Main.Mute=Off\r", "Off", false},
 		{"Invalid response no equals", "Main.Power", "", true},
+		{"Invalid response query", "Main.Power?", "", true},
 		{"Invalid response empty", "", "", true},
-		{"Response with multiple equals", "Main.Test=Value=Extra\n", "Val", false},
+		{"Response with multiple equals", "Main.Test=Value=Extra\n", "Value=Extra", false},
 	}
 
 	for _, tt := range tests {
@@ -111,75 +119,6 @@ func TestExtractValue(t *testing.T) {
 	}
 }
 
-func TestTrimSuffix(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected string
-	}{
-		{"String with newline", "Hello\n", "Hell"},
-		{"String with carriage return and newline", "World\r\n", "World"},
-		{"String with just carriage return", "Test\r", "Tes"},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := trimSuffix(tt.input)
-			if result != tt.expected {
-				t.Errorf("trimSuffix(%q) = %q, want %q", tt.input, result, tt.expected)
-			}
-		})
-	}
-}
-
-// Test that trimSuffix panics on empty or too short strings (expected behavior)
-func TestTrimSuffixPanic(t *testing.T) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Error("trimSuffix with empty string should panic")
-		}
-	}()
-
-	trimSuffix("")
-}
-
-func TestTrimSuffixPanicSingleChar(t *testing.T) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Error("trimSuffix with single character should panic")
-		}
-	}()
-
-	trimSuffix("A")
-}
-
-func TestInc(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    []byte
-		expected []byte
-	}{
-		{"Simple increment", []byte{192, 168, 1, 1}, []byte{192, 168, 1, 2}},
-		{"Rollover single byte", []byte{192, 168, 1, 255}, []byte{192, 168, 2, 0}},
-		{"Multiple rollover", []byte{192, 168, 255, 255}, []byte{192, 169, 0, 0}},
-		{"All ones", []byte{255, 255, 255, 255}, []byte{0, 0, 0, 0}},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Make a copy since inc modifies in place
-			input := make([]byte, len(tt.input))
-			copy(input, tt.input)
-
-			inc(input)
-
-			if !reflect.DeepEqual(input, tt.expected) {
-				t.Errorf("inc(%v) = %v, want %v", tt.input, input, tt.expected)
-			}
-		})
-	}
-}
-
 // TestDirectionConstants ensures the direction constants have expected values
 func TestDirectionConstants(t *testing.T) {
 	if DirectionUp != 1 {
@@ -210,7 +149,7 @@ func TestNewDeviceValidation(t *testing.T) {
 		shouldErr      bool
 		skipConnection bool
 	}{
-		{"Invalid IP address", "invalid.ip", "", true, false},
+		{"Invalid address", "invalid ip!", "", true, false},
 		{"Empty IP address", "", "", true, false},
 		{"Valid IP format", "192.168.1.1", "30001", false, true}, // Skip actual connection
 	}
@@ -237,3 +176,77 @@ func TestNewDeviceValidation(t *testing.T) {
 		})
 	}
 }
+
+// connectAmp connects to a fake amplifier configured by opts
+func connectAmp(t *testing.T, opts ...fakeamp.Option) (*Device, *fakeamp.Amp) {
+	t.Helper()
+	amp := fakeamp.Start(t, opts...)
+	d, err := New(amp.Host, amp.Port)
+	if err != nil {
+		t.Fatalf("New(%s, %s) unexpected error: %v", amp.Host, amp.Port, err)
+	}
+	t.Cleanup(func() { d.Disconnect() })
+	return d, amp
+}
+
+func TestGetPowerStateContextCancel(t *testing.T) {
+	d, _ := connectAmp(t, fakeamp.Silent())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := d.GetPowerStateContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetPowerStateContext() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("GetPowerStateContext() returned after %v, want prompt return on cancel", elapsed)
+	}
+	if d.IsConnected() {
+		t.Error("connection should be dropped after a cancelled command")
+	}
+}
+
+func TestGetVolumeContextDeadline(t *testing.T) {
+	d, _ := connectAmp(t, fakeamp.Silent())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := d.GetVolumeContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetVolumeContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("GetVolumeContext() returned after %v, want the context deadline to apply", elapsed)
+	}
+}
+
+func TestSendCancelledBeforeStart(t *testing.T) {
+	d := &Device{IP: net.ParseIP("127.0.0.1"), Port: defaultPort}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := d.send(ctx, "Main.Power?"); !errors.Is(err, context.Canceled) {
+		t.Errorf("send() error = %v, want context.Canceled", err)
+	}
+	if d.IsConnected() {
+		t.Error("send() should not dial when the context is already cancelled")
+	}
+}
+
+func TestCommandDeadline(t *testing.T) {
+	before := time.Now()
+	deadline := commandDeadline(context.Background())
+	if deadline.Before(before.Add(commandTimeout)) {
+		t.Errorf("commandDeadline() = %v, want at least %v after %v", deadline, commandTimeout, before)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ctxDeadline, _ := ctx.Deadline()
+	if got := commandDeadline(ctx); !got.Equal(ctxDeadline) {
+		t.Errorf("commandDeadline() = %v, want context deadline %v", got, ctxDeadline)
+	}
+}
@@ -0,0 +1,312 @@
+package nadapi
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// announceWait bounds how long the announcement based providers collect
+// replies
+const announceWait = 2 * time.Second
+
+// DiscoveryProvider finds NAD devices by one discovery method
+type DiscoveryProvider interface {
+	// Name identifies the method in logs, e.g. "mdns"
+	Name() string
+	// Discover reports devices to events as they are confirmed and returns
+	// all of them once done or when ctx is done
+	Discover(ctx context.Context, events *DiscoveryEvents) ([]DiscoveredDevice, error)
+}
+
+// DiscoveryProgress describes how far a discovery has come
+type DiscoveryProgress struct {
+	Provider string // Method in progress, e.g. "sweep"
+	Subnet   string // Range being swept, empty for the announcement methods
+	Probed   int    // Addresses done
+	Total    int    // Addresses to do, 0 when unknown
+	Found    int    // Devices found so far by all methods
+}
+
+// DiscoveryEvents receives the results of a discovery as they come in.
+// A nil *DiscoveryEvents and nil functions are ignored.
+type DiscoveryEvents struct {
+	OnDevice   func(DiscoveredDevice)
+	OnProgress func(DiscoveryProgress)
+}
+
+// Device reports a confirmed device
+func (e *DiscoveryEvents) Device(dev DiscoveredDevice) {
+	if e != nil && e.OnDevice != nil {
+		e.OnDevice(dev)
+	}
+}
+
+// Progress reports the progress of a provider
+func (e *DiscoveryEvents) Progress(p DiscoveryProgress) {
+	if e != nil && e.OnProgress != nil {
+		e.OnProgress(p)
+	}
+}
+
+// Discoverer combines discovery providers. The providers run concurrently,
+// then the fallback looks for the devices that did not announce themselves,
+// unless MaxDevices were already found. Their results are merged by address.
+type Discoverer struct {
+	Providers []DiscoveryProvider
+	Fallback  DiscoveryProvider
+	// MaxDevices stops discovery once this many devices are found, 0 for
+	// no limit
+	MaxDevices int
+	// Events receives every device once, as soon as it is confirmed, and
+	// the progress of the providers. Calls are serialized and must not block.
+	Events DiscoveryEvents
+}
+
+// DiscoveryOptions configures the default discovery providers. Zero values
+// select the defaults.
+type DiscoveryOptions struct {
+	Port       string       // Control port devices are confirmed on, 30001
+	Include    []AddrRange  // Ranges to sweep instead of the local subnets
+	Exclude    []AddrRange  // Ranges never to sweep
+	Interfaces []string     // Interfaces whose subnets are swept, all when empty
+	Workers    int          // Concurrent sweep probes, 64
+	Rate       int          // Sweep probes started per second, unlimited when zero
+	Match      ModelMatcher // Models to report, MatchKnownModels when nil
+	MaxDevices int          // Stop once this many devices are found, 0 for no limit
+
+	// The pre-pass of DiscoverDevicesWithCacheOptions probes the neighbor
+	// table and previously discovered devices before a full discovery
+	SkipPrepass   bool     // Always run the full discovery
+	NeighborTable string   // Neighbor table file, /proc/net/arp when empty
+	OUIs          []string // Only probe neighbors whose MAC starts with one of these, all when empty
+
+	Events DiscoveryEvents // Receives devices and progress as they come in
+}
+
+// NewDiscoverer returns a discoverer that listens for mDNS and SSDP
+// announcements, then sweeps the local subnets for the devices that do not
+// announce themselves
+func NewDiscoverer() *Discoverer {
+	return NewDiscovererWithOptions(DiscoveryOptions{})
+}
+
+// NewDiscovererWithOptions returns the default discoverer configured by opts
+func NewDiscovererWithOptions(opts DiscoveryOptions) *Discoverer {
+	return &Discoverer{
+		Providers: []DiscoveryProvider{
+			&MDNSProvider{Port: opts.Port, Match: opts.Match},
+			&SSDPProvider{Port: opts.Port, Match: opts.Match},
+		},
+		Fallback: &SweepProvider{
+			Include:    opts.Include,
+			Exclude:    opts.Exclude,
+			Interfaces: opts.Interfaces,
+			Port:       opts.Port,
+			Workers:    opts.Workers,
+			Rate:       opts.Rate,
+			Match:      opts.Match,
+		},
+		MaxDevices: opts.MaxDevices,
+		Events:     opts.Events,
+	}
+}
+
+// Discover runs the providers, then the fallback unless discovery stopped
+// early, and returns the devices found with duplicates removed
+func (dz *Discoverer) Discover(ctx context.Context) ([]DiscoveredDevice, error) {
+	log.WithFields(log.Fields{
+		"providers":  len(dz.Providers),
+		"maxDevices": dz.MaxDevices,
+	}).Debug("Starting NAD device discovery")
+
+	// Reaching MaxDevices cancels the providers still running
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := dz.events(cancel)
+
+	results := make([][]DiscoveredDevice, len(dz.Providers))
+	var wg sync.WaitGroup
+	for i, p := range dz.Providers {
+		wg.Add(1)
+		go func(i int, p DiscoveryProvider) {
+			defer wg.Done()
+			found, err := p.Discover(ctx, events)
+			if err != nil {
+				log.WithError(err).WithField("provider", p.Name()).Debug("Discovery provider failed")
+			}
+			log.WithFields(log.Fields{
+				"provider":     p.Name(),
+				"devicesFound": len(found),
+			}).Debug("Discovery provider finished")
+			results[i] = found
+		}(i, p)
+	}
+	wg.Wait()
+
+	// Some devices never announce themselves, so the fallback runs even
+	// when others did, unless enough were found or the caller gave up
+	devices := mergeDevices(results...)
+	if dz.Fallback != nil && ctx.Err() == nil {
+		log.WithFields(log.Fields{
+			"provider":  dz.Fallback.Name(),
+			"announced": len(devices),
+		}).Debug("Looking for devices that do not announce themselves")
+		found, err := dz.Fallback.Discover(ctx, events)
+		if err != nil {
+			if len(devices) == 0 {
+				return nil, err
+			}
+			log.WithError(err).WithField("provider", dz.Fallback.Name()).Debug("Fallback provider failed")
+		}
+		devices = mergeDevices(devices, found)
+	}
+	devices = dz.limit(devices)
+	log.WithField("totalDevices", len(devices)).Debug("Device discovery completed")
+	return devices, nil
+}
+
+// events returns the events the providers report to. They pass every new
+// device on to dz.Events once and call stop when MaxDevices is reached.
+func (dz *Discoverer) events(stop context.CancelFunc) *DiscoveryEvents {
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	return &DiscoveryEvents{
+		OnDevice: func(dev DiscoveredDevice) {
+			mu.Lock()
+			defer mu.Unlock()
+			key := deviceKey(dev)
+			if seen[key] || (dz.MaxDevices > 0 && len(seen) >= dz.MaxDevices) {
+				return
+			}
+			seen[key] = true
+			dz.Events.Device(dev)
+			if dz.MaxDevices > 0 && len(seen) >= dz.MaxDevices {
+				log.WithField("maxDevices", dz.MaxDevices).Debug("Found enough devices, stopping discovery")
+				stop()
+			}
+		},
+		OnProgress: func(p DiscoveryProgress) {
+			mu.Lock()
+			defer mu.Unlock()
+			p.Found = len(seen)
+			dz.Events.Progress(p)
+		},
+	}
+}
+
+// limit truncates devices to MaxDevices
+func (dz *Discoverer) limit(devices []DiscoveredDevice) []DiscoveredDevice {
+	if dz.MaxDevices > 0 && len(devices) > dz.MaxDevices {
+		return devices[:dz.MaxDevices]
+	}
+	return devices
+}
+
+// mergeDevices concatenates device lists, keeping the first record of every
+// address
+func mergeDevices(lists ...[]DiscoveredDevice) []DiscoveredDevice {
+	var devices []DiscoveredDevice
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		for _, dev := range list {
+			key := deviceKey(dev)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			devices = append(devices, dev)
+		}
+	}
+	return devices
+}
+
+// deviceKey identifies a device by its address, whichever way it is written
+func deviceKey(dev DiscoveredDevice) string {
+	return net.JoinHostPort(canonicalHost(dev.IP), dev.Port)
+}
+
+// canonicalHost returns the normal form of an IP address, so that e.g.
+// IPv4-mapped IPv6 addresses match their IPv4 form, or host unchanged
+func canonicalHost(host string) string {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return ip.Unmap().String()
+	}
+	return strings.ToLower(host)
+}
+
+// candidateVerifier probes the control port of candidate addresses as they
+// come in, reporting the ones that answer like a NAD device
+type candidateVerifier struct {
+	ctx    context.Context
+	port   string
+	method string       // Name of the provider the candidates come from
+	match  ModelMatcher // Models to report, MatchKnownModels when nil
+	events *DiscoveryEvents
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	devices []DiscoveredDevice
+}
+
+// add starts probing ip on the verifier's port
+func (v *candidateVerifier) add(ip string) {
+	v.probe(ip, v.port)
+}
+
+// probe starts probing ip on port
+func (v *candidateVerifier) probe(ip, port string) {
+	v.wg.Add(1)
+	go func() {
+		defer v.wg.Done()
+		if device := identify(v.ctx, ip, port, v.method, v.match); device != nil {
+			v.mu.Lock()
+			v.devices = append(v.devices, *device)
+			v.mu.Unlock()
+			v.events.Device(*device)
+		}
+	}()
+}
+
+// wait returns the confirmed devices once every probe is done
+func (v *candidateVerifier) wait() []DiscoveredDevice {
+	v.wg.Wait()
+	return v.devices
+}
+
+// collectWait returns when a provider should stop collecting replies: after
+// wait, or when ctx is done if that comes first
+func collectWait(ctx context.Context, wait time.Duration) time.Time {
+	if wait <= 0 {
+		wait = announceWait
+	}
+	deadline := time.Now().Add(wait)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		return ctxDeadline
+	}
+	return deadline
+}
+
+// DiscoverDevices searches the local network for NAD devices
+func DiscoverDevices(timeout time.Duration) ([]DiscoveredDevice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return DiscoverDevicesContext(ctx)
+}
+
+// DiscoverDevicesContext searches the local network for NAD devices with
+// the default providers until the search completes or ctx is done
+func DiscoverDevicesContext(ctx context.Context) ([]DiscoveredDevice, error) {
+	return NewDiscoverer().Discover(ctx)
+}
+
+// DiscoverDevicesWithOptions searches the local network for NAD devices with
+// the default providers configured by opts
+func DiscoverDevicesWithOptions(ctx context.Context, opts DiscoveryOptions) ([]DiscoveredDevice, error) {
+	return NewDiscovererWithOptions(opts).Discover(ctx)
+}
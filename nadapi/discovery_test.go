@@ -0,0 +1,334 @@
+package nadapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// udpResponder starts a fake announcer on loopback that answers every
+// datagram with the reply built by respond, if any
+func udpResponder(t *testing.T, respond func(req []byte) []byte) string {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 9000)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if reply := respond(buf[:n]); reply != nil {
+				conn.WriteToUDP(reply, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// mdnsAnswer answers a PTR query for the NAD service with the address of a
+// device on loopback
+func mdnsAnswer(t *testing.T) func([]byte) []byte {
+	return func(req []byte) []byte {
+		var q dnsmessage.Message
+		if err := q.Unpack(req); err != nil || len(q.Questions) != 1 {
+			return nil
+		}
+		question := q.Questions[0]
+		if question.Type != dnsmessage.TypePTR || question.Name.String() != mdnsService {
+			return nil
+		}
+		if question.Class&(1<<15) == 0 {
+			t.Errorf("mDNS query did not ask for a unicast reply")
+		}
+
+		instance := dnsmessage.MustNewName("C338." + mdnsService)
+		target := dnsmessage.MustNewName("nad-c338.local.")
+		b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+		b.StartAnswers()
+		b.PTRResource(dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 120},
+			dnsmessage.PTRResource{PTR: instance})
+		b.StartAdditionals()
+		b.AResource(dnsmessage.ResourceHeader{Name: target, Class: dnsmessage.ClassINET, TTL: 120},
+			dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}})
+		msg, err := b.Finish()
+		if err != nil {
+			t.Errorf("failed to build mDNS reply: %v", err)
+			return nil
+		}
+		return msg
+	}
+}
+
+func TestMDNSProvider(t *testing.T) {
+	amp := fakeamp.Start(t, fakeamp.Values(map[string]string{"Main.Model": "C338"}))
+	ip, port := amp.Host, amp.Port
+	addr := udpResponder(t, mdnsAnswer(t))
+
+	p := &MDNSProvider{Addr: addr, Port: port, Wait: 200 * time.Millisecond}
+	devices, err := p.Discover(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Discover() unexpected error: %v", err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("Discover() found %d devices, want 1", len(devices))
+	}
+	if devices[0].IP != ip || devices[0].Port != port || devices[0].Model != "C338" {
+		t.Errorf("Discover() = %+v, want C338 at %s:%s", devices[0], ip, port)
+	}
+}
+
+func TestSSDPProvider(t *testing.T) {
+	amp := fakeamp.Start(t, fakeamp.Values(map[string]string{"Main.Model": "C338"}))
+	ip, port := amp.Host, amp.Port
+	addr := udpResponder(t, func(req []byte) []byte {
+		if !strings.HasPrefix(string(req), "M-SEARCH * HTTP/1.1\r\n") {
+			return nil
+		}
+		return []byte("HTTP/1.1 200 OK\r\n" +
+			"CACHE-CONTROL: max-age=1800\r\n" +
+			"LOCATION: http://127.0.0.1:11000/description.xml\r\n" +
+			"SERVER: Linux UPnP/1.0 BluOS/3.20\r\n" +
+			"ST: upnp:rootdevice\r\n" +
+			"USN: uuid:1234::upnp:rootdevice\r\n\r\n")
+	})
+
+	p := &SSDPProvider{Addr: addr, Port: port, Wait: 200 * time.Millisecond}
+	devices, err := p.Discover(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Discover() unexpected error: %v", err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("Discover() found %d devices, want 1", len(devices))
+	}
+	if devices[0].IP != ip || devices[0].Port != port {
+		t.Errorf("Discover() = %+v, want device at %s:%s", devices[0], ip, port)
+	}
+}
+
+func TestParseSSDPReply(t *testing.T) {
+	from := netip.MustParseAddr("192.168.1.20")
+	tests := []struct {
+		name  string
+		reply string
+		want  []string
+	}{
+		{
+			name:  "location host",
+			reply: "HTTP/1.1 200 OK\r\nSERVER: BluOS/3.20\r\nLOCATION: http://192.168.1.50:11000/\r\n\r\n",
+			want:  []string{"192.168.1.50"},
+		},
+		{
+			name:  "sender without location",
+			reply: "HTTP/1.1 200 OK\r\nST: urn:schemas-upnp-org:device:NAD:1\r\n\r\n",
+			want:  []string{"192.168.1.20"},
+		},
+		{
+			name:  "other vendor",
+			reply: "HTTP/1.1 200 OK\r\nSERVER: Linux UPnP/1.0 Sonos/70.3\r\nLOCATION: http://192.168.1.60:1400/\r\n\r\n",
+		},
+		{
+			name:  "not http",
+			reply: "garbage",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseSSDPReply([]byte(tt.reply), from)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("parseSSDPReply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeDevices(t *testing.T) {
+	mdns := []DiscoveredDevice{
+		{IP: "192.168.1.50", Port: "30001", Model: "C338"},
+		{IP: "fe80::1", Port: "30001", Model: "C658"},
+	}
+	ssdp := []DiscoveredDevice{
+		{IP: "::ffff:192.168.1.50", Port: "30001", Model: "C338"},
+		{IP: "192.168.1.51", Port: "30001", Model: "M10"},
+	}
+
+	got := mergeDevices(mdns, ssdp)
+	want := []string{"192.168.1.50", "fe80::1", "192.168.1.51"}
+	if len(got) != len(want) {
+		t.Fatalf("mergeDevices() returned %d devices, want %d", len(got), len(want))
+	}
+	for i, dev := range got {
+		if dev.IP != want[i] {
+			t.Errorf("mergeDevices()[%d].IP = %q, want %q", i, dev.IP, want[i])
+		}
+	}
+}
+
+// fakeProvider reports fixed results and records whether it ran. With
+// block set it then waits for ctx like a provider still searching.
+type fakeProvider struct {
+	name    string
+	devices []DiscoveredDevice
+	err     error
+	block   bool
+	ran     bool
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) Discover(ctx context.Context, events *DiscoveryEvents) ([]DiscoveredDevice, error) {
+	p.ran = true
+	events.Progress(DiscoveryProgress{Provider: p.name})
+	for _, dev := range p.devices {
+		events.Device(dev)
+	}
+	if p.block {
+		<-ctx.Done()
+	}
+	return p.devices, p.err
+}
+
+func TestDiscovererFallback(t *testing.T) {
+	device := DiscoveredDevice{IP: "192.168.1.50", Port: "30001", Model: "C338"}
+
+	t.Run("providers found devices", func(t *testing.T) {
+		sweep := &fakeProvider{name: "sweep"}
+		dz := &Discoverer{
+			Providers: []DiscoveryProvider{
+				&fakeProvider{name: "mdns", devices: []DiscoveredDevice{device}},
+				&fakeProvider{name: "ssdp", devices: []DiscoveredDevice{device}},
+			},
+			Fallback: sweep,
+		}
+		devices, err := dz.Discover(context.Background())
+		if err != nil {
+			t.Fatalf("Discover() unexpected error: %v", err)
+		}
+		if len(devices) != 1 {
+			t.Errorf("Discover() found %d devices, want 1", len(devices))
+		}
+		if !sweep.ran {
+			t.Error("fallback did not run for the devices that do not announce themselves")
+		}
+	})
+
+	t.Run("providers found nothing", func(t *testing.T) {
+		sweep := &fakeProvider{name: "sweep", devices: []DiscoveredDevice{device}}
+		dz := &Discoverer{
+			Providers: []DiscoveryProvider{
+				&fakeProvider{name: "mdns", err: errors.New("no multicast route")},
+				&fakeProvider{name: "ssdp"},
+			},
+			Fallback: sweep,
+		}
+		devices, err := dz.Discover(context.Background())
+		if err != nil {
+			t.Fatalf("Discover() unexpected error: %v", err)
+		}
+		if !sweep.ran {
+			t.Error("fallback did not run although providers found nothing")
+		}
+		if len(devices) != 1 || devices[0] != device {
+			t.Errorf("Discover() = %+v, want [%+v]", devices, device)
+		}
+	})
+}
+
+func TestDiscovererSweepsBesideAnnouncements(t *testing.T) {
+	// One amp announces itself on 127.0.0.1, another only listens on 127.0.0.2
+	amp := sweepAmp(t)
+	include, _ := ParseAddrRanges([]string{"127.0.0.1-127.0.0.2"})
+	dz := &Discoverer{
+		Providers: []DiscoveryProvider{
+			&MDNSProvider{Addr: udpResponder(t, mdnsAnswer(t)), Port: amp.Port, Wait: 200 * time.Millisecond},
+		},
+		Fallback: &SweepProvider{Include: include, Port: amp.Port},
+	}
+
+	devices, err := dz.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover() unexpected error: %v", err)
+	}
+	var ips []string
+	for _, dev := range devices {
+		ips = append(ips, dev.IP)
+	}
+	slices.Sort(ips)
+	if want := []string{"127.0.0.1", "127.0.0.2"}; !slices.Equal(ips, want) {
+		t.Errorf("Discover() found %v, want %v", ips, want)
+	}
+}
+
+func TestDiscovererStreamsDevices(t *testing.T) {
+	first := DiscoveredDevice{IP: "192.168.1.50", Port: "30001", Model: "C338"}
+	second := DiscoveredDevice{IP: "192.168.1.51", Port: "30001", Model: "M10"}
+
+	var streamed []DiscoveredDevice
+	var progress []DiscoveryProgress
+	dz := &Discoverer{
+		Providers: []DiscoveryProvider{
+			&fakeProvider{name: "mdns", devices: []DiscoveredDevice{first, second}},
+			&fakeProvider{name: "ssdp", devices: []DiscoveredDevice{first}},
+		},
+		Events: DiscoveryEvents{
+			OnDevice:   func(dev DiscoveredDevice) { streamed = append(streamed, dev) },
+			OnProgress: func(p DiscoveryProgress) { progress = append(progress, p) },
+		},
+	}
+	if _, err := dz.Discover(context.Background()); err != nil {
+		t.Fatalf("Discover() unexpected error: %v", err)
+	}
+	if len(streamed) != 2 {
+		t.Errorf("OnDevice called %d times, want once per device (2): %+v", len(streamed), streamed)
+	}
+	if len(progress) != 2 {
+		t.Errorf("OnProgress called %d times, want once per provider (2)", len(progress))
+	}
+}
+
+func TestDiscovererStopsAtMaxDevices(t *testing.T) {
+	devices := []DiscoveredDevice{
+		{IP: "192.168.1.50", Port: "30001", Model: "C338"},
+		{IP: "192.168.1.51", Port: "30001", Model: "M10"},
+		{IP: "192.168.1.52", Port: "30001", Model: "C658"},
+	}
+	sweep := &fakeProvider{name: "sweep"}
+	streamed := 0
+	dz := &Discoverer{
+		Providers:  []DiscoveryProvider{&fakeProvider{name: "mdns", devices: devices, block: true}},
+		Fallback:   sweep,
+		MaxDevices: 2,
+		Events: DiscoveryEvents{
+			OnDevice: func(DiscoveredDevice) { streamed++ },
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	found, err := dz.Discover(ctx)
+	if err != nil {
+		t.Fatalf("Discover() unexpected error: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Discover() ran until the deadline instead of stopping at MaxDevices")
+	}
+	if len(found) != 2 || streamed != 2 {
+		t.Errorf("Discover() returned %d devices and streamed %d, want 2 and 2", len(found), streamed)
+	}
+	if sweep.ran {
+		t.Error("fallback ran after discovery stopped early")
+	}
+}
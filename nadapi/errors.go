@@ -0,0 +1,52 @@
+package nadapi
+
+import "errors"
+
+// Kinds of errors the device API returns. Errors are wrapped, so test for
+// them with errors.Is.
+var (
+	// ErrTimeout is returned when the device does not answer a command in
+	// time
+	ErrTimeout = errors.New("command timeout")
+	// ErrNotConnected is returned when the device cannot be reached or the
+	// connection to it is lost
+	ErrNotConnected = errors.New("device not reachable")
+	// ErrUnsupported is returned for commands the model or platform does
+	// not support
+	ErrUnsupported = errors.New("not supported")
+	// ErrInvalidValue is returned for arguments out of range or unknown to
+	// the device, such as a brightness level or a source name
+	ErrInvalidValue = errors.New("invalid value")
+	// ErrStandby is returned when a command gets no answer because the
+	// device is in standby, where it only accepts power commands
+	ErrStandby = errors.New("device is in standby")
+	// ErrVolumeLimit is returned for volume changes over the volume limits
+	// of the device or the maximum of the model, see VolumeLimitError
+	ErrVolumeLimit = errors.New("volume over the limit")
+	// ErrSuperseded is returned for a set command that was still waiting
+	// to be sent when a newer set of the same key replaced it
+	ErrSuperseded = errors.New("superseded by a newer command")
+)
+
+// kindError tags an error with one of the kinds above while keeping its
+// message
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// withKind tags err with kind, leaving nil and already tagged errors alone
+func withKind(kind, err error) error {
+	if err == nil || errors.Is(err, kind) {
+		return err
+	}
+	return &kindError{kind: kind, err: err}
+}
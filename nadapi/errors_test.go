@@ -0,0 +1,71 @@
+package nadapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+func TestWithKind(t *testing.T) {
+	cause := errors.New("dial tcp: connection refused")
+	err := withKind(ErrNotConnected, cause)
+	if err.Error() != cause.Error() {
+		t.Errorf("withKind() message = %q, want %q", err, cause)
+	}
+	if !errors.Is(err, ErrNotConnected) || !errors.Is(err, cause) {
+		t.Errorf("withKind() = %v, want both the kind and the cause", err)
+	}
+	if withKind(ErrNotConnected, err) != err {
+		t.Error("withKind() should not tag an error twice")
+	}
+	if withKind(ErrTimeout, nil) != nil {
+		t.Error("withKind(nil) should be nil")
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	// A port nothing listens on
+	closed := fakeamp.Start(t)
+	closed.Stop()
+	ip, closedPort := closed.Host, closed.Port
+	if _, err := New(ip, closedPort); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("New() of a closed port error = %v, want ErrNotConnected", err)
+	}
+
+	if _, err := ParseAddress("modem://ttyS0", ""); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("ParseAddress() error = %v, want ErrInvalidValue", err)
+	}
+	if _, err := ParseZone("zone9"); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("ParseZone() error = %v, want ErrInvalidValue", err)
+	}
+
+	d, _ := connectAmp(t, fakeamp.Values(map[string]string{"Main.Model": "C338"}))
+	ctx := context.Background()
+	if err := d.SetBrightnessContext(ctx, 9); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("SetBrightness(9) error = %v, want ErrInvalidValue", err)
+	}
+	if err := d.Zone(Zone2).PowerOnContext(ctx); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Zone 2 power on of a C338 error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestStandbyDeviceIgnoringCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the command timeout")
+	}
+	// Like a device in standby, the fake answers power queries only
+	d, _ := connectAmp(t, fakeamp.Values(map[string]string{"Main.Power": "Off"}))
+
+	_, err := d.GetVolume()
+	if !errors.Is(err, ErrStandby) {
+		t.Fatalf("GetVolume() in standby error = %v, want ErrStandby", err)
+	}
+	if errors.Is(err, ErrTimeout) {
+		t.Errorf("GetVolume() in standby error = %v, should not be a timeout", err)
+	}
+	if !d.IsConnected() {
+		t.Error("device in standby should stay connected")
+	}
+}
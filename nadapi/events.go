@@ -0,0 +1,246 @@
+package nadapi
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/galamiram/nadctl/nadapi/protocol"
+)
+
+// EventType identifies which part of the device state an event reports
+type EventType int
+
+const (
+	// EventOther is a state report for a key without a dedicated type
+	EventOther EventType = iota
+	// EventPower -
+	EventPower
+	// EventVolume -
+	EventVolume
+	// EventSource -
+	EventSource
+	// EventMute -
+	EventMute
+	// EventBrightness -
+	EventBrightness
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// further events to it are dropped
+const subscriberBuffer = 16
+
+var eventTypes = map[string]EventType{
+	"main.power":      EventPower,
+	"main.volume":     EventVolume,
+	"main.source":     EventSource,
+	"main.mute":       EventMute,
+	"main.brightness": EventBrightness,
+	"zone2.power":     EventPower,
+	"zone2.volume":    EventVolume,
+	"zone2.source":    EventSource,
+	"zone2.mute":      EventMute,
+}
+
+// String returns a human-readable name for the event type
+func (t EventType) String() string {
+	switch t {
+	case EventPower:
+		return "power"
+	case EventVolume:
+		return "volume"
+	case EventSource:
+		return "source"
+	case EventMute:
+		return "mute"
+	case EventBrightness:
+		return "brightness"
+	default:
+		return "other"
+	}
+}
+
+// Event is a state change the device reported without being asked, e.g.
+// after the front panel or IR remote was used
+type Event struct {
+	Type  EventType
+	Key   string // protocol key, e.g. "Main.Volume"
+	Value string // raw value, e.g. "-32"
+	Time  time.Time
+}
+
+// Zone returns the zone the event belongs to, or "" for keys outside the
+// zones such as SourceN settings
+func (e Event) Zone() ZoneID {
+	return zoneOfKey(e.Key)
+}
+
+// zoneOfKey returns the zone of a protocol key, or "" for keys outside the
+// zones
+func zoneOfKey(key string) ZoneID {
+	prefix, _, _ := strings.Cut(key, ".")
+	for _, z := range []ZoneID{MainZone, Zone2} {
+		if strings.EqualFold(prefix, string(z)) {
+			return z
+		}
+	}
+	return ""
+}
+
+// Subscribe registers for unsolicited state change events from the device.
+// Events are delivered on the returned channel until ctx is done, at which
+// point the channel is closed. A subscriber that falls behind misses events
+// rather than stalling the connection.
+func (d *Device) Subscribe(ctx context.Context) <-chan Event {
+	ch := make(chan Event, subscriberBuffer)
+
+	d.subsMu.Lock()
+	if d.subs == nil {
+		d.subs = make(map[chan Event]struct{})
+	}
+	d.subs[ch] = struct{}{}
+	d.subsMu.Unlock()
+
+	log.WithField("device", d.String()).Debug("Added event subscriber")
+
+	go func() {
+		<-ctx.Done()
+		d.subsMu.Lock()
+		delete(d.subs, ch)
+		close(ch)
+		d.subsMu.Unlock()
+		log.WithField("device", d.String()).Debug("Removed event subscriber")
+	}()
+
+	return ch
+}
+
+// publish hands an unsolicited line to every subscriber
+func (d *Device) publish(line string) {
+	msg, err := protocol.Parse(line)
+	if err != nil || msg.Op != protocol.OpSet {
+		log.WithFields(log.Fields{
+			"device": d.String(),
+			"line":   line,
+		}).Debug("Ignoring unsolicited line without a value")
+		return
+	}
+	key, value := msg.Key, msg.Value
+
+	ev := Event{
+		Type:  eventTypes[strings.ToLower(key)],
+		Key:   key,
+		Value: value,
+		Time:  time.Now(),
+	}
+
+	log.WithFields(log.Fields{
+		"device": d.String(),
+		"key":    ev.Key,
+		"value":  ev.Value,
+	}).Debug("Received unsolicited event from device")
+
+	if ev.Type == EventVolume {
+		d.volumeReported(ev.Zone(), value)
+	}
+
+	// Inputs renamed or toggled on the front panel
+	if strings.HasPrefix(strings.ToLower(key), "source") {
+		d.forgetInputs()
+	}
+
+	d.subsMu.Lock()
+	defer d.subsMu.Unlock()
+	for ch := range d.subs {
+		select {
+		case ch <- ev:
+		default:
+			log.WithField("device", d.String()).Debug("Event subscriber full, dropping event")
+		}
+	}
+}
+
+// connReader owns the read side of one device connection. It splits the
+// stream into lines and routes each line either to the commands awaiting a
+// reply for that key or, when nothing claims it, to the device subscribers.
+type connReader struct {
+	conn net.Conn
+	done chan struct{} // closed when the read loop exits
+	err  error         // error that ended the read loop, valid once done is closed
+
+	mu       sync.Mutex
+	waitKeys map[string]bool // Lower-case keys of the commands awaiting a reply
+	reply    chan string     // Receives the reply line for each of waitKeys
+}
+
+// startReader begins reading from conn in the background
+func (d *Device) startReader(conn net.Conn) *connReader {
+	r := &connReader{
+		conn: conn,
+		done: make(chan struct{}),
+	}
+	go r.run(d.publish)
+	return r
+}
+
+func (r *connReader) run(publish func(string)) {
+	defer close(r.done)
+
+	scanner := protocol.NewScanner(r.conn)
+	for scanner.Scan() {
+		line := scanner.Text()
+		key := messageKey(line)
+		r.mu.Lock()
+		if r.waitKeys[strings.ToLower(key)] {
+			delete(r.waitKeys, strings.ToLower(key))
+			r.reply <- line
+			r.mu.Unlock()
+			continue
+		}
+		r.mu.Unlock()
+
+		publish(line)
+	}
+	r.err = scanner.Err()
+	if r.err == nil {
+		r.err = io.EOF
+	}
+}
+
+// expect registers interest in the next line carrying each of keys and
+// returns the channel they will be delivered on, in the order they arrive
+func (r *connReader) expect(keys ...string) <-chan string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.waitKeys = make(map[string]bool, len(keys))
+	for _, key := range keys {
+		r.waitKeys[strings.ToLower(key)] = true
+	}
+	r.reply = make(chan string, len(keys))
+	return r.reply
+}
+
+// forget drops any outstanding expectation, so a late reply is treated as
+// an unsolicited line
+func (r *connReader) forget() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.waitKeys = nil
+	r.reply = nil
+}
+
+// messageKey returns the protocol key of a command or reply, e.g.
+// "Main.Volume" for "Main.Volume=-20", "Main.Volume?" and "Main.Volume+",
+// or "" for a malformed line
+func messageKey(line string) string {
+	msg, err := protocol.Parse(line)
+	if err != nil {
+		return ""
+	}
+	return msg.Key
+}
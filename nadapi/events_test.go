@@ -0,0 +1,96 @@
+package nadapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+func TestSendSkipsUnsolicitedLines(t *testing.T) {
+	d, _ := connectAmp(t, fakeamp.Reply("Main.Volume=-32", "Main.Power=On"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := d.Subscribe(ctx)
+
+	state, err := d.GetPowerState()
+	if err != nil {
+		t.Fatalf("GetPowerState() unexpected error: %v", err)
+	}
+	if state != "On" {
+		t.Errorf("GetPowerState() = %q, want %q", state, "On")
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Type != EventVolume || ev.Key != "Main.Volume" || ev.Value != "-32" {
+			t.Errorf("event = %+v, want volume event with value -32", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected unsolicited volume event")
+	}
+}
+
+func TestSubscribeClosesOnCancel(t *testing.T) {
+	d := &Device{IP: net.ParseIP("127.0.0.1"), Port: defaultPort}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := d.Subscribe(ctx)
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("expected closed channel, got event")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription channel not closed after cancel")
+	}
+}
+
+func TestPublishDropsForSlowSubscriber(t *testing.T) {
+	d := &Device{IP: net.ParseIP("127.0.0.1"), Port: defaultPort}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := d.Subscribe(ctx)
+
+	for i := 0; i < subscriberBuffer+5; i++ {
+		d.publish("Main.Mute=On\r\n")
+	}
+	if len(events) != subscriberBuffer {
+		t.Errorf("buffered events = %d, want %d", len(events), subscriberBuffer)
+	}
+	if ev := <-events; ev.Type != EventMute || ev.Value != "On" {
+		t.Errorf("event = %+v, want mute event with value On", ev)
+	}
+}
+
+func TestMessageKey(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"Main.Power?", "Main.Power"},
+		{"Main.Volume=-20.000000", "Main.Volume"},
+		{"Main.Volume+", "Main.Volume"},
+		{"Main.Volume-", "Main.Volume"},
+		{"Main.Source=Stream", "Main.Source"},
+	}
+
+	for _, tt := range tests {
+		if got := messageKey(tt.line); got != tt.want {
+			t.Errorf("messageKey(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestEventTypeString(t *testing.T) {
+	if got := EventBrightness.String(); got != "brightness" {
+		t.Errorf("EventBrightness.String() = %q, want %q", got, "brightness")
+	}
+	if got := EventOther.String(); got != "other" {
+		t.Errorf("EventOther.String() = %q, want %q", got, "other")
+	}
+}
@@ -0,0 +1,326 @@
+package nadapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// FadeCurve shapes how a fade spreads the volume change over its duration
+type FadeCurve int
+
+const (
+	// FadeLinear changes the volume by the same number of dB at every step
+	FadeLinear FadeCurve = iota
+	// FadeLogarithmic makes most of the change early and slows down as it
+	// nears the target
+	FadeLogarithmic
+)
+
+const (
+	// fadeStepDB is the finest volume step of a fade
+	fadeStepDB = 0.5
+	// minFadeStepInterval bounds how often a fade sends a volume command
+	minFadeStepInterval = 100 * time.Millisecond
+)
+
+// ErrFadeCancelled is returned by Fade.Wait when the fade was cancelled,
+// e.g. by a newer fade or a volume change made elsewhere
+var ErrFadeCancelled = errors.New("fade cancelled")
+
+var (
+	errFadeSuperseded = fmt.Errorf("%w by a newer fade", ErrFadeCancelled)
+	errFadeOverridden = fmt.Errorf("%w by a volume change", ErrFadeCancelled)
+	errFadeStopped    = fmt.Errorf("%w by disconnecting", ErrFadeCancelled)
+)
+
+// ParseFadeCurve converts a curve name such as "linear" or "log" to a
+// FadeCurve. An empty name is linear.
+func ParseFadeCurve(s string) (FadeCurve, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "linear", "lin":
+		return FadeLinear, nil
+	case "logarithmic", "log":
+		return FadeLogarithmic, nil
+	}
+	return 0, withKind(ErrInvalidValue, fmt.Errorf("unknown fade curve %q. Valid curves: linear, logarithmic", s))
+}
+
+// String returns the name of the curve
+func (c FadeCurve) String() string {
+	if c == FadeLogarithmic {
+		return "logarithmic"
+	}
+	return "linear"
+}
+
+// at returns the share of the volume change made after share x of the
+// steps, both from 0 to 1
+func (c FadeCurve) at(x float64) float64 {
+	if c == FadeLogarithmic {
+		return math.Log10(1 + 9*x)
+	}
+	return x
+}
+
+// FadeProgress reports a step of a fade
+type FadeProgress struct {
+	Zone   ZoneID
+	Volume float64 // Volume set by the step
+	Target float64
+	Step   int // From 1 to Steps
+	Steps  int
+}
+
+// Fade is a volume fade running in the background, see Zone.FadeVolume
+type Fade struct {
+	zone     *Zone
+	start    float64
+	target   float64
+	curve    FadeCurve
+	steps    int
+	interval time.Duration
+	progress chan FadeProgress
+	cancel   context.CancelCauseFunc
+	done     chan struct{}
+	err      error // Valid once done is closed
+
+	mu        sync.Mutex
+	low, high float64 // Volumes around the last step, to tell them from changes made elsewhere
+}
+
+// FadeVolume steps the volume from its current level to target over
+// duration, following curve. See Zone.FadeVolume.
+func (d *Device) FadeVolume(ctx context.Context, target float64, duration time.Duration, curve FadeCurve) (*Fade, error) {
+	return d.main().FadeVolume(ctx, target, duration, curve)
+}
+
+// FadeVolume starts stepping the volume of the zone from its current level
+// to target over duration, following curve, and returns once the fade is
+// under way. The fade ends early when ctx is done, when a newer fade starts
+// on the zone or when the volume is changed meanwhile, with the volume
+// methods or on the device itself. A target over the volume limits, or a
+// fade whose steps are over the step limit, is refused.
+func (z *Zone) FadeVolume(ctx context.Context, target float64, duration time.Duration, curve FadeCurve) (*Fade, error) {
+	log.WithFields(log.Fields{
+		"device":   z.d.String(),
+		"zone":     z.id,
+		"target":   target,
+		"duration": duration,
+		"curve":    curve,
+	}).Debug("Starting volume fade")
+
+	if duration < 0 {
+		return nil, withKind(ErrInvalidValue, fmt.Errorf("negative fade duration %v", duration))
+	}
+	caps, err := z.d.CapabilitiesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	target = max(target, caps.MinVolume)
+	if err := z.checkVolume(ctx, math.Inf(-1), target); err != nil {
+		return nil, err
+	}
+
+	// Stop a running fade before reading the volume it is moving
+	z.d.stopFade(z.id, errFadeSuperseded)
+	start, err := z.GetVolumeFloatContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := int(math.Ceil(math.Abs(target-start) / fadeStepDB))
+	steps = max(min(steps, int(duration/minFadeStepInterval)), 1)
+
+	f := &Fade{
+		zone:     z,
+		start:    start,
+		target:   target,
+		curve:    curve,
+		steps:    steps,
+		interval: duration / time.Duration(steps),
+		progress: make(chan FadeProgress, steps),
+		done:     make(chan struct{}),
+		low:      start,
+		high:     start,
+	}
+	// Rising steps are held to the step limit; the first is the largest
+	if first := f.volumeAt(1); first > start {
+		if err := z.checkVolume(ctx, start, first); err != nil {
+			return nil, err
+		}
+	}
+
+	fadeCtx, cancel := context.WithCancelCause(ctx)
+	f.cancel = cancel
+	z.d.startFade(f)
+	go f.run(fadeCtx)
+	return f, nil
+}
+
+// Progress returns a channel receiving every step of the fade. It is
+// closed when the fade ends and holds all the steps, so it need not be
+// drained.
+func (f *Fade) Progress() <-chan FadeProgress {
+	return f.progress
+}
+
+// Wait blocks until the fade ends and returns why it ended early, if it did
+func (f *Fade) Wait() error {
+	<-f.done
+	return f.err
+}
+
+// Cancel stops the fade at the volume it has reached
+func (f *Fade) Cancel() {
+	f.cancel(ErrFadeCancelled)
+}
+
+// run sends the steps of the fade, spread evenly over its duration
+func (f *Fade) run(ctx context.Context) {
+	defer close(f.done)
+	defer close(f.progress)
+	defer f.zone.d.endFade(f)
+	defer f.cancel(nil)
+
+	began := time.Now()
+	last := f.start
+	for step := 1; step <= f.steps; step++ {
+		select {
+		case <-ctx.Done():
+			f.err = context.Cause(ctx)
+			f.logEnd(last)
+			return
+		case <-time.After(time.Until(began.Add(time.Duration(step) * f.interval))):
+		}
+
+		volume := f.volumeAt(step)
+		if volume != last {
+			f.mu.Lock()
+			f.low, f.high = min(last, volume), max(last, volume)
+			f.mu.Unlock()
+
+			cmd := fmt.Sprintf("%s=%f", f.zone.key("Volume"), volume)
+			if _, err := f.zone.send(ctx, cmd); err != nil {
+				if ctx.Err() != nil {
+					err = context.Cause(ctx)
+				}
+				f.err = fmt.Errorf("fade volume: %w", err)
+				f.logEnd(last)
+				return
+			}
+			last = volume
+		}
+		f.progress <- FadeProgress{
+			Zone:   f.zone.id,
+			Volume: volume,
+			Target: f.target,
+			Step:   step,
+			Steps:  f.steps,
+		}
+	}
+	f.logEnd(last)
+}
+
+// volumeAt returns the volume of a step, rounded to fadeStepDB on the way
+func (f *Fade) volumeAt(step int) float64 {
+	if step == f.steps {
+		return f.target
+	}
+	v := f.start + (f.target-f.start)*f.curve.at(float64(step)/float64(f.steps))
+	return math.Round(v/fadeStepDB) * fadeStepDB
+}
+
+// owns reports whether a volume the device reported may come from the
+// steps of the fade
+func (f *Fade) owns(volume float64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return volume >= f.low-fadeStepDB && volume <= f.high+fadeStepDB
+}
+
+func (f *Fade) logEnd(volume float64) {
+	entry := log.WithFields(log.Fields{
+		"device": f.zone.d.String(),
+		"zone":   f.zone.id,
+		"volume": volume,
+		"target": f.target,
+	})
+	if f.err != nil {
+		entry.WithError(f.err).Debug("Volume fade ended early")
+		return
+	}
+	entry.Debug("Volume fade finished")
+}
+
+// startFade registers f as the fade of its zone, cancelling the one it
+// replaces
+func (d *Device) startFade(f *Fade) {
+	d.fadesMu.Lock()
+	defer d.fadesMu.Unlock()
+	if d.fades == nil {
+		d.fades = make(map[ZoneID]*Fade)
+	}
+	if old := d.fades[f.zone.id]; old != nil {
+		old.cancel(errFadeSuperseded)
+	}
+	d.fades[f.zone.id] = f
+}
+
+// endFade unregisters f once it is over
+func (d *Device) endFade(f *Fade) {
+	d.fadesMu.Lock()
+	defer d.fadesMu.Unlock()
+	if d.fades[f.zone.id] == f {
+		delete(d.fades, f.zone.id)
+	}
+}
+
+// stopFade cancels the fade of zone, if one is running, with cause
+func (d *Device) stopFade(zone ZoneID, cause error) {
+	d.fadesMu.Lock()
+	defer d.fadesMu.Unlock()
+	if f := d.fades[zone]; f != nil {
+		f.cancel(cause)
+		delete(d.fades, zone)
+	}
+}
+
+// stopFades cancels the fades of every zone
+func (d *Device) stopFades() {
+	d.fadesMu.Lock()
+	defer d.fadesMu.Unlock()
+	for zone, f := range d.fades {
+		f.cancel(errFadeStopped)
+		delete(d.fades, zone)
+	}
+}
+
+// volumeReported cancels the fade of zone when the device reports a volume
+// the fade did not set, i.e. one changed on the front panel or with the
+// remote
+func (d *Device) volumeReported(zone ZoneID, value string) {
+	volume, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	d.fadesMu.Lock()
+	f := d.fades[zone]
+	d.fadesMu.Unlock()
+	if f == nil || f.owns(volume) {
+		return
+	}
+	log.WithFields(log.Fields{
+		"device": d.String(),
+		"zone":   zone,
+		"volume": volume,
+	}).Debug("Volume changed on the device, cancelling fade")
+	f.cancel(errFadeOverridden)
+}
@@ -0,0 +1,151 @@
+package nadapi
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+// newVolumeDevice connects to a fake C338 that is on at volume
+func newVolumeDevice(t *testing.T, volume float64) (*Device, *fakeamp.Amp) {
+	t.Helper()
+	return connectAmp(t, fakeamp.FactoryInputs(), fakeamp.Values(map[string]string{
+		"Main.Model":  "C338",
+		"Main.Power":  "On",
+		"Main.Source": "Stream",
+		"Main.Volume": strconv.FormatFloat(volume, 'f', 1, 64),
+	}))
+}
+
+// ampVolume returns the volume of amp in dB
+func ampVolume(amp *fakeamp.Amp) float64 {
+	v, _ := strconv.ParseFloat(amp.Value("Main.Volume"), 64)
+	return v
+}
+
+func TestParseFadeCurve(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    FadeCurve
+		wantErr bool
+	}{
+		{"", FadeLinear, false},
+		{"Linear", FadeLinear, false},
+		{"log", FadeLogarithmic, false},
+		{"logarithmic", FadeLogarithmic, false},
+		{"exponential", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFadeCurve(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFadeCurve(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("ParseFadeCurve(%q) error = %v, want ErrInvalidValue", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseFadeCurve(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFadeVolumeAt(t *testing.T) {
+	linear := &Fade{start: -40, target: -20, steps: 4, curve: FadeLinear}
+	for step, want := range map[int]float64{1: -35, 2: -30, 3: -25, 4: -20} {
+		if got := linear.volumeAt(step); got != want {
+			t.Errorf("linear volumeAt(%d) = %v, want %v", step, got, want)
+		}
+	}
+
+	log := &Fade{start: -40, target: -20, steps: 4, curve: FadeLogarithmic}
+	first := log.volumeAt(1) - log.start
+	last := log.target - log.volumeAt(3)
+	if first <= last {
+		t.Errorf("logarithmic fade steps %v dB first and %v dB last, want it to slow down", first, last)
+	}
+}
+
+func TestFadeVolume(t *testing.T) {
+	d, amp := newVolumeDevice(t, -40)
+
+	fade, err := d.FadeVolume(context.Background(), -30, 300*time.Millisecond, FadeLinear)
+	if err != nil {
+		t.Fatalf("FadeVolume() unexpected error: %v", err)
+	}
+	var steps []FadeProgress
+	for p := range fade.Progress() {
+		steps = append(steps, p)
+	}
+	if err := fade.Wait(); err != nil {
+		t.Fatalf("Wait() unexpected error: %v", err)
+	}
+
+	// 20 steps of 0.5 dB, capped to one every 100ms
+	if len(steps) != 3 {
+		t.Fatalf("fade reported %d steps, want 3: %+v", len(steps), steps)
+	}
+	if last := steps[len(steps)-1]; last.Volume != -30 || last.Step != 3 || last.Steps != 3 {
+		t.Errorf("last step = %+v, want step 3 of 3 at -30 dB", last)
+	}
+	if got := ampVolume(amp); got != -30 {
+		t.Errorf("device volume = %v, want -30", got)
+	}
+}
+
+func TestFadeCancelled(t *testing.T) {
+	t.Run("by a volume change", func(t *testing.T) {
+		d, amp := newVolumeDevice(t, -40)
+		fade, err := d.FadeVolume(context.Background(), -20, 2*time.Second, FadeLinear)
+		if err != nil {
+			t.Fatalf("FadeVolume() unexpected error: %v", err)
+		}
+		if err := d.SetVolume(-50); err != nil {
+			t.Fatalf("SetVolume() unexpected error: %v", err)
+		}
+		if err := fade.Wait(); !errors.Is(err, ErrFadeCancelled) {
+			t.Errorf("Wait() error = %v, want ErrFadeCancelled", err)
+		}
+		time.Sleep(200 * time.Millisecond)
+		if got := ampVolume(amp); got != -50 {
+			t.Errorf("device volume = %v, want -50 as set after the fade", got)
+		}
+	})
+
+	t.Run("by a newer fade", func(t *testing.T) {
+		d, amp := newVolumeDevice(t, -40)
+		first, err := d.FadeVolume(context.Background(), -20, 2*time.Second, FadeLinear)
+		if err != nil {
+			t.Fatalf("FadeVolume() unexpected error: %v", err)
+		}
+		second, err := d.FadeVolume(context.Background(), -45, 0, FadeLinear)
+		if err != nil {
+			t.Fatalf("FadeVolume() unexpected error: %v", err)
+		}
+		if err := first.Wait(); !errors.Is(err, ErrFadeCancelled) {
+			t.Errorf("first Wait() error = %v, want ErrFadeCancelled", err)
+		}
+		if err := second.Wait(); err != nil {
+			t.Errorf("second Wait() unexpected error: %v", err)
+		}
+		if got := ampVolume(amp); got != -45 {
+			t.Errorf("device volume = %v, want -45", got)
+		}
+	})
+
+	t.Run("on the device", func(t *testing.T) {
+		d, amp := newVolumeDevice(t, -40)
+		fade, err := d.FadeVolume(context.Background(), -20, 2*time.Second, FadeLinear)
+		if err != nil {
+			t.Fatalf("FadeVolume() unexpected error: %v", err)
+		}
+		time.Sleep(250 * time.Millisecond)
+		amp.Notify("Main.Volume", "-60.0") // Turned on the front panel
+		if err := fade.Wait(); !errors.Is(err, ErrFadeCancelled) {
+			t.Errorf("Wait() error = %v, want ErrFadeCancelled", err)
+		}
+	})
+}
@@ -0,0 +1,330 @@
+package nadapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AllDevicesGroup names the group of every device DeviceEntries returns,
+// unless the config defines a group of that name
+const AllDevicesGroup = "all"
+
+// ErrUnknownGroup is returned when a group reference names no group
+var ErrUnknownGroup = errors.New("unknown group")
+
+// GroupMember is a device of a group
+type GroupMember struct {
+	Device       string  // Device reference, see SelectDevice
+	VolumeOffset float64 // dB added to volumes set on the group
+}
+
+// String returns the member as ParseGroup accepts it
+func (m GroupMember) String() string {
+	if m.VolumeOffset == 0 {
+		return m.Device
+	}
+	return m.Device + "@" + strconv.FormatFloat(m.VolumeOffset, 'f', -1, 64)
+}
+
+// Group is a set of devices controlled together
+type Group struct {
+	Name    string
+	Members []GroupMember
+}
+
+// ParseGroup parses the members of a group. Each spec holds one or more
+// devices joined by "+", each optionally followed by "@" and the offset in
+// dB its volume keeps to the group volume, e.g. "office+kitchen@-3".
+func ParseGroup(name string, specs ...string) (Group, error) {
+	group := Group{Name: name}
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		for _, part := range strings.Split(spec, "+") {
+			part = strings.TrimSpace(part)
+			member := GroupMember{Device: part}
+			if i := strings.LastIndex(part, "@"); i >= 0 {
+				offset, err := strconv.ParseFloat(strings.TrimSpace(part[i+1:]), 64)
+				if err != nil {
+					return Group{}, fmt.Errorf("group %q: invalid volume offset in %q", name, part)
+				}
+				member = GroupMember{Device: strings.TrimSpace(part[:i]), VolumeOffset: offset}
+			}
+			if member.Device == "" {
+				return Group{}, fmt.Errorf("group %q: empty device in %q", name, spec)
+			}
+			if seen[strings.ToLower(member.Device)] {
+				return Group{}, fmt.Errorf("group %q: %s is listed twice", name, member.Device)
+			}
+			seen[strings.ToLower(member.Device)] = true
+			group.Members = append(group.Members, member)
+		}
+	}
+	if len(group.Members) == 0 {
+		return Group{}, fmt.Errorf("group %q has no devices", name)
+	}
+	return group, nil
+}
+
+// GroupNames returns the names of the configured groups (name to member
+// specs) in order
+func GroupNames(configured map[string][]string) []string {
+	names := make([]string, 0, len(configured))
+	for name := range configured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveGroup returns the group ref selects: a group of configured, "all"
+// for every entry, or devices joined by "+" as ParseGroup accepts them
+func ResolveGroup(configured map[string][]string, entries []DeviceEntry, ref string) (Group, error) {
+	ref = strings.TrimSpace(ref)
+	for name, specs := range configured {
+		if strings.EqualFold(name, ref) {
+			return ParseGroup(name, specs...)
+		}
+	}
+
+	if strings.EqualFold(ref, AllDevicesGroup) {
+		if len(entries) == 0 {
+			return Group{}, fmt.Errorf("group %q: no devices to control", ref)
+		}
+		group := Group{Name: AllDevicesGroup}
+		for _, e := range entries {
+			device := e.Name
+			if device == "" {
+				device = e.Known.ID
+			}
+			group.Members = append(group.Members, GroupMember{Device: device})
+		}
+		return group, nil
+	}
+
+	if !strings.Contains(ref, "+") {
+		return Group{}, fmt.Errorf("%q: %w", ref, ErrUnknownGroup)
+	}
+	return ParseGroup(ref, ref)
+}
+
+// GroupResult is the outcome of a group command on one member
+type GroupResult struct {
+	Member GroupMember
+	Value  string // What the command reports, e.g. the new volume
+	Err    error
+}
+
+// GroupCommand is a command run on one member of a group
+type GroupCommand func(ctx context.Context, z *Zone, m GroupMember) (string, error)
+
+// GroupExecutor runs commands on the devices of a group concurrently.
+// Members are connected on first use and stay connected until Close, so
+// that a member that fails to connect does not hold up the others.
+type GroupExecutor struct {
+	Group   Group
+	connect func(ctx context.Context, m GroupMember) (*Device, error)
+
+	mu      sync.Mutex // Serializes runs and guards zone
+	zone    ZoneID     // Zone the commands act on, the main zone when empty
+	devices []*Device
+	shared  []bool // Set for devices connected by the caller, see Share
+}
+
+// NewGroupExecutor returns an executor for group that connects to its
+// members with connect
+func NewGroupExecutor(group Group, connect func(ctx context.Context, m GroupMember) (*Device, error)) *GroupExecutor {
+	return &GroupExecutor{
+		Group:   group,
+		connect: connect,
+		devices: make([]*Device, len(group.Members)),
+		shared:  make([]bool, len(group.Members)),
+	}
+}
+
+// Share makes member i use d, a device the caller connected, rather than a
+// connection of its own, e.g. the device a UI shows. Close leaves d
+// connected. A device shared before for another member is dropped, as the
+// caller may have disconnected it.
+func (e *GroupExecutor) Share(i int, d *Device) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for j := range e.devices {
+		if e.shared[j] && j != i {
+			e.devices[j], e.shared[j] = nil, false
+		}
+	}
+	if e.devices[i] == d {
+		e.shared[i] = true
+		return
+	}
+	if e.devices[i] != nil && !e.shared[i] {
+		if err := e.devices[i].Disconnect(); err != nil {
+			log.WithError(err).WithField("device", e.Group.Members[i].Device).Debug("Failed to disconnect group member")
+		}
+	}
+	e.devices[i], e.shared[i] = d, true
+}
+
+// SetZone makes the following commands act on zone of every member. A run
+// under way keeps the zone it started with.
+func (e *GroupExecutor) SetZone(zone ZoneID) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.zone = zone
+}
+
+// Run runs command on every member at once and returns the result of each,
+// in the order of the members
+func (e *GroupExecutor) Run(ctx context.Context, command GroupCommand) []GroupResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	zone := e.zone
+	if zone == "" {
+		zone = MainZone
+	}
+	results := make([]GroupResult, len(e.Group.Members))
+	var wg sync.WaitGroup
+	for i, m := range e.Group.Members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = GroupResult{Member: m}
+			if e.devices[i] == nil {
+				d, err := e.connect(ctx, m)
+				if err != nil {
+					results[i].Err = err
+					return
+				}
+				e.devices[i] = d
+			}
+			results[i].Value, results[i].Err = command(ctx, e.devices[i].Zone(zone), m)
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			log.WithError(r.Err).WithField("device", r.Member.Device).Debug("Group command failed on device")
+		}
+	}
+	log.WithFields(log.Fields{
+		"group":   e.Group.Name,
+		"devices": len(results),
+		"failed":  failed,
+	}).Debug("Ran group command")
+	return results
+}
+
+// PowerOn turns every member on
+func (e *GroupExecutor) PowerOn(ctx context.Context) []GroupResult {
+	return e.Run(ctx, func(ctx context.Context, z *Zone, _ GroupMember) (string, error) {
+		return PowerOn.String(), z.PowerOnContext(ctx)
+	})
+}
+
+// PowerOff turns every member off
+func (e *GroupExecutor) PowerOff(ctx context.Context) []GroupResult {
+	return e.Run(ctx, func(ctx context.Context, z *Zone, _ GroupMember) (string, error) {
+		return PowerOff.String(), z.PowerOffContext(ctx)
+	})
+}
+
+// SetMute mutes or unmutes every member
+func (e *GroupExecutor) SetMute(ctx context.Context, muted bool) []GroupResult {
+	return e.Run(ctx, func(ctx context.Context, z *Zone, _ GroupMember) (string, error) {
+		if muted {
+			return "Muted", z.SetMuteContext(ctx, true)
+		}
+		return "Unmuted", z.SetMuteContext(ctx, false)
+	})
+}
+
+// SetVolume sets every member to volume plus its volume offset
+func (e *GroupExecutor) SetVolume(ctx context.Context, volume float64) []GroupResult {
+	return e.Run(ctx, func(ctx context.Context, z *Zone, m GroupMember) (string, error) {
+		if err := z.SetVolumeContext(ctx, volume+m.VolumeOffset); err != nil {
+			return "", err
+		}
+		return volumeResult(ctx, z)
+	})
+}
+
+// TuneVolume raises or lowers the volume of every member by one step,
+// keeping the offsets between them
+func (e *GroupExecutor) TuneVolume(ctx context.Context, direction Direction) []GroupResult {
+	return e.Run(ctx, func(ctx context.Context, z *Zone, _ GroupMember) (string, error) {
+		if err := z.TuneVolumeContext(ctx, direction); err != nil {
+			return "", err
+		}
+		return volumeResult(ctx, z)
+	})
+}
+
+// SetSource selects the input source, by factory or custom name, on every
+// member
+func (e *GroupExecutor) SetSource(ctx context.Context, source string) []GroupResult {
+	return e.Run(ctx, func(ctx context.Context, z *Zone, _ GroupMember) (string, error) {
+		inputs, err := z.d.InputsContext(ctx)
+		if err != nil {
+			return "", err
+		}
+		input, ok := FindInput(inputs, source)
+		if !ok {
+			return "", withKind(ErrInvalidValue, fmt.Errorf("%q is not a source of this device", source))
+		}
+		if !input.Enabled {
+			return "", withKind(ErrInvalidValue, fmt.Errorf("source %q is disabled on this device", input.DisplayName()))
+		}
+		return input.DisplayName(), z.SetSourceContext(ctx, input.Name)
+	})
+}
+
+// Close disconnects the members the executor connected
+func (e *GroupExecutor) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var errs []error
+	for i, d := range e.devices {
+		if d != nil && !e.shared[i] {
+			errs = append(errs, d.Disconnect())
+		}
+		e.devices[i], e.shared[i] = nil, false
+	}
+	return errors.Join(errs...)
+}
+
+// GroupError returns an error naming the members a group command failed
+// on, or nil if it succeeded on all of them
+func GroupError(results []GroupResult) error {
+	var failed []string
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r.Member.Device)
+			errs = append(errs, fmt.Errorf("%s: %w", r.Member.Device, r.Err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed on %d of %d devices (%s): %w", len(failed), len(results), strings.Join(failed, ", "), errors.Join(errs...))
+}
+
+// volumeResult reports the volume of z after a change
+func volumeResult(ctx context.Context, z *Zone) (string, error) {
+	volume, err := z.GetVolumeFloatContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%.1f dB", volume), nil
+}
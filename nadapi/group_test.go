@@ -0,0 +1,180 @@
+package nadapi
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+func TestParseGroup(t *testing.T) {
+	group, err := ParseGroup("downstairs", "living-room", "office+kitchen@-3", " den @ 2.5 ")
+	if err != nil {
+		t.Fatalf("ParseGroup() error = %v", err)
+	}
+	want := []GroupMember{
+		{Device: "living-room"},
+		{Device: "office"},
+		{Device: "kitchen", VolumeOffset: -3},
+		{Device: "den", VolumeOffset: 2.5},
+	}
+	if len(group.Members) != len(want) {
+		t.Fatalf("ParseGroup() members = %+v, want %+v", group.Members, want)
+	}
+	for i, m := range group.Members {
+		if m != want[i] {
+			t.Errorf("member %d = %+v, want %+v", i, m, want[i])
+		}
+	}
+	if s := group.Members[2].String(); s != "kitchen@-3" {
+		t.Errorf("GroupMember.String() = %q, want kitchen@-3", s)
+	}
+
+	for _, specs := range [][]string{
+		{},
+		{"office+"},
+		{"kitchen@loud"},
+		{"office", "Office"},
+	} {
+		if _, err := ParseGroup("bad", specs...); err == nil {
+			t.Errorf("ParseGroup(%q) should fail", specs)
+		}
+	}
+}
+
+func TestResolveGroup(t *testing.T) {
+	configured := map[string][]string{"downstairs": {"living-room", "kitchen@-3"}}
+	entries := []DeviceEntry{
+		{Name: "living-room", Address: "10.0.0.5"},
+		{Address: "10.0.0.7", Known: &KnownDevice{ID: "00:1a:2b:00:00:07"}},
+	}
+
+	group, err := ResolveGroup(configured, entries, "Downstairs")
+	if err != nil || group.Name != "downstairs" || len(group.Members) != 2 {
+		t.Errorf("ResolveGroup(Downstairs) = %+v, %v, want the configured group", group, err)
+	}
+
+	group, err = ResolveGroup(configured, entries, "all")
+	if err != nil || len(group.Members) != 2 || group.Members[1].Device != "00:1a:2b:00:00:07" {
+		t.Errorf("ResolveGroup(all) = %+v, %v, want every entry", group, err)
+	}
+
+	group, err = ResolveGroup(configured, entries, "office+kitchen")
+	if err != nil || len(group.Members) != 2 {
+		t.Errorf("ResolveGroup(office+kitchen) = %+v, %v, want an ad hoc group", group, err)
+	}
+
+	if _, err := ResolveGroup(configured, entries, "upstairs"); !errors.Is(err, ErrUnknownGroup) {
+		t.Errorf("ResolveGroup(upstairs) error = %v, want ErrUnknownGroup", err)
+	}
+}
+
+func TestGroupExecutorReportsEachDevice(t *testing.T) {
+	amp := fakeamp.Start(t, fakeamp.Unsolicited("Main.Power=On"), fakeamp.Values(map[string]string{"Main.Volume": "-30"}))
+	ip, port := amp.Host, amp.Port
+	// A port nothing listens on
+	closed := fakeamp.Start(t)
+	closed.Stop()
+	closedPort := closed.Port
+
+	group, _ := ParseGroup("test", "up+down")
+	connect := func(ctx context.Context, m GroupMember) (*Device, error) {
+		if m.Device == "down" {
+			return NewContext(ctx, ip, closedPort)
+		}
+		return NewContext(ctx, ip, port)
+	}
+	exec := NewGroupExecutor(group, connect)
+	defer exec.Close()
+
+	results := exec.Run(context.Background(), func(ctx context.Context, z *Zone, _ GroupMember) (string, error) {
+		return z.GetVolumeContext(ctx)
+	})
+	if len(results) != 2 {
+		t.Fatalf("Run() = %+v, want a result per member", results)
+	}
+	if r := results[0]; r.Member.Device != "up" || r.Err != nil || r.Value != "-30" {
+		t.Errorf("result of the answering device = %+v, want the volume", r)
+	}
+	if r := results[1]; r.Member.Device != "down" || r.Err == nil {
+		t.Errorf("result of the unreachable device = %+v, want an error", r)
+	}
+
+	err := GroupError(results)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 devices (down)") {
+		t.Errorf("GroupError() = %v, want the failed device named", err)
+	}
+	if err := GroupError(results[:1]); err != nil {
+		t.Errorf("GroupError() of a success = %v, want nil", err)
+	}
+}
+
+func TestGroupExecutorSetZone(t *testing.T) {
+	group, _ := ParseGroup("test", "a+b")
+	exec := NewGroupExecutor(group, func(ctx context.Context, m GroupMember) (*Device, error) {
+		return &Device{}, nil
+	})
+	zoneOf := func(ctx context.Context, z *Zone, _ GroupMember) (string, error) {
+		return string(z.ID()), nil
+	}
+
+	if r := exec.Run(context.Background(), zoneOf); r[0].Value != string(MainZone) {
+		t.Errorf("Run() acted on %s, want the main zone by default", r[0].Value)
+	}
+
+	// Changing the zone while commands run is safe
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		exec.SetZone(Zone2)
+	}()
+	exec.Run(context.Background(), zoneOf)
+	<-done
+	for _, r := range exec.Run(context.Background(), zoneOf) {
+		if r.Value != string(Zone2) {
+			t.Errorf("Run() acted on %s of %s after SetZone(Zone2)", r.Value, r.Member.Device)
+		}
+	}
+}
+
+func TestGroupExecutorShare(t *testing.T) {
+	amp := fakeamp.Start(t, fakeamp.Values(map[string]string{"Main.Volume": "-30"}))
+	shared, err := NewContext(context.Background(), amp.Host, amp.Port)
+	if err != nil {
+		t.Fatalf("NewContext() unexpected error: %v", err)
+	}
+	defer shared.Disconnect()
+
+	group, _ := ParseGroup("test", "here+there")
+	var connected []string
+	exec := NewGroupExecutor(group, func(ctx context.Context, m GroupMember) (*Device, error) {
+		connected = append(connected, m.Device)
+		return NewContext(ctx, amp.Host, amp.Port)
+	})
+	exec.Share(0, shared)
+	shared.mu.Lock()
+	conn := shared.conn
+	shared.mu.Unlock()
+
+	results := exec.Run(context.Background(), func(ctx context.Context, z *Zone, _ GroupMember) (string, error) {
+		return z.GetVolumeContext(ctx)
+	})
+	if err := GroupError(results); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if !slices.Equal(connected, []string{"there"}) {
+		t.Errorf("Run() connected to %v, want only the member not shared", connected)
+	}
+
+	if err := exec.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	if shared.conn == nil || shared.conn != conn {
+		t.Error("Close() disconnected the shared device")
+	}
+}
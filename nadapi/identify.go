@@ -0,0 +1,254 @@
+package nadapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/galamiram/nadctl/nadapi/protocol"
+)
+
+const (
+	// probeDialTimeout bounds connecting to a candidate address
+	probeDialTimeout = 2 * time.Second
+	// probeReplyTimeout bounds each query of a candidate that accepted the
+	// connection
+	probeReplyTimeout = 2 * time.Second
+	// versionReplyTimeout bounds the firmware query, which not every model
+	// answers
+	versionReplyTimeout = 500 * time.Millisecond
+	// reverseLookupTimeout bounds the reverse DNS lookup of a found device
+	reverseLookupTimeout = 500 * time.Millisecond
+)
+
+// neighborTable is the neighbor table MAC addresses of found devices are
+// looked up in, replaced by tests
+var neighborTable = defaultNeighborTable
+
+// ModelMatcher decides whether the Main.Model reply of a candidate
+// identifies a device discovery should report
+type ModelMatcher func(model string) bool
+
+// MatchKnownModels accepts the models of the capability registry and any
+// model that names itself NAD. It is the default policy.
+func MatchKnownModels(model string) bool {
+	if _, ok := LookupCapabilities(model); ok {
+		return true
+	}
+	return strings.Contains(strings.ToUpper(model), "NAD")
+}
+
+// MatchAnyModel accepts every device that answers the model query
+func MatchAnyModel(string) bool { return true }
+
+// MatchModels accepts models matching one of the shell patterns, e.g. "C3*".
+// Models and patterns are compared without case, spaces or a NAD prefix.
+func MatchModels(patterns ...string) ModelMatcher {
+	return func(model string) bool {
+		m := normalizeModel(model)
+		for _, p := range patterns {
+			if ok, _ := path.Match(normalizeModel(p), m); ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ParseModelMatcher builds the policy for a list of model specs: "known"
+// for MatchKnownModels, "any" for MatchAnyModel, or model patterns. A
+// model is accepted if any spec accepts it; no specs select the default.
+func ParseModelMatcher(specs []string) (ModelMatcher, error) {
+	var matchers []ModelMatcher
+	var patterns []string
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		switch strings.ToLower(spec) {
+		case "":
+		case "known":
+			matchers = append(matchers, MatchKnownModels)
+		case "any":
+			matchers = append(matchers, MatchAnyModel)
+		default:
+			if _, err := path.Match(normalizeModel(spec), ""); err != nil {
+				return nil, fmt.Errorf("invalid model pattern %q: %w", spec, err)
+			}
+			patterns = append(patterns, spec)
+		}
+	}
+	if len(patterns) > 0 {
+		matchers = append(matchers, MatchModels(patterns...))
+	}
+	if len(matchers) == 0 {
+		return nil, nil
+	}
+	return func(model string) bool {
+		for _, match := range matchers {
+			if match(model) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// identify probes the control port of ip and, if a device the policy
+// accepts answers, returns it with every detail that can be found out
+func identify(ctx context.Context, ip, port, method string, match ModelMatcher) *DiscoveredDevice {
+	device := testNADDevice(ctx, ip, port, match)
+	if device == nil {
+		return nil
+	}
+	device.Method = method
+	device.MAC = lookupMAC(ip)
+	device.Hostname = reverseLookup(ctx, ip)
+
+	log.WithFields(log.Fields{
+		"ip":       ip,
+		"model":    device.Model,
+		"firmware": device.Firmware,
+		"mac":      device.MAC,
+		"hostname": device.Hostname,
+		"latency":  device.Latency,
+		"method":   method,
+	}).Debug("Identified NAD device")
+	return device
+}
+
+// testNADDevice tests if the control port of an IP address answers like a
+// device match accepts, nil selecting MatchKnownModels
+func testNADDevice(ctx context.Context, ip, port string, match ModelMatcher) *DiscoveredDevice {
+	log.WithField("ip", ip).Debug("Testing IP for NAD device")
+	if match == nil {
+		match = MatchKnownModels
+	}
+
+	dialer := net.Dialer{Timeout: probeDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, port))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"ip":    ip,
+			"error": err.Error(),
+		}).Debug("Failed to connect to IP")
+		return nil
+	}
+
+	// Ensure connection is always closed
+	defer func() {
+		if closeErr := conn.Close(); closeErr != nil {
+			log.WithFields(log.Fields{
+				"ip":    ip,
+				"error": closeErr.Error(),
+			}).Debug("Error closing test connection")
+		} else {
+			log.WithField("ip", ip).Debug("Successfully closed test connection")
+		}
+	}()
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	log.WithField("ip", ip).Debug("Successfully connected, testing for NAD device")
+	scanner := protocol.NewScanner(conn)
+
+	// Try to get the model to verify it's a NAD device
+	start := time.Now()
+	model, err := probeQuery(ctx, conn, scanner, "Main.Model", probeReplyTimeout)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"ip":      ip,
+			"command": "Main.Model?",
+			"error":   err.Error(),
+		}).Debug("Failed to query model")
+		return nil
+	}
+	latency := time.Since(start)
+
+	if !match(model) {
+		log.WithFields(log.Fields{
+			"ip":    ip,
+			"model": model,
+		}).Debug("Device model does not match the model policy")
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"ip":    ip,
+		"model": model,
+	}).Debug("Confirmed NAD device")
+
+	device := &DiscoveredDevice{
+		IP:      ip,
+		Model:   model,
+		Port:    port,
+		Latency: latency,
+	}
+	if firmware, err := probeQuery(ctx, conn, scanner, "Main.Version", versionReplyTimeout); err == nil {
+		device.Firmware = firmware
+	} else {
+		log.WithError(err).WithField("ip", ip).Debug("Device did not report its firmware version")
+	}
+	return device
+}
+
+// probeQuery sends the query for key and returns the value of the reply,
+// skipping unsolicited lines
+func probeQuery(ctx context.Context, conn net.Conn, scanner *protocol.Scanner, key string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+
+	if err := protocol.Encode(conn, protocol.Query(key)); err != nil {
+		return "", err
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		log.WithFields(log.Fields{
+			"key":      key,
+			"response": line,
+		}).Debug("Received probe response")
+		if messageKey(line) == key {
+			return extractValue(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// lookupMAC returns the MAC address of ip from the neighbor table, or ""
+// when ip is not on the local link
+func lookupMAC(ip string) string {
+	neighbors, err := ReadNeighborTable(neighborTable)
+	if err != nil {
+		return ""
+	}
+	host := canonicalHost(ip)
+	for _, n := range neighbors {
+		if canonicalHost(n.IP) == host {
+			return n.MAC
+		}
+	}
+	return ""
+}
+
+// reverseLookup returns the DNS name of ip without the trailing dot, or ""
+func reverseLookup(ctx context.Context, ip string) string {
+	ctx, cancel := context.WithTimeout(ctx, reverseLookupTimeout)
+	defer cancel()
+	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
@@ -0,0 +1,111 @@
+package nadapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+func TestMatchKnownModels(t *testing.T) {
+	tests := []struct {
+		model string
+		want  bool
+	}{
+		{"C338", true},
+		{"C368", true},
+		{"C 368", true},
+		{"NAD T 758 V3i", true},
+		{"NAD Unknown", true},
+		{"Sonos Amp", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := MatchKnownModels(tt.model); got != tt.want {
+			t.Errorf("MatchKnownModels(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestParseModelMatcher(t *testing.T) {
+	tests := []struct {
+		specs []string
+		model string
+		want  bool
+	}{
+		{[]string{"C3*"}, "C368", true},
+		{[]string{"c3*"}, "NAD C 338", true},
+		{[]string{"C3*"}, "M10", false},
+		{[]string{"T7*", "M10"}, "M10", true},
+		{[]string{"known", "Sonos*"}, "Sonos Amp", true},
+		{[]string{"known"}, "Sonos Amp", false},
+		{[]string{"any"}, "Sonos Amp", true},
+	}
+	for _, tt := range tests {
+		match, err := ParseModelMatcher(tt.specs)
+		if err != nil {
+			t.Fatalf("ParseModelMatcher(%q) unexpected error: %v", tt.specs, err)
+		}
+		if got := match(tt.model); got != tt.want {
+			t.Errorf("ParseModelMatcher(%q)(%q) = %v, want %v", tt.specs, tt.model, got, tt.want)
+		}
+	}
+
+	if match, err := ParseModelMatcher(nil); err != nil || match != nil {
+		t.Errorf("ParseModelMatcher(nil) = %v, %v, want nil, nil", match != nil, err)
+	}
+	if _, err := ParseModelMatcher([]string{"C3["}); err == nil {
+		t.Error("ParseModelMatcher with a malformed pattern should fail")
+	}
+}
+
+func TestIdentifyEnrichesDevice(t *testing.T) {
+	amp := fakeamp.Start(t, fakeamp.Unsolicited("Main.Power=On"), fakeamp.Values(map[string]string{
+		"Main.Model":   "C368",
+		"Main.Version": "V2.10",
+	}))
+	ip, port := amp.Host, amp.Port
+	original := neighborTable
+	neighborTable = writeNeighborTable(t,
+		"127.0.0.1        0x1         0x2         00:1A:2B:3C:4D:5E     *        lo")
+	t.Cleanup(func() { neighborTable = original })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	device := identify(ctx, ip, port, "sweep", nil)
+	if device == nil {
+		t.Fatal("identify() = nil, want the C368")
+	}
+	if device.Model != "C368" || device.Firmware != "V2.10" {
+		t.Errorf("identify() model, firmware = %q, %q, want C368, V2.10", device.Model, device.Firmware)
+	}
+	if device.MAC != "00:1a:2b:3c:4d:5e" {
+		t.Errorf("identify() MAC = %q, want 00:1a:2b:3c:4d:5e", device.MAC)
+	}
+	if device.Latency <= 0 {
+		t.Errorf("identify() latency = %v, want > 0", device.Latency)
+	}
+	if device.Method != "sweep" {
+		t.Errorf("identify() method = %q, want sweep", device.Method)
+	}
+}
+
+func TestTestNADDeviceAppliesModelPolicy(t *testing.T) {
+	amp := fakeamp.Start(t, fakeamp.Unsolicited("Main.Power=On"), fakeamp.Values(map[string]string{"Main.Model": "C368"}))
+	ip, port := amp.Host, amp.Port
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if device := testNADDevice(ctx, ip, port, MatchModels("T7*")); device != nil {
+		t.Errorf("testNADDevice() with policy T7* = %+v, want nil", device)
+	}
+	// The model answers but not the firmware query
+	device := testNADDevice(ctx, ip, port, MatchModels("C3*"))
+	if device == nil {
+		t.Fatal("testNADDevice() with policy C3* = nil, want the C368")
+	}
+	if device.Firmware != "" {
+		t.Errorf("testNADDevice() firmware = %q, want none", device.Firmware)
+	}
+}
@@ -0,0 +1,170 @@
+package nadapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// inputQueryTimeout bounds the wait for each batch of SourceN replies. Models
+// that do not support input naming stay silent, so this keeps falling back to
+// factory names quick.
+const inputQueryTimeout = time.Second
+
+// Input is one source input of the device as configured by its owner
+type Input struct {
+	Index   int    `json:"index"`                 // 1-based, as in Source1.Name
+	Name    string `json:"name"`                  // factory name used with Main.Source
+	Custom  string `json:"custom_name,omitempty"` // owner-assigned name, if renamed
+	Enabled bool   `json:"enabled"`
+}
+
+// DisplayName returns the custom name of a renamed input, otherwise the
+// factory name
+func (in Input) DisplayName() string {
+	if in.Custom != "" {
+		return in.Custom
+	}
+	return in.Name
+}
+
+// FindInput looks up an input by custom or factory name, case-insensitively.
+// Custom names win, so a renamed input can shadow another factory name.
+func FindInput(inputs []Input, name string) (Input, bool) {
+	for _, in := range inputs {
+		if in.Custom != "" && strings.EqualFold(in.Custom, name) {
+			return in, true
+		}
+	}
+	for _, in := range inputs {
+		if strings.EqualFold(in.Name, name) {
+			return in, true
+		}
+	}
+	return Input{}, false
+}
+
+// inputBySource finds the input with the given factory name, as reported by
+// Main.Source
+func inputBySource(inputs []Input, source string) (Input, bool) {
+	for _, in := range inputs {
+		if strings.EqualFold(in.Name, source) {
+			return in, true
+		}
+	}
+	return Input{}, false
+}
+
+// inputNames returns the display names of the enabled inputs
+func inputNames(inputs []Input) []string {
+	var names []string
+	for _, in := range inputs {
+		if in.Enabled {
+			names = append(names, in.DisplayName())
+		}
+	}
+	return names
+}
+
+// parseEnabled converts a SourceN.Enabled value to a bool
+func parseEnabled(s string) (bool, error) {
+	switch {
+	case strings.EqualFold(s, "Yes"), strings.EqualFold(s, "On"):
+		return true, nil
+	case strings.EqualFold(s, "No"), strings.EqualFold(s, "Off"):
+		return false, nil
+	}
+	return false, withKind(ErrInvalidValue, fmt.Errorf("unknown enabled value %q", s))
+}
+
+// Inputs returns the inputs of the device with their custom names and
+// enabled flags
+func (d *Device) Inputs() ([]Input, error) {
+	return d.InputsContext(context.Background())
+}
+
+// InputsContext is like Inputs but takes a context. The inputs are read once
+// per Device; a rename reported by the device causes them to be read again.
+// Models that do not report input names get their factory names, all enabled.
+func (d *Device) InputsContext(ctx context.Context) ([]Input, error) {
+	d.inputsMu.Lock()
+	defer d.inputsMu.Unlock()
+
+	if d.inputs != nil && !d.inputsStale.Load() {
+		return d.inputs, nil
+	}
+	d.inputsStale.Store(false)
+
+	caps, err := d.CapabilitiesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([]Input, len(caps.Sources))
+	for i, name := range caps.Sources {
+		inputs[i] = Input{Index: i + 1, Name: name, Enabled: true}
+	}
+
+	// Every setting of every input is asked at once; those a model leaves
+	// unanswered keep their factory setting
+	keys := make([]string, 0, 2*len(inputs))
+	for _, in := range inputs {
+		keys = append(keys, fmt.Sprintf("Source%d.Name", in.Index), fmt.Sprintf("Source%d.Enabled", in.Index))
+	}
+	values, err := d.query(ctx, keys, inputQueryTimeout)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, fmt.Errorf("get inputs: %w", ctxErr)
+	}
+	if err != nil || len(values) == 0 {
+		log.WithError(err).WithField("device", d.String()).Debug("Device does not report input settings, using factory names")
+	}
+	for i := range inputs {
+		inputs[i].apply(values)
+	}
+
+	log.WithFields(log.Fields{
+		"device": d.String(),
+		"inputs": inputNames(inputs),
+	}).Debug("Retrieved device inputs")
+
+	d.inputs = inputs
+	return inputs, nil
+}
+
+// apply sets the custom name and enabled flag of in from values, by
+// lower-case key as query returns them. A setting missing or unreadable
+// keeps its factory value.
+func (in *Input) apply(values map[string]string) {
+	if custom, ok := values[fmt.Sprintf("source%d.name", in.Index)]; ok {
+		if custom != "" && !strings.EqualFold(custom, in.Name) {
+			in.Custom = custom
+		}
+	}
+	if val, ok := values[fmt.Sprintf("source%d.enabled", in.Index)]; ok {
+		if enabled, err := parseEnabled(val); err == nil {
+			in.Enabled = enabled
+		} else {
+			log.WithError(err).WithField("input", in.Index).Debug("Ignoring unreadable input setting")
+		}
+	}
+}
+
+// forgetInputs marks the cached inputs stale so they are read again on next
+// use. It is called from the reader goroutine and must not take inputsMu,
+// which InputsContext holds while waiting for replies.
+func (d *Device) forgetInputs() {
+	d.inputsStale.Store(true)
+}
+
+// CurrentInput returns the input that is currently selected
+func (d *Device) CurrentInput() (Input, error) {
+	return d.CurrentInputContext(context.Background())
+}
+
+// CurrentInputContext is like CurrentInput but takes a context
+func (d *Device) CurrentInputContext(ctx context.Context) (Input, error) {
+	return d.main().CurrentInputContext(ctx)
+}
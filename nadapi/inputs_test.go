@@ -0,0 +1,208 @@
+package nadapi
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+// addInputReplies adds SourceN.Name and SourceN.Enabled replies for every
+// input of model to replies. Inputs missing from names keep their factory
+// name; inputs listed in disabled report "No".
+func addInputReplies(replies map[string]string, model string, names map[int]string, disabled []int) {
+	caps, _ := LookupCapabilities(model)
+	for i, src := range caps.Sources {
+		index := i + 1
+		name := src
+		if custom, ok := names[index]; ok {
+			name = custom
+		}
+		replies[fmt.Sprintf("Source%d.Name", index)] = name
+		replies[fmt.Sprintf("Source%d.Enabled", index)] = "Yes"
+	}
+	for _, index := range disabled {
+		replies[fmt.Sprintf("Source%d.Enabled", index)] = "No"
+	}
+}
+
+func newInputsDevice(t *testing.T, names map[int]string, disabled []int) *Device {
+	t.Helper()
+	replies := map[string]string{
+		"Main.Model":  "C338",
+		"Main.Source": "Stream",
+	}
+	addInputReplies(replies, "C338", names, disabled)
+	d, _ := connectAmp(t, fakeamp.Values(replies))
+	return d
+}
+
+func TestInputs(t *testing.T) {
+	d := newInputsDevice(t, map[int]string{4: "Turntable", 3: "tv"}, []int{2})
+
+	inputs, err := d.Inputs()
+	if err != nil {
+		t.Fatalf("Inputs() unexpected error: %v", err)
+	}
+	if len(inputs) != 8 {
+		t.Fatalf("Inputs() returned %d inputs, want 8", len(inputs))
+	}
+
+	want := []Input{
+		{Index: 1, Name: "Stream", Enabled: true},
+		{Index: 2, Name: "Wireless", Enabled: false},
+		{Index: 3, Name: "TV", Enabled: true}, // same name in other case is not a rename
+		{Index: 4, Name: "Phono", Custom: "Turntable", Enabled: true},
+	}
+	for i, w := range want {
+		if inputs[i] != w {
+			t.Errorf("Inputs()[%d] = %+v, want %+v", i, inputs[i], w)
+		}
+	}
+	if got := inputs[3].DisplayName(); got != "Turntable" {
+		t.Errorf("DisplayName() = %q, want %q", got, "Turntable")
+	}
+}
+
+func TestInputsFallBackToFactoryNames(t *testing.T) {
+	d, amp := connectAmp(t, fakeamp.Values(map[string]string{"Main.Model": "C338"}))
+	d.mu.Lock()
+	conn := d.conn
+	d.mu.Unlock()
+	amp.TakeQueried()
+
+	inputs, err := d.Inputs()
+	if err != nil {
+		t.Fatalf("Inputs() unexpected error: %v", err)
+	}
+	// One unanswered batch is enough to give up on the rest
+	var asked int
+	for _, key := range amp.TakeQueried() {
+		if strings.HasPrefix(key, "Source") {
+			asked++
+		}
+	}
+	if asked != maxQueryBatch {
+		t.Errorf("Inputs() asked %d input settings, want one batch of %d", asked, maxQueryBatch)
+	}
+	for i, in := range inputs {
+		if in.Name != defaultCapabilities.Sources[i] || in.Custom != "" || !in.Enabled {
+			t.Errorf("Inputs()[%d] = %+v, want factory input %s enabled", i, in, defaultCapabilities.Sources[i])
+		}
+	}
+
+	// The unanswered input queries are no reason to reconnect
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.conn != conn {
+		t.Error("Inputs() replaced the connection after the unanswered input queries")
+	}
+}
+
+func TestInputsFallBackPerSetting(t *testing.T) {
+	// A model that reports input names but not whether they are enabled
+	replies := map[string]string{"Main.Model": "C338"}
+	addInputReplies(replies, "C338", map[int]string{4: "Turntable", 8: "Optical"}, nil)
+	for key := range replies {
+		if strings.HasSuffix(key, ".Enabled") {
+			delete(replies, key)
+		}
+	}
+	d, _ := connectAmp(t, fakeamp.Values(replies))
+
+	start := time.Now()
+	inputs, err := d.Inputs()
+	if err != nil {
+		t.Fatalf("Inputs() unexpected error: %v", err)
+	}
+	// The settings are asked in batches rather than waiting on each input
+	if elapsed := time.Since(start); elapsed > 3*inputQueryTimeout {
+		t.Errorf("Inputs() took %v, want at most %v", elapsed, 3*inputQueryTimeout)
+	}
+	if in := inputs[3]; in.Custom != "Turntable" || !in.Enabled {
+		t.Errorf("Inputs()[3] = %+v, want Turntable enabled", in)
+	}
+	if in := inputs[7]; in.Custom != "Optical" || !in.Enabled {
+		t.Errorf("Inputs()[7] = %+v, want Optical enabled", in)
+	}
+}
+
+func TestFindInput(t *testing.T) {
+	inputs := []Input{
+		{Index: 1, Name: "Stream", Enabled: true},
+		{Index: 2, Name: "TV", Custom: "Stream", Enabled: true},
+		{Index: 3, Name: "Phono", Custom: "Turntable", Enabled: true},
+	}
+
+	tests := []struct {
+		name      string
+		wantIndex int
+		wantOK    bool
+	}{
+		{"turntable", 3, true},
+		{"Phono", 3, true},
+		{"Stream", 2, true}, // custom name shadows factory name
+		{"Coax1", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := FindInput(inputs, tt.name)
+		if ok != tt.wantOK || got.Index != tt.wantIndex {
+			t.Errorf("FindInput(%q) = %+v, %v; want index %d, %v", tt.name, got, ok, tt.wantIndex, tt.wantOK)
+		}
+	}
+}
+
+func TestParseEnabled(t *testing.T) {
+	for _, s := range []string{"Yes", "on"} {
+		if v, err := parseEnabled(s); err != nil || !v {
+			t.Errorf("parseEnabled(%q) = %v, %v; want true, nil", s, v, err)
+		}
+	}
+	for _, s := range []string{"No", "Off"} {
+		if v, err := parseEnabled(s); err != nil || v {
+			t.Errorf("parseEnabled(%q) = %v, %v; want false, nil", s, v, err)
+		}
+	}
+	if _, err := parseEnabled("Maybe"); err == nil {
+		t.Error("parseEnabled(Maybe) expected error, got nil")
+	}
+}
+
+func TestSetSourceByCustomName(t *testing.T) {
+	replies := map[string]string{"Main.Model": "C338"}
+	addInputReplies(replies, "C338", map[int]string{4: "Turntable"}, []int{2})
+	replies["Main.Source"] = "Stream"
+	d, amp := connectAmp(t, fakeamp.Values(replies))
+
+	if err := d.SetSource("Turntable"); err != nil {
+		t.Errorf("SetSource(Turntable) unexpected error: %v", err)
+	}
+	// The device is told the factory name
+	if got := amp.Value("Main.Source"); got != "Phono" {
+		t.Errorf("source after SetSource(Turntable) = %q, want Phono", got)
+	}
+
+	err := d.SetSource("Wireless")
+	if err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("SetSource(Wireless) error = %v, want disabled input rejected", err)
+	}
+}
+
+func TestInputsReloadAfterSourceEvent(t *testing.T) {
+	d := newInputsDevice(t, map[int]string{4: "Turntable"}, nil)
+
+	if _, err := d.Inputs(); err != nil {
+		t.Fatalf("Inputs() unexpected error: %v", err)
+	}
+	if d.inputsStale.Load() {
+		t.Fatal("inputs stale right after loading")
+	}
+
+	d.publish("Source4.Name=Record Player\r\n")
+	if !d.inputsStale.Load() {
+		t.Error("inputs not marked stale after Source4.Name event")
+	}
+}
@@ -0,0 +1,233 @@
+package nadapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// VolumeLimits is a volume safety policy. A device given one with
+// SetVolumeLimits refuses volume changes that break it with a
+// *VolumeLimitError, rather than clamping them.
+type VolumeLimits struct {
+	Max       float64            // Highest volume, in dB
+	MaxStep   float64            // Largest increase of a single change, in dB; 0 for any
+	SourceMax map[string]float64 // Lower highest volumes by source name, factory or custom, e.g. "Phono"
+}
+
+// ParseVolumeLimits builds a policy from its settings as text, as they come
+// from a config file. An empty max or step leaves it unlimited.
+func ParseVolumeLimits(max, maxStep string, sourceMax map[string]string) (VolumeLimits, error) {
+	limits := VolumeLimits{Max: math.Inf(1)}
+	var err error
+	if strings.TrimSpace(max) != "" {
+		if limits.Max, err = strconv.ParseFloat(strings.TrimSpace(max), 64); err != nil {
+			return VolumeLimits{}, withKind(ErrInvalidValue, fmt.Errorf("invalid volume limit %q", max))
+		}
+	}
+	if strings.TrimSpace(maxStep) != "" {
+		limits.MaxStep, err = strconv.ParseFloat(strings.TrimSpace(maxStep), 64)
+		if err != nil || limits.MaxStep <= 0 {
+			return VolumeLimits{}, withKind(ErrInvalidValue, fmt.Errorf("invalid volume step limit %q, want a positive number of dB", maxStep))
+		}
+	}
+	for source, value := range sourceMax {
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return VolumeLimits{}, withKind(ErrInvalidValue, fmt.Errorf("invalid volume limit %q for source %s", value, source))
+		}
+		if limits.SourceMax == nil {
+			limits.SourceMax = make(map[string]float64)
+		}
+		limits.SourceMax[source] = v
+	}
+	return limits, nil
+}
+
+// MaxFor returns the highest volume while one of sources plays, e.g. the
+// factory and custom names of an input, and the source whose limit applies
+// if it is not the overall one
+func (l VolumeLimits) MaxFor(sources ...string) (max float64, source string) {
+	max = l.Max
+	for name, limit := range l.SourceMax {
+		for _, s := range sources {
+			if s != "" && strings.EqualFold(name, s) && limit < max {
+				max, source = limit, s
+			}
+		}
+	}
+	return max, source
+}
+
+// namesCustom reports whether a source limit names something other than one
+// of the factory sources, so it may be the custom name of an input
+func (l VolumeLimits) namesCustom(factory []string) bool {
+	for name := range l.SourceMax {
+		if !slices.ContainsFunc(factory, func(s string) bool { return strings.EqualFold(s, name) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// VolumeLimitError reports a volume change refused by a VolumeLimits policy
+// or by the range of the model
+type VolumeLimitError struct {
+	Volume float64 // Volume asked for
+	From   float64 // Volume before the change, for the step limit
+	Limit  float64 // Highest volume, or largest increase, allowed
+	Source string  // Source whose limit applies, if any
+	Step   bool    // Set when the increase, not the volume, is over the limit
+	Model  bool    // Set when the limit is the highest volume of the model
+}
+
+func (e *VolumeLimitError) Error() string {
+	switch {
+	case e.Step:
+		return fmt.Sprintf("raising the volume from %.1f to %.1f dB at once is over the step limit of %.1f dB", e.From, e.Volume, e.Limit)
+	case e.Model:
+		return fmt.Sprintf("volume %.1f dB is over the %.1f dB maximum of the model", e.Volume, e.Limit)
+	case e.Source != "":
+		return fmt.Sprintf("volume %.1f dB is over the %.1f dB limit for %s", e.Volume, e.Limit, e.Source)
+	}
+	return fmt.Sprintf("volume %.1f dB is over the %.1f dB limit", e.Volume, e.Limit)
+}
+
+// Is makes every VolumeLimitError match ErrVolumeLimit
+func (e *VolumeLimitError) Is(target error) bool {
+	return target == ErrVolumeLimit
+}
+
+// SetVolumeLimits makes the device refuse volume changes that break limits,
+// in every zone
+func (d *Device) SetVolumeLimits(limits VolumeLimits) {
+	d.limitsMu.Lock()
+	defer d.limitsMu.Unlock()
+	d.limits = &limits
+}
+
+// VolumeLimits returns the volume policy of the device and whether it has
+// one
+func (d *Device) VolumeLimits() (VolumeLimits, bool) {
+	d.limitsMu.Lock()
+	defer d.limitsMu.Unlock()
+	if d.limits == nil {
+		return VolumeLimits{}, false
+	}
+	return *d.limits, true
+}
+
+// checkVolume returns a *VolumeLimitError if changing the volume of the
+// zone from current to volume exceeds the maximum of the model or breaks
+// the limits of the device. A NaN current is read from the device when the
+// step limit needs it; an infinite one skips the step limit.
+func (z *Zone) checkVolume(ctx context.Context, current, volume float64) error {
+	caps, err := z.d.CapabilitiesContext(ctx)
+	if err != nil {
+		return err
+	}
+	if volume > caps.MaxVolume {
+		return &VolumeLimitError{Volume: volume, Limit: caps.MaxVolume, Model: true}
+	}
+
+	limits, ok := z.d.VolumeLimits()
+	if !ok {
+		return nil
+	}
+	max, source := limits.Max, ""
+	if len(limits.SourceMax) > 0 {
+		// Only the selected input is read, not every input of the model, as
+		// this runs before each volume change
+		src, err := z.GetSourceContext(ctx)
+		if err != nil {
+			return err
+		}
+		custom := ""
+		if limits.namesCustom(caps.Sources) {
+			if custom, err = z.d.customName(ctx, caps.Sources, src); err != nil {
+				return err
+			}
+		}
+		max, source = limits.MaxFor(src, custom)
+	}
+	if volume > max {
+		return z.refuse(&VolumeLimitError{Volume: volume, Limit: max, Source: source})
+	}
+
+	if limits.MaxStep <= 0 || math.IsInf(current, 0) {
+		return nil
+	}
+	if math.IsNaN(current) {
+		if current, err = z.GetVolumeFloatContext(ctx); err != nil {
+			return err
+		}
+	}
+	if volume-current > limits.MaxStep {
+		return z.refuse(&VolumeLimitError{Volume: volume, From: current, Limit: limits.MaxStep, Step: true})
+	}
+	return nil
+}
+
+// customName returns the custom name of the input with factory name src,
+// from the inputs already read if they are current or else from that one
+// input. Inputs without one, or on models that do not name them, have "".
+func (d *Device) customName(ctx context.Context, factory []string, src string) (string, error) {
+	d.inputsMu.Lock()
+	if d.inputs != nil && !d.inputsStale.Load() {
+		in, _ := inputBySource(d.inputs, src)
+		d.inputsMu.Unlock()
+		return in.Custom, nil
+	}
+	d.inputsMu.Unlock()
+
+	index := slices.IndexFunc(factory, func(s string) bool { return strings.EqualFold(s, src) })
+	if index < 0 {
+		return "", nil
+	}
+	in := Input{Index: index + 1, Name: factory[index]}
+	values, err := d.query(ctx, []string{fmt.Sprintf("Source%d.Name", in.Index)}, inputQueryTimeout)
+	if err != nil {
+		return "", err
+	}
+	in.apply(values)
+	return in.Custom, nil
+}
+
+// checkSource returns a *VolumeLimitError if the current volume of the zone
+// is over the limit for input, which is about to be selected
+func (z *Zone) checkSource(ctx context.Context, input Input) error {
+	limits, ok := z.d.VolumeLimits()
+	if !ok || len(limits.SourceMax) == 0 {
+		return nil
+	}
+	max, source := limits.MaxFor(input.Name, input.Custom)
+	if source == "" {
+		return nil
+	}
+	volume, err := z.GetVolumeFloatContext(ctx)
+	if err != nil {
+		return err
+	}
+	if volume > max {
+		return z.refuse(&VolumeLimitError{Volume: volume, Limit: max, Source: source})
+	}
+	return nil
+}
+
+// refuse logs a volume change the limits of the device refused
+func (z *Zone) refuse(err *VolumeLimitError) error {
+	log.WithFields(log.Fields{
+		"device": z.d.String(),
+		"zone":   z.id,
+		"volume": err.Volume,
+		"limit":  err.Limit,
+		"source": err.Source,
+		"step":   err.Step,
+	}).Debug("Volume change refused by the volume limits")
+	return err
+}
@@ -0,0 +1,149 @@
+package nadapi
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestParseVolumeLimits(t *testing.T) {
+	limits, err := ParseVolumeLimits("-10", "6", map[string]string{"phono": "-30"})
+	if err != nil {
+		t.Fatalf("ParseVolumeLimits() unexpected error: %v", err)
+	}
+	if limits.Max != -10 || limits.MaxStep != 6 || limits.SourceMax["phono"] != -30 {
+		t.Errorf("ParseVolumeLimits() = %+v, want max -10, step 6 and phono -30", limits)
+	}
+
+	limits, err = ParseVolumeLimits("", "", nil)
+	if err != nil || !math.IsInf(limits.Max, 1) || limits.MaxStep != 0 {
+		t.Errorf("ParseVolumeLimits() of no settings = %+v, %v; want no limits", limits, err)
+	}
+
+	for _, tt := range []struct{ max, step, source string }{
+		{"loud", "", ""},
+		{"", "0", ""},
+		{"", "-3", ""},
+		{"", "", "quiet"},
+	} {
+		sources := map[string]string{}
+		if tt.source != "" {
+			sources["Phono"] = tt.source
+		}
+		if _, err := ParseVolumeLimits(tt.max, tt.step, sources); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("ParseVolumeLimits(%q, %q, %v) error = %v, want ErrInvalidValue", tt.max, tt.step, sources, err)
+		}
+	}
+}
+
+func TestVolumeLimitsMaxFor(t *testing.T) {
+	limits := VolumeLimits{Max: -10, SourceMax: map[string]float64{"phono": -30, "Turntable": -35, "TV": 0}}
+	tests := []struct {
+		sources    []string
+		wantMax    float64
+		wantSource string
+	}{
+		{[]string{"Stream"}, -10, ""},
+		{[]string{"Phono"}, -30, "Phono"},
+		{[]string{"Phono", "Turntable"}, -35, "Turntable"},
+		{[]string{"TV"}, -10, ""}, // Source limits only lower the overall one
+	}
+	for _, tt := range tests {
+		max, source := limits.MaxFor(tt.sources...)
+		if max != tt.wantMax || source != tt.wantSource {
+			t.Errorf("MaxFor(%v) = %v, %q; want %v, %q", tt.sources, max, source, tt.wantMax, tt.wantSource)
+		}
+	}
+}
+
+func TestVolumeLimitsEnforced(t *testing.T) {
+	d, amp := newVolumeDevice(t, -40)
+	ctx := context.Background()
+
+	// Without limits only the maximum of the model applies
+	var limitErr *VolumeLimitError
+	if err := d.SetVolume(12); !errors.As(err, &limitErr) || !limitErr.Model {
+		t.Errorf("SetVolume(12) error = %v, want the model maximum", err)
+	}
+
+	d.SetVolumeLimits(VolumeLimits{Max: -10, MaxStep: 6, SourceMax: map[string]float64{"phono": -38}})
+
+	if err := d.SetVolume(-5); !errors.As(err, &limitErr) || limitErr.Limit != -10 {
+		t.Errorf("SetVolume(-5) error = %v, want the -10 dB limit", err)
+	}
+	if err := d.SetVolume(-30); !errors.As(err, &limitErr) || !limitErr.Step {
+		t.Errorf("SetVolume(-30) error = %v, want the step limit", err)
+	}
+	if err := d.SetVolume(-36); err != nil {
+		t.Errorf("SetVolume(-36) unexpected error: %v", err)
+	}
+	if _, err := d.FadeVolume(ctx, -15, 0, FadeLinear); !errors.Is(err, ErrVolumeLimit) {
+		t.Errorf("FadeVolume() in a single step error = %v, want ErrVolumeLimit", err)
+	}
+	if _, err := d.Raw(ctx, "Main.Volume=0"); !errors.Is(err, ErrVolumeLimit) {
+		t.Errorf("Raw(Main.Volume=0) error = %v, want ErrVolumeLimit", err)
+	}
+
+	// Phono plays at most at -38 dB
+	if err := d.SetSource("Phono"); !errors.As(err, &limitErr) || limitErr.Source != "Phono" {
+		t.Errorf("SetSource(Phono) at -36 dB error = %v, want the Phono limit", err)
+	}
+	if err := d.SetVolume(-40); err != nil {
+		t.Fatalf("SetVolume(-40) unexpected error: %v", err)
+	}
+	if err := d.SetSource("Phono"); err != nil {
+		t.Fatalf("SetSource(Phono) at -40 dB unexpected error: %v", err)
+	}
+	if err := d.SetVolume(-37); !errors.As(err, &limitErr) || limitErr.Limit != -38 {
+		t.Errorf("SetVolume(-37) on Phono error = %v, want the -38 dB limit", err)
+	}
+	for _, want := range []float64{-39, -38} {
+		if err := d.TuneVolume(DirectionUp); err != nil {
+			t.Errorf("TuneVolume(up) to %v dB unexpected error: %v", want, err)
+		}
+	}
+	if err := d.TuneVolume(DirectionUp); !errors.Is(err, ErrVolumeLimit) {
+		t.Errorf("TuneVolume(up) to -37 dB on Phono error = %v, want ErrVolumeLimit", err)
+	}
+
+	if got := ampVolume(amp); got != -38 {
+		t.Errorf("device volume = %v, want -38 after the refused changes", got)
+	}
+}
+
+func TestVolumeLimitsReadOnlyTheSelectedInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		sourceMax map[string]float64
+		want      []string
+	}{
+		{"factory name", map[string]float64{"Phono": -38}, []string{"Main.Source"}},
+		{"custom name", map[string]float64{"Turntable": -38}, []string{"Main.Source", "Source1.Name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, amp := newVolumeDevice(t, -40)
+			d.SetVolumeLimits(VolumeLimits{Max: -10, SourceMax: tt.sourceMax})
+			if _, err := d.Capabilities(); err != nil {
+				t.Fatalf("Capabilities() unexpected error: %v", err)
+			}
+			amp.TakeQueried()
+
+			if err := d.SetVolume(-39); err != nil {
+				t.Fatalf("SetVolume(-39) unexpected error: %v", err)
+			}
+			var got []string
+			for _, key := range amp.TakeQueried() {
+				if key != "Main.Volume" {
+					got = append(got, key)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SetVolume(-39) queried %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package nadapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// mdnsService is the service type BluOS based NAD units announce
+	mdnsService = "_musc._tcp.local."
+	mdnsAddr    = "224.0.0.251:5353"
+)
+
+// MDNSProvider finds devices that announce themselves over multicast DNS.
+// Announced hosts are confirmed on the control port before being reported.
+type MDNSProvider struct {
+	Service string        // Service type to query, _musc._tcp.local. when empty
+	Addr    string        // Where to send the query, the mDNS group when empty
+	Port    string        // Control port to confirm on, 30001 when empty
+	Wait    time.Duration // How long to collect replies, 2s when zero
+	Match   ModelMatcher  // Models to report, MatchKnownModels when nil
+}
+
+// Name returns "mdns"
+func (p *MDNSProvider) Name() string { return "mdns" }
+
+// Discover queries for the service and confirms every host that answers
+func (p *MDNSProvider) Discover(ctx context.Context, events *DiscoveryEvents) ([]DiscoveredDevice, error) {
+	service := p.Service
+	if service == "" {
+		service = mdnsService
+	}
+	if !strings.HasSuffix(service, ".") {
+		service += "."
+	}
+	addr := p.Addr
+	if addr == "" {
+		addr = mdnsAddr
+	}
+	port := p.Port
+	if port == "" {
+		port = defaultPort
+	}
+
+	query, err := mdnsQuery(service)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mDNS address %q: %w", addr, err)
+	}
+
+	// Answers to a query from an ephemeral port come back unicast to it
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mDNS socket: %w", err)
+	}
+	defer conn.Close()
+
+	log.WithFields(log.Fields{
+		"service": service,
+		"addr":    addr,
+	}).Debug("Sending mDNS query")
+	if _, err := conn.WriteToUDP(query, dst); err != nil {
+		return nil, fmt.Errorf("failed to send mDNS query: %w", err)
+	}
+
+	events.Progress(DiscoveryProgress{Provider: p.Name()})
+
+	v := &candidateVerifier{ctx: ctx, port: port, method: p.Name(), match: p.Match, events: events}
+	readReplies(ctx, conn, p.Wait, func(pkt []byte, from netip.Addr) []string {
+		return parseMDNSReply(pkt, service, from)
+	}, v.add)
+	return v.wait(), nil
+}
+
+// mdnsQuery builds a PTR query for service asking for unicast replies
+func mdnsQuery(service string) ([]byte, error) {
+	name, err := dnsmessage.NewName(service)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mDNS service %q: %w", service, err)
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	// The top bit of the class requests a unicast reply (RFC 6762 5.4)
+	if err := b.Question(dnsmessage.Question{
+		Name:  name,
+		Type:  dnsmessage.TypePTR,
+		Class: dnsmessage.ClassINET | 1<<15,
+	}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// parseMDNSReply returns the addresses of the host announcing service in
+// pkt, falling back to the sender when the reply carries no address records
+func parseMDNSReply(pkt []byte, service string, from netip.Addr) []string {
+	var m dnsmessage.Message
+	if err := m.Unpack(pkt); err != nil || !m.Header.Response {
+		return nil
+	}
+
+	matched := false
+	var addrs []string
+	for _, rr := range append(m.Answers, m.Additionals...) {
+		switch body := rr.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if strings.EqualFold(rr.Header.Name.String(), service) {
+				matched = true
+			}
+		case *dnsmessage.AResource:
+			addrs = append(addrs, netip.AddrFrom4(body.A).String())
+		case *dnsmessage.AAAAResource:
+			addrs = append(addrs, netip.AddrFrom16(body.AAAA).String())
+		}
+	}
+	if !matched {
+		return nil
+	}
+	if len(addrs) == 0 {
+		addrs = []string{from.Unmap().String()}
+	}
+	log.WithFields(log.Fields{
+		"from":      from.String(),
+		"addresses": addrs,
+	}).Debug("Received mDNS announcement")
+	return addrs
+}
+
+// readReplies passes every new host parse extracts from the datagrams that
+// arrive on conn to found, until wait has passed or ctx is done
+func readReplies(ctx context.Context, conn *net.UDPConn, wait time.Duration, parse func([]byte, netip.Addr) []string, found func(string)) {
+	conn.SetReadDeadline(collectWait(ctx, wait))
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetReadDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	seen := make(map[string]bool)
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			return
+		}
+		for _, h := range parse(buf[:n], from.Addr()) {
+			if !seen[h] {
+				seen[h] = true
+				found(h)
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package nadapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Feature is an optional capability that only some models have
+type Feature string
+
+const (
+	// FeatureBluetooth - Bluetooth audio input
+	FeatureBluetooth Feature = "bluetooth"
+	// FeatureBluOS - built-in BluOS streaming
+	FeatureBluOS Feature = "bluos"
+	// FeatureToneControls - bass, treble and balance adjustment
+	FeatureToneControls Feature = "tone_controls"
+	// FeatureZone2 - independently controlled second zone
+	FeatureZone2 Feature = "zone2"
+)
+
+// Capabilities describes what a NAD model supports
+type Capabilities struct {
+	Model         string    `json:"model"`
+	Sources       []string  `json:"sources"`
+	MinVolume     float64   `json:"min_volume_db"`
+	MaxVolume     float64   `json:"max_volume_db"`
+	MaxBrightness int       `json:"max_brightness"`
+	Features      []Feature `json:"features"`
+}
+
+// profiles is the registry of known models. Model is matched against the
+// Main.Model response, see LookupCapabilities.
+var profiles = []Capabilities{
+	{
+		Model:         "C338",
+		Sources:       []string{"Stream", "Wireless", "TV", "Phono", "Coax1", "Coax2", "Opt1", "Opt2"},
+		MinVolume:     -80,
+		MaxVolume:     10,
+		MaxBrightness: 3,
+		Features:      []Feature{FeatureBluetooth},
+	},
+	{
+		Model:         "C368",
+		Sources:       []string{"Coax1", "Coax2", "Opt1", "Opt2", "Line1", "Line2", "Phono", "Bluetooth"},
+		MinVolume:     -90,
+		MaxVolume:     12,
+		MaxBrightness: 3,
+		Features:      []Feature{FeatureBluetooth, FeatureToneControls},
+	},
+	{
+		Model:         "C658",
+		Sources:       []string{"Stream", "Coax1", "Coax2", "Opt1", "Opt2", "Line1", "Line2", "Phono", "Bluetooth"},
+		MinVolume:     -90,
+		MaxVolume:     12,
+		MaxBrightness: 3,
+		Features:      []Feature{FeatureBluetooth, FeatureBluOS, FeatureToneControls},
+	},
+	{
+		Model:         "T 758",
+		Sources:       []string{"HDMI1", "HDMI2", "HDMI3", "HDMI4", "HDMI5", "Coax1", "Opt1", "Opt2", "Analog1", "BluOS"},
+		MinVolume:     -99,
+		MaxVolume:     12,
+		MaxBrightness: 3,
+		Features:      []Feature{FeatureBluOS, FeatureToneControls, FeatureZone2},
+	},
+}
+
+// defaultCapabilities applies to models missing from the registry
+var defaultCapabilities = Capabilities{
+	Sources:       sources,
+	MinVolume:     -80,
+	MaxVolume:     10,
+	MaxBrightness: maxBrightness,
+}
+
+// KnownModels returns the models in the capability registry
+func KnownModels() []string {
+	models := make([]string, len(profiles))
+	for i, p := range profiles {
+		models[i] = p.Model
+	}
+	return models
+}
+
+// normalizeModel reduces a model string to a comparable form, so that
+// "NAD T 758 V3i", "T758" and "t 758" all compare equal on their prefix
+func normalizeModel(model string) string {
+	m := strings.ToUpper(model)
+	m = strings.ReplaceAll(m, " ", "")
+	return strings.TrimPrefix(m, "NAD")
+}
+
+// LookupCapabilities finds the profile for a Main.Model response. Unknown
+// models get the default profile and ok is false.
+func LookupCapabilities(model string) (caps Capabilities, ok bool) {
+	m := normalizeModel(model)
+	if m != "" {
+		for _, p := range profiles {
+			if strings.HasPrefix(m, normalizeModel(p.Model)) {
+				return p, true
+			}
+		}
+	}
+
+	caps = defaultCapabilities
+	caps.Model = model
+	return caps, false
+}
+
+// HasFeature reports whether the model supports f
+func (c Capabilities) HasFeature(f Feature) bool {
+	for _, have := range c.Features {
+		if have == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Source returns the source name as the device spells it, matching name
+// case-insensitively. ok is false if the model has no such source.
+func (c Capabilities) Source(name string) (source string, ok bool) {
+	for _, s := range c.Sources {
+		if strings.EqualFold(s, name) {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// IsValidSource checks if the model has the given source
+func (c Capabilities) IsValidSource(name string) bool {
+	_, ok := c.Source(name)
+	return ok
+}
+
+// BrightnessLevels returns the display brightness levels of the model
+func (c Capabilities) BrightnessLevels() []int {
+	levels := make([]int, c.MaxBrightness+1)
+	for i := range levels {
+		levels[i] = i
+	}
+	return levels
+}
+
+// IsValidBrightnessLevel checks if level is a brightness level of the model
+func (c Capabilities) IsValidBrightnessLevel(level int) bool {
+	return level >= 0 && level <= c.MaxBrightness
+}
+
+// Capabilities returns the capability profile of the connected model
+func (d *Device) Capabilities() (Capabilities, error) {
+	return d.CapabilitiesContext(context.Background())
+}
+
+// CapabilitiesContext is like Capabilities but takes a context. The model is
+// queried once per Device and the profile cached.
+func (d *Device) CapabilitiesContext(ctx context.Context) (Capabilities, error) {
+	d.capsMu.Lock()
+	defer d.capsMu.Unlock()
+
+	if d.caps != nil {
+		return *d.caps, nil
+	}
+
+	model, err := d.GetModelContext(ctx)
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	caps, ok := LookupCapabilities(model)
+	log.WithFields(log.Fields{
+		"device": d.String(),
+		"model":  model,
+		"known":  ok,
+	}).Debug("Resolved device capabilities")
+
+	d.caps = &caps
+	return caps, nil
+}
+
+// requireFeature returns an error if the connected model is known to lack f.
+// Models missing from the registry are given the benefit of the doubt.
+func (d *Device) requireFeature(ctx context.Context, f Feature) error {
+	caps, err := d.CapabilitiesContext(ctx)
+	if err != nil {
+		return err
+	}
+	if caps.HasFeature(f) {
+		return nil
+	}
+	if _, known := LookupCapabilities(caps.Model); !known {
+		return nil
+	}
+	return withKind(ErrUnsupported, fmt.Errorf("model %s does not support %s", caps.Model, f))
+}
@@ -0,0 +1,110 @@
+package nadapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+func TestLookupCapabilities(t *testing.T) {
+	tests := []struct {
+		model     string
+		wantModel string
+		wantOK    bool
+	}{
+		{"C338", "C338", true},
+		{"NAD C368", "C368", true},
+		{"c658", "C658", true},
+		{"NAD T 758 V3i", "T 758", true},
+		{"T758", "T 758", true},
+		{"M10", "M10", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		caps, ok := LookupCapabilities(tt.model)
+		if ok != tt.wantOK {
+			t.Errorf("LookupCapabilities(%q) ok = %v, want %v", tt.model, ok, tt.wantOK)
+		}
+		if caps.Model != tt.wantModel {
+			t.Errorf("LookupCapabilities(%q) model = %q, want %q", tt.model, caps.Model, tt.wantModel)
+		}
+		if len(caps.Sources) == 0 {
+			t.Errorf("LookupCapabilities(%q) has no sources", tt.model)
+		}
+	}
+}
+
+func TestUnknownModelUsesDefaults(t *testing.T) {
+	caps, _ := LookupCapabilities("Mystery Amp")
+	if !reflect.DeepEqual(caps.Sources, GetAvailableSources()) {
+		t.Errorf("unknown model sources = %v, want defaults %v", caps.Sources, GetAvailableSources())
+	}
+	if caps.MinVolume != -80 || caps.MaxVolume != 10 {
+		t.Errorf("unknown model volume range = %v..%v, want -80..10", caps.MinVolume, caps.MaxVolume)
+	}
+	if caps.MaxBrightness != maxBrightness {
+		t.Errorf("unknown model max brightness = %d, want %d", caps.MaxBrightness, maxBrightness)
+	}
+}
+
+func TestCapabilitiesSource(t *testing.T) {
+	caps, _ := LookupCapabilities("C368")
+
+	if got, ok := caps.Source("line1"); !ok || got != "Line1" {
+		t.Errorf("Source(line1) = %q, %v; want Line1, true", got, ok)
+	}
+	if caps.IsValidSource("Stream") {
+		t.Error("C368 should not have a Stream source")
+	}
+	if !IsValidSource("line1") {
+		t.Error("IsValidSource(line1) should accept sources of any known model")
+	}
+}
+
+func TestCapabilitiesFeatures(t *testing.T) {
+	t758, _ := LookupCapabilities("T 758")
+	if !t758.HasFeature(FeatureZone2) {
+		t.Error("T 758 should support zone 2")
+	}
+	c338, _ := LookupCapabilities("C338")
+	if c338.HasFeature(FeatureZone2) {
+		t.Error("C338 should not support zone 2")
+	}
+}
+
+func TestCapabilitiesBrightnessLevels(t *testing.T) {
+	caps := Capabilities{MaxBrightness: 2}
+	if got := caps.BrightnessLevels(); !reflect.DeepEqual(got, []int{0, 1, 2}) {
+		t.Errorf("BrightnessLevels() = %v, want [0 1 2]", got)
+	}
+	if caps.IsValidBrightnessLevel(3) {
+		t.Error("IsValidBrightnessLevel(3) should be false for max brightness 2")
+	}
+}
+
+func TestKnownModels(t *testing.T) {
+	got := strings.Join(KnownModels(), ",")
+	if got != "C338,C368,C658,T 758" {
+		t.Errorf("KnownModels() = %s", got)
+	}
+}
+
+func TestSetSourceRejectsSourceOfOtherModel(t *testing.T) {
+	d, _ := connectAmp(t, fakeamp.Values(map[string]string{"Main.Model": "C368"}))
+
+	err := d.SetSource("Stream")
+	if err == nil || !strings.Contains(err.Error(), "invalid source") {
+		t.Fatalf("SetSource(Stream) error = %v, want invalid source", err)
+	}
+
+	caps, err := d.Capabilities()
+	if err != nil {
+		t.Fatalf("Capabilities() unexpected error: %v", err)
+	}
+	if caps.Model != "C368" {
+		t.Errorf("Capabilities() model = %q, want C368", caps.Model)
+	}
+}
@@ -0,0 +1,138 @@
+package nadapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/galamiram/nadctl/nadapi/protocol"
+)
+
+// DefaultCommandInterval is the command interval NewFromSettings uses when
+// the settings set none. Keys held down in the TUI repeat faster than an
+// amplifier takes commands.
+const DefaultCommandInterval = 50 * time.Millisecond
+
+// pacer lets the commands of a device through one at a time, at least
+// interval apart. A set command waiting for its turn is superseded by a
+// newer set of the same key, so a burst of changes sends only the last.
+type pacer struct {
+	mu       sync.Mutex
+	turn     chan struct{}            // Holds a token while a command is under way
+	interval time.Duration            // Least time between a reply and the next command
+	last     time.Time                // When the last command ended
+	waiting  map[string]chan struct{} // Lower-case keys of the set commands waiting, closed when superseded
+}
+
+// SetCommandInterval makes the device wait at least interval after each
+// command before sending the next, for amplifiers that drop commands sent
+// in quick succession. 0, the default of New, sends commands as soon as the
+// previous one is answered.
+//
+// Whatever the interval, a set command still waiting for its turn, e.g.
+// behind a slow reply, is replaced by a newer set of the same key and fails
+// with ErrSuperseded.
+func (d *Device) SetCommandInterval(interval time.Duration) {
+	d.pacer.mu.Lock()
+	defer d.pacer.mu.Unlock()
+	d.pacer.interval = max(interval, 0)
+}
+
+// CommandInterval returns the interval set by SetCommandInterval
+func (d *Device) CommandInterval() time.Duration {
+	d.pacer.mu.Lock()
+	defer d.pacer.mu.Unlock()
+	return d.pacer.interval
+}
+
+// wait blocks until msgs may be sent and returns the function ending their
+// turn. A lone set command returns ErrSuperseded if a newer set of its key
+// comes in meanwhile.
+func (p *pacer) wait(ctx context.Context, msgs []protocol.Message) (func(), error) {
+	p.mu.Lock()
+	if p.turn == nil {
+		p.turn = make(chan struct{}, 1)
+	}
+	turn := p.turn
+
+	// Only a lone set can be replaced; a batch of queries waits its turn
+	var key string
+	var superseded chan struct{}
+	if len(msgs) == 1 && msgs[0].Op == protocol.OpSet {
+		key = strings.ToLower(msgs[0].Key)
+		if older, ok := p.waiting[key]; ok {
+			close(older)
+		}
+		if p.waiting == nil {
+			p.waiting = make(map[string]chan struct{})
+		}
+		superseded = make(chan struct{})
+		p.waiting[key] = superseded
+	}
+	p.mu.Unlock()
+
+	// stopWaiting takes the command out of the waiting ones, reporting
+	// whether it was superseded first
+	stopWaiting := func() bool {
+		if superseded == nil {
+			return false
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		select {
+		case <-superseded:
+			return true
+		default:
+		}
+		delete(p.waiting, key)
+		return false
+	}
+
+	select {
+	case turn <- struct{}{}:
+	case <-superseded:
+		return nil, fmt.Errorf("%s: %w", msgs[0], ErrSuperseded)
+	case <-ctx.Done():
+		if stopWaiting() {
+			return nil, fmt.Errorf("%s: %w", msgs[0], ErrSuperseded)
+		}
+		return nil, fmt.Errorf("command cancelled: %w", ctx.Err())
+	}
+	end := func() {
+		p.mu.Lock()
+		p.last = time.Now()
+		p.mu.Unlock()
+		<-turn
+	}
+
+	p.mu.Lock()
+	pause := time.Until(p.last.Add(p.interval))
+	p.mu.Unlock()
+	if pause > 0 {
+		log.WithFields(log.Fields{
+			"command": msgs[0].String(),
+			"pause":   pause,
+		}).Debug("Pacing command")
+		timer := time.NewTimer(pause)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-superseded:
+		case <-ctx.Done():
+		}
+	}
+
+	if stopWaiting() {
+		<-turn // Nothing was sent, so the pause still holds for the next
+		return nil, fmt.Errorf("%s: %w", msgs[0], ErrSuperseded)
+	}
+	if err := ctx.Err(); err != nil {
+		<-turn
+		return nil, fmt.Errorf("command cancelled: %w", err)
+	}
+	return end, nil
+}
@@ -0,0 +1,61 @@
+package nadapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCommandInterval(t *testing.T) {
+	d, _ := newVolumeDevice(t, -40)
+	d.SetCommandInterval(100 * time.Millisecond)
+
+	start := time.Now()
+	for range 3 {
+		if _, err := d.GetVolume(); err != nil {
+			t.Fatalf("GetVolume() unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("3 commands took %v, want at least 2 intervals of 100ms", elapsed)
+	}
+}
+
+func TestSetCommandsCoalesced(t *testing.T) {
+	d, amp := newVolumeDevice(t, -40)
+	if _, err := d.CapabilitiesContext(context.Background()); err != nil {
+		t.Fatalf("Capabilities() unexpected error: %v", err)
+	}
+	d.SetCommandInterval(200 * time.Millisecond)
+	if _, err := d.GetVolume(); err != nil {
+		t.Fatalf("GetVolume() unexpected error: %v", err)
+	}
+
+	// Each set comes in while the one before waits for the interval to pass
+	volumes := []float64{-30, -25, -20}
+	errs := make([]error, len(volumes))
+	var wg sync.WaitGroup
+	for i, v := range volumes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = d.SetVolume(v)
+		}()
+		time.Sleep(30 * time.Millisecond)
+	}
+	wg.Wait()
+
+	for i, err := range errs[:len(errs)-1] {
+		if !errors.Is(err, ErrSuperseded) {
+			t.Errorf("SetVolume(%v) error = %v, want ErrSuperseded", volumes[i], err)
+		}
+	}
+	if err := errs[len(errs)-1]; err != nil {
+		t.Errorf("SetVolume(-20) unexpected error: %v", err)
+	}
+	if got := ampVolume(amp); got != -20 {
+		t.Errorf("device volume = %v, want -20", got)
+	}
+}
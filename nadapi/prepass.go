@@ -0,0 +1,233 @@
+package nadapi
+
+import (
+	"bufio"
+	"context"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultNeighborTable is the IPv4 neighbor (ARP) table of Linux
+	defaultNeighborTable = "/proc/net/arp"
+	// prepassTimeout bounds the pre-pass; addresses that take longer to
+	// answer are left to the full discovery
+	prepassTimeout = 2 * time.Second
+)
+
+// Neighbor is an entry of the kernel neighbor table
+type Neighbor struct {
+	IP        string
+	MAC       string // Lower case, colon separated
+	Interface string
+}
+
+// ReadNeighborTable parses a neighbor table in the format of /proc/net/arp,
+// skipping incomplete entries
+func ReadNeighborTable(path string) ([]Neighbor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var neighbors []Neighbor
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Header line
+	for scanner.Scan() {
+		// IP address, HW type, Flags, HW address, Mask, Device
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		if fields[2] == "0x0" || fields[3] == "00:00:00:00:00:00" {
+			continue
+		}
+		neighbors = append(neighbors, Neighbor{
+			IP:        fields[0],
+			MAC:       strings.ToLower(fields[3]),
+			Interface: fields[5],
+		})
+	}
+	return neighbors, scanner.Err()
+}
+
+// NeighborProvider finds devices among the hosts in the kernel neighbor
+// table, i.e. the hosts this machine talked to recently. It only probes a
+// handful of addresses, so it answers in milliseconds.
+type NeighborProvider struct {
+	Table      string       // Neighbor table file, /proc/net/arp when empty
+	OUIs       []string     // Only probe MAC addresses with these prefixes, e.g. "00:11:22"
+	Interfaces []string     // Only probe neighbors on these interfaces, all when empty
+	Include    []AddrRange  // Only probe addresses in these ranges, all when empty
+	Exclude    []AddrRange  // Never probe addresses in these ranges
+	Port       string       // Control port, 30001 when empty
+	Match      ModelMatcher // Models to report, MatchKnownModels when nil
+}
+
+// Name returns "neighbors"
+func (p *NeighborProvider) Name() string { return "neighbors" }
+
+// Discover probes the neighbors that pass the filters
+func (p *NeighborProvider) Discover(ctx context.Context, events *DiscoveryEvents) ([]DiscoveredDevice, error) {
+	table := p.Table
+	if table == "" {
+		table = neighborTable
+	}
+	port := p.Port
+	if port == "" {
+		port = defaultPort
+	}
+
+	neighbors, err := ReadNeighborTable(table)
+	if err != nil {
+		// Not every platform has one
+		log.WithError(err).WithField("table", table).Debug("Failed to read neighbor table")
+		return nil, nil
+	}
+
+	v := &candidateVerifier{ctx: ctx, port: port, method: p.Name(), match: p.Match, events: events}
+	probed := 0
+	for _, n := range neighbors {
+		if !p.wanted(n) {
+			continue
+		}
+		probed++
+		v.add(n.IP)
+	}
+	log.WithFields(log.Fields{
+		"table":     table,
+		"neighbors": len(neighbors),
+		"probed":    probed,
+	}).Debug("Probing neighbor table entries")
+	return v.wait(), nil
+}
+
+// wanted reports whether neighbor n passes the filters
+func (p *NeighborProvider) wanted(n Neighbor) bool {
+	if len(p.Interfaces) > 0 && !slices.Contains(p.Interfaces, n.Interface) {
+		return false
+	}
+	if len(p.OUIs) > 0 && !slices.ContainsFunc(p.OUIs, func(oui string) bool {
+		return strings.HasPrefix(n.MAC, normalizeOUI(oui))
+	}) {
+		return false
+	}
+	ip, err := netip.ParseAddr(n.IP)
+	if err != nil {
+		return false
+	}
+	return inRanges(ip, p.Include, p.Exclude)
+}
+
+// normalizeOUI returns a MAC prefix such as 00-11-22 or 001122 in the lower
+// case, colon separated form of the neighbor table
+func normalizeOUI(oui string) string {
+	hex := strings.ToLower(strings.NewReplacer(":", "", "-", "", ".", "").Replace(oui))
+	var b strings.Builder
+	for i := 0; i < len(hex); i += 2 {
+		if i > 0 {
+			b.WriteByte(':')
+		}
+		b.WriteString(hex[i:min(i+2, len(hex))])
+	}
+	return b.String()
+}
+
+// inRanges reports whether ip is in one of include, or include is empty,
+// and in none of exclude
+func inRanges(ip netip.Addr, include, exclude []AddrRange) bool {
+	for _, r := range exclude {
+		if r.Contains(ip) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, r := range include {
+		if r.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// HistoryProvider finds devices at the addresses where devices were found
+// before
+type HistoryProvider struct {
+	Devices []DiscoveredDevice // Devices found before, the discovery history when nil
+	Include []AddrRange        // Only probe addresses in these ranges, all when empty
+	Exclude []AddrRange        // Never probe addresses in these ranges
+	Match   ModelMatcher       // Models to report, MatchKnownModels when nil
+}
+
+// Name returns "history"
+func (p *HistoryProvider) Name() string { return "history" }
+
+// Discover probes every device of the history on the port it was found on
+func (p *HistoryProvider) Discover(ctx context.Context, events *DiscoveryEvents) ([]DiscoveredDevice, error) {
+	history := p.Devices
+	if history == nil {
+		history = loadDeviceHistory()
+	}
+	log.WithField("devices", len(history)).Debug("Probing previously discovered devices")
+
+	v := &candidateVerifier{ctx: ctx, method: p.Name(), match: p.Match, events: events}
+	for _, dev := range history {
+		if ip, err := netip.ParseAddr(dev.IP); err == nil && !inRanges(ip, p.Include, p.Exclude) {
+			continue
+		}
+		port := dev.Port
+		if port == "" {
+			port = defaultPort
+		}
+		v.probe(dev.IP, port)
+	}
+	return v.wait(), nil
+}
+
+// NewPrepass returns a discoverer that only probes the neighbor table and
+// the history of previously discovered devices. It is much faster than a
+// full discovery but only finds devices this machine has seen before.
+func NewPrepass(opts DiscoveryOptions) *Discoverer {
+	return &Discoverer{
+		Providers: []DiscoveryProvider{
+			&NeighborProvider{
+				Table:      opts.NeighborTable,
+				OUIs:       opts.OUIs,
+				Interfaces: opts.Interfaces,
+				Include:    opts.Include,
+				Exclude:    opts.Exclude,
+				Port:       opts.Port,
+				Match:      opts.Match,
+			},
+			&HistoryProvider{Include: opts.Include, Exclude: opts.Exclude, Match: opts.Match},
+		},
+		MaxDevices: opts.MaxDevices,
+		Events:     opts.Events,
+	}
+}
+
+// discoverPrepass runs the pre-pass for opts, returning quickly
+func discoverPrepass(ctx context.Context, opts DiscoveryOptions) []DiscoveredDevice {
+	ctx, cancel := context.WithTimeout(ctx, prepassTimeout)
+	defer cancel()
+
+	start := time.Now()
+	devices, err := NewPrepass(opts).Discover(ctx)
+	if err != nil {
+		log.WithError(err).Debug("Discovery pre-pass failed")
+		return nil
+	}
+	log.WithFields(log.Fields{
+		"devicesFound": len(devices),
+		"elapsed":      time.Since(start),
+	}).Debug("Discovery pre-pass completed")
+	return devices
+}
@@ -0,0 +1,173 @@
+package nadapi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+// writeNeighborTable writes a fake /proc/net/arp with the given entries
+func writeNeighborTable(t *testing.T, entries ...string) string {
+	t.Helper()
+	table := "IP address       HW type     Flags       HW address            Mask     Device\n"
+	for _, e := range entries {
+		table += e + "\n"
+	}
+	path := filepath.Join(t.TempDir(), "arp")
+	if err := os.WriteFile(path, []byte(table), 0644); err != nil {
+		t.Fatalf("failed to write neighbor table: %v", err)
+	}
+	return path
+}
+
+// useTempCache points the cache at a file in a temporary directory
+func useTempCache(t *testing.T) {
+	t.Helper()
+	tempDir := t.TempDir()
+	original := getCacheFilePathFunc
+	getCacheFilePathFunc = func() (string, error) {
+		return filepath.Join(tempDir, ".nadctl_cache.json"), nil
+	}
+	t.Cleanup(func() { getCacheFilePathFunc = original })
+}
+
+func TestReadNeighborTable(t *testing.T) {
+	path := writeNeighborTable(t,
+		"192.168.1.1      0x1         0x2         AA:BB:CC:00:00:01     *        eth0",
+		"192.168.1.7      0x1         0x0         00:00:00:00:00:00     *        eth0",
+		"10.0.0.9         0x1         0x2         aa:bb:cc:00:00:09     *        wlan0",
+	)
+
+	neighbors, err := ReadNeighborTable(path)
+	if err != nil {
+		t.Fatalf("ReadNeighborTable() unexpected error: %v", err)
+	}
+	want := []Neighbor{
+		{IP: "192.168.1.1", MAC: "aa:bb:cc:00:00:01", Interface: "eth0"},
+		{IP: "10.0.0.9", MAC: "aa:bb:cc:00:00:09", Interface: "wlan0"},
+	}
+	if len(neighbors) != len(want) {
+		t.Fatalf("ReadNeighborTable() = %+v, want %+v", neighbors, want)
+	}
+	for i := range want {
+		if neighbors[i] != want[i] {
+			t.Errorf("ReadNeighborTable()[%d] = %+v, want %+v", i, neighbors[i], want[i])
+		}
+	}
+}
+
+func TestNormalizeOUI(t *testing.T) {
+	for _, oui := range []string{"00:1A:2B", "00-1a-2b", "001A2B", "001a.2b"} {
+		if got := normalizeOUI(oui); got != "00:1a:2b" {
+			t.Errorf("normalizeOUI(%q) = %q, want %q", oui, got, "00:1a:2b")
+		}
+	}
+}
+
+func TestNeighborProviderFilters(t *testing.T) {
+	port := sweepAmp(t).Port
+	table := writeNeighborTable(t,
+		"127.0.0.1        0x1         0x2         00:1a:2b:00:00:01     *        eth0",
+		"127.0.0.2        0x1         0x2         66:77:88:00:00:02     *        eth0",
+		"127.0.0.3        0x1         0x2         00:1a:2b:00:00:03     *        wlan0",
+		"127.0.0.4        0x1         0x2         00:1a:2b:00:00:04     *        eth0",
+	)
+	exclude, _ := ParseAddrRanges([]string{"127.0.0.4"})
+
+	p := &NeighborProvider{
+		Table:      table,
+		OUIs:       []string{"00-1A-2B"},
+		Interfaces: []string{"eth0"},
+		Exclude:    exclude,
+		Port:       port,
+	}
+	devices, err := p.Discover(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Discover() unexpected error: %v", err)
+	}
+	if len(devices) != 1 || devices[0].IP != "127.0.0.1" {
+		t.Errorf("Discover() = %+v, want only the device at 127.0.0.1", devices)
+	}
+}
+
+func TestNeighborProviderMissingTable(t *testing.T) {
+	p := &NeighborProvider{Table: filepath.Join(t.TempDir(), "missing")}
+	devices, err := p.Discover(context.Background(), nil)
+	if err != nil || len(devices) != 0 {
+		t.Errorf("Discover() = %v, %v, want no devices and no error", devices, err)
+	}
+}
+
+func TestHistoryProvider(t *testing.T) {
+	amp := fakeamp.Start(t, fakeamp.Values(map[string]string{"Main.Model": "C338"}))
+	ip, port := amp.Host, amp.Port
+	p := &HistoryProvider{Devices: []DiscoveredDevice{
+		{IP: ip, Port: port, Model: "C338"},
+		{IP: "192.0.2.1", Port: port, Model: "C338"}, // Excluded below
+	}}
+	p.Exclude, _ = ParseAddrRanges([]string{"192.0.2.0/24"})
+
+	devices, err := p.Discover(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Discover() unexpected error: %v", err)
+	}
+	if len(devices) != 1 || devices[0].IP != ip || devices[0].Port != port {
+		t.Errorf("Discover() = %+v, want the device at %s:%s", devices, ip, port)
+	}
+}
+
+func TestDiscoverDevicesWithCachePrepass(t *testing.T) {
+	useTempCache(t)
+	port := sweepAmp(t).Port
+	table := writeNeighborTable(t,
+		"127.0.0.1        0x1         0x2         00:1a:2b:00:00:01     *        eth0",
+	)
+
+	start := time.Now()
+	devices, fromCache, err := DiscoverDevicesWithCacheOptions(10*time.Second, false, DefaultCacheTTL,
+		DiscoveryOptions{Port: port, NeighborTable: table})
+	if err != nil {
+		t.Fatalf("DiscoverDevicesWithCacheOptions() unexpected error: %v", err)
+	}
+	if fromCache {
+		t.Error("DiscoverDevicesWithCacheOptions() fromCache = true, want false")
+	}
+	if len(devices) != 1 || devices[0].IP != "127.0.0.1" {
+		t.Fatalf("DiscoverDevicesWithCacheOptions() = %+v, want the device at 127.0.0.1", devices)
+	}
+	// The announcement providers alone would take announceWait
+	if elapsed := time.Since(start); elapsed >= announceWait {
+		t.Errorf("pre-pass took %v, want it to skip the full discovery", elapsed)
+	}
+
+	history := loadDeviceHistory()
+	if len(history) != 1 || history[0].IP != "127.0.0.1" {
+		t.Errorf("loadDeviceHistory() = %+v, want the discovered device", history)
+	}
+	// The pre-pass may have missed devices, so it is no cached discovery
+	if cached, err := LoadCachedDevices(); err != nil || len(cached) != 0 {
+		t.Errorf("LoadCachedDevices() = %+v, %v, want no devices after a pre-pass", cached, err)
+	}
+}
+
+func TestSaveCachedDevicesKeepsHistory(t *testing.T) {
+	useTempCache(t)
+	first := DiscoveredDevice{IP: "192.168.1.100", Model: "C338", Port: "30001"}
+	second := DiscoveredDevice{IP: "192.168.1.101", Model: "M10", Port: "30001"}
+
+	if err := SaveCachedDevices([]DiscoveredDevice{first}, DefaultCacheTTL); err != nil {
+		t.Fatalf("SaveCachedDevices() error = %v", err)
+	}
+	if err := SaveCachedDevices([]DiscoveredDevice{second}, DefaultCacheTTL); err != nil {
+		t.Fatalf("SaveCachedDevices() error = %v", err)
+	}
+
+	history := loadDeviceHistory()
+	if len(history) != 2 || history[0] != second || history[1] != first {
+		t.Errorf("loadDeviceHistory() = %+v, want [%+v %+v]", history, second, first)
+	}
+}
@@ -0,0 +1,99 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// MaxLineLength bounds the lines the framers accept, so that a peer sending
+// garbage without line breaks cannot grow the buffer forever. A Scanner
+// fails on longer lines, a Framer drops them.
+const MaxLineLength = 4096
+
+// SplitLines is a bufio.SplitFunc for protocol lines. A CR, an LF or both end
+// a line; devices differ in which they send. The empty lines this yields
+// between the CR and the LF of a CRLF are left to the caller to skip, rather
+// than waiting for a byte that may never come after a final CR.
+func SplitLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Scanner reads the lines of a stream, skipping blank ones
+type Scanner struct {
+	s    *bufio.Scanner
+	line string
+}
+
+// NewScanner returns a scanner reading from r
+func NewScanner(r io.Reader) *Scanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 256), MaxLineLength)
+	s.Split(SplitLines)
+	return &Scanner{s: s}
+}
+
+// Scan advances to the next line, returning false at the end of the stream
+// or on an error
+func (s *Scanner) Scan() bool {
+	for s.s.Scan() {
+		if s.line = trimLine(s.s.Text()); s.line != "" {
+			return true
+		}
+	}
+	s.line = ""
+	return false
+}
+
+// Text returns the current line without its terminator
+func (s *Scanner) Text() string {
+	return s.line
+}
+
+// Err returns the error that stopped the scanner, nil at the end of the
+// stream
+func (s *Scanner) Err() error {
+	return s.s.Err()
+}
+
+// Framer splits the chunks of a stream read by the caller into lines, for
+// readers that cannot hand the stream to a Scanner, e.g. because they poll
+// with read deadlines. A line may span any number of chunks.
+type Framer struct {
+	buf []byte
+}
+
+// Feed adds a chunk of the stream and returns the lines it completes,
+// skipping blank ones
+func (f *Framer) Feed(chunk []byte) []string {
+	f.buf = append(f.buf, chunk...)
+	var lines []string
+	for {
+		advance, token, _ := SplitLines(f.buf, false)
+		if advance == 0 {
+			break
+		}
+		if line := trimLine(string(token)); line != "" {
+			lines = append(lines, line)
+		}
+		f.buf = f.buf[advance:]
+	}
+	if len(f.buf) > MaxLineLength {
+		f.buf = nil
+	}
+	return lines
+}
+
+// Flush returns the unterminated rest of the stream, if anything but
+// blanks, and discards it
+func (f *Framer) Flush() string {
+	line := trimLine(string(f.buf))
+	f.buf = nil
+	return line
+}
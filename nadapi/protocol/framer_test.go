@@ -0,0 +1,74 @@
+package protocol
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestScannerTerminators(t *testing.T) {
+	stream := "Main.Power=On\rMain.Volume=-20\nMain.Mute=Off\r\n\r\n\x00Main.Source=TV\r\rMain.Model=C338"
+	// One byte per read puts every split point between two reads
+	s := NewScanner(iotest.OneByteReader(strings.NewReader(stream)))
+	var lines []string
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	want := []string{"Main.Power=On", "Main.Volume=-20", "Main.Mute=Off", "Main.Source=TV", "Main.Model=C338"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
+
+func TestScannerDeliversLineOnCR(t *testing.T) {
+	// A reply ending in CR must not wait for an LF that never comes
+	r, w := io.Pipe()
+	defer w.Close()
+	s := NewScanner(r)
+	go w.Write([]byte("Main.Power=On\r"))
+	if !s.Scan() || s.Text() != "Main.Power=On" {
+		t.Fatalf("Scan() = %q, %v, want the line", s.Text(), s.Err())
+	}
+}
+
+func TestScannerLineTooLong(t *testing.T) {
+	s := NewScanner(strings.NewReader(strings.Repeat("x", MaxLineLength+1)))
+	if s.Scan() {
+		t.Fatalf("Scan() = %q, want failure", s.Text())
+	}
+	if !errors.Is(s.Err(), bufio.ErrTooLong) {
+		t.Errorf("Err() = %v, want bufio.ErrTooLong", s.Err())
+	}
+}
+
+func TestFramer(t *testing.T) {
+	var f Framer
+	if lines := f.Feed([]byte("Main.Vol")); len(lines) != 0 {
+		t.Errorf("Feed(partial) = %q, want no lines", lines)
+	}
+	lines := f.Feed([]byte("ume=-20\r\nMain.Power?\r"))
+	if want := []string{"Main.Volume=-20", "Main.Power?"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("Feed() = %q, want %q", lines, want)
+	}
+	if lines := f.Feed([]byte("\nMain.Mute+")); len(lines) != 0 {
+		t.Errorf("Feed(LF, partial) = %q, want no lines", lines)
+	}
+	if line := f.Flush(); line != "Main.Mute+" {
+		t.Errorf("Flush() = %q, want Main.Mute+", line)
+	}
+	if line := f.Flush(); line != "" {
+		t.Errorf("second Flush() = %q, want nothing", line)
+	}
+
+	f.Feed([]byte(strings.Repeat("x", MaxLineLength+1)))
+	if lines := f.Feed([]byte("\rMain.Power?\r")); !reflect.DeepEqual(lines, []string{"Main.Power?"}) {
+		t.Errorf("Feed() after an overlong line = %q, want the next line", lines)
+	}
+}
@@ -0,0 +1,130 @@
+// Package protocol implements the line protocol NAD devices speak on their
+// control port. Every command and reply is one line made of a key, an
+// operator and, for "=", a value, e.g. "Main.Volume=-20" or "Main.Power?".
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Operator is what a message does with its key
+type Operator byte
+
+const (
+	// OpQuery asks for the value of the key
+	OpQuery Operator = '?'
+	// OpSet sets the value of the key, or in a reply reports it
+	OpSet Operator = '='
+	// OpIncrease steps the key up, toggling it for on/off keys
+	OpIncrease Operator = '+'
+	// OpDecrease steps the key down, toggling it for on/off keys
+	OpDecrease Operator = '-'
+)
+
+// Terminator ends every line the encoder writes
+const Terminator = "\r"
+
+// ErrMalformed is returned for lines and messages that are not a key
+// followed by an operator
+var ErrMalformed = errors.New("malformed message")
+
+// Message is one command or reply
+type Message struct {
+	Key   string   // e.g. "Main.Volume"
+	Op    Operator // What the message does with Key
+	Value string   // Value of OpSet messages, empty for the others
+}
+
+// Query returns the message asking for the value of key
+func Query(key string) Message {
+	return Message{Key: key, Op: OpQuery}
+}
+
+// Set returns the message setting key to value
+func Set(key, value string) Message {
+	return Message{Key: key, Op: OpSet, Value: value}
+}
+
+// String returns the message as it goes on the wire, without the terminator
+func (m Message) String() string {
+	if m.Op == OpSet {
+		return m.Key + "=" + m.Value
+	}
+	return m.Key + string(m.Op)
+}
+
+// Validate reports whether the message can be encoded as one line
+func (m Message) Validate() error {
+	switch {
+	case m.Key == "" || strings.ContainsAny(m.Key, "=?\r\n\x00"):
+		return fmt.Errorf("%w: invalid key %q", ErrMalformed, m.Key)
+	case m.Op != OpQuery && m.Op != OpSet && m.Op != OpIncrease && m.Op != OpDecrease:
+		return fmt.Errorf("%w: invalid operator %q", ErrMalformed, m.Op)
+	case m.Op != OpSet && m.Value != "":
+		return fmt.Errorf("%w: %q takes no value", ErrMalformed, m.String())
+	case strings.ContainsAny(m.Value, "\r\n\x00"):
+		return fmt.Errorf("%w: line break in the value of %s", ErrMalformed, m.Key)
+	}
+	return nil
+}
+
+// Parse parses one line, with or without its terminator. Everything after
+// the first "=" is the value, so values may contain "=" themselves.
+func Parse(line string) (Message, error) {
+	line = trimLine(line)
+	if line == "" {
+		return Message{}, fmt.Errorf("%w: empty line", ErrMalformed)
+	}
+
+	var m Message
+	if i := strings.IndexAny(line, "=?"); i >= 0 {
+		m.Key = strings.TrimSpace(line[:i])
+		m.Op = Operator(line[i])
+		if m.Op == OpSet {
+			m.Value = strings.TrimSpace(line[i+1:])
+		} else if i != len(line)-1 {
+			return Message{}, fmt.Errorf("%w: text after the query in %q", ErrMalformed, line)
+		}
+	} else {
+		m.Key = strings.TrimSpace(line[:len(line)-1])
+		m.Op = Operator(line[len(line)-1])
+		if m.Op != OpIncrease && m.Op != OpDecrease {
+			return Message{}, fmt.Errorf("%w: no operator in %q", ErrMalformed, line)
+		}
+	}
+	if m.Key == "" {
+		return Message{}, fmt.Errorf("%w: no key in %q", ErrMalformed, line)
+	}
+	return m, nil
+}
+
+// Encode writes m to w as one terminated line, in a single write so that
+// concurrent writers cannot interleave
+func Encode(w io.Writer, m Message) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, m.String()+Terminator)
+	return err
+}
+
+// EncodeAll writes msgs to w as terminated lines, all in a single write
+func EncodeAll(w io.Writer, msgs ...Message) error {
+	var b strings.Builder
+	for _, m := range msgs {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		b.WriteString(m.String() + Terminator)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// trimLine strips the whitespace, line breaks and NUL padding around a line
+func trimLine(line string) string {
+	return strings.Trim(line, " \t\r\n\x00")
+}
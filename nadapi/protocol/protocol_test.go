@@ -0,0 +1,99 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want Message
+	}{
+		{"Main.Power?", Message{Key: "Main.Power", Op: OpQuery}},
+		{"Main.Volume=-20.5\r\n", Message{Key: "Main.Volume", Op: OpSet, Value: "-20.5"}},
+		{"\rMain.Mute=On\r", Message{Key: "Main.Mute", Op: OpSet, Value: "On"}},
+		{"Main.Volume+", Message{Key: "Main.Volume", Op: OpIncrease}},
+		{"Main.Volume-\n", Message{Key: "Main.Volume", Op: OpDecrease}},
+		{"Source1.Name=A=B", Message{Key: "Source1.Name", Op: OpSet, Value: "A=B"}},
+		{"Source2.Name=What?", Message{Key: "Source2.Name", Op: OpSet, Value: "What?"}},
+		{"Source3.Name=", Message{Key: "Source3.Name", Op: OpSet}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.line)
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", tt.line, got, err, tt.want)
+		}
+	}
+
+	for _, line := range []string{"", "\r\n", "Main.Power", "=On", "?", "Main.Power?On", "+"} {
+		if _, err := Parse(line); !errors.Is(err, ErrMalformed) {
+			t.Errorf("Parse(%q) error = %v, want ErrMalformed", line, err)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	var buf bytes.Buffer
+	for _, m := range []Message{
+		Query("Main.Power"),
+		Set("Source1.Name", "A=B"),
+		{Key: "Main.Volume", Op: OpDecrease},
+	} {
+		if err := Encode(&buf, m); err != nil {
+			t.Fatalf("Encode(%+v) error = %v", m, err)
+		}
+	}
+	if want := "Main.Power?\rSource1.Name=A=B\rMain.Volume-\r"; buf.String() != want {
+		t.Errorf("Encode() wrote %q, want %q", buf.String(), want)
+	}
+
+	for _, m := range []Message{
+		{Op: OpQuery},
+		{Key: "Main.Power", Op: 'x'},
+		{Key: "Main.Power", Op: OpQuery, Value: "On"},
+		Set("Source1.Name", "Two\rLines"),
+		Query("Main.Power=On\rMain.Power"),
+	} {
+		if err := Encode(&buf, m); !errors.Is(err, ErrMalformed) {
+			t.Errorf("Encode(%+v) error = %v, want ErrMalformed", m, err)
+		}
+	}
+}
+
+func TestEncodeAll(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, Query("Main.Power"), Query("Main.Volume")); err != nil {
+		t.Fatalf("EncodeAll() error = %v", err)
+	}
+	if want := "Main.Power?\rMain.Volume?\r"; buf.String() != want {
+		t.Errorf("EncodeAll() wrote %q, want %q", buf.String(), want)
+	}
+
+	// Nothing is written when a message is malformed
+	buf.Reset()
+	if err := EncodeAll(&buf, Query("Main.Power"), Message{Op: OpQuery}); !errors.Is(err, ErrMalformed) {
+		t.Errorf("EncodeAll() error = %v, want ErrMalformed", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("EncodeAll() of a malformed batch wrote %q", buf.String())
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, m := range []Message{
+		Query("Zone2.Volume"),
+		Set("Main.Volume", "-12.5"),
+		Set("Source4.Name", "Living = Room"),
+		{Key: "Main.Source", Op: OpIncrease},
+	} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, m); err != nil {
+			t.Fatalf("Encode(%+v) error = %v", m, err)
+		}
+		if got, err := Parse(buf.String()); err != nil || got != m {
+			t.Errorf("Parse(Encode(%+v)) = %+v, %v", m, got, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package nadapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/galamiram/nadctl/nadapi/protocol"
+)
+
+// maxQueryBatch bounds the queries written at once, keeping a batch within
+// the small input buffer of the device
+const maxQueryBatch = 8
+
+// Query reads several keys, e.g. "Main.Volume" and "Main.Mute", writing
+// the queries at once so that they cost a single round trip rather than
+// one each. The values are returned by key as given.
+func (d *Device) Query(keys ...string) (map[string]string, error) {
+	return d.QueryContext(context.Background(), keys...)
+}
+
+// QueryContext is like Query but takes a context
+func (d *Device) QueryContext(ctx context.Context, keys ...string) (map[string]string, error) {
+	found, err := d.query(ctx, keys, 0)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		values[key] = found[strings.ToLower(key)]
+	}
+	return values, nil
+}
+
+// query reads keys in batches of maxQueryBatch and returns their values by
+// lower-case key. A probeTimeout above zero bounds the wait for each batch,
+// for keys that some models leave unanswered: those are missing from the
+// values, and once a whole batch goes unanswered the rest are not asked.
+func (d *Device) query(ctx context.Context, keys []string, probeTimeout time.Duration) (map[string]string, error) {
+	log.WithFields(log.Fields{
+		"device": d.String(),
+		"keys":   keys,
+	}).Debug("Querying keys")
+
+	// Keys differing only in case are asked once, as the device answers once
+	var msgs []protocol.Message
+	asked := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		msg := protocol.Query(key)
+		if err := msg.Validate(); err != nil {
+			return nil, withKind(ErrInvalidValue, err)
+		}
+		if !asked[strings.ToLower(key)] {
+			asked[strings.ToLower(key)] = true
+			msgs = append(msgs, msg)
+		}
+	}
+
+	found := make(map[string]string, len(msgs))
+	for len(msgs) > 0 {
+		batch := msgs[:min(len(msgs), maxQueryBatch)]
+		msgs = msgs[len(batch):]
+		replies, err := d.exchange(ctx, batch, probeTimeout)
+		if err != nil {
+			return nil, err
+		}
+		answered := 0
+		for i, reply := range replies {
+			if reply == "" {
+				continue // Left unanswered by a probe
+			}
+			value, err := extractValue(reply)
+			if err != nil {
+				if probeTimeout > 0 {
+					continue
+				}
+				return nil, fmt.Errorf("get %s: %w", batch[i].Key, err)
+			}
+			found[strings.ToLower(batch[i].Key)] = value
+			answered++
+		}
+		if answered == 0 && len(msgs) > 0 {
+			log.WithFields(log.Fields{
+				"device":  d.String(),
+				"skipped": len(msgs),
+			}).Debug("Device answered none of the probed keys, not asking the rest")
+			break
+		}
+	}
+	return found, nil
+}
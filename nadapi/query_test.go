@@ -0,0 +1,69 @@
+package nadapi
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+func TestQuery(t *testing.T) {
+	replies := map[string]string{
+		"Main.Power":  "On",
+		"Main.Volume": "-40.0",
+		"Main.Mute":   "Off",
+	}
+	for i := 1; i <= 10; i++ {
+		replies[fmt.Sprintf("Source%d.Name", i)] = fmt.Sprintf("Input %d", i)
+	}
+	d, _ := connectAmp(t, fakeamp.Values(replies))
+
+	got, err := d.Query("Main.Power", "Main.Volume", "main.volume", "Main.Mute")
+	if err != nil {
+		t.Fatalf("Query() unexpected error: %v", err)
+	}
+	want := map[string]string{"Main.Power": "On", "Main.Volume": "-40.0", "main.volume": "-40.0", "Main.Mute": "Off"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Query() = %v, want %v", got, want)
+	}
+
+	// More keys than fit a batch take several round trips
+	var keys []string
+	for i := 1; i <= 10; i++ {
+		keys = append(keys, fmt.Sprintf("Source%d.Name", i))
+	}
+	got, err = d.Query(keys...)
+	if err != nil {
+		t.Fatalf("Query() of %d keys unexpected error: %v", len(keys), err)
+	}
+	if got["Source10.Name"] != "Input 10" || len(got) != len(keys) {
+		t.Errorf("Query() of %d keys = %v", len(keys), got)
+	}
+
+	if _, err := d.Query("Main.Power", "Main.Power=On"); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Query() of a malformed key error = %v, want ErrInvalidValue", err)
+	}
+}
+
+// TestQueryPipelined checks the queries are all written before the first
+// reply is awaited, with a device that answers only once it has read them
+// all, in reverse order
+func TestQueryPipelined(t *testing.T) {
+	keys := []string{"Main.Power", "Main.Volume", "Main.Source", "Main.Mute"}
+	values := make(map[string]string)
+	for _, key := range keys {
+		values[key] = "value of " + key
+	}
+	d, _ := connectAmp(t, fakeamp.Pipelined(len(keys)), fakeamp.Values(values))
+
+	got, err := d.Query(keys...)
+	if err != nil {
+		t.Fatalf("Query() unexpected error: %v", err)
+	}
+	for _, key := range keys {
+		if got[key] != "value of "+key {
+			t.Errorf("Query()[%s] = %q, want %q", key, got[key], "value of "+key)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package nadapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/galamiram/nadctl/nadapi/protocol"
+)
+
+// Raw sends one protocol line, e.g. "Main.Bass?" or "Main.Dimmer=On", and
+// returns the reply of the device. It reaches the keys the typed methods do
+// not cover; the device checks the line, so a key it does not know usually
+// goes unanswered and ends in ErrTimeout.
+func (d *Device) Raw(ctx context.Context, line string) (protocol.Message, error) {
+	msg, err := protocol.Parse(line)
+	if err != nil {
+		return protocol.Message{}, withKind(ErrInvalidValue, err)
+	}
+
+	if err := d.checkRaw(ctx, msg); err != nil {
+		return protocol.Message{}, err
+	}
+
+	log.WithFields(log.Fields{
+		"device": d.String(),
+		"line":   msg.String(),
+	}).Debug("Sending raw command")
+
+	res, err := d.send(ctx, msg.String())
+	if err != nil {
+		return protocol.Message{}, err
+	}
+	reply, err := protocol.Parse(res)
+	if err != nil {
+		return protocol.Message{}, fmt.Errorf("%s: %w", msg, err)
+	}
+
+	// Inputs renamed or toggled behind the back of the cached list
+	if msg.Op != protocol.OpQuery && strings.HasPrefix(strings.ToLower(msg.Key), "source") {
+		d.forgetInputs()
+	}
+	return reply, nil
+}
+
+// RawLines sends lines in order with Raw and returns the replies, stopping
+// at the first line that fails. The replies before the failure are
+// returned with the error.
+func (d *Device) RawLines(ctx context.Context, lines []string) ([]protocol.Message, error) {
+	replies := make([]protocol.Message, 0, len(lines))
+	for _, line := range lines {
+		reply, err := d.Raw(ctx, line)
+		if err != nil {
+			return replies, fmt.Errorf("%s: %w", strings.TrimSpace(line), err)
+		}
+		replies = append(replies, reply)
+	}
+	return replies, nil
+}
+
+// checkRaw holds the volume and source changes of a raw command to the
+// volume limits of the device
+func (d *Device) checkRaw(ctx context.Context, msg protocol.Message) error {
+	zone := zoneOfKey(msg.Key)
+	if zone == "" {
+		return nil
+	}
+	z := d.Zone(zone)
+	_, name, _ := strings.Cut(msg.Key, ".")
+	switch {
+	case strings.EqualFold(name, "Volume") && msg.Op == protocol.OpSet:
+		volume, err := strconv.ParseFloat(msg.Value, 64)
+		if err != nil {
+			return withKind(ErrInvalidValue, fmt.Errorf("invalid volume %q", msg.Value))
+		}
+		return z.checkVolume(ctx, math.NaN(), volume)
+	case strings.EqualFold(name, "Volume") && msg.Op == protocol.OpIncrease:
+		volume, err := z.GetVolumeFloatContext(ctx)
+		if err != nil {
+			return err
+		}
+		return z.checkVolume(ctx, volume, volume+1)
+	case strings.EqualFold(name, "Source") && msg.Op == protocol.OpSet:
+		inputs, err := d.InputsContext(ctx)
+		if err != nil {
+			return err
+		}
+		if in, ok := FindInput(inputs, msg.Value); ok {
+			return z.checkSource(ctx, in)
+		}
+	}
+	return nil
+}
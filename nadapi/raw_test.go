@@ -0,0 +1,35 @@
+package nadapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+	"github.com/galamiram/nadctl/nadapi/protocol"
+)
+
+func TestRaw(t *testing.T) {
+	d, _ := connectAmp(t, fakeamp.Unsolicited("Main.Power=On"), fakeamp.Values(map[string]string{
+		"Main.Bass":    "-2",
+		"Source1.Name": "A=B",
+	}))
+	ctx := context.Background()
+
+	reply, err := d.Raw(ctx, " Main.Bass? ")
+	if want := protocol.Set("Main.Bass", "-2"); err != nil || reply != want {
+		t.Errorf("Raw(Main.Bass?) = %+v, %v, want %+v", reply, err, want)
+	}
+
+	if _, err := d.Raw(ctx, "Main.Bass"); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Raw() of a line without operator error = %v, want ErrInvalidValue", err)
+	}
+
+	replies, err := d.RawLines(ctx, []string{"Source1.Name?", "Main.Power?", "bogus"})
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("RawLines() error = %v, want ErrInvalidValue for the last line", err)
+	}
+	if len(replies) != 2 || replies[0].Value != "A=B" || replies[1].Value != "On" {
+		t.Errorf("RawLines() replies = %+v, want the replies before the failure", replies)
+	}
+}
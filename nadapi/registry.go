@@ -0,0 +1,266 @@
+package nadapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrUnknownDevice is returned when a device reference matches no device of
+// the registry
+var ErrUnknownDevice = errors.New("unknown device")
+
+// KnownDevice is an entry of the registry of devices seen before. Unlike the
+// discovery cache it does not expire, and it follows the device when DHCP
+// moves it to another address.
+type KnownDevice struct {
+	ID        string    `json:"id"`              // MAC address, or the address while the MAC is unknown
+	Alias     string    `json:"alias,omitempty"` // Name given by the user, e.g. "living-room"
+	Model     string    `json:"model"`
+	Firmware  string    `json:"firmware,omitempty"`
+	MAC       string    `json:"mac,omitempty"`
+	IP        string    `json:"ip"` // Address the device was last seen at
+	Port      string    `json:"port"`
+	Hostname  string    `json:"hostname,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Name returns the alias of the device, or its ID if it has none
+func (k KnownDevice) Name() string {
+	if k.Alias != "" {
+		return k.Alias
+	}
+	return k.ID
+}
+
+// Device returns the device as last discovered
+func (k KnownDevice) Device() DiscoveredDevice {
+	return DiscoveredDevice{
+		IP:       k.IP,
+		Model:    k.Model,
+		Port:     k.Port,
+		Firmware: k.Firmware,
+		MAC:      k.MAC,
+		Hostname: k.Hostname,
+	}
+}
+
+// LoadKnownDevices returns the registry of devices seen before, in the
+// order they were first seen
+func LoadKnownDevices() ([]KnownDevice, error) {
+	cache, err := LoadAppCache()
+	if err != nil {
+		return nil, err
+	}
+	return cache.Known, nil
+}
+
+// ResolveKnownDevice finds the device of the registry that ref names: its
+// alias, MAC address, ID, host name or last seen IP address
+func ResolveKnownDevice(ref string) (KnownDevice, error) {
+	known, err := LoadKnownDevices()
+	if err != nil {
+		return KnownDevice{}, err
+	}
+	i := findKnownDevice(known, ref)
+	if i < 0 {
+		return KnownDevice{}, fmt.Errorf("%q: %w", ref, ErrUnknownDevice)
+	}
+	log.WithFields(log.Fields{
+		"ref":     ref,
+		"id":      known[i].ID,
+		"address": known[i].IP,
+	}).Debug("Resolved known device")
+	return known[i], nil
+}
+
+// SetKnownDeviceAlias gives the device ref names an alias, or removes its
+// alias if alias is empty
+func SetKnownDeviceAlias(ref, alias string) error {
+	alias = strings.TrimSpace(alias)
+	return updateKnownDevices(func(known []KnownDevice) ([]KnownDevice, error) {
+		i := findKnownDevice(known, ref)
+		if i < 0 {
+			return nil, fmt.Errorf("%q: %w", ref, ErrUnknownDevice)
+		}
+		if alias != "" {
+			for j, k := range known {
+				if j != i && strings.EqualFold(k.Alias, alias) {
+					return nil, fmt.Errorf("alias %q is already used by %s", alias, k.ID)
+				}
+			}
+		}
+		known[i].Alias = alias
+		return known, nil
+	})
+}
+
+// ForgetKnownDevice removes the device ref names from the registry
+func ForgetKnownDevice(ref string) error {
+	return updateKnownDevices(func(known []KnownDevice) ([]KnownDevice, error) {
+		i := findKnownDevice(known, ref)
+		if i < 0 {
+			return nil, fmt.Errorf("%q: %w", ref, ErrUnknownDevice)
+		}
+		return append(known[:i], known[i+1:]...), nil
+	})
+}
+
+// RelocateKnownDevice searches the network for a known device that no
+// longer answers at its last address and returns it at its new one. Only
+// devices whose MAC address is known can be recognized at another address.
+func RelocateKnownDevice(timeout time.Duration, ref string, opts DiscoveryOptions) (KnownDevice, error) {
+	device, err := ResolveKnownDevice(ref)
+	if err != nil {
+		return KnownDevice{}, err
+	}
+	if device.MAC == "" {
+		return KnownDevice{}, fmt.Errorf("%s did not answer at %s and its MAC address is unknown", device.Name(), device.IP)
+	}
+
+	log.WithFields(log.Fields{
+		"device":      device.Name(),
+		"mac":         device.MAC,
+		"lastAddress": device.IP,
+	}).Debug("Searching for a known device that moved")
+
+	// Discovery records the devices it finds in the registry
+	opts.MaxDevices = 0
+	if _, _, err := DiscoverDevicesWithCacheOptions(timeout, false, DefaultCacheTTL, opts); err != nil {
+		return KnownDevice{}, err
+	}
+	moved, err := ResolveKnownDevice(device.MAC)
+	if err != nil {
+		return KnownDevice{}, err
+	}
+	if !moved.LastSeen.After(device.LastSeen) {
+		return KnownDevice{}, fmt.Errorf("%s was not found on the network", device.Name())
+	}
+	return moved, nil
+}
+
+// updateKnownDevices applies update to the registry and saves the result
+func updateKnownDevices(update func([]KnownDevice) ([]KnownDevice, error)) error {
+	cache, err := LoadAppCache()
+	if err != nil {
+		return err
+	}
+	known, err := update(cache.Known)
+	if err != nil {
+		return err
+	}
+	cache.Known = known
+	return SaveAppCache(cache)
+}
+
+// recordKnownDevices adds the devices of a discovery to the registry, or
+// updates their entries when they are known
+func recordKnownDevices(known []KnownDevice, devices []DiscoveredDevice, now time.Time) []KnownDevice {
+	for _, dev := range devices {
+		i := -1
+		if dev.MAC != "" {
+			i = indexKnownDevice(known, func(k KnownDevice) bool { return k.MAC == dev.MAC })
+		}
+		if i < 0 {
+			// Entries without a MAC address are tracked by address
+			key := deviceKey(dev)
+			i = indexKnownDevice(known, func(k KnownDevice) bool {
+				return k.MAC == "" && deviceKey(k.Device()) == key
+			})
+		}
+
+		if i < 0 {
+			known = append(known, KnownDevice{
+				ID:        knownDeviceID(dev),
+				Model:     dev.Model,
+				Firmware:  dev.Firmware,
+				MAC:       dev.MAC,
+				IP:        dev.IP,
+				Port:      dev.Port,
+				Hostname:  dev.Hostname,
+				FirstSeen: now,
+				LastSeen:  now,
+			})
+			log.WithFields(log.Fields{
+				"id":    knownDeviceID(dev),
+				"model": dev.Model,
+			}).Debug("Added device to the registry")
+			continue
+		}
+
+		k := &known[i]
+		if deviceKey(k.Device()) != deviceKey(dev) {
+			log.WithFields(log.Fields{
+				"device": k.Name(),
+				"from":   k.IP,
+				"to":     dev.IP,
+			}).Debug("Known device moved to a new address")
+		}
+		k.IP, k.Port, k.Model = dev.IP, dev.Port, dev.Model
+		if dev.MAC != "" {
+			k.MAC = dev.MAC
+			k.ID = dev.MAC
+		}
+		if dev.Firmware != "" {
+			k.Firmware = dev.Firmware
+		}
+		if dev.Hostname != "" {
+			k.Hostname = dev.Hostname
+		}
+		k.LastSeen = now
+	}
+	return known
+}
+
+// knownDeviceID returns the registry ID of a discovered device
+func knownDeviceID(dev DiscoveredDevice) string {
+	if dev.MAC != "" {
+		return dev.MAC
+	}
+	return deviceKey(dev)
+}
+
+// findKnownDevice returns the index of the device ref names, or -1. Names
+// take precedence over addresses, and of several devices last seen at the
+// same address the most recent one wins.
+func findKnownDevice(known []KnownDevice, ref string) int {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return -1
+	}
+	if i := indexKnownDevice(known, func(k KnownDevice) bool { return strings.EqualFold(k.Alias, ref) }); i >= 0 {
+		return i
+	}
+	mac := normalizeOUI(ref)
+	if i := indexKnownDevice(known, func(k KnownDevice) bool {
+		return strings.EqualFold(k.ID, ref) || (k.MAC != "" && k.MAC == mac)
+	}); i >= 0 {
+		return i
+	}
+
+	host := canonicalHost(strings.TrimSuffix(strings.TrimPrefix(ref, "["), "]"))
+	found := -1
+	for i, k := range known {
+		if canonicalHost(k.IP) != host && !strings.EqualFold(k.Hostname, ref) {
+			continue
+		}
+		if found < 0 || k.LastSeen.After(known[found].LastSeen) {
+			found = i
+		}
+	}
+	return found
+}
+
+// indexKnownDevice returns the index of the first device match accepts, or -1
+func indexKnownDevice(known []KnownDevice, match func(KnownDevice) bool) int {
+	for i, k := range known {
+		if match(k) {
+			return i
+		}
+	}
+	return -1
+}
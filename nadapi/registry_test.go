@@ -0,0 +1,105 @@
+package nadapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecordKnownDevicesFollowsMovedDevice(t *testing.T) {
+	seen := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	known := recordKnownDevices(nil, []DiscoveredDevice{
+		{IP: "10.0.0.5", Model: "C338", Port: "30001", MAC: "00:1a:2b:00:00:05"},
+		{IP: "10.0.0.6", Model: "M10", Port: "30001"},
+	}, seen)
+	if len(known) != 2 || known[0].ID != "00:1a:2b:00:00:05" || known[1].ID != "10.0.0.6:30001" {
+		t.Fatalf("recordKnownDevices() = %+v, want entries keyed by MAC and by address", known)
+	}
+	known[0].Alias = "living-room"
+
+	// DHCP moved the C338, and the M10 now reports its MAC address
+	moved := seen.Add(time.Hour)
+	known = recordKnownDevices(known, []DiscoveredDevice{
+		{IP: "10.0.0.9", Model: "C338", Port: "30001", MAC: "00:1a:2b:00:00:05", Firmware: "V2.10"},
+		{IP: "10.0.0.6", Model: "M10", Port: "30001", MAC: "00:1a:2b:00:00:06"},
+	}, moved)
+	if len(known) != 2 {
+		t.Fatalf("recordKnownDevices() = %+v, want the two devices updated", known)
+	}
+	if k := known[0]; k.Alias != "living-room" || k.IP != "10.0.0.9" || k.Firmware != "V2.10" ||
+		!k.FirstSeen.Equal(seen) || !k.LastSeen.Equal(moved) {
+		t.Errorf("moved device = %+v, want alias kept and address, firmware and last seen updated", k)
+	}
+	if k := known[1]; k.ID != "00:1a:2b:00:00:06" || k.MAC != "00:1a:2b:00:00:06" {
+		t.Errorf("device with new MAC = %+v, want it keyed by its MAC", k)
+	}
+}
+
+func TestFindKnownDevice(t *testing.T) {
+	seen := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	known := []KnownDevice{
+		{ID: "00:1a:2b:00:00:05", Alias: "office", MAC: "00:1a:2b:00:00:05", IP: "10.0.0.5", Hostname: "amp.lan", LastSeen: seen},
+		{ID: "00:1a:2b:00:00:06", MAC: "00:1a:2b:00:00:06", IP: "10.0.0.6", LastSeen: seen},
+		// Seen at the address of the office amp after DHCP moved that one
+		{ID: "10.0.0.5:30001", IP: "10.0.0.5", LastSeen: seen.Add(time.Hour)},
+	}
+	tests := []struct {
+		ref  string
+		want int
+	}{
+		{"office", 0},
+		{"OFFICE", 0},
+		{"00-1A-2B-00-00-06", 1},
+		{"00:1a:2b:00:00:06", 1},
+		{"amp.lan", 0},
+		{"10.0.0.5", 2},
+		{"10.0.0.6", 1},
+		{"kitchen", -1},
+		{"", -1},
+	}
+	for _, tt := range tests {
+		if got := findKnownDevice(known, tt.ref); got != tt.want {
+			t.Errorf("findKnownDevice(%q) = %d, want %d", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestKnownDeviceAliases(t *testing.T) {
+	useTempCache(t)
+	err := SaveCachedDevices([]DiscoveredDevice{
+		{IP: "10.0.0.5", Model: "C338", Port: "30001", MAC: "00:1a:2b:00:00:05"},
+		{IP: "10.0.0.6", Model: "M10", Port: "30001", MAC: "00:1a:2b:00:00:06"},
+	}, DefaultCacheTTL)
+	if err != nil {
+		t.Fatalf("SaveCachedDevices() error = %v", err)
+	}
+
+	if err := SetKnownDeviceAlias("10.0.0.5", "living-room"); err != nil {
+		t.Fatalf("SetKnownDeviceAlias() error = %v", err)
+	}
+	if err := SetKnownDeviceAlias("10.0.0.6", "Living-Room"); err == nil {
+		t.Error("SetKnownDeviceAlias() with an alias in use should fail")
+	}
+	if err := SetKnownDeviceAlias("kitchen", "x"); !errors.Is(err, ErrUnknownDevice) {
+		t.Errorf("SetKnownDeviceAlias() of an unknown device error = %v, want ErrUnknownDevice", err)
+	}
+
+	// The registry survives clearing the cache
+	if err := ClearCache(); err != nil {
+		t.Fatalf("ClearCache() error = %v", err)
+	}
+	device, err := ResolveKnownDevice("living-room")
+	if err != nil {
+		t.Fatalf("ResolveKnownDevice() error = %v", err)
+	}
+	if device.IP != "10.0.0.5" {
+		t.Errorf("ResolveKnownDevice() = %+v, want the device at 10.0.0.5", device)
+	}
+
+	if err := ForgetKnownDevice("living-room"); err != nil {
+		t.Fatalf("ForgetKnownDevice() error = %v", err)
+	}
+	if _, err := ResolveKnownDevice("living-room"); !errors.Is(err, ErrUnknownDevice) {
+		t.Errorf("ResolveKnownDevice() after forgetting error = %v, want ErrUnknownDevice", err)
+	}
+}
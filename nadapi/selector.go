@@ -0,0 +1,175 @@
+package nadapi
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrAmbiguousDevice is returned when a device selector matches several
+// devices
+var ErrAmbiguousDevice = errors.New("ambiguous device")
+
+// DeviceEntry is a device that can be selected: a device named in the config
+// file, a known device, or both
+type DeviceEntry struct {
+	Name    string       // Config name or alias, empty for unnamed known devices
+	Address string       // Address as accepted by ParseAddress
+	Port    string       // Control port, the default when empty
+	Known   *KnownDevice // Registry entry, nil for configured devices never discovered
+}
+
+// String returns the name of the entry, or its address if it has none
+func (e DeviceEntry) String() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	if e.Port != "" && !strings.Contains(e.Address, "://") {
+		return net.JoinHostPort(e.Address, e.Port)
+	}
+	return e.Address
+}
+
+// DeviceEntries returns the devices a selector can choose from: the devices
+// named in configured (name to address) sorted by name, then the known
+// devices not among them in the order they were first seen
+func DeviceEntries(configured map[string]string) ([]DeviceEntry, error) {
+	known, err := LoadKnownDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(configured))
+	for name := range configured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var entries []DeviceEntry
+	claimed := make(map[int]bool)
+	for _, name := range names {
+		addr, port := configured[name], ""
+		// Accept host:port besides the forms of ParseAddress
+		if host, p, err := net.SplitHostPort(addr); err == nil && !strings.Contains(addr, "://") {
+			addr, port = host, p
+		}
+		t, err := ParseAddress(addr, port)
+		if err != nil {
+			return nil, fmt.Errorf("device %q: %w", name, err)
+		}
+		entry := DeviceEntry{Name: name, Address: configured[name]}
+		if tcp, ok := t.(*TCPTransport); ok {
+			entry.Address, entry.Port = tcp.Host, tcp.Port
+			key := deviceKey(DiscoveredDevice{IP: tcp.Host, Port: tcp.Port})
+			for i := range known {
+				if !claimed[i] && deviceKey(known[i].Device()) == key {
+					claimed[i] = true
+					entry.Known = &known[i]
+					break
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	for i := range known {
+		if claimed[i] {
+			continue
+		}
+		entries = append(entries, DeviceEntry{
+			Name:    known[i].Alias,
+			Address: known[i].IP,
+			Port:    known[i].Port,
+			Known:   &known[i],
+		})
+	}
+	return entries, nil
+}
+
+// SelectDevice returns the entry ref selects: by its 1-based index, its name
+// or alias, MAC address, host name or IP address, or a unique part of its
+// name. A ref matching several entries is an ErrAmbiguousDevice.
+func SelectDevice(entries []DeviceEntry, ref string) (DeviceEntry, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return DeviceEntry{}, fmt.Errorf("no device selected: %w", ErrUnknownDevice)
+	}
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(entries) {
+			return DeviceEntry{}, fmt.Errorf("device index %d out of range 1-%d: %w", n, len(entries), ErrUnknownDevice)
+		}
+		return entries[n-1], nil
+	}
+
+	mac := normalizeOUI(ref)
+	host := canonicalHost(strings.TrimSuffix(strings.TrimPrefix(ref, "["), "]"))
+	lower := strings.ToLower(ref)
+	matchers := []func(DeviceEntry) bool{
+		// Names and aliases
+		func(e DeviceEntry) bool {
+			return strings.EqualFold(e.Name, ref) || (e.Known != nil && strings.EqualFold(e.Known.Alias, ref))
+		},
+		// Registry IDs and MAC addresses
+		func(e DeviceEntry) bool {
+			return e.Known != nil && (strings.EqualFold(e.Known.ID, ref) || (e.Known.MAC != "" && e.Known.MAC == mac))
+		},
+		// Addresses and host names
+		func(e DeviceEntry) bool {
+			return canonicalHost(e.Address) == host || e.Address == ref ||
+				(e.Known != nil && strings.EqualFold(e.Known.Hostname, ref))
+		},
+		// Parts of names, as the Spotify device selection does
+		func(e DeviceEntry) bool {
+			return e.Name != "" && strings.Contains(strings.ToLower(e.Name), lower)
+		},
+	}
+
+	for _, match := range matchers {
+		var found []DeviceEntry
+		for _, e := range entries {
+			if match(e) {
+				found = append(found, e)
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			log.WithFields(log.Fields{
+				"ref":    ref,
+				"device": found[0].String(),
+			}).Debug("Selected device")
+			return found[0], nil
+		default:
+			if e, ok := latestSighting(found); ok {
+				return e, nil
+			}
+			names := make([]string, len(found))
+			for i, e := range found {
+				names[i] = e.String()
+			}
+			return DeviceEntry{}, fmt.Errorf("%q: %w, it matches %s", ref, ErrAmbiguousDevice, strings.Join(names, ", "))
+		}
+	}
+	return DeviceEntry{}, fmt.Errorf("%q: %w", ref, ErrUnknownDevice)
+}
+
+// latestSighting resolves an address shared by several unnamed known
+// devices, which happens when DHCP hands the address of one to another: only
+// the device seen there last can still be at it
+func latestSighting(found []DeviceEntry) (DeviceEntry, bool) {
+	latest := found[0]
+	for _, e := range found {
+		if e.Known == nil || e.Name != "" || deviceKey(e.Known.Device()) != deviceKey(found[0].Known.Device()) {
+			return DeviceEntry{}, false
+		}
+		if e.Known.LastSeen.After(latest.Known.LastSeen) {
+			latest = e
+		}
+	}
+	return latest, true
+}
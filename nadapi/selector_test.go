@@ -0,0 +1,100 @@
+package nadapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeviceEntries(t *testing.T) {
+	useTempCache(t)
+	err := SaveCachedDevices([]DiscoveredDevice{
+		{IP: "10.0.0.5", Model: "C338", Port: "30001", MAC: "00:1a:2b:00:00:05"},
+		{IP: "10.0.0.6", Model: "M10", Port: "30001", MAC: "00:1a:2b:00:00:06"},
+	}, DefaultCacheTTL)
+	if err != nil {
+		t.Fatalf("SaveCachedDevices() error = %v", err)
+	}
+
+	entries, err := DeviceEntries(map[string]string{
+		"office":  "10.0.0.6",
+		"bedroom": "serial:///dev/ttyUSB0",
+		"den":     "10.0.0.8:30002",
+	})
+	if err != nil {
+		t.Fatalf("DeviceEntries() error = %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("DeviceEntries() = %+v, want 4 entries", entries)
+	}
+	// Configured devices come first by name, then the other known devices
+	if e := entries[0]; e.Name != "bedroom" || e.Address != "serial:///dev/ttyUSB0" || e.Known != nil {
+		t.Errorf("entries[0] = %+v, want the configured serial device", e)
+	}
+	if e := entries[1]; e.Name != "den" || e.Address != "10.0.0.8" || e.Port != "30002" {
+		t.Errorf("entries[1] = %+v, want the configured device with its port", e)
+	}
+	if e := entries[2]; e.Name != "office" || e.Port != "30001" || e.Known == nil || e.Known.Model != "M10" {
+		t.Errorf("entries[2] = %+v, want the configured device matched to the known M10", e)
+	}
+	if e := entries[3]; e.Name != "" || e.Address != "10.0.0.5" || e.Known == nil {
+		t.Errorf("entries[3] = %+v, want the unnamed known C338", e)
+	}
+
+	if _, err := DeviceEntries(map[string]string{"broken": "ftp://amp"}); err == nil {
+		t.Error("DeviceEntries() with an invalid address should fail")
+	}
+}
+
+func TestSelectDevice(t *testing.T) {
+	seen := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	office := &KnownDevice{ID: "00:1a:2b:00:00:05", MAC: "00:1a:2b:00:00:05", IP: "10.0.0.5", Port: "30001", Hostname: "amp.lan", LastSeen: seen}
+	kitchen := &KnownDevice{ID: "00:1a:2b:00:00:06", Alias: "kitchen", MAC: "00:1a:2b:00:00:06", IP: "10.0.0.6", Port: "30001", LastSeen: seen}
+	stale := &KnownDevice{ID: "10.0.0.7:30001", IP: "10.0.0.7", Port: "30001", LastSeen: seen}
+	moved := &KnownDevice{ID: "00:1a:2b:00:00:08", MAC: "00:1a:2b:00:00:08", IP: "10.0.0.7", Port: "30001", LastSeen: seen.Add(time.Hour)}
+	entries := []DeviceEntry{
+		{Name: "living-room", Address: "10.0.0.9", Port: "30001"},
+		{Name: "office", Address: "10.0.0.5", Port: "30001", Known: office},
+		{Name: "office-2", Address: "serial:///dev/ttyUSB0"},
+		{Name: "kitchen", Address: "10.0.0.6", Port: "30001", Known: kitchen},
+		{Address: "10.0.0.7", Port: "30001", Known: stale},
+		{Address: "10.0.0.7", Port: "30001", Known: moved},
+	}
+
+	tests := []struct {
+		ref  string
+		want int
+	}{
+		{"1", 0},
+		{"4", 3},
+		{"office", 1},
+		{"OFFICE", 1},
+		{"living", 0},
+		{"00-1A-2B-00-00-06", 3},
+		{"amp.lan", 1},
+		{"10.0.0.6", 3},
+		{"serial:///dev/ttyUSB0", 2},
+		// Only the device seen there last can still be at a shared address
+		{"10.0.0.7", 5},
+	}
+	for _, tt := range tests {
+		got, err := SelectDevice(entries, tt.ref)
+		if err != nil {
+			t.Errorf("SelectDevice(%q) unexpected error: %v", tt.ref, err)
+			continue
+		}
+		if got != entries[tt.want] {
+			t.Errorf("SelectDevice(%q) = %s, want %s", tt.ref, got, entries[tt.want])
+		}
+	}
+
+	for _, ref := range []string{"0", "7", "garage", ""} {
+		if _, err := SelectDevice(entries, ref); !errors.Is(err, ErrUnknownDevice) {
+			t.Errorf("SelectDevice(%q) error = %v, want ErrUnknownDevice", ref, err)
+		}
+	}
+	// "of" is part of office and office-2
+	if _, err := SelectDevice(entries, "of"); !errors.Is(err, ErrAmbiguousDevice) {
+		t.Errorf("SelectDevice(\"of\") error = %v, want ErrAmbiguousDevice", err)
+	}
+}
@@ -0,0 +1,82 @@
+package nadapi
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+var baudRates = map[int]uint32{
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+}
+
+// serialConn is an open serial port. The file is non-blocking, so read and
+// write deadlines work as they do on a network connection.
+type serialConn struct {
+	*os.File
+}
+
+func (c *serialConn) LocalAddr() net.Addr  { return serialAddr(c.Name()) }
+func (c *serialConn) RemoteAddr() net.Addr { return serialAddr(c.Name()) }
+
+// OpenSerial opens the serial port at path in raw 8N1 mode at the given
+// baud rate and returns it as a connection
+func OpenSerial(path string, baud int) (net.Conn, error) {
+	speed, ok := baudRates[baud]
+	if !ok {
+		return nil, withKind(ErrUnsupported, fmt.Errorf("unsupported baud rate %d", baud))
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open serial port: %w", err)
+	}
+
+	// Fd would switch the file back to blocking mode, so configure the
+	// port through the raw descriptor instead
+	raw, err := f.SyscallConn()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open serial port: %w", err)
+	}
+	var termErr error
+	if err := raw.Control(func(fd uintptr) {
+		termErr = setRawMode(int(fd), speed)
+	}); err != nil {
+		termErr = err
+	}
+	if termErr != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to configure serial port %s: %w", path, termErr)
+	}
+
+	return &serialConn{File: f}, nil
+}
+
+// setRawMode puts the terminal fd into raw 8N1 mode without flow control
+func setRawMode(fd int, speed uint32) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP |
+		unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF | unix.IXANY
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL | speed
+	t.Ispeed = speed
+	t.Ospeed = speed
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+
+	return unix.IoctlSetTermios(fd, unix.TCSETS, t)
+}
@@ -0,0 +1,78 @@
+package nadapi
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPTY allocates a pseudo terminal pair and returns the master side and
+// the path of the slave side
+func openPTY(t *testing.T) (*os.File, string) {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("pseudo terminals unavailable: %v", err)
+	}
+	t.Cleanup(func() { master.Close() })
+
+	fd := int(master.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		t.Fatalf("failed to unlock pty: %v", err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		t.Fatalf("failed to get pty number: %v", err)
+	}
+	return master, "/dev/pts/" + strconv.Itoa(n)
+}
+
+func TestSerialTransport(t *testing.T) {
+	master, path := openPTY(t)
+
+	// Answer power queries like an amplifier on the other end of the cable
+	go func() {
+		r := bufio.NewReader(master)
+		for {
+			line, err := r.ReadString('?')
+			if err != nil {
+				return
+			}
+			if strings.TrimSpace(line) == "Main.Power?" {
+				master.Write([]byte("Main.Power=On\r\n"))
+			}
+		}
+	}()
+
+	d, err := New("serial://"+path, "")
+	if err != nil {
+		t.Fatalf("New(serial://%s) unexpected error: %v", path, err)
+	}
+	defer d.Disconnect()
+
+	if d.IP != nil {
+		t.Errorf("IP = %v, want nil for a serial device", d.IP)
+	}
+	if got := d.String(); got != "serial://"+path {
+		t.Errorf("String() = %q, want %q", got, "serial://"+path)
+	}
+
+	state, err := d.GetPowerState()
+	if err != nil {
+		t.Fatalf("GetPowerState() unexpected error: %v", err)
+	}
+	if state != "On" {
+		t.Errorf("GetPowerState() = %q, want %q", state, "On")
+	}
+}
+
+func TestOpenSerialUnsupportedBaud(t *testing.T) {
+	_, path := openPTY(t)
+	if _, err := OpenSerial(path, 1234); err == nil {
+		t.Error("OpenSerial() with unsupported baud rate expected error, got nil")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package nadapi
+
+import (
+	"errors"
+	"net"
+)
+
+// OpenSerial is only available on Linux
+func OpenSerial(path string, baud int) (net.Conn, error) {
+	return nil, withKind(ErrUnsupported, errors.New("serial transport is not supported on this platform"))
+}
@@ -0,0 +1,106 @@
+package nadapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Settings reads values of a config file, as *viper.Viper does
+type Settings interface {
+	GetString(key string) string
+	GetStringMapString(key string) map[string]string
+	GetStringSlice(key string) []string
+	GetInt(key string) int
+	GetDuration(key string) time.Duration
+	IsSet(key string) bool
+}
+
+// AddressFromSettings returns the device address of settings, preferring an
+// address URI such as serial:///dev/ttyUSB0 over a plain IP
+func AddressFromSettings(settings Settings) string {
+	if addr := settings.GetString("address"); addr != "" {
+		return addr
+	}
+	return settings.GetString("ip")
+}
+
+// VolumeLimitsFromSettings reads the volume safety limits of the volume
+// section of settings: volume.max, volume.max_step and volume.source_max
+func VolumeLimitsFromSettings(settings Settings) (VolumeLimits, error) {
+	limits, err := ParseVolumeLimits(
+		settings.GetString("volume.max"),
+		settings.GetString("volume.max_step"),
+		settings.GetStringMapString("volume.source_max"),
+	)
+	if err != nil {
+		return VolumeLimits{}, fmt.Errorf("invalid volume limits: %w", err)
+	}
+	return limits, nil
+}
+
+// CommandIntervalFromSettings returns the command_interval of settings, or
+// DefaultCommandInterval if it is unset
+func CommandIntervalFromSettings(settings Settings) time.Duration {
+	if settings.IsSet("command_interval") {
+		return settings.GetDuration("command_interval")
+	}
+	return DefaultCommandInterval
+}
+
+// DiscoveryOptionsFromSettings reads the discovery settings of the discovery
+// section of settings, e.g. discovery.subnets and discovery.models
+func DiscoveryOptionsFromSettings(settings Settings) (DiscoveryOptions, error) {
+	include, err := ParseAddrRanges(settings.GetStringSlice("discovery.subnets"))
+	if err != nil {
+		return DiscoveryOptions{}, err
+	}
+	exclude, err := ParseAddrRanges(settings.GetStringSlice("discovery.exclude"))
+	if err != nil {
+		return DiscoveryOptions{}, err
+	}
+	match, err := ParseModelMatcher(settings.GetStringSlice("discovery.models"))
+	if err != nil {
+		return DiscoveryOptions{}, err
+	}
+	opts := DiscoveryOptions{
+		Port:       settings.GetString("discovery.port"),
+		Include:    include,
+		Exclude:    exclude,
+		Interfaces: settings.GetStringSlice("discovery.interfaces"),
+		Workers:    settings.GetInt("discovery.workers"),
+		Rate:       settings.GetInt("discovery.rate"),
+		OUIs:       settings.GetStringSlice("discovery.oui"),
+		Match:      match,
+	}
+	log.WithFields(log.Fields{
+		"port":       opts.Port,
+		"include":    include,
+		"exclude":    exclude,
+		"interfaces": opts.Interfaces,
+		"workers":    opts.Workers,
+		"rate":       opts.Rate,
+		"oui":        opts.OUIs,
+		"models":     settings.GetStringSlice("discovery.models"),
+	}).Debug("Discovery settings")
+	return opts, nil
+}
+
+// NewFromSettings connects to the device at addr like NewContext, holds it
+// to the volume limits of settings, see VolumeLimitsFromSettings, and paces
+// its commands by CommandIntervalFromSettings
+func NewFromSettings(ctx context.Context, addr, port string, settings Settings) (*Device, error) {
+	limits, err := VolumeLimitsFromSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	device, err := NewContext(ctx, addr, port)
+	if err != nil {
+		return nil, err
+	}
+	device.SetVolumeLimits(limits)
+	device.SetCommandInterval(CommandIntervalFromSettings(settings))
+	return device, nil
+}
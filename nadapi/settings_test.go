@@ -0,0 +1,94 @@
+package nadapi
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// mapSettings serves settings from maps, like a parsed config file
+type mapSettings struct {
+	strings map[string]string
+	maps    map[string]map[string]string
+	slices  map[string][]string
+}
+
+func (s mapSettings) GetString(key string) string                     { return s.strings[key] }
+func (s mapSettings) GetStringMapString(key string) map[string]string { return s.maps[key] }
+func (s mapSettings) GetStringSlice(key string) []string              { return s.slices[key] }
+func (s mapSettings) GetInt(key string) int {
+	n, _ := strconv.Atoi(s.strings[key])
+	return n
+}
+func (s mapSettings) GetDuration(key string) time.Duration {
+	d, _ := time.ParseDuration(s.strings[key])
+	return d
+}
+func (s mapSettings) IsSet(key string) bool {
+	_, ok := s.strings[key]
+	return ok
+}
+
+func TestAddressFromSettings(t *testing.T) {
+	settings := mapSettings{strings: map[string]string{"ip": "192.168.1.100"}}
+	if got := AddressFromSettings(settings); got != "192.168.1.100" {
+		t.Errorf("AddressFromSettings() = %q, want the ip", got)
+	}
+	settings.strings["address"] = "serial:///dev/ttyUSB0"
+	if got := AddressFromSettings(settings); got != "serial:///dev/ttyUSB0" {
+		t.Errorf("AddressFromSettings() = %q, want the address URI over the ip", got)
+	}
+}
+
+func TestVolumeLimitsFromSettings(t *testing.T) {
+	settings := mapSettings{
+		strings: map[string]string{"volume.max": "-10", "volume.max_step": "6"},
+		maps:    map[string]map[string]string{"volume.source_max": {"Phono": "-30"}},
+	}
+	limits, err := VolumeLimitsFromSettings(settings)
+	if err != nil {
+		t.Fatalf("VolumeLimitsFromSettings() unexpected error: %v", err)
+	}
+	if limits.Max != -10 || limits.MaxStep != 6 || limits.SourceMax["Phono"] != -30 {
+		t.Errorf("VolumeLimitsFromSettings() = %+v, want max -10, step 6 and Phono -30", limits)
+	}
+
+	settings.strings["volume.max"] = "loud"
+	if _, err := VolumeLimitsFromSettings(settings); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("VolumeLimitsFromSettings() of an invalid max error = %v, want ErrInvalidValue", err)
+	}
+}
+
+func TestCommandIntervalFromSettings(t *testing.T) {
+	settings := mapSettings{strings: map[string]string{}}
+	if got := CommandIntervalFromSettings(settings); got != DefaultCommandInterval {
+		t.Errorf("CommandIntervalFromSettings() unset = %v, want %v", got, DefaultCommandInterval)
+	}
+	settings.strings["command_interval"] = "0s"
+	if got := CommandIntervalFromSettings(settings); got != 0 {
+		t.Errorf("CommandIntervalFromSettings() of 0s = %v, want 0", got)
+	}
+}
+
+func TestDiscoveryOptionsFromSettings(t *testing.T) {
+	settings := mapSettings{
+		strings: map[string]string{"discovery.port": "30002", "discovery.workers": "8"},
+		slices: map[string][]string{
+			"discovery.subnets": {"10.2.30.0/24"},
+			"discovery.models":  {"C3*"},
+		},
+	}
+	opts, err := DiscoveryOptionsFromSettings(settings)
+	if err != nil {
+		t.Fatalf("DiscoveryOptionsFromSettings() unexpected error: %v", err)
+	}
+	if opts.Port != "30002" || opts.Workers != 8 || len(opts.Include) != 1 || opts.Match == nil {
+		t.Errorf("DiscoveryOptionsFromSettings() = %+v, want port 30002, 8 workers, a subnet and a model match", opts)
+	}
+
+	settings.slices["discovery.exclude"] = []string{"not a range"}
+	if _, err := DiscoveryOptionsFromSettings(settings); err == nil {
+		t.Error("DiscoveryOptionsFromSettings() of an invalid range succeeded, want an error")
+	}
+}
@@ -0,0 +1,114 @@
+package nadapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const ssdpAddr = "239.255.255.250:1900"
+
+// ssdpMarkers are found in the SERVER, ST or USN headers of NAD and BluOS
+// units
+var ssdpMarkers = []string{"nad", "bluos", "bluesound"}
+
+// SSDPProvider finds devices that answer SSDP searches. Answering hosts are
+// confirmed on the control port before being reported.
+type SSDPProvider struct {
+	Addr         string        // Where to send the search, the SSDP group when empty
+	SearchTarget string        // ST of the search, ssdp:all when empty
+	Port         string        // Control port to confirm on, 30001 when empty
+	Wait         time.Duration // How long to collect replies, 2s when zero
+	Match        ModelMatcher  // Models to report, MatchKnownModels when nil
+}
+
+// Name returns "ssdp"
+func (p *SSDPProvider) Name() string { return "ssdp" }
+
+// Discover sends an M-SEARCH and confirms every NAD host that answers
+func (p *SSDPProvider) Discover(ctx context.Context, events *DiscoveryEvents) ([]DiscoveredDevice, error) {
+	addr := p.Addr
+	if addr == "" {
+		addr = ssdpAddr
+	}
+	st := p.SearchTarget
+	if st == "" {
+		st = "ssdp:all"
+	}
+	port := p.Port
+	if port == "" {
+		port = defaultPort
+	}
+
+	dst, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSDP address %q: %w", addr, err)
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SSDP socket: %w", err)
+	}
+	defer conn.Close()
+
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddr + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 1\r\n" +
+		"ST: " + st + "\r\n\r\n"
+
+	log.WithFields(log.Fields{
+		"st":   st,
+		"addr": addr,
+	}).Debug("Sending SSDP search")
+	if _, err := conn.WriteToUDP([]byte(search), dst); err != nil {
+		return nil, fmt.Errorf("failed to send SSDP search: %w", err)
+	}
+
+	events.Progress(DiscoveryProgress{Provider: p.Name()})
+
+	v := &candidateVerifier{ctx: ctx, port: port, method: p.Name(), match: p.Match, events: events}
+	readReplies(ctx, conn, p.Wait, parseSSDPReply, v.add)
+	return v.wait(), nil
+}
+
+// parseSSDPReply returns the host of a NAD unit answering a search, taken
+// from the LOCATION header or else the sender
+func parseSSDPReply(pkt []byte, from netip.Addr) []string {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(pkt)), nil)
+	if err != nil {
+		return nil
+	}
+	resp.Body.Close()
+
+	id := strings.ToLower(resp.Header.Get("Server") + " " + resp.Header.Get("St") + " " + resp.Header.Get("Usn"))
+	isNAD := false
+	for _, marker := range ssdpMarkers {
+		if strings.Contains(id, marker) {
+			isNAD = true
+			break
+		}
+	}
+	if !isNAD {
+		return nil
+	}
+
+	host := from.Unmap().String()
+	if loc, err := url.Parse(resp.Header.Get("Location")); err == nil && loc.Hostname() != "" {
+		host = loc.Hostname()
+	}
+	log.WithFields(log.Fields{
+		"from":   from.String(),
+		"host":   host,
+		"server": resp.Header.Get("Server"),
+	}).Debug("Received SSDP reply")
+	return []string{host}
+}
@@ -0,0 +1,154 @@
+package nadapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PowerState is the power state reported by the device
+type PowerState int
+
+const (
+	// PowerUnknown means the state has not been read or was not understood
+	PowerUnknown PowerState = iota
+	// PowerOn -
+	PowerOn
+	// PowerOff means the device is in standby
+	PowerOff
+)
+
+// String returns the state as the device spells it
+func (p PowerState) String() string {
+	switch p {
+	case PowerOn:
+		return "On"
+	case PowerOff:
+		return "Off"
+	default:
+		return "Unknown"
+	}
+}
+
+// MarshalText encodes the state as "On", "Off" or "Unknown"
+func (p PowerState) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// ParsePowerState converts a Main.Power value to a PowerState
+func ParsePowerState(s string) (PowerState, error) {
+	switch {
+	case strings.EqualFold(s, "On"):
+		return PowerOn, nil
+	case strings.EqualFold(s, "Off"), strings.EqualFold(s, "Standby"):
+		return PowerOff, nil
+	}
+	return PowerUnknown, withKind(ErrInvalidValue, fmt.Errorf("unknown power state %q", s))
+}
+
+// parseOnOff converts an "On"/"Off" value such as Main.Mute to a bool
+func parseOnOff(s string) (bool, error) {
+	switch {
+	case strings.EqualFold(s, "On"):
+		return true, nil
+	case strings.EqualFold(s, "Off"):
+		return false, nil
+	}
+	return false, withKind(ErrInvalidValue, fmt.Errorf("unknown on/off value %q", s))
+}
+
+// State is a snapshot of the main zone of the device
+type State struct {
+	Power      PowerState `json:"power"`
+	Volume     float64    `json:"volume_db"`
+	Source     string     `json:"source"`
+	SourceName string     `json:"source_name"` // Custom name of the source, if renamed
+	Muted      bool       `json:"muted"`
+	Brightness int        `json:"brightness"`
+	Model      string     `json:"model"`
+}
+
+// GetPower retrieves the current power state
+func (d *Device) GetPower() (PowerState, error) {
+	return d.GetPowerContext(context.Background())
+}
+
+// GetPowerContext is like GetPower but takes a context
+func (d *Device) GetPowerContext(ctx context.Context) (PowerState, error) {
+	return d.main().GetPowerContext(ctx)
+}
+
+// IsMuted reports whether the device is muted
+func (d *Device) IsMuted() (bool, error) {
+	return d.IsMutedContext(context.Background())
+}
+
+// IsMutedContext is like IsMuted but takes a context
+func (d *Device) IsMutedContext(ctx context.Context) (bool, error) {
+	return d.main().IsMutedContext(ctx)
+}
+
+// State retrieves power, volume, source, mute, brightness and model in one call
+func (d *Device) State() (State, error) {
+	return d.StateContext(context.Background())
+}
+
+// StateContext is like State but takes a context. The keys are queried in
+// a single round trip. A device in standby answers only for its power, so
+// its state is just PowerOff.
+func (d *Device) StateContext(ctx context.Context) (State, error) {
+	log.WithField("device", d.String()).Debug("Getting device state")
+
+	z := d.main()
+	values, err := d.QueryContext(ctx, append(z.stateKeys(), "Main.Brightness", "Main.Model")...)
+	if errors.Is(err, ErrStandby) {
+		log.WithField("device", d.String()).Debug("Device in standby, reporting its power only")
+		return State{Power: PowerOff}, nil
+	}
+	if err != nil {
+		return State{}, fmt.Errorf("get state: %w", err)
+	}
+	zs, err := z.parseState(ctx, values)
+	if err != nil {
+		return State{}, err
+	}
+	s := State{
+		Power:      zs.Power,
+		Volume:     zs.Volume,
+		Source:     zs.Source,
+		SourceName: zs.SourceName,
+		Muted:      zs.Muted,
+		Model:      values["Main.Model"],
+	}
+	if s.Brightness, err = strconv.Atoi(values["Main.Brightness"]); err != nil {
+		return State{}, fmt.Errorf("failed to parse brightness: %w", err)
+	}
+
+	log.WithFields(log.Fields{
+		"device":     d.String(),
+		"power":      s.Power,
+		"volume":     s.Volume,
+		"source":     s.Source,
+		"muted":      s.Muted,
+		"brightness": s.Brightness,
+		"model":      s.Model,
+	}).Debug("Retrieved device state")
+	return s, nil
+}
+
+// VolumeString returns the volume formatted for display, e.g. "-32.0 dB"
+func (s State) VolumeString() string {
+	return strconv.FormatFloat(s.Volume, 'f', 1, 64) + " dB"
+}
+
+// MuteString returns "On" when muted and "Off" otherwise
+func (s State) MuteString() string {
+	if s.Muted {
+		return "On"
+	}
+	return "Off"
+}
@@ -0,0 +1,96 @@
+package nadapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+func TestParsePowerState(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    PowerState
+		wantErr bool
+	}{
+		{"On", PowerOn, false},
+		{"off", PowerOff, false},
+		{"Standby", PowerOff, false},
+		{"Maybe", PowerUnknown, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePowerState(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePowerState(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParsePowerState(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseOnOff(t *testing.T) {
+	if v, err := parseOnOff("On"); err != nil || !v {
+		t.Errorf("parseOnOff(On) = %v, %v; want true, nil", v, err)
+	}
+	if v, err := parseOnOff("Off"); err != nil || v {
+		t.Errorf("parseOnOff(Off) = %v, %v; want false, nil", v, err)
+	}
+	if _, err := parseOnOff("Sometimes"); err == nil {
+		t.Error("parseOnOff(Sometimes) expected error, got nil")
+	}
+}
+
+func TestStateJSON(t *testing.T) {
+	s := State{Power: PowerOn, Volume: -32.5, Source: "TV", SourceName: "Television", Muted: true, Brightness: 2, Model: "C338"}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	want := `{"power":"On","volume_db":-32.5,"source":"TV","source_name":"Television","muted":true,"brightness":2,"model":"C338"}`
+	if string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+	if got := s.VolumeString(); got != "-32.5 dB" {
+		t.Errorf("VolumeString() = %q, want %q", got, "-32.5 dB")
+	}
+	if got := s.MuteString(); got != "On" {
+		t.Errorf("MuteString() = %q, want %q", got, "On")
+	}
+}
+
+func TestDeviceState(t *testing.T) {
+	replies := map[string]string{
+		"Main.Power":      "On",
+		"Main.Volume":     "-40.0",
+		"Main.Source":     "Phono",
+		"Main.Mute":       "Off",
+		"Main.Brightness": "1",
+		"Main.Model":      "C338",
+	}
+	addInputReplies(replies, "C338", map[int]string{4: "Turntable"}, nil)
+	d, _ := connectAmp(t, fakeamp.Values(replies))
+
+	got, err := d.State()
+	if err != nil {
+		t.Fatalf("State() unexpected error: %v", err)
+	}
+	want := State{Power: PowerOn, Volume: -40, Source: "Phono", SourceName: "Turntable", Muted: false, Brightness: 1, Model: "C338"}
+	if got != want {
+		t.Errorf("State() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDeviceStateInStandby(t *testing.T) {
+	// A device in standby answers only for its power
+	d, _ := connectAmp(t, fakeamp.Values(map[string]string{"Main.Power": "Off"}))
+
+	got, err := d.State()
+	if err != nil {
+		t.Fatalf("State() unexpected error: %v", err)
+	}
+	if want := (State{Power: PowerOff}); got != want {
+		t.Errorf("State() = %+v, want %+v", got, want)
+	}
+}
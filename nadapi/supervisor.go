@@ -0,0 +1,377 @@
+package nadapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ConnState is the health of a supervised device connection
+type ConnState int
+
+const (
+	// ConnConnecting is the state until the first connection is established
+	ConnConnecting ConnState = iota
+	// ConnReady means the connection is up and answering
+	ConnReady
+	// ConnDegraded means the connection was lost and is being re-established;
+	// commands wait for it instead of failing
+	ConnDegraded
+	// ConnDown means reconnecting has failed for longer than the outage
+	// window; commands fail immediately while retries continue
+	ConnDown
+)
+
+const (
+	defaultKeepalive    = 30 * time.Second
+	defaultMinBackoff   = 250 * time.Millisecond
+	defaultMaxBackoff   = 30 * time.Second
+	defaultOutageWindow = 10 * time.Second
+	// probeCommand is a harmless query answered by every model
+	probeCommand = "Main.Model?"
+)
+
+// ErrDeviceDown is returned for commands sent while a supervised device is
+// down
+var ErrDeviceDown = errors.New("device is down")
+
+// String returns a human-readable name for the connection state
+func (s ConnState) String() string {
+	switch s {
+	case ConnConnecting:
+		return "connecting"
+	case ConnReady:
+		return "ready"
+	case ConnDegraded:
+		return "degraded"
+	default:
+		return "down"
+	}
+}
+
+// SuperviseOptions configures Device.Supervise. Zero values select the
+// defaults.
+type SuperviseOptions struct {
+	KeepaliveInterval time.Duration // Time between probes of the connection, 30s
+	MinBackoff        time.Duration // First reconnect delay, 250ms
+	MaxBackoff        time.Duration // Longest reconnect delay, 30s
+	OutageWindow      time.Duration // How long commands wait for a reconnect before the device is down, 10s
+
+	// OnStateChange is called from the supervisor goroutine on every
+	// transition and must not block
+	OnStateChange func(old, new ConnState)
+}
+
+// supervisor keeps the connection of one device alive
+type supervisor struct {
+	d      *Device
+	opts   SuperviseOptions
+	cancel context.CancelFunc
+	lostCh chan struct{} // signals the loop that a command found the connection broken
+
+	mu      sync.Mutex
+	state   ConnState
+	stopped bool
+	changed chan struct{} // closed and replaced on every transition
+}
+
+// Supervise keeps the device connected until ctx is done or Disconnect is
+// called. The connection is probed every keepalive interval and re-dialed
+// with exponential backoff when it fails. Commands sent while reconnecting
+// wait for the connection to come back, and fail with ErrDeviceDown once the
+// outage outlasts the outage window.
+func (d *Device) Supervise(ctx context.Context, opts SuperviseOptions) {
+	if opts.KeepaliveInterval <= 0 {
+		opts.KeepaliveInterval = defaultKeepalive
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaultMinBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(defaultMaxBackoff, opts.MinBackoff)
+	}
+	if opts.OutageWindow <= 0 {
+		opts.OutageWindow = defaultOutageWindow
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &supervisor{
+		d:       d,
+		opts:    opts,
+		cancel:  cancel,
+		lostCh:  make(chan struct{}, 1),
+		state:   ConnConnecting,
+		changed: make(chan struct{}),
+	}
+	if d.IsConnected() {
+		s.state = ConnReady
+	}
+
+	d.supMu.Lock()
+	if d.sup != nil {
+		d.sup.cancel()
+	}
+	d.sup = s
+	d.supMu.Unlock()
+
+	log.WithFields(log.Fields{
+		"device":    d.String(),
+		"keepalive": opts.KeepaliveInterval,
+		"state":     s.state,
+	}).Debug("Supervising device connection")
+
+	go s.run(ctx)
+}
+
+// ConnState returns the state of the connection. A device that is not
+// supervised is ready while it has an open connection and down otherwise.
+func (d *Device) ConnState() ConnState {
+	if s := d.supervisor(); s != nil {
+		return s.State()
+	}
+	if d.IsConnected() {
+		return ConnReady
+	}
+	return ConnDown
+}
+
+func (d *Device) supervisor() *supervisor {
+	d.supMu.Lock()
+	defer d.supMu.Unlock()
+	return d.sup
+}
+
+// stopSupervising ends supervision, e.g. when the device is disconnected
+func (d *Device) stopSupervising() {
+	if s := d.supervisor(); s != nil {
+		s.cancel()
+	}
+}
+
+// connLost tells the supervisor, if any, that a command found the
+// connection broken
+func (d *Device) connLost() {
+	if s := d.supervisor(); s != nil {
+		select {
+		case s.lostCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// State returns the current connection state
+func (s *supervisor) State() ConnState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+func (s *supervisor) setState(state ConnState) {
+	s.mu.Lock()
+	old := s.state
+	if old == state {
+		s.mu.Unlock()
+		return
+	}
+	s.state = state
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.mu.Unlock()
+
+	log.WithFields(log.Fields{
+		"device": s.d.String(),
+		"from":   old,
+		"to":     state,
+	}).Debug("Connection state changed")
+
+	if s.opts.OnStateChange != nil {
+		s.opts.OnStateChange(old, state)
+	}
+}
+
+// waitReady blocks a command until the connection is ready, the device is
+// down or ctx is done
+func (s *supervisor) waitReady(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		state, stopped, changed := s.state, s.stopped, s.changed
+		s.mu.Unlock()
+
+		if stopped || state == ConnReady {
+			return nil
+		}
+		if state == ConnDown {
+			return fmt.Errorf("%s: %w", s.d.String(), withKind(ErrNotConnected, ErrDeviceDown))
+		}
+
+		log.WithFields(log.Fields{
+			"device": s.d.String(),
+			"state":  state,
+		}).Debug("Holding command until the connection is back")
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return fmt.Errorf("command cancelled: %w", ctx.Err())
+		}
+	}
+}
+
+func (s *supervisor) run(ctx context.Context) {
+	defer s.stop()
+
+	backoff := s.opts.MinBackoff
+	var outageStart time.Time
+	for {
+		if s.State() == ConnReady {
+			if !s.watch(ctx) {
+				return
+			}
+			s.setState(ConnDegraded)
+			outageStart = time.Time{}
+			backoff = s.opts.MinBackoff
+		}
+		if outageStart.IsZero() {
+			outageStart = time.Now()
+		}
+
+		err := s.d.redial(ctx)
+		if err == nil {
+			s.setState(ConnReady)
+			outageStart = time.Time{}
+			backoff = s.opts.MinBackoff
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(outageStart) >= s.opts.OutageWindow {
+			s.setState(ConnDown)
+		}
+
+		log.WithError(err).WithFields(log.Fields{
+			"device":  s.d.String(),
+			"backoff": backoff,
+		}).Debug("Reconnect failed, backing off")
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, s.opts.MaxBackoff)
+	}
+}
+
+// watch probes a ready connection until it fails, returning false if
+// supervision ended first
+func (s *supervisor) watch(ctx context.Context) bool {
+	// A failure reported before the connection came back is stale
+	select {
+	case <-s.lostCh:
+	default:
+	}
+
+	ticker := time.NewTicker(s.opts.KeepaliveInterval)
+	defer ticker.Stop()
+	for {
+		reader := s.d.activeReader()
+		var done <-chan struct{}
+		if reader != nil {
+			done = reader.done
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-s.lostCh:
+			return true
+		case <-done:
+			// The device closed the connection, unless we replaced it ourselves
+			if s.d.dropReader(reader) {
+				log.WithError(reader.err).WithField("device", s.d.String()).Debug("Connection closed by device")
+				return true
+			}
+		case <-ticker.C:
+			// A device in standby ignores the probe but still answers
+			if _, err := s.d.send(ctx, probeCommand); err != nil && !errors.Is(err, ErrStandby) {
+				if ctx.Err() != nil {
+					return false
+				}
+				log.WithError(err).WithField("device", s.d.String()).Debug("Keepalive probe failed")
+				return true
+			}
+		}
+	}
+}
+
+// stop marks supervision as ended and releases the commands waiting on it
+func (s *supervisor) stop() {
+	s.d.supMu.Lock()
+	if s.d.sup == s {
+		s.d.sup = nil
+	}
+	s.d.supMu.Unlock()
+
+	s.mu.Lock()
+	s.stopped = true
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.mu.Unlock()
+
+	log.WithField("device", s.d.String()).Debug("Stopped supervising device connection")
+}
+
+// activeReader returns the reader of the open connection, or nil
+func (d *Device) activeReader() *connReader {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.reader
+}
+
+// dropReader closes the connection of r if it is still the active one and
+// reports whether it was
+func (d *Device) dropReader(r *connReader) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.reader != r {
+		return false
+	}
+	if err := d.closeConn(); err != nil {
+		log.WithError(err).WithField("device", d.String()).Debug("Error closing dead connection")
+	}
+	return true
+}
+
+// redial replaces a missing or dead connection with a new one
+func (d *Device) redial(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.conn != nil {
+		select {
+		case <-d.reader.done:
+		default:
+			// Still alive, e.g. a command reconnected on its own
+			return nil
+		}
+		if err := d.closeConn(); err != nil {
+			log.WithError(err).WithField("device", d.String()).Debug("Error closing dead connection")
+		}
+	}
+
+	log.WithField("device", d.String()).Debug("Redialing device")
+	conn, err := d.newConn(ctx)
+	if err != nil {
+		return err
+	}
+	d.setConn(conn)
+	log.WithField("device", d.String()).Debug("Successfully redialed device")
+	return nil
+}
@@ -0,0 +1,190 @@
+package nadapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+// stateRecorder collects connection state transitions
+func stateRecorder() (func(old, new ConnState), <-chan ConnState) {
+	ch := make(chan ConnState, 32)
+	return func(_, new ConnState) { ch <- new }, ch
+}
+
+func waitForState(t *testing.T, states <-chan ConnState, want ConnState, within time.Duration) {
+	t.Helper()
+	timeout := time.After(within)
+	for {
+		select {
+		case got := <-states:
+			if got == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("connection never reached state %v", want)
+		}
+	}
+}
+
+func TestSuperviseReconnectsAfterDrop(t *testing.T) {
+	d, amp := connectAmp(t, fakeamp.Values(map[string]string{"Main.Model": "C338"}))
+
+	onChange, states := stateRecorder()
+	d.Supervise(context.Background(), SuperviseOptions{
+		MinBackoff:    10 * time.Millisecond,
+		OnStateChange: onChange,
+	})
+	if got := d.ConnState(); got != ConnReady {
+		t.Fatalf("ConnState() = %v, want %v", got, ConnReady)
+	}
+
+	amp.DropConns()
+	waitForState(t, states, ConnDegraded, 3*time.Second)
+	waitForState(t, states, ConnReady, 3*time.Second)
+
+	if _, err := d.GetModel(); err != nil {
+		t.Errorf("GetModel() after reconnect unexpected error: %v", err)
+	}
+}
+
+func TestSuperviseHoldsCommandsDuringOutage(t *testing.T) {
+	d, amp := connectAmp(t, fakeamp.Values(map[string]string{"Main.Model": "C338"}))
+
+	onChange, states := stateRecorder()
+	d.Supervise(context.Background(), SuperviseOptions{
+		MinBackoff:    10 * time.Millisecond,
+		MaxBackoff:    20 * time.Millisecond,
+		OutageWindow:  5 * time.Second,
+		OnStateChange: onChange,
+	})
+
+	amp.Stop()
+	waitForState(t, states, ConnDegraded, 3*time.Second)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := d.GetModel()
+		result <- err
+	}()
+
+	// The command must wait for the device rather than fail
+	select {
+	case err := <-result:
+		t.Fatalf("GetModel() returned %v during the outage, want it held", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	amp.Restart()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("GetModel() unexpected error after outage: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("GetModel() still held after the device came back")
+	}
+}
+
+func TestSuperviseReportsDown(t *testing.T) {
+	d, amp := connectAmp(t, fakeamp.Values(map[string]string{"Main.Model": "C338"}))
+
+	onChange, states := stateRecorder()
+	d.Supervise(context.Background(), SuperviseOptions{
+		MinBackoff:    10 * time.Millisecond,
+		MaxBackoff:    20 * time.Millisecond,
+		OutageWindow:  100 * time.Millisecond,
+		OnStateChange: onChange,
+	})
+
+	amp.Stop()
+	waitForState(t, states, ConnDown, 3*time.Second)
+
+	if _, err := d.GetModel(); !errors.Is(err, ErrDeviceDown) {
+		t.Errorf("GetModel() error = %v, want ErrDeviceDown", err)
+	}
+}
+
+func TestSuperviseKeepaliveDetectsDeadConnection(t *testing.T) {
+	if testing.Short() {
+		t.Skip("keepalive probe waits for the command timeout")
+	}
+
+	d, _ := connectAmp(t, fakeamp.Silent())
+
+	onChange, states := stateRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.Supervise(ctx, SuperviseOptions{
+		KeepaliveInterval: 10 * time.Millisecond,
+		OnStateChange:     onChange,
+	})
+
+	// The silent device never answers the probe, so it times out
+	waitForState(t, states, ConnDegraded, 2*commandTimeout)
+}
+
+func TestSuperviseKeepaliveInStandby(t *testing.T) {
+	if testing.Short() {
+		t.Skip("keepalive probe waits for the command timeout")
+	}
+
+	// A device in standby answers nothing but its power state
+	d, _ := connectAmp(t, fakeamp.Values(map[string]string{"Main.Power": "Off"}))
+	onChange, states := stateRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.Supervise(ctx, SuperviseOptions{
+		KeepaliveInterval: 10 * time.Millisecond,
+		OnStateChange:     onChange,
+	})
+
+	timeout := time.After(commandTimeout + 2*standbyCheckTimeout)
+	for {
+		select {
+		case got := <-states:
+			if got != ConnReady {
+				t.Fatalf("connection of a device in standby went %v", got)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+func TestSuperviseStopsOnDisconnect(t *testing.T) {
+	d, _ := connectAmp(t, fakeamp.Values(map[string]string{"Main.Model": "C338"}))
+	d.Supervise(context.Background(), SuperviseOptions{})
+
+	if err := d.Disconnect(); err != nil {
+		t.Fatalf("Disconnect() unexpected error: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for d.supervisor() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("supervisor still running after Disconnect()")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := d.ConnState(); got != ConnDown {
+		t.Errorf("ConnState() = %v, want %v", got, ConnDown)
+	}
+}
+
+func TestConnStateString(t *testing.T) {
+	tests := map[ConnState]string{
+		ConnConnecting: "connecting",
+		ConnReady:      "ready",
+		ConnDegraded:   "degraded",
+		ConnDown:       "down",
+	}
+	for state, want := range tests {
+		if got := state.String(); got != want {
+			t.Errorf("ConnState(%d).String() = %q, want %q", state, got, want)
+		}
+	}
+}
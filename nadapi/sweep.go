@@ -0,0 +1,384 @@
+package nadapi
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultSweepWorkers bounds the probes a sweep has in flight
+	defaultSweepWorkers = 64
+	// maxIPv6SweepBits is the largest IPv6 host part swept by default, a /120
+	maxIPv6SweepBits = 8
+	// maxRangeBits is the largest host part of a configured range, a /8
+	maxRangeBits = 24
+	// progressInterval is the time between progress reports of a sweep
+	progressInterval = 250 * time.Millisecond
+)
+
+// AddrRange is an inclusive range of IP addresses
+type AddrRange struct {
+	From netip.Addr
+	To   netip.Addr
+	// subnet is set for ranges given as a prefix, whose network and
+	// broadcast addresses are not hosts
+	subnet bool
+}
+
+// ParseAddrRange parses a CIDR subnet (10.2.30.0/24), an address range
+// (10.2.30.10-10.2.30.50) or a single address
+func ParseAddrRange(s string) (AddrRange, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return AddrRange{}, fmt.Errorf("invalid subnet %q: %w", s, err)
+		}
+		if p.Addr().BitLen()-p.Bits() > maxRangeBits {
+			return AddrRange{}, fmt.Errorf("invalid subnet %q: larger than a /%d", s, p.Addr().BitLen()-maxRangeBits)
+		}
+		return prefixRange(p), nil
+	}
+
+	from, to, isRange := strings.Cut(s, "-")
+	first, err := netip.ParseAddr(strings.TrimSpace(from))
+	if err != nil {
+		return AddrRange{}, fmt.Errorf("invalid address range %q: %w", s, err)
+	}
+	last := first
+	if isRange {
+		if last, err = netip.ParseAddr(strings.TrimSpace(to)); err != nil {
+			return AddrRange{}, fmt.Errorf("invalid address range %q: %w", s, err)
+		}
+	}
+	first, last = first.WithZone(""), last.WithZone("")
+	switch {
+	case first.BitLen() != last.BitLen():
+		return AddrRange{}, fmt.Errorf("invalid address range %q: mixes IPv4 and IPv6", s)
+	case last.Less(first):
+		return AddrRange{}, fmt.Errorf("invalid address range %q: ends before it starts", s)
+	}
+	if span := netip.PrefixFrom(first, first.BitLen()-maxRangeBits).Masked(); !span.Contains(last) {
+		return AddrRange{}, fmt.Errorf("invalid address range %q: larger than a /%d", s, first.BitLen()-maxRangeBits)
+	}
+	return AddrRange{From: first, To: last}, nil
+}
+
+// ParseAddrRanges parses a list of ranges as accepted by ParseAddrRange
+func ParseAddrRanges(specs []string) ([]AddrRange, error) {
+	var ranges []AddrRange
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		r, err := ParseAddrRange(spec)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// prefixRange returns the addresses of subnet p
+func prefixRange(p netip.Prefix) AddrRange {
+	p = p.Masked()
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	last, _ := netip.AddrFromSlice(b)
+	return AddrRange{From: p.Addr(), To: last, subnet: true}
+}
+
+// Contains reports whether ip is in the range
+func (r AddrRange) Contains(ip netip.Addr) bool {
+	ip = ip.Unmap().WithZone("")
+	return ip.BitLen() == r.From.BitLen() && !ip.Less(r.From) && !r.To.Less(ip)
+}
+
+// String returns the range as accepted by ParseAddrRange
+func (r AddrRange) String() string {
+	if r.From == r.To {
+		return r.From.String()
+	}
+	return r.From.String() + "-" + r.To.String()
+}
+
+// size returns the number of addresses in the range
+func (r AddrRange) size() int {
+	from, to := r.From.As16(), r.To.As16()
+	return int(binary.BigEndian.Uint64(to[8:])-binary.BigEndian.Uint64(from[8:])) + 1
+}
+
+// isHost reports whether ip is a host address of the range
+func (r AddrRange) isHost(ip netip.Addr) bool {
+	if !r.subnet || r.From.Next() == r.To || r.From == r.To {
+		// Point-to-point links and single addresses have no network address
+		return true
+	}
+	return ip != r.From && (!ip.Is4() || ip != r.To)
+}
+
+// SweepProvider finds devices by probing the control port of every address
+// in a set of ranges, by default the subnets of the local interfaces. It is
+// slow, so it runs after the announcement based providers to find the devices
+// that do not announce themselves.
+type SweepProvider struct {
+	Include    []AddrRange  // Ranges to sweep instead of the local subnets
+	Exclude    []AddrRange  // Ranges never to sweep
+	Interfaces []string     // Interfaces whose subnets are swept, all when empty
+	Port       string       // Control port, 30001 when empty
+	Workers    int          // Concurrent probes, 64 when zero
+	Rate       int          // Probes started per second, unlimited when zero
+	Match      ModelMatcher // Models to report, MatchKnownModels when nil
+}
+
+// Name returns "sweep"
+func (p *SweepProvider) Name() string { return "sweep" }
+
+// Discover sweeps the ranges until the sweep completes or ctx is done
+func (p *SweepProvider) Discover(ctx context.Context, events *DiscoveryEvents) ([]DiscoveredDevice, error) {
+	deadline, _ := ctx.Deadline()
+	log.WithField("deadline", deadline).Debug("Starting subnet sweep")
+
+	ranges, local := p.Include, []netip.Addr(nil)
+	if len(ranges) == 0 {
+		var err error
+		if ranges, local, err = p.localSubnets(); err != nil {
+			return nil, err
+		}
+	}
+
+	devices := p.sweep(ctx, ranges, events, func(ip netip.Addr) bool {
+		for _, r := range p.Exclude {
+			if r.Contains(ip) {
+				return true
+			}
+		}
+		for _, addr := range local {
+			if ip == addr {
+				return true
+			}
+		}
+		return false
+	})
+
+	log.WithFields(log.Fields{
+		"totalDevices": len(devices),
+		"deadline":     deadline,
+	}).Debug("Subnet sweep completed")
+
+	return devices, nil
+}
+
+// localSubnets returns the subnets of the selected interfaces and the
+// addresses of this host on them
+func (p *SweepProvider) localSubnets() ([]AddrRange, []netip.Addr, error) {
+	var interfaces []net.Interface
+	if len(p.Interfaces) == 0 {
+		all, err := net.Interfaces()
+		if err != nil {
+			log.WithError(err).Debug("Failed to get network interfaces")
+			return nil, nil, fmt.Errorf("failed to get network interfaces: %v", err)
+		}
+		interfaces = all
+	} else {
+		for _, name := range p.Interfaces {
+			iface, err := net.InterfaceByName(name)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unknown network interface %q: %v", name, err)
+			}
+			interfaces = append(interfaces, *iface)
+		}
+	}
+
+	log.WithField("interfaceCount", len(interfaces)).Debug("Retrieved network interfaces")
+
+	var ranges []AddrRange
+	var local []netip.Addr
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			log.WithFields(log.Fields{
+				"interface": iface.Name,
+				"flags":     iface.Flags,
+			}).Debug("Skipping interface (down or loopback)")
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			log.WithError(err).WithField("interface", iface.Name).Debug("Failed to get interface addresses")
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip, ok := netip.AddrFromSlice(ipNet.IP)
+			if !ok {
+				continue
+			}
+			ip = ip.Unmap()
+			ones, _ := ipNet.Mask.Size()
+			if ip.Is4() && ones >= 96 {
+				ones -= 96
+			}
+			// IPv6 prefixes are normally /64 and far too large to sweep
+			if ip.Is6() && ip.BitLen()-ones > maxIPv6SweepBits {
+				log.WithFields(log.Fields{
+					"interface": iface.Name,
+					"address":   addr.String(),
+				}).Debug("Skipping IPv6 subnet too large to scan")
+				continue
+			}
+
+			r := prefixRange(netip.PrefixFrom(ip, ones))
+			log.WithFields(log.Fields{
+				"interface": iface.Name,
+				"subnet":    r.String(),
+			}).Debug("Sweeping subnet for NAD devices")
+			ranges = append(ranges, r)
+			local = append(local, ip)
+		}
+	}
+	if len(p.Interfaces) > 0 && len(ranges) == 0 {
+		return nil, nil, errors.New("no sweepable subnet on the selected interfaces")
+	}
+	return ranges, local, nil
+}
+
+// sweep probes every host address of ranges that skip does not reject with
+// a bounded pool of workers, starting at most Rate probes a second, and
+// reports devices and progress to events
+func (p *SweepProvider) sweep(ctx context.Context, ranges []AddrRange, events *DiscoveryEvents, skip func(netip.Addr) bool) []DiscoveredDevice {
+	port := p.Port
+	if port == "" {
+		port = defaultPort
+	}
+	workers := p.Workers
+	if workers <= 0 {
+		workers = defaultSweepWorkers
+	}
+	var limiter <-chan time.Time
+	if p.Rate > 0 {
+		interval := max(time.Second/time.Duration(p.Rate), time.Microsecond)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	log.WithFields(log.Fields{
+		"ranges":  len(ranges),
+		"workers": workers,
+		"rate":    p.Rate,
+		"port":    port,
+	}).Debug("Sweeping address ranges")
+
+	total := 0
+	for _, r := range ranges {
+		total += r.size()
+	}
+	// done counts the addresses probed or skipped
+	var done atomic.Int64
+	var subnet atomic.Pointer[string]
+	progress := func() DiscoveryProgress {
+		pr := DiscoveryProgress{Provider: p.Name(), Probed: int(done.Load()), Total: total}
+		if s := subnet.Load(); s != nil {
+			pr.Subnet = *s
+		}
+		return pr
+	}
+	stopProgress, progressDone := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(progressDone)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				events.Progress(progress())
+			case <-stopProgress:
+				return
+			}
+		}
+	}()
+
+	targets := make(chan netip.Addr)
+	go func() {
+		defer close(targets)
+		scanned := 0
+		for _, r := range ranges {
+			name := r.String()
+			subnet.Store(&name)
+			for ip := r.From; ip.IsValid() && !r.To.Less(ip); ip = ip.Next() {
+				if !r.isHost(ip) || skip(ip) {
+					done.Add(1)
+					continue
+				}
+				if limiter != nil {
+					select {
+					case <-limiter:
+					case <-ctx.Done():
+						return
+					}
+				}
+				select {
+				case targets <- ip:
+					scanned++
+				case <-ctx.Done():
+					log.WithFields(log.Fields{
+						"range":      r.String(),
+						"scannedIPs": scanned,
+						"error":      ctx.Err(),
+					}).Debug("Subnet sweep cancelled due to context")
+					return
+				}
+			}
+		}
+		log.WithField("scannedIPs", scanned).Debug("All addresses queued for sweep")
+	}()
+
+	var devices []DiscoveredDevice
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ip := range targets {
+				device := identify(ctx, ip.String(), port, p.Name(), p.Match)
+				done.Add(1)
+				if device == nil {
+					continue
+				}
+				mu.Lock()
+				devices = append(devices, *device)
+				mu.Unlock()
+				log.WithFields(log.Fields{
+					"ip":    device.IP,
+					"model": device.Model,
+				}).Debug("Found NAD device")
+				events.Device(*device)
+			}
+		}()
+	}
+	wg.Wait()
+	close(stopProgress)
+	<-progressDone
+	events.Progress(progress())
+
+	return mergeDevices(devices)
+}
@@ -0,0 +1,176 @@
+package nadapi
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+func TestParseAddrRange(t *testing.T) {
+	tests := []struct {
+		spec     string
+		from, to string
+		wantErr  bool
+	}{
+		{spec: "10.2.30.0/24", from: "10.2.30.0", to: "10.2.30.255"},
+		{spec: "10.2.30.17/28", from: "10.2.30.16", to: "10.2.30.31"},
+		{spec: "10.2.30.10-10.2.30.50", from: "10.2.30.10", to: "10.2.30.50"},
+		{spec: " 10.2.30.7 ", from: "10.2.30.7", to: "10.2.30.7"},
+		{spec: "fd00::/120", from: "fd00::", to: "fd00::ff"},
+		{spec: "10.0.0.0/8", from: "10.0.0.0", to: "10.255.255.255"},
+		{spec: "10.0.0.0/7", wantErr: true},
+		{spec: "10.0.0.1-11.0.0.1", wantErr: true},
+		{spec: "10.2.30.50-10.2.30.10", wantErr: true},
+		{spec: "10.2.30.1-fd00::1", wantErr: true},
+		{spec: "fd00::/64", wantErr: true},
+		{spec: "office", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			r, err := ParseAddrRange(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseAddrRange(%q) = %v, want error", tt.spec, r)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAddrRange(%q) unexpected error: %v", tt.spec, err)
+			}
+			if r.From.String() != tt.from || r.To.String() != tt.to {
+				t.Errorf("ParseAddrRange(%q) = %v, want %s-%s", tt.spec, r, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestAddrRangeHosts(t *testing.T) {
+	subnet, _ := ParseAddrRange("192.168.1.0/30")
+	explicit, _ := ParseAddrRange("192.168.1.0-192.168.1.3")
+	p2p, _ := ParseAddrRange("192.168.1.0/31")
+	for _, tt := range []struct {
+		r    AddrRange
+		ip   string
+		want bool
+	}{
+		{subnet, "192.168.1.0", false},
+		{subnet, "192.168.1.1", true},
+		{subnet, "192.168.1.3", false},
+		{explicit, "192.168.1.0", true},
+		{explicit, "192.168.1.3", true},
+		{p2p, "192.168.1.0", true},
+	} {
+		if got := tt.r.isHost(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("%v.isHost(%s) = %v, want %v", tt.r, tt.ip, got, tt.want)
+		}
+	}
+
+	if !subnet.Contains(netip.MustParseAddr("::ffff:192.168.1.2")) {
+		t.Errorf("%v.Contains(::ffff:192.168.1.2) = false, want true", subnet)
+	}
+	if subnet.Contains(netip.MustParseAddr("192.168.1.4")) {
+		t.Errorf("%v.Contains(192.168.1.4) = true, want false", subnet)
+	}
+}
+
+// sweepAmp starts a slow C338 on every loopback address, for the probes of
+// a sweep to find
+func sweepAmp(t *testing.T) *fakeamp.Amp {
+	return fakeamp.Start(t, fakeamp.AllAddresses(), fakeamp.Delay(20*time.Millisecond),
+		fakeamp.Values(map[string]string{"Main.Model": "C338", "Main.Version": "V2.03"}))
+}
+
+func TestSweepProviderBoundsWorkers(t *testing.T) {
+	amp := sweepAmp(t)
+	port := amp.Port
+	include, _ := ParseAddrRanges([]string{"127.0.0.1-127.0.0.12"})
+	exclude, _ := ParseAddrRanges([]string{"127.0.0.5", "127.0.0.10-127.0.0.11"})
+
+	p := &SweepProvider{Include: include, Exclude: exclude, Port: port, Workers: 3}
+	devices, err := p.Discover(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Discover() unexpected error: %v", err)
+	}
+	if len(devices) != 9 {
+		t.Errorf("Discover() found %d devices, want 9", len(devices))
+	}
+	for _, dev := range devices {
+		ip := netip.MustParseAddr(dev.IP)
+		for _, r := range exclude {
+			if r.Contains(ip) {
+				t.Errorf("Discover() probed excluded address %s", ip)
+			}
+		}
+		if dev.Port != port {
+			t.Errorf("Discover() device port = %q, want %q", dev.Port, port)
+		}
+	}
+	if got := amp.PeakInFlight(); got > 3 {
+		t.Errorf("sweep had %d probes in flight, want at most 3", got)
+	}
+}
+
+func TestSweepProviderReportsProgress(t *testing.T) {
+	port := sweepAmp(t).Port
+	include, _ := ParseAddrRanges([]string{"127.0.0.0/29"})
+
+	var mu sync.Mutex
+	var streamed []DiscoveredDevice
+	var last DiscoveryProgress
+	events := &DiscoveryEvents{
+		OnDevice: func(dev DiscoveredDevice) {
+			mu.Lock()
+			streamed = append(streamed, dev)
+			mu.Unlock()
+		},
+		OnProgress: func(p DiscoveryProgress) {
+			mu.Lock()
+			last = p
+			mu.Unlock()
+		},
+	}
+
+	p := &SweepProvider{Include: include, Port: port}
+	devices, err := p.Discover(context.Background(), events)
+	if err != nil {
+		t.Fatalf("Discover() unexpected error: %v", err)
+	}
+	if len(devices) != 6 || len(streamed) != 6 {
+		t.Errorf("Discover() returned %d devices and streamed %d, want 6 and 6", len(devices), len(streamed))
+	}
+	// The network and broadcast addresses count as done without a probe
+	want := DiscoveryProgress{Provider: "sweep", Subnet: "127.0.0.0-127.0.0.7", Probed: 8, Total: 8}
+	if last != want {
+		t.Errorf("last progress = %+v, want %+v", last, want)
+	}
+}
+
+func TestSweepProviderRateLimit(t *testing.T) {
+	port := sweepAmp(t).Port
+	include, _ := ParseAddrRanges([]string{"127.0.0.1-127.0.0.5"})
+
+	p := &SweepProvider{Include: include, Port: port, Rate: 50}
+	start := time.Now()
+	devices, err := p.Discover(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Discover() unexpected error: %v", err)
+	}
+	if len(devices) != 5 {
+		t.Errorf("Discover() found %d devices, want 5", len(devices))
+	}
+	// Five probes at 50 a second cannot all start within 80ms
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("sweep of 5 addresses at 50/s took %v, want at least 80ms", elapsed)
+	}
+}
+
+func TestSweepProviderUnknownInterface(t *testing.T) {
+	p := &SweepProvider{Interfaces: []string{"nadctl-missing0"}}
+	if _, err := p.Discover(context.Background(), nil); err == nil {
+		t.Error("Discover() with an unknown interface succeeded, want error")
+	}
+}
@@ -0,0 +1,284 @@
+package nadapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Tone control ranges, in dB
+const (
+	MinTone    = -6
+	MaxTone    = 6
+	MinBalance = -18 // fully left
+	MaxBalance = 18  // fully right
+)
+
+// Tone holds the tone control settings of the main zone
+type Tone struct {
+	Bass    int  `json:"bass_db"`
+	Treble  int  `json:"treble_db"`
+	Balance int  `json:"balance_db"`
+	Defeat  bool `json:"tone_defeat"` // true bypasses bass and treble
+}
+
+// GetBass retrieves the bass level in dB
+func (d *Device) GetBass() (int, error) {
+	return d.GetBassContext(context.Background())
+}
+
+// GetBassContext is like GetBass but takes a context
+func (d *Device) GetBassContext(ctx context.Context) (int, error) {
+	return d.getLevel(ctx, "Main.Bass")
+}
+
+// SetBass sets the bass level in dB, between MinTone and MaxTone
+func (d *Device) SetBass(db int) error {
+	return d.SetBassContext(context.Background(), db)
+}
+
+// SetBassContext is like SetBass but takes a context
+func (d *Device) SetBassContext(ctx context.Context, db int) error {
+	return d.setLevel(ctx, "Main.Bass", db, MinTone, MaxTone)
+}
+
+// TuneBass raises or lowers the bass by 1 dB, stopping at the range limits
+func (d *Device) TuneBass(direction Direction) error {
+	return d.TuneBassContext(context.Background(), direction)
+}
+
+// TuneBassContext is like TuneBass but takes a context
+func (d *Device) TuneBassContext(ctx context.Context, direction Direction) error {
+	return d.tuneLevel(ctx, "Main.Bass", direction, MinTone, MaxTone)
+}
+
+// GetTreble retrieves the treble level in dB
+func (d *Device) GetTreble() (int, error) {
+	return d.GetTrebleContext(context.Background())
+}
+
+// GetTrebleContext is like GetTreble but takes a context
+func (d *Device) GetTrebleContext(ctx context.Context) (int, error) {
+	return d.getLevel(ctx, "Main.Treble")
+}
+
+// SetTreble sets the treble level in dB, between MinTone and MaxTone
+func (d *Device) SetTreble(db int) error {
+	return d.SetTrebleContext(context.Background(), db)
+}
+
+// SetTrebleContext is like SetTreble but takes a context
+func (d *Device) SetTrebleContext(ctx context.Context, db int) error {
+	return d.setLevel(ctx, "Main.Treble", db, MinTone, MaxTone)
+}
+
+// TuneTreble raises or lowers the treble by 1 dB, stopping at the range limits
+func (d *Device) TuneTreble(direction Direction) error {
+	return d.TuneTrebleContext(context.Background(), direction)
+}
+
+// TuneTrebleContext is like TuneTreble but takes a context
+func (d *Device) TuneTrebleContext(ctx context.Context, direction Direction) error {
+	return d.tuneLevel(ctx, "Main.Treble", direction, MinTone, MaxTone)
+}
+
+// GetBalance retrieves the balance in dB; negative values favour the left
+// channel
+func (d *Device) GetBalance() (int, error) {
+	return d.GetBalanceContext(context.Background())
+}
+
+// GetBalanceContext is like GetBalance but takes a context
+func (d *Device) GetBalanceContext(ctx context.Context) (int, error) {
+	return d.getLevel(ctx, "Main.Balance")
+}
+
+// SetBalance sets the balance in dB, between MinBalance and MaxBalance
+func (d *Device) SetBalance(db int) error {
+	return d.SetBalanceContext(context.Background(), db)
+}
+
+// SetBalanceContext is like SetBalance but takes a context
+func (d *Device) SetBalanceContext(ctx context.Context, db int) error {
+	return d.setLevel(ctx, "Main.Balance", db, MinBalance, MaxBalance)
+}
+
+// TuneBalance shifts the balance by 1 dB; DirectionUp moves it right
+func (d *Device) TuneBalance(direction Direction) error {
+	return d.TuneBalanceContext(context.Background(), direction)
+}
+
+// TuneBalanceContext is like TuneBalance but takes a context
+func (d *Device) TuneBalanceContext(ctx context.Context, direction Direction) error {
+	return d.tuneLevel(ctx, "Main.Balance", direction, MinBalance, MaxBalance)
+}
+
+// GetToneDefeat reports whether tone defeat is on
+func (d *Device) GetToneDefeat() (bool, error) {
+	return d.GetToneDefeatContext(context.Background())
+}
+
+// GetToneDefeatContext is like GetToneDefeat but takes a context
+func (d *Device) GetToneDefeatContext(ctx context.Context) (bool, error) {
+	if err := d.requireFeature(ctx, FeatureToneControls); err != nil {
+		return false, err
+	}
+	res, err := d.send(ctx, "Main.ToneDefeat?")
+	if err != nil {
+		return false, fmt.Errorf("get tone defeat: %w", err)
+	}
+	val, err := extractValue(res)
+	if err != nil {
+		return false, fmt.Errorf("get tone defeat: %w", err)
+	}
+	on, err := parseOnOff(val)
+	if err != nil {
+		return false, fmt.Errorf("get tone defeat: %w", err)
+	}
+	return on, nil
+}
+
+// SetToneDefeat turns tone defeat on or off
+func (d *Device) SetToneDefeat(on bool) error {
+	return d.SetToneDefeatContext(context.Background(), on)
+}
+
+// SetToneDefeatContext is like SetToneDefeat but takes a context
+func (d *Device) SetToneDefeatContext(ctx context.Context, on bool) error {
+	if err := d.requireFeature(ctx, FeatureToneControls); err != nil {
+		return err
+	}
+	val := "Off"
+	if on {
+		val = "On"
+	}
+	log.WithFields(log.Fields{
+		"device": d.String(),
+		"value":  val,
+	}).Debug("Setting tone defeat")
+	_, err := d.send(ctx, "Main.ToneDefeat="+val)
+	return err
+}
+
+// ToggleToneDefeat flips tone defeat
+func (d *Device) ToggleToneDefeat() error {
+	return d.ToggleToneDefeatContext(context.Background())
+}
+
+// ToggleToneDefeatContext is like ToggleToneDefeat but takes a context
+func (d *Device) ToggleToneDefeatContext(ctx context.Context) error {
+	on, err := d.GetToneDefeatContext(ctx)
+	if err != nil {
+		return err
+	}
+	return d.SetToneDefeatContext(ctx, !on)
+}
+
+// Tone retrieves bass, treble, balance and tone defeat in one call
+func (d *Device) Tone() (Tone, error) {
+	return d.ToneContext(context.Background())
+}
+
+// ToneContext is like Tone but takes a context. The keys are queried in a
+// single round trip.
+func (d *Device) ToneContext(ctx context.Context) (Tone, error) {
+	if err := d.requireFeature(ctx, FeatureToneControls); err != nil {
+		return Tone{}, err
+	}
+	values, err := d.QueryContext(ctx, "Main.Bass", "Main.Treble", "Main.Balance", "Main.ToneDefeat")
+	if err != nil {
+		return Tone{}, fmt.Errorf("get tone: %w", err)
+	}
+
+	var t Tone
+	if t.Bass, err = parseLevel("Main.Bass", values["Main.Bass"]); err != nil {
+		return Tone{}, err
+	}
+	if t.Treble, err = parseLevel("Main.Treble", values["Main.Treble"]); err != nil {
+		return Tone{}, err
+	}
+	if t.Balance, err = parseLevel("Main.Balance", values["Main.Balance"]); err != nil {
+		return Tone{}, err
+	}
+	if t.Defeat, err = parseOnOff(values["Main.ToneDefeat"]); err != nil {
+		return Tone{}, fmt.Errorf("get tone defeat: %w", err)
+	}
+	return t, nil
+}
+
+// getLevel queries a dB level such as Main.Bass. Some firmware reports
+// fractional values, which are rounded.
+func (d *Device) getLevel(ctx context.Context, key string) (int, error) {
+	if err := d.requireFeature(ctx, FeatureToneControls); err != nil {
+		return 0, err
+	}
+	log.WithFields(log.Fields{
+		"device": d.String(),
+		"key":    key,
+	}).Debug("Getting tone level")
+
+	res, err := d.send(ctx, key+"?")
+	if err != nil {
+		return 0, fmt.Errorf("get %s: %w", key, err)
+	}
+	val, err := extractValue(res)
+	if err != nil {
+		return 0, fmt.Errorf("get %s: %w", key, err)
+	}
+	return parseLevel(key, val)
+}
+
+// parseLevel converts the value of a dB level such as Main.Bass
+func parseLevel(key, val string) (int, error) {
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, fmt.Errorf("get %s: failed to parse %q: %w", key, val, err)
+	}
+	return int(math.Round(f)), nil
+}
+
+// setLevel sets a dB level such as Main.Bass after checking it is in range
+func (d *Device) setLevel(ctx context.Context, key string, db, min, max int) error {
+	if db < min || db > max {
+		return withKind(ErrInvalidValue, fmt.Errorf("invalid %s level %d. Valid range: %d to %d dB", key, db, min, max))
+	}
+	if err := d.requireFeature(ctx, FeatureToneControls); err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"device": d.String(),
+		"key":    key,
+		"level":  db,
+	}).Debug("Setting tone level")
+
+	_, err := d.send(ctx, fmt.Sprintf("%s=%d", key, db))
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"device": d.String(),
+			"key":    key,
+		}).Debug("Failed to set tone level")
+	}
+	return err
+}
+
+// tuneLevel steps a dB level by direction, leaving it alone at the limits
+func (d *Device) tuneLevel(ctx context.Context, key string, direction Direction, min, max int) error {
+	level, err := d.getLevel(ctx, key)
+	if err != nil {
+		return err
+	}
+	newLevel := level + int(direction)
+	if newLevel < min || newLevel > max {
+		log.WithFields(log.Fields{
+			"device": d.String(),
+			"key":    key,
+			"level":  level,
+		}).Debug("Tone level already at limit")
+		return nil
+	}
+	return d.setLevel(ctx, key, newLevel, min, max)
+}
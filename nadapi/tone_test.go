@@ -0,0 +1,87 @@
+package nadapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+func TestTone(t *testing.T) {
+	d, _ := connectAmp(t, fakeamp.Values(map[string]string{
+		"Main.Model":      "C658",
+		"Main.Bass":       "2.6", // fractional values are rounded
+		"Main.Treble":     "-3",
+		"Main.Balance":    "-5",
+		"Main.ToneDefeat": "On",
+	}))
+
+	got, err := d.Tone()
+	if err != nil {
+		t.Fatalf("Tone() unexpected error: %v", err)
+	}
+	want := Tone{Bass: 3, Treble: -3, Balance: -5, Defeat: true}
+	if got != want {
+		t.Errorf("Tone() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSetToneLevelValidatesRange(t *testing.T) {
+	d, _ := connectAmp(t, fakeamp.Values(map[string]string{"Main.Model": "C658"}))
+
+	tests := []struct {
+		name string
+		set  func(int) error
+		db   int
+	}{
+		{"bass", d.SetBass, MaxTone + 1},
+		{"treble", d.SetTreble, MinTone - 1},
+		{"balance", d.SetBalance, MaxBalance + 1},
+	}
+
+	for _, tt := range tests {
+		err := tt.set(tt.db)
+		if err == nil || !strings.Contains(err.Error(), "Valid range") {
+			t.Errorf("set %s %d error = %v, want range error", tt.name, tt.db, err)
+		}
+	}
+}
+
+func TestSetBassSendsLevel(t *testing.T) {
+	d, amp := connectAmp(t, fakeamp.Values(map[string]string{"Main.Model": "C658", "Main.Bass": "0"}))
+
+	if err := d.SetBass(-4); err != nil {
+		t.Errorf("SetBass(-4) unexpected error: %v", err)
+	}
+	if got := amp.Value("Main.Bass"); got != "-4" {
+		t.Errorf("bass after SetBass(-4) = %q, want -4", got)
+	}
+}
+
+func TestToneRequiresFeature(t *testing.T) {
+	d, _ := connectAmp(t, fakeamp.Values(map[string]string{"Main.Model": "C338"}))
+
+	_, err := d.GetBass()
+	if err == nil || !strings.Contains(err.Error(), "does not support") {
+		t.Errorf("GetBass() on C338 error = %v, want unsupported feature", err)
+	}
+	err = d.SetToneDefeat(true)
+	if err == nil || !strings.Contains(err.Error(), "does not support") {
+		t.Errorf("SetToneDefeat() on C338 error = %v, want unsupported feature", err)
+	}
+}
+
+func TestToneAllowedOnUnknownModel(t *testing.T) {
+	d, _ := connectAmp(t, fakeamp.Values(map[string]string{
+		"Main.Model":  "M33",
+		"Main.Treble": "1",
+	}))
+
+	got, err := d.GetTreble()
+	if err != nil {
+		t.Fatalf("GetTreble() on unknown model unexpected error: %v", err)
+	}
+	if got != 1 {
+		t.Errorf("GetTreble() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,192 @@
+package nadapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultBaudRate is the RS-232 speed of NAD amplifiers
+const defaultBaudRate = 115200
+
+// Transport opens connections to a device. Each call to Dial returns a new
+// connection; the device closes the previous one before dialing again.
+type Transport interface {
+	Dial(ctx context.Context) (net.Conn, error)
+	// String returns the transport as an address URI, e.g. tcp://host:30001
+	String() string
+}
+
+// TCPTransport reaches a device over the network control port
+type TCPTransport struct {
+	Host string // IP address or host name, resolved again on every dial
+	Port string
+}
+
+// Dial opens a TCP connection to the device
+func (t *TCPTransport) Dial(ctx context.Context) (net.Conn, error) {
+	connString := net.JoinHostPort(t.Host, t.Port)
+	log.WithField("connString", connString).Debug("Creating new TCP connection")
+
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", connString)
+	if err != nil {
+		log.WithError(err).WithField("connString", connString).Debug("Failed to create TCP connection")
+	} else {
+		log.WithFields(log.Fields{
+			"connString": connString,
+			"remoteAddr": conn.RemoteAddr().String(),
+		}).Debug("Successfully created TCP connection")
+	}
+	return conn, err
+}
+
+func (t *TCPTransport) String() string {
+	return "tcp://" + net.JoinHostPort(t.Host, t.Port)
+}
+
+// SerialTransport reaches a device over its RS-232 port at 8N1
+type SerialTransport struct {
+	Path     string // Device path, e.g. /dev/ttyUSB0
+	BaudRate int    // Line speed, 115200 when zero
+}
+
+// Dial opens and configures the serial port
+func (t *SerialTransport) Dial(ctx context.Context) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	baud := t.BaudRate
+	if baud == 0 {
+		baud = defaultBaudRate
+	}
+	log.WithFields(log.Fields{
+		"path": t.Path,
+		"baud": baud,
+	}).Debug("Opening serial port")
+
+	conn, err := OpenSerial(t.Path, baud)
+	if err != nil {
+		log.WithError(err).WithField("path", t.Path).Debug("Failed to open serial port")
+	} else {
+		log.WithField("path", t.Path).Debug("Successfully opened serial port")
+	}
+	return conn, err
+}
+
+func (t *SerialTransport) String() string {
+	s := "serial://" + t.Path
+	if t.BaudRate != 0 && t.BaudRate != defaultBaudRate {
+		s += "?baud=" + strconv.Itoa(t.BaudRate)
+	}
+	return s
+}
+
+// serialAddr is the net.Addr of a serial port
+type serialAddr string
+
+func (a serialAddr) Network() string { return "serial" }
+func (a serialAddr) String() string  { return string(a) }
+
+// ParseAddress returns the transport for a configured device address:
+// tcp://host:port, serial:///dev/ttyUSB0 (optionally with ?baud=N), or a
+// plain IPv4 address, IPv6 address or host name reached on port, or on the
+// default port if port is empty. An address it cannot parse is an
+// ErrInvalidValue.
+func ParseAddress(addr, port string) (Transport, error) {
+	t, err := parseAddress(addr, port)
+	return t, withKind(ErrInvalidValue, err)
+}
+
+func parseAddress(addr, port string) (Transport, error) {
+	if port == "" {
+		port = defaultPort
+	}
+
+	if !strings.Contains(addr, "://") {
+		host, err := parseHost(addr)
+		if err != nil {
+			return nil, err
+		}
+		return &TCPTransport{Host: host, Port: port}, nil
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device address %q: %w", addr, err)
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		host, err := parseHost(u.Hostname())
+		if err != nil {
+			return nil, fmt.Errorf("invalid device address %q: %w", addr, err)
+		}
+		p := u.Port()
+		if p == "" {
+			p = port
+		}
+		return &TCPTransport{Host: host, Port: p}, nil
+	case "serial":
+		path := u.Path
+		if u.Host != "" {
+			// serial://ttyUSB0 names a device relative to /dev
+			path = "/dev/" + u.Host + u.Path
+		}
+		if path == "" {
+			return nil, fmt.Errorf("invalid device address %q: missing serial device path", addr)
+		}
+		t := &SerialTransport{Path: path}
+		if b := u.Query().Get("baud"); b != "" {
+			baud, err := strconv.Atoi(b)
+			if err != nil || baud <= 0 {
+				return nil, fmt.Errorf("invalid device address %q: bad baud rate %q", addr, b)
+			}
+			t.BaudRate = baud
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("invalid device address %q: unsupported scheme %q (want tcp or serial)", addr, u.Scheme)
+	}
+}
+
+// parseHost validates the host part of a device address: an IPv4 or IPv6
+// address, optionally in brackets and with a zone, or a host name
+func parseHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return ip.String(), nil
+	}
+	if !isHostname(host) {
+		return "", errors.New("failed to parse address: not an IP address or host name")
+	}
+	return host, nil
+}
+
+// isHostname reports whether s is a syntactically valid DNS host name
+func isHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			switch {
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
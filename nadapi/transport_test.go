@@ -0,0 +1,121 @@
+package nadapi
+
+import (
+	"net"
+	"testing"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     string
+		port     string
+		expected string
+		hasError bool
+	}{
+		{"Plain IP uses default port", "192.168.1.10", "", "tcp://192.168.1.10:30001", false},
+		{"Plain IP with port", "192.168.1.10", "30002", "tcp://192.168.1.10:30002", false},
+		{"TCP URI", "tcp://192.168.1.10:30003", "", "tcp://192.168.1.10:30003", false},
+		{"TCP URI without port", "tcp://192.168.1.10", "", "tcp://192.168.1.10:30001", false},
+		{"TCP URI with IPv6", "tcp://[::1]:30001", "", "tcp://[::1]:30001", false},
+		{"Serial URI", "serial:///dev/ttyUSB0", "", "serial:///dev/ttyUSB0", false},
+		{"Serial URI relative to /dev", "serial://ttyUSB1", "", "serial:///dev/ttyUSB1", false},
+		{"Serial URI with baud", "serial:///dev/ttyS0?baud=9600", "", "serial:///dev/ttyS0?baud=9600", false},
+		{"Serial URI with default baud", "serial:///dev/ttyS0?baud=115200", "", "serial:///dev/ttyS0", false},
+		{"Host name", "amp.lan", "", "tcp://amp.lan:30001", false},
+		{"TCP URI with host name", "tcp://amp.lan:30002", "", "tcp://amp.lan:30002", false},
+		{"Plain IPv6", "fe80::1", "", "tcp://[fe80::1]:30001", false},
+		{"Bracketed IPv6", "[2001:db8::10]", "30002", "tcp://[2001:db8::10]:30002", false},
+		{"IPv6 with zone", "fe80::1%eth0", "", "tcp://[fe80::1%eth0]:30001", false},
+		{"Invalid host", "invalid ip!", "", "", true},
+		{"Host name with empty label", "amp..lan", "", "", true},
+		{"Empty address", "", "", "", true},
+		{"Unknown scheme", "udp://192.168.1.10:30001", "", "", true},
+		{"Serial without path", "serial://", "", "", true},
+		{"Serial with bad baud", "serial:///dev/ttyS0?baud=fast", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := ParseAddress(tt.addr, tt.port)
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("ParseAddress(%q, %q) expected error, got %v", tt.addr, tt.port, tr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAddress(%q, %q) unexpected error: %v", tt.addr, tt.port, err)
+			}
+			if got := tr.String(); got != tt.expected {
+				t.Errorf("ParseAddress(%q, %q) = %q, want %q", tt.addr, tt.port, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewSetsIPForTCP(t *testing.T) {
+	amp := fakeamp.Start(t, fakeamp.Silent())
+	ip, port := amp.Host, amp.Port
+	d, err := New("tcp://"+ip+":"+port, "")
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	defer d.Disconnect()
+
+	if d.IP.String() != ip || d.Port != port {
+		t.Errorf("New() IP/Port = %s/%s, want %s/%s", d.IP, d.Port, ip, port)
+	}
+	if _, ok := d.Transport().(*TCPTransport); !ok {
+		t.Errorf("Transport() = %T, want *TCPTransport", d.Transport())
+	}
+}
+
+func TestNewWithHostname(t *testing.T) {
+	port := fakeamp.Start(t, fakeamp.Silent()).Port
+	d, err := New("localhost", port)
+	if err != nil {
+		t.Skipf("localhost does not resolve here: %v", err)
+	}
+	defer d.Disconnect()
+
+	if d.Host != "localhost" {
+		t.Errorf("Host = %q, want %q", d.Host, "localhost")
+	}
+	if d.IP != nil {
+		t.Errorf("IP = %v, want nil for a host name", d.IP)
+	}
+	if got := d.String(); got != "tcp://localhost:"+port {
+		t.Errorf("String() = %q, want %q", got, "tcp://localhost:"+port)
+	}
+}
+
+func TestNewWithIPv6(t *testing.T) {
+	ln, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	d, err := New("::1", port)
+	if err != nil {
+		t.Fatalf("New(::1, %s) unexpected error: %v", port, err)
+	}
+	defer d.Disconnect()
+
+	if !d.IP.Equal(net.ParseIP("::1")) {
+		t.Errorf("IP = %v, want ::1", d.IP)
+	}
+}
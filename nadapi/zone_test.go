@@ -0,0 +1,101 @@
+package nadapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+)
+
+func TestParseZone(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ZoneID
+		wantErr bool
+	}{
+		{"", MainZone, false},
+		{"main", MainZone, false},
+		{"Zone2", Zone2, false},
+		{"2", Zone2, false},
+		{"zone3", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseZone(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseZone(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCapabilitiesZones(t *testing.T) {
+	tests := []struct {
+		model string
+		want  []ZoneID
+	}{
+		{"C338", []ZoneID{MainZone}},
+		{"T 758", []ZoneID{MainZone, Zone2}},
+	}
+
+	for _, tt := range tests {
+		caps, _ := LookupCapabilities(tt.model)
+		if got := caps.Zones(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Zones() for %s = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestZoneState(t *testing.T) {
+	replies := map[string]string{
+		"Main.Model":   "T 758",
+		"Zone2.Power":  "On",
+		"Zone2.Volume": "-30",
+		"Zone2.Source": "HDMI2",
+		"Zone2.Mute":   "On",
+	}
+	addInputReplies(replies, "T 758", map[int]string{2: "Kitchen TV"}, nil)
+	d, _ := connectAmp(t, fakeamp.Values(replies))
+
+	got, err := d.Zone(Zone2).State()
+	if err != nil {
+		t.Fatalf("Zone(Zone2).State() unexpected error: %v", err)
+	}
+	want := ZoneState{
+		Zone:       Zone2,
+		Power:      PowerOn,
+		Volume:     -30,
+		Source:     "HDMI2",
+		SourceName: "Kitchen TV",
+		Muted:      true,
+	}
+	if got != want {
+		t.Errorf("Zone(Zone2).State() = %+v, want %+v", got, want)
+	}
+}
+
+func TestZone2RequiresFeature(t *testing.T) {
+	d, _ := connectAmp(t, fakeamp.Values(map[string]string{"Main.Model": "C338"}))
+
+	_, err := d.Zone(Zone2).GetPower()
+	if err == nil || !strings.Contains(err.Error(), "does not support") {
+		t.Errorf("Zone(Zone2).GetPower() on C338 error = %v, want unsupported feature", err)
+	}
+}
+
+func TestEventZone(t *testing.T) {
+	tests := []struct {
+		key  string
+		want ZoneID
+	}{
+		{"Main.Volume", MainZone},
+		{"Zone2.Power", Zone2},
+		{"Source4.Name", ""},
+	}
+
+	for _, tt := range tests {
+		if got := (Event{Key: tt.key}).Zone(); got != tt.want {
+			t.Errorf("Event{Key: %q}.Zone() = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
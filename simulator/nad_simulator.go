@@ -1,49 +1,94 @@
 package simulator
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+
+	"github.com/galamiram/nadctl/nadapi"
+	"github.com/galamiram/nadctl/nadapi/protocol"
 )
 
 // NADSimulator simulates a NAD receiver for testing
 type NADSimulator struct {
 	listener    net.Listener
+	ptySlave    net.Conn // Slave side of the pseudo terminal, held open while serving one
 	state       *DeviceState
+	caps        nadapi.Capabilities // Profile of the simulated model
 	stateMutex  sync.RWMutex
 	connections map[net.Conn]bool
 	connMutex   sync.RWMutex
-	running     bool
+	writeMutex  sync.Mutex  // Serializes writes to client connections
+	running     atomic.Bool // Read by the connection goroutines while Stop clears it
 	stopChan    chan bool
 }
 
 // DeviceState holds the simulated device state
 type DeviceState struct {
 	Power      string  // "On" or "Off"
-	Volume     float64 // Volume in dB (-80 to +10)
+	Volume     float64 // Volume in dB, within the range of the model
 	Source     string  // Current input source
 	Mute       string  // "On" or "Off"
-	Brightness int     // Display brightness (0-3)
+	Brightness int     // Display brightness (0 to the model maximum)
 	Model      string  // Device model
+	Version    string  // Firmware version
+
+	// Tone controls, answered only for models with tone controls
+	Bass       int    // Bass in dB
+	Treble     int    // Treble in dB
+	Balance    int    // Balance in dB, negative is left
+	ToneDefeat string // "On" or "Off"
+
+	InputNames     map[int]string // Custom input names by 1-based index
+	DisabledInputs map[int]bool   // Inputs hidden from selection by 1-based index
+
+	Zone2 ZoneState // Second zone, answered only for models with Zone 2
 }
 
-// NewNADSimulator creates a new NAD device simulator
+// ZoneState holds the simulated state of a secondary zone
+type ZoneState struct {
+	Power  string  // "On" or "Off"
+	Volume float64 // Volume in dB
+	Source string  // Current input source
+	Mute   string  // "On" or "Off"
+}
+
+// NewNADSimulator creates a new NAD device simulator posing as a C338
 func NewNADSimulator() *NADSimulator {
+	return NewNADSimulatorForModel("C338")
+}
+
+// NewNADSimulatorForModel creates a simulator for the given model, taking
+// its sources, volume range and brightness levels from the model profile
+func NewNADSimulatorForModel(model string) *NADSimulator {
+	caps, _ := nadapi.LookupCapabilities(model)
 	return &NADSimulator{
 		state: &DeviceState{
 			Power:      "Off",
 			Volume:     -30.0,
-			Source:     "Stream",
+			Source:     caps.Sources[0],
 			Mute:       "Off",
 			Brightness: 2,
-			Model:      "NAD T 758 V3i",
+			Model:      model,
+			Version:    "V2.03",
+			ToneDefeat: "Off",
+			Zone2: ZoneState{
+				Power:  "Off",
+				Volume: -40.0,
+				Source: caps.Sources[0],
+				Mute:   "Off",
+			},
 		},
+		caps:        caps,
 		connections: make(map[net.Conn]bool),
 		stopChan:    make(chan bool),
 	}
@@ -61,7 +106,7 @@ func (sim *NADSimulator) Start(port string) error {
 	}
 
 	sim.listener = listener
-	sim.running = true
+	sim.running.Store(true)
 
 	log.WithField("port", port).Info("🎵 NAD Simulator started")
 	log.Info("📱 Connect your TUI with: nadctl tui --config simulator.yaml")
@@ -73,11 +118,9 @@ func (sim *NADSimulator) Start(port string) error {
 
 // Stop shuts down the simulator
 func (sim *NADSimulator) Stop() error {
-	if !sim.running {
+	if !sim.running.CompareAndSwap(true, false) {
 		return nil
 	}
-
-	sim.running = false
 	close(sim.stopChan)
 
 	// Close all connections
@@ -91,6 +134,9 @@ func (sim *NADSimulator) Stop() error {
 	if sim.listener != nil {
 		sim.listener.Close()
 	}
+	if sim.ptySlave != nil {
+		sim.ptySlave.Close()
+	}
 
 	log.Info("NAD Simulator stopped")
 	return nil
@@ -98,10 +144,10 @@ func (sim *NADSimulator) Stop() error {
 
 // acceptConnections handles incoming connections
 func (sim *NADSimulator) acceptConnections() {
-	for sim.running {
+	for sim.running.Load() {
 		conn, err := sim.listener.Accept()
 		if err != nil {
-			if sim.running {
+			if sim.running.Load() {
 				log.WithError(err).Error("Failed to accept connection")
 			}
 			continue
@@ -127,117 +173,135 @@ func (sim *NADSimulator) handleConnection(conn net.Conn) {
 		log.WithField("client", conn.RemoteAddr()).Info("Client disconnected")
 	}()
 
-	reader := bufio.NewReader(conn)
-	writer := bufio.NewWriter(conn)
 	buffer := make([]byte, 1024)
+	var framer protocol.Framer
 
-	for sim.running {
+	for sim.running.Load() {
 		// Set a short read timeout to handle commands without newlines
 		conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
 
 		// Read available data
-		n, err := reader.Read(buffer)
+		n, err := conn.Read(buffer)
 		if err != nil {
 			// Check if it's just a timeout (no data available)
-			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-				// Continue waiting for data
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				// A client that went quiet after an unterminated command
+				// is done sending it
+				if command := framer.Flush(); command != "" {
+					if !sim.serveCommand(conn, command) {
+						return
+					}
+				}
 				continue
 			}
 			// Real error or EOF
-			if err.Error() != "EOF" && sim.running {
+			if !errors.Is(err, io.EOF) && sim.running.Load() {
 				log.WithError(err).Debug("Error reading from client")
 			}
 			break
 		}
 
-		if n == 0 {
-			continue
+		// Commands split across reads are joined by the framer
+		for _, command := range framer.Feed(buffer[:n]) {
+			if !sim.serveCommand(conn, command) {
+				return
+			}
 		}
+	}
+}
 
-		// Process the received data
-		data := string(buffer[:n])
-		commands := sim.extractCommands(data)
-
-		for _, command := range commands {
-			if command == "" {
-				continue
-			}
+// serveCommand answers one command line from conn, returning false when the
+// connection is broken
+func (sim *NADSimulator) serveCommand(conn net.Conn, command string) bool {
+	msg, err := protocol.Parse(command)
+	if err != nil {
+		log.WithError(err).WithField("client", conn.RemoteAddr()).Debug("Ignoring malformed command")
+		return true
+	}
 
-			log.WithFields(log.Fields{
-				"client":  conn.RemoteAddr(),
-				"command": command,
-			}).Debug("Received command")
+	log.WithFields(log.Fields{
+		"client":  conn.RemoteAddr(),
+		"command": msg.String(),
+	}).Debug("Received command")
 
-			response := sim.processCommand(command)
+	response := sim.processCommand(msg)
+	if response == "" {
+		return true
+	}
 
-			if response != "" {
-				_, err := writer.WriteString(response + "\r\n")
-				if err != nil {
-					log.WithError(err).Error("Failed to write response")
-					return
-				}
-				writer.Flush()
+	sim.writeMutex.Lock()
+	err = sim.writeLine(conn, response)
+	sim.writeMutex.Unlock()
+	if err != nil {
+		log.WithError(err).Error("Failed to write response")
+		return false
+	}
 
-				log.WithFields(log.Fields{
-					"client":   conn.RemoteAddr(),
-					"response": response,
-				}).Debug("Sent response")
-			}
-		}
+	log.WithFields(log.Fields{
+		"client":   conn.RemoteAddr(),
+		"response": response,
+	}).Debug("Sent response")
 
-		// Small delay to prevent busy loop
-		time.Sleep(10 * time.Millisecond)
+	// Like the real amplifier, report state changes to the other clients
+	if msg.Op != protocol.OpQuery {
+		sim.notify(conn, response)
 	}
+	return true
 }
 
-// extractCommands extracts individual commands from received data
-func (sim *NADSimulator) extractCommands(data string) []string {
-	var commands []string
-
-	// Clean up the data
-	data = strings.TrimSpace(data)
-	if data == "" {
-		return commands
+// writeLine encodes a response line to conn. The caller must hold
+// sim.writeMutex.
+func (sim *NADSimulator) writeLine(conn net.Conn, line string) error {
+	msg, err := protocol.Parse(line)
+	if err != nil {
+		return err
 	}
+	return protocol.Encode(conn, msg)
+}
+
+// notify pushes an unsolicited state line to every client except from
+func (sim *NADSimulator) notify(from net.Conn, line string) {
+	sim.connMutex.RLock()
+	defer sim.connMutex.RUnlock()
 
-	// Split by common delimiters
-	parts := strings.FieldsFunc(data, func(c rune) bool {
-		return c == '\n' || c == '\r' || c == '\000'
-	})
+	sim.writeMutex.Lock()
+	defer sim.writeMutex.Unlock()
 
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part == "" {
+	for conn := range sim.connections {
+		if conn == from {
 			continue
 		}
-
-		// Check if it's a valid command pattern
-		if strings.HasSuffix(part, "?") ||
-			strings.Contains(part, "=") ||
-			strings.HasSuffix(part, "+") ||
-			strings.HasSuffix(part, "-") {
-			commands = append(commands, part)
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+		if err := sim.writeLine(conn, line); err != nil {
+			log.WithError(err).WithField("client", conn.RemoteAddr()).Debug("Failed to push notification")
+			continue
 		}
-	}
+		conn.SetWriteDeadline(time.Time{})
 
-	return commands
+		log.WithFields(log.Fields{
+			"client": conn.RemoteAddr(),
+			"line":   line,
+		}).Debug("Pushed notification")
+	}
 }
 
 // processCommand handles NAD protocol commands
-func (sim *NADSimulator) processCommand(command string) string {
+func (sim *NADSimulator) processCommand(msg protocol.Message) string {
 	sim.stateMutex.Lock()
 	defer sim.stateMutex.Unlock()
 
-	command = strings.TrimSpace(command)
+	command := msg.String()
 
-	// Handle queries (commands ending with ?)
-	if strings.HasSuffix(command, "?") {
-		return sim.handleQuery(command)
+	// Second zone commands
+	if strings.HasPrefix(command, "Zone2.") {
+		return sim.handleZone2(command)
 	}
 
-	// Handle set commands (commands with =)
-	if strings.Contains(command, "=") {
-		return sim.handleSet(command)
+	switch msg.Op {
+	case protocol.OpQuery:
+		return sim.handleQuery(command)
+	case protocol.OpSet:
+		return sim.handleSet(msg.Key, msg.Value)
 	}
 
 	// Handle toggle commands
@@ -265,22 +329,56 @@ func (sim *NADSimulator) handleQuery(command string) string {
 	case "Main.Model?":
 		return fmt.Sprintf("Main.Model=%s", sim.state.Model)
 
+	case "Main.Version?":
+		return fmt.Sprintf("Main.Version=%s", sim.state.Version)
+
+	case "Main.Bass?", "Main.Treble?", "Main.Balance?", "Main.ToneDefeat?":
+		return sim.handleToneQuery(strings.TrimSuffix(command, "?"))
+
 	default:
+		if index, field, ok := sim.inputKey(strings.TrimSuffix(command, "?")); ok {
+			switch field {
+			case "Name":
+				return fmt.Sprintf("Source%d.Name=%s", index, sim.inputName(index))
+			case "Enabled":
+				return fmt.Sprintf("Source%d.Enabled=%s", index, sim.inputEnabled(index))
+			}
+		}
 		log.WithField("command", command).Warn("Unknown query command")
 		return ""
 	}
 }
 
-// handleSet processes set commands
-func (sim *NADSimulator) handleSet(command string) string {
-	parts := strings.SplitN(command, "=", 2)
-	if len(parts) != 2 {
-		return ""
+// inputKey splits a SourceN.Field key of an input of the model
+func (sim *NADSimulator) inputKey(key string) (index int, field string, ok bool) {
+	var rest string
+	if _, err := fmt.Sscanf(key, "Source%d.%s", &index, &rest); err != nil {
+		return 0, "", false
 	}
+	if index < 1 || index > len(sim.caps.Sources) {
+		return 0, "", false
+	}
+	return index, rest, true
+}
+
+// inputName returns the custom or factory name of input index
+func (sim *NADSimulator) inputName(index int) string {
+	if name, ok := sim.state.InputNames[index]; ok {
+		return name
+	}
+	return sim.caps.Sources[index-1]
+}
 
-	key := strings.TrimSpace(parts[0])
-	value := strings.TrimSpace(parts[1])
+// inputEnabled returns "Yes" or "No" for input index
+func (sim *NADSimulator) inputEnabled(index int) string {
+	if sim.state.DisabledInputs[index] {
+		return "No"
+	}
+	return "Yes"
+}
 
+// handleSet processes set commands
+func (sim *NADSimulator) handleSet(key, value string) string {
 	switch key {
 	case "Main.Power":
 		if value == "On" || value == "Off" {
@@ -295,11 +393,11 @@ func (sim *NADSimulator) handleSet(command string) string {
 
 	case "Main.Volume":
 		if vol, err := strconv.ParseFloat(value, 64); err == nil {
-			// Clamp volume to realistic range
-			if vol < -80 {
-				vol = -80
-			} else if vol > 10 {
-				vol = 10
+			// Clamp volume to the range of the model
+			if vol < sim.caps.MinVolume {
+				vol = sim.caps.MinVolume
+			} else if vol > sim.caps.MaxVolume {
+				vol = sim.caps.MaxVolume
 			}
 			oldVol := sim.state.Volume
 			sim.state.Volume = vol
@@ -311,8 +409,7 @@ func (sim *NADSimulator) handleSet(command string) string {
 		}
 
 	case "Main.Source":
-		sources := []string{"Stream", "Wireless", "TV", "Phono", "Coax1", "Coax2", "Opt1", "Opt2"}
-		for _, source := range sources {
+		for _, source := range sim.caps.Sources {
 			if strings.EqualFold(value, source) {
 				oldSource := sim.state.Source
 				sim.state.Source = source
@@ -337,7 +434,7 @@ func (sim *NADSimulator) handleSet(command string) string {
 
 	case "Main.Brightness":
 		if brightness, err := strconv.Atoi(value); err == nil {
-			if brightness >= 0 && brightness <= 3 {
+			if sim.caps.IsValidBrightnessLevel(brightness) {
 				oldBrightness := sim.state.Brightness
 				sim.state.Brightness = brightness
 				log.WithFields(log.Fields{
@@ -347,9 +444,45 @@ func (sim *NADSimulator) handleSet(command string) string {
 				return fmt.Sprintf("Main.Brightness=%d", sim.state.Brightness)
 			}
 		}
+
+	case "Main.Bass", "Main.Treble", "Main.Balance", "Main.ToneDefeat":
+		if res := sim.handleToneSet(key, value); res != "" {
+			return res
+		}
+
+	default:
+		if index, field, ok := sim.inputKey(key); ok {
+			switch field {
+			case "Name":
+				if sim.state.InputNames == nil {
+					sim.state.InputNames = make(map[int]string)
+				}
+				sim.state.InputNames[index] = value
+				log.WithFields(log.Fields{
+					"input": index,
+					"name":  value,
+				}).Info("Input renamed")
+				return fmt.Sprintf("Source%d.Name=%s", index, value)
+			case "Enabled":
+				if value == "Yes" || value == "No" {
+					if sim.state.DisabledInputs == nil {
+						sim.state.DisabledInputs = make(map[int]bool)
+					}
+					sim.state.DisabledInputs[index] = value == "No"
+					log.WithFields(log.Fields{
+						"input":   index,
+						"enabled": value,
+					}).Info("Input enabled changed")
+					return fmt.Sprintf("Source%d.Enabled=%s", index, value)
+				}
+			}
+		}
 	}
 
-	log.WithField("command", command).Warn("Invalid set command")
+	log.WithFields(log.Fields{
+		"key":   key,
+		"value": value,
+	}).Warn("Invalid set command")
 	return ""
 }
 
@@ -379,8 +512,8 @@ func (sim *NADSimulator) handleToggle(command string) string {
 	case "Main.Volume+":
 		// Increase volume
 		sim.state.Volume += 1.0
-		if sim.state.Volume > 10 {
-			sim.state.Volume = 10
+		if sim.state.Volume > sim.caps.MaxVolume {
+			sim.state.Volume = sim.caps.MaxVolume
 		}
 		log.WithField("volume", sim.state.Volume).Info("Volume increased")
 		return fmt.Sprintf("Main.Volume=%.1f", sim.state.Volume)
@@ -388,15 +521,15 @@ func (sim *NADSimulator) handleToggle(command string) string {
 	case "Main.Volume-":
 		// Decrease volume
 		sim.state.Volume -= 1.0
-		if sim.state.Volume < -80 {
-			sim.state.Volume = -80
+		if sim.state.Volume < sim.caps.MinVolume {
+			sim.state.Volume = sim.caps.MinVolume
 		}
 		log.WithField("volume", sim.state.Volume).Info("Volume decreased")
 		return fmt.Sprintf("Main.Volume=%.1f", sim.state.Volume)
 
 	case "Main.Source+":
 		// Next source
-		sources := []string{"Stream", "Wireless", "TV", "Phono", "Coax1", "Coax2", "Opt1", "Opt2"}
+		sources := sim.caps.Sources
 		currentIndex := 0
 		for i, source := range sources {
 			if source == sim.state.Source {
@@ -411,7 +544,7 @@ func (sim *NADSimulator) handleToggle(command string) string {
 
 	case "Main.Source-":
 		// Previous source
-		sources := []string{"Stream", "Wireless", "TV", "Phono", "Coax1", "Coax2", "Opt1", "Opt2"}
+		sources := sim.caps.Sources
 		currentIndex := 0
 		for i, source := range sources {
 			if source == sim.state.Source {
@@ -426,7 +559,7 @@ func (sim *NADSimulator) handleToggle(command string) string {
 
 	case "Main.Brightness+":
 		// Increase brightness
-		if sim.state.Brightness < 3 {
+		if sim.state.Brightness < sim.caps.MaxBrightness {
 			sim.state.Brightness++
 		}
 		log.WithField("brightness", sim.state.Brightness).Info("Brightness increased")
@@ -440,12 +573,188 @@ func (sim *NADSimulator) handleToggle(command string) string {
 		log.WithField("brightness", sim.state.Brightness).Info("Brightness decreased")
 		return fmt.Sprintf("Main.Brightness=%d", sim.state.Brightness)
 
+	case "Main.Bass+", "Main.Bass-", "Main.Treble+", "Main.Treble-",
+		"Main.Balance+", "Main.Balance-", "Main.ToneDefeat+", "Main.ToneDefeat-":
+		return sim.handleToneToggle(command)
+
 	default:
 		log.WithField("command", command).Warn("Unknown toggle command")
 		return ""
 	}
 }
 
+// handleZone2 processes queries, sets and toggles for the second zone.
+// Models without Zone 2 stay silent like the real devices.
+func (sim *NADSimulator) handleZone2(command string) string {
+	if !sim.caps.HasFeature(nadapi.FeatureZone2) {
+		log.WithField("command", command).Warn("Zone 2 command on model without Zone 2")
+		return ""
+	}
+	zone := &sim.state.Zone2
+
+	// Queries
+	switch command {
+	case "Zone2.Power?":
+		return fmt.Sprintf("Zone2.Power=%s", zone.Power)
+	case "Zone2.Volume?":
+		return fmt.Sprintf("Zone2.Volume=%.1f", zone.Volume)
+	case "Zone2.Source?":
+		return fmt.Sprintf("Zone2.Source=%s", zone.Source)
+	case "Zone2.Mute?":
+		return fmt.Sprintf("Zone2.Mute=%s", zone.Mute)
+	}
+
+	// Toggles
+	switch command {
+	case "Zone2.Power+", "Zone2.Power-":
+		if zone.Power == "On" {
+			zone.Power = "Off"
+		} else {
+			zone.Power = "On"
+		}
+		return fmt.Sprintf("Zone2.Power=%s", zone.Power)
+	case "Zone2.Mute+", "Zone2.Mute-":
+		if zone.Mute == "On" {
+			zone.Mute = "Off"
+		} else {
+			zone.Mute = "On"
+		}
+		return fmt.Sprintf("Zone2.Mute=%s", zone.Mute)
+	case "Zone2.Volume+":
+		zone.Volume = min(zone.Volume+1, sim.caps.MaxVolume)
+		return fmt.Sprintf("Zone2.Volume=%.1f", zone.Volume)
+	case "Zone2.Volume-":
+		zone.Volume = max(zone.Volume-1, sim.caps.MinVolume)
+		return fmt.Sprintf("Zone2.Volume=%.1f", zone.Volume)
+	}
+
+	// Sets
+	key, value, ok := strings.Cut(command, "=")
+	if !ok {
+		log.WithField("command", command).Warn("Unknown Zone 2 command")
+		return ""
+	}
+	switch key {
+	case "Zone2.Power", "Zone2.Mute":
+		if value == "On" || value == "Off" {
+			if key == "Zone2.Power" {
+				zone.Power = value
+			} else {
+				zone.Mute = value
+			}
+			log.WithFields(log.Fields{
+				"key":   key,
+				"value": value,
+			}).Info("Zone 2 changed")
+			return fmt.Sprintf("%s=%s", key, value)
+		}
+	case "Zone2.Volume":
+		if vol, err := strconv.ParseFloat(value, 64); err == nil {
+			zone.Volume = min(max(vol, sim.caps.MinVolume), sim.caps.MaxVolume)
+			log.WithField("volume", zone.Volume).Info("Zone 2 volume changed")
+			return fmt.Sprintf("Zone2.Volume=%.1f", zone.Volume)
+		}
+	case "Zone2.Source":
+		if source, ok := sim.caps.Source(value); ok {
+			zone.Source = source
+			log.WithField("source", source).Info("Zone 2 source changed")
+			return fmt.Sprintf("Zone2.Source=%s", zone.Source)
+		}
+	}
+
+	log.WithField("command", command).Warn("Invalid Zone 2 command")
+	return ""
+}
+
+// toneLevel returns the state field and range of a tone level key
+func (sim *NADSimulator) toneLevel(key string) (level *int, min, max int) {
+	switch key {
+	case "Main.Bass":
+		return &sim.state.Bass, nadapi.MinTone, nadapi.MaxTone
+	case "Main.Treble":
+		return &sim.state.Treble, nadapi.MinTone, nadapi.MaxTone
+	case "Main.Balance":
+		return &sim.state.Balance, nadapi.MinBalance, nadapi.MaxBalance
+	}
+	return nil, 0, 0
+}
+
+// handleToneQuery answers tone queries; models without tone controls stay silent
+func (sim *NADSimulator) handleToneQuery(key string) string {
+	if !sim.caps.HasFeature(nadapi.FeatureToneControls) {
+		log.WithField("key", key).Warn("Tone query on model without tone controls")
+		return ""
+	}
+	if key == "Main.ToneDefeat" {
+		return fmt.Sprintf("Main.ToneDefeat=%s", sim.state.ToneDefeat)
+	}
+	level, _, _ := sim.toneLevel(key)
+	return fmt.Sprintf("%s=%d", key, *level)
+}
+
+// handleToneSet processes tone set commands
+func (sim *NADSimulator) handleToneSet(key, value string) string {
+	if !sim.caps.HasFeature(nadapi.FeatureToneControls) {
+		return ""
+	}
+	if key == "Main.ToneDefeat" {
+		if value != "On" && value != "Off" {
+			return ""
+		}
+		sim.state.ToneDefeat = value
+		log.WithField("toneDefeat", value).Info("Tone defeat changed")
+		return fmt.Sprintf("Main.ToneDefeat=%s", value)
+	}
+
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return ""
+	}
+	level, min, max := sim.toneLevel(key)
+	if v < min {
+		v = min
+	} else if v > max {
+		v = max
+	}
+	old := *level
+	*level = v
+	log.WithFields(log.Fields{
+		"key": key,
+		"old": old,
+		"new": v,
+	}).Info("Tone level changed")
+	return fmt.Sprintf("%s=%d", key, v)
+}
+
+// handleToneToggle steps tone levels by 1 dB and flips tone defeat
+func (sim *NADSimulator) handleToneToggle(command string) string {
+	if !sim.caps.HasFeature(nadapi.FeatureToneControls) {
+		return ""
+	}
+	key := command[:len(command)-1]
+	if key == "Main.ToneDefeat" {
+		if sim.state.ToneDefeat == "On" {
+			sim.state.ToneDefeat = "Off"
+		} else {
+			sim.state.ToneDefeat = "On"
+		}
+		log.WithField("toneDefeat", sim.state.ToneDefeat).Info("Tone defeat toggled")
+		return fmt.Sprintf("Main.ToneDefeat=%s", sim.state.ToneDefeat)
+	}
+
+	level, min, max := sim.toneLevel(key)
+	if strings.HasSuffix(command, "+") && *level < max {
+		*level++
+	} else if strings.HasSuffix(command, "-") && *level > min {
+		*level--
+	}
+	log.WithFields(log.Fields{
+		"key":   key,
+		"level": *level,
+	}).Info("Tone level stepped")
+	return fmt.Sprintf("%s=%d", key, *level)
+}
+
 // GetState returns current device state (for monitoring/debugging)
 func (sim *NADSimulator) GetState() DeviceState {
 	sim.stateMutex.RLock()
@@ -458,4 +767,5 @@ func (sim *NADSimulator) SetState(state DeviceState) {
 	sim.stateMutex.Lock()
 	defer sim.stateMutex.Unlock()
 	sim.state = &state
+	sim.caps, _ = nadapi.LookupCapabilities(state.Model)
 }
@@ -0,0 +1,79 @@
+package simulator
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/galamiram/nadctl/nadapi"
+)
+
+// ptyConn is the master side of a pseudo terminal, standing in for the
+// amplifier end of an RS-232 cable
+type ptyConn struct {
+	*os.File
+}
+
+func (c *ptyConn) LocalAddr() net.Addr  { return ptyAddr(c.Name()) }
+func (c *ptyConn) RemoteAddr() net.Addr { return ptyAddr(c.Name()) }
+
+type ptyAddr string
+
+func (a ptyAddr) Network() string { return "pty" }
+func (a ptyAddr) String() string  { return string(a) }
+
+// StartPTY begins serving the simulator on a new pseudo terminal and
+// returns the path clients open as a serial port, e.g. /dev/pts/3
+func (sim *NADSimulator) StartPTY() (string, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to start simulator: %v", err)
+	}
+
+	// Fd would switch the file to blocking mode and break read deadlines
+	raw, err := master.SyscallConn()
+	if err != nil {
+		master.Close()
+		return "", fmt.Errorf("failed to start simulator: %v", err)
+	}
+	var n int
+	var ptyErr error
+	if err := raw.Control(func(fd uintptr) {
+		if ptyErr = unix.IoctlSetPointerInt(int(fd), unix.TIOCSPTLCK, 0); ptyErr != nil {
+			return
+		}
+		n, ptyErr = unix.IoctlGetInt(int(fd), unix.TIOCGPTN)
+	}); err != nil {
+		ptyErr = err
+	}
+	if ptyErr != nil {
+		master.Close()
+		return "", fmt.Errorf("failed to start simulator: %v", ptyErr)
+	}
+	path := "/dev/pts/" + strconv.Itoa(n)
+
+	// Keep the slave side open in raw mode so the master survives clients
+	// coming and going, like a cable left plugged in
+	slave, err := nadapi.OpenSerial(path, 115200)
+	if err != nil {
+		master.Close()
+		return "", fmt.Errorf("failed to start simulator: %v", err)
+	}
+
+	conn := &ptyConn{File: master}
+	sim.ptySlave = slave
+	sim.running.Store(true)
+
+	sim.connMutex.Lock()
+	sim.connections[conn] = true
+	sim.connMutex.Unlock()
+
+	log.WithField("path", path).Info("🎵 NAD Simulator started on pseudo terminal")
+
+	go sim.handleConnection(conn)
+	return path, nil
+}
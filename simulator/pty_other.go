@@ -0,0 +1,10 @@
+//go:build !linux
+
+package simulator
+
+import "errors"
+
+// StartPTY is only available on Linux
+func (sim *NADSimulator) StartPTY() (string, error) {
+	return "", errors.New("pseudo terminals are not supported on this platform")
+}
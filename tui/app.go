@@ -1,10 +1,14 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -24,6 +28,9 @@ import (
 	"github.com/spf13/viper"
 )
 
+// volumeFadeDuration is how long the fade key takes to reach a volume
+const volumeFadeDuration = 3 * time.Second
+
 // Tab represents different application tabs
 type Tab int
 
@@ -70,6 +77,13 @@ type App struct {
 	processing     bool          // true when a command is being processed
 	resultChan     chan tea.Msg  // channel for results from background processing
 
+	// ctx bounds all device I/O issued by the command queue; cancel aborts
+	// any in-flight command during cleanup
+	ctx    context.Context
+	cancel context.CancelFunc
+	// stopEvents ends the event subscription on the current device
+	stopEvents context.CancelFunc
+
 	// Tab system
 	currentTab  Tab       // current active tab
 	tabs        []TabInfo // available tabs
@@ -91,6 +105,29 @@ type App struct {
 	spotifyDeviceMode      bool             // true when in device selection mode
 	spotifyDevicesLoaded   bool             // true when device list has been loaded
 
+	// Source picker
+	sourcePickerMode      bool // true when choosing an input from the list
+	sourcePickerSelection int  // currently selected input index
+
+	// Device switcher state
+	devicePickerMode      bool                 // true when choosing a device from the list
+	devicePickerSelection int                  // currently selected device index
+	devicePickerEntries   []nadapi.DeviceEntry // devices to choose from
+	devicePickerGroups    []string             // groups to choose from, listed after the devices
+
+	// group is the group the power, mute, volume and source picker keys act
+	// on, nil when they act on the connected device only
+	group *nadapi.GroupExecutor
+
+	// zone is the zone the power, volume, source and mute keys act on
+	zone nadapi.ZoneID
+
+	// Device discovery in progress
+	discovering  bool
+	discovery    nadapi.DiscoveryProgress
+	discoveryBar progress.Model
+	discovered   []nadapi.DiscoveredDevice // Devices found by the last discovery
+
 	// Demo mode (no NAD device required)
 	demoMode bool // true when running in demo mode
 
@@ -102,15 +139,54 @@ type App struct {
 
 // DeviceStatus holds the current device state
 type DeviceStatus struct {
-	Power         string
-	Volume        float64
-	VolumeStr     string
-	Source        string
-	Mute          string
-	Brightness    int
-	BrightnessStr string
-	Model         string
-	IP            string
+	nadapi.State
+	Known  bool                // false until the state has been read from the device
+	Caps   nadapi.Capabilities // profile of the connected model, valid when Known
+	Inputs []nadapi.Input      // inputs with their custom names, valid when Known
+	Tone   nadapi.Tone         // tone controls, valid when HasTone
+	// HasTone is true when the model has tone controls and they were read
+	HasTone bool
+	// Zone is the zone Power, Volume, Source, SourceName and Muted belong to
+	Zone nadapi.ZoneID
+	IP   string
+}
+
+// volumeRange returns the volume limits of the connected model
+func (s DeviceStatus) volumeRange() (min, max float64) {
+	if !s.Known {
+		return -80, 10
+	}
+	return s.Caps.MinVolume, s.Caps.MaxVolume
+}
+
+// volumePercent maps a volume level onto the 0-1 scale of the volume bar
+func (s DeviceStatus) volumePercent(volume float64) float64 {
+	min, max := s.volumeRange()
+	return (volume - min) / (max - min)
+}
+
+// brightnessPercent maps the brightness onto the 0-1 scale of the brightness bar
+func (s DeviceStatus) brightnessPercent() float64 {
+	if !s.Known || s.Caps.MaxBrightness == 0 {
+		return 0
+	}
+	return float64(s.Brightness) / float64(s.Caps.MaxBrightness)
+}
+
+// volumeString returns the volume for display
+func (s DeviceStatus) volumeString() string {
+	if !s.Known {
+		return "Unknown"
+	}
+	return s.VolumeString()
+}
+
+// brightnessString returns the brightness level for display
+func (s DeviceStatus) brightnessString() string {
+	if !s.Known {
+		return "Unknown"
+	}
+	return strconv.Itoa(s.Brightness)
 }
 
 // MessageType represents the type of message to display
@@ -142,13 +218,29 @@ const (
 	CmdVolumeSet
 	CmdVolumeUp
 	CmdVolumeDown
+	CmdVolumeFade
 	CmdSourceNext
 	CmdSourcePrev
+	CmdSourceSet
 	CmdBrightnessUp
 	CmdBrightnessDown
+	CmdBassUp
+	CmdBassDown
+	CmdTrebleUp
+	CmdTrebleDown
+	CmdBalanceLeft
+	CmdBalanceRight
+	CmdToneDefeatToggle
 	CmdRefreshStatus
 	CmdDiscoverDevices
 	CmdConnectDevice
+	// Group commands, run on every device of a group
+	CmdGroupPower
+	CmdGroupMute
+	CmdGroupVolumeSet
+	CmdGroupVolumeUp
+	CmdGroupVolumeDown
+	CmdGroupSourceSet
 	// Spotify commands
 	CmdSpotifyPlayPause
 	CmdSpotifyNext
@@ -168,6 +260,7 @@ const (
 type QueuedCommand struct {
 	Type      CommandType
 	Params    map[string]interface{}
+	Zone      nadapi.ZoneID // zone selected when the command was queued
 	ID        string
 	Timestamp time.Time
 }
@@ -187,28 +280,17 @@ func NewCommandQueue() *CommandQueue {
 	}
 }
 
-// Add adds a command to the queue, replacing similar commands for efficiency
+// Add adds a command to the queue. Device commands keep their order; the
+// device paces them and drops volume sets overtaken by newer ones, see
+// nadapi.NewFromSettings. Only the latest status refresh is kept.
 func (cq *CommandQueue) Add(cmd QueuedCommand) {
 	cq.mutex.Lock()
 	defer cq.mutex.Unlock()
 
-	// For volume commands, replace existing volume commands instead of queuing
-	if cmd.Type == CmdVolumeSet || cmd.Type == CmdVolumeUp || cmd.Type == CmdVolumeDown {
-		// Remove existing volume commands
-		filtered := make([]QueuedCommand, 0)
-		for _, existing := range cq.commands {
-			if existing.Type != CmdVolumeSet && existing.Type != CmdVolumeUp && existing.Type != CmdVolumeDown {
-				filtered = append(filtered, existing)
-			}
-		}
-		cq.commands = filtered
-	}
-
-	// For refresh commands, only keep the latest one
 	if cmd.Type == CmdRefreshStatus {
 		filtered := make([]QueuedCommand, 0)
 		for _, existing := range cq.commands {
-			if existing.Type != CmdRefreshStatus {
+			if existing.Type != cmd.Type {
 				filtered = append(filtered, existing)
 			}
 		}
@@ -257,6 +339,7 @@ type keyMap struct {
 	VolumeUp   key.Binding
 	VolumeDown key.Binding
 	VolumeSet  key.Binding
+	VolumeFade key.Binding
 	Refresh    key.Binding
 	Discover   key.Binding
 	Help       key.Binding
@@ -287,6 +370,18 @@ type keyMap struct {
 	SpotifyDeviceUp     key.Binding
 	SpotifyDeviceDown   key.Binding
 	SpotifyDeviceSelect key.Binding
+	// Source picker
+	SourcePicker key.Binding
+	// Tone controls
+	BassUp       key.Binding
+	BassDown     key.Binding
+	TrebleUp     key.Binding
+	TrebleDown   key.Binding
+	BalanceLeft  key.Binding
+	BalanceRight key.Binding
+	ToneDefeat   key.Binding
+	ZoneSwitch   key.Binding
+	DevicePicker key.Binding
 }
 
 // ShortHelp returns the key bindings to be shown in the mini help view
@@ -297,10 +392,11 @@ func (k keyMap) ShortHelp() []key.Binding {
 // FullHelp returns the key bindings to be shown in the full help view
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Power, k.Mute, k.VolumeUp, k.VolumeDown, k.VolumeSet},
-		{k.Left, k.Right, k.Up, k.Down},
+		{k.Power, k.Mute, k.VolumeUp, k.VolumeDown, k.VolumeSet, k.VolumeFade},
+		{k.Left, k.Right, k.SourcePicker, k.ZoneSwitch, k.Up, k.Down},
+		{k.BassDown, k.BassUp, k.TrebleDown, k.TrebleUp, k.BalanceLeft, k.BalanceRight, k.ToneDefeat},
 		{k.SpotifyToggle, k.SpotifyPlayPause, k.SpotifyNext, k.SpotifyPrev},
-		{k.SpotifyAuth, k.SpotifyDisconnect, k.Refresh, k.Discover, k.Help, k.Quit},
+		{k.SpotifyAuth, k.SpotifyDisconnect, k.Refresh, k.Discover, k.DevicePicker, k.Help, k.Quit},
 	}
 }
 
@@ -341,6 +437,10 @@ var keys = keyMap{
 		key.WithKeys("s"),
 		key.WithHelp("s", "set volume"),
 	),
+	VolumeFade: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "fade to the volume entered or adjusted"),
+	),
 	Refresh: key.NewBinding(
 		key.WithKeys("r"),
 		key.WithHelp("r", "refresh status"),
@@ -383,6 +483,18 @@ var keys = keyMap{
 	SpotifyDeviceUp:     key.NewBinding(key.WithKeys("+"), key.WithHelp("+", "increase Spotify device volume")),
 	SpotifyDeviceDown:   key.NewBinding(key.WithKeys("-"), key.WithHelp("-", "decrease Spotify device volume")),
 	SpotifyDeviceSelect: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "select Spotify device")),
+	// Source picker
+	SourcePicker: key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "pick source")),
+	// Tone controls
+	BassUp:       key.NewBinding(key.WithKeys("]"), key.WithHelp("]", "bass up")),
+	BassDown:     key.NewBinding(key.WithKeys("["), key.WithHelp("[", "bass down")),
+	TrebleUp:     key.NewBinding(key.WithKeys("}"), key.WithHelp("}", "treble up")),
+	TrebleDown:   key.NewBinding(key.WithKeys("{"), key.WithHelp("{", "treble down")),
+	BalanceLeft:  key.NewBinding(key.WithKeys(","), key.WithHelp(",", "balance left")),
+	BalanceRight: key.NewBinding(key.WithKeys("."), key.WithHelp(".", "balance right")),
+	ToneDefeat:   key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "toggle tone defeat")),
+	ZoneSwitch:   key.NewBinding(key.WithKeys("z"), key.WithHelp("z", "switch zone")),
+	DevicePicker: key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "choose device or group")),
 }
 
 // NewApp creates a new TUI application
@@ -393,7 +505,7 @@ func NewApp() *App {
 
 	// Initialize volume input
 	volumeInput := textinput.New()
-	volumeInput.Placeholder = "Enter volume (-80 to +10)"
+	volumeInput.Placeholder = "Enter volume in dB"
 	volumeInput.CharLimit = 6
 	volumeInput.Width = 25
 
@@ -418,7 +530,11 @@ func NewApp() *App {
 		log.Debug("Spotify not configured - set spotify.client_id in config (no client secret needed for PKCE)")
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &App{
+		ctx:            ctx,
+		cancel:         cancel,
 		keys:           keys,
 		help:           help.New(),
 		autoRefresh:    true,
@@ -436,6 +552,7 @@ func NewApp() *App {
 		adjustTimer:    nil,
 		commandQueue:   NewCommandQueue(),
 		processing:     false,
+		zone:           nadapi.MainZone,
 		resultChan:     make(chan tea.Msg, 10), // Buffered channel
 		// Tab system
 		currentTab:  TabDevice,
@@ -452,6 +569,7 @@ func NewApp() *App {
 		spotifyState:     nil,
 		spotifyEnabled:   true, // Show Spotify panel by default
 		spotifyProgress:  spotifyProgress,
+		discoveryBar:     progress.New(progress.WithDefaultGradient()),
 		// Spotify auth
 		spotifyAuthMode:  false,
 		spotifyAuthInput: spotifyAuthInput,
@@ -516,19 +634,21 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Handle input mode (volume setting)
 		if a.inputMode {
 			switch {
-			case key.Matches(msg, key.NewBinding(key.WithKeys("enter"))):
-				// Process volume input
+			case key.Matches(msg, key.NewBinding(key.WithKeys("enter"))), key.Matches(msg, a.keys.VolumeFade):
+				// Process volume input, fading to it with the fade key
+				fade := key.Matches(msg, a.keys.VolumeFade)
 				volumeStr := a.volumeInput.Value()
 				if volumeStr != "" {
 					if volume, err := strconv.ParseFloat(volumeStr, 64); err == nil {
 						// Validate volume range
-						if volume >= -80 && volume <= 10 {
+						min, max := a.status.volumeRange()
+						if volume >= min && volume <= max {
 							a.inputMode = false
 							a.volumeInput.Reset()
 							a.setMessage("Setting volume...", MessageInfo)
-							return a, a.setSpecificVolume(volume)
+							return a, a.setSpecificVolume(volume, fade)
 						} else {
-							a.setMessage("Volume must be between -80 and +10 dB", MessageError)
+							a.setMessage(fmt.Sprintf("Volume must be between %.0f and %+.0f dB", min, max), MessageError)
 							return a, nil
 						}
 					} else {
@@ -593,7 +713,11 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			switch {
 			case key.Matches(msg, key.NewBinding(key.WithKeys("enter"))):
 				// Commit volume adjustment immediately
-				return a, a.commitVolumeAdjustment()
+				return a, a.commitVolumeAdjustment(false)
+
+			case key.Matches(msg, a.keys.VolumeFade):
+				// Fade to the adjusted volume instead of jumping to it
+				return a, a.commitVolumeAdjustment(true)
 
 			case key.Matches(msg, key.NewBinding(key.WithKeys("esc"))):
 				// Cancel volume adjustment
@@ -650,6 +774,56 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+		// Handle source picker mode
+		if a.sourcePickerMode {
+			switch {
+			case key.Matches(msg, a.keys.Up):
+				a.sourcePickerSelectionUp()
+				return a, nil
+
+			case key.Matches(msg, a.keys.Down):
+				a.sourcePickerSelectionDown()
+				return a, nil
+
+			case key.Matches(msg, key.NewBinding(key.WithKeys("enter"))):
+				return a, a.selectPickedSource()
+
+			case key.Matches(msg, key.NewBinding(key.WithKeys("esc"))),
+				key.Matches(msg, a.keys.SourcePicker):
+				a.sourcePickerMode = false
+				a.setMessage("Source selection cancelled", MessageInfo)
+				return a, nil
+
+			default:
+				return a, nil
+			}
+		}
+
+		// Handle device switcher mode
+		if a.devicePickerMode {
+			switch {
+			case key.Matches(msg, a.keys.Up):
+				a.devicePickerSelectionUp()
+				return a, nil
+
+			case key.Matches(msg, a.keys.Down):
+				a.devicePickerSelectionDown()
+				return a, nil
+
+			case key.Matches(msg, key.NewBinding(key.WithKeys("enter"))):
+				return a, a.selectPickedDevice()
+
+			case key.Matches(msg, key.NewBinding(key.WithKeys("esc"))),
+				key.Matches(msg, a.keys.DevicePicker):
+				a.devicePickerMode = false
+				a.setMessage("Device selection cancelled", MessageInfo)
+				return a, nil
+
+			default:
+				return a, nil
+			}
+		}
+
 		// Handle basic keys that should always work
 		switch {
 		case key.Matches(msg, a.keys.Quit):
@@ -692,6 +866,15 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Discovery should work even when not connected
 			return a, a.discoverDevices()
 
+		case key.Matches(msg, a.keys.DevicePicker):
+			// Switching devices should work even when not connected
+			if a.demoMode {
+				a.setMessage("Demo mode - device switching disabled", MessageInfo)
+				return a, nil
+			}
+			a.enterDevicePicker()
+			return a, nil
+
 		case key.Matches(msg, a.keys.Refresh):
 			// Allow refresh even when not connected (will show appropriate message)
 			if a.connected {
@@ -820,7 +1003,8 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Enter volume input mode
 				a.inputMode = true
 				a.volumeInput.Focus()
-				a.setMessage("Enter volume level (-80 to +10 dB):", MessageInfo)
+				min, max := a.status.volumeRange()
+				a.setMessage(fmt.Sprintf("Enter volume level (%.0f to %+.0f dB):", min, max), MessageInfo)
 				return a, textinput.Blink
 
 			case key.Matches(msg, a.keys.Left):
@@ -829,6 +1013,28 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case key.Matches(msg, a.keys.Right):
 				return a, a.nextSource()
 
+			case key.Matches(msg, a.keys.SourcePicker):
+				a.enterSourcePicker()
+				return a, nil
+
+			case key.Matches(msg, a.keys.ZoneSwitch):
+				return a, a.switchZone()
+
+			case key.Matches(msg, a.keys.BassUp):
+				return a, a.toneCommand(CmdBassUp, "Bass up")
+			case key.Matches(msg, a.keys.BassDown):
+				return a, a.toneCommand(CmdBassDown, "Bass down")
+			case key.Matches(msg, a.keys.TrebleUp):
+				return a, a.toneCommand(CmdTrebleUp, "Treble up")
+			case key.Matches(msg, a.keys.TrebleDown):
+				return a, a.toneCommand(CmdTrebleDown, "Treble down")
+			case key.Matches(msg, a.keys.BalanceLeft):
+				return a, a.toneCommand(CmdBalanceLeft, "Balance left")
+			case key.Matches(msg, a.keys.BalanceRight):
+				return a, a.toneCommand(CmdBalanceRight, "Balance right")
+			case key.Matches(msg, a.keys.ToneDefeat):
+				return a, a.toneCommand(CmdToneDefeatToggle, "Tone defeat toggle")
+
 			case key.Matches(msg, a.keys.Up):
 				// Handle differently if we're in the logs tab
 				if a.currentTab == TabLogs {
@@ -877,7 +1083,8 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				key.Matches(msg, a.keys.VolumeDown),
 				key.Matches(msg, a.keys.VolumeSet),
 				key.Matches(msg, a.keys.Left),
-				key.Matches(msg, a.keys.Right):
+				key.Matches(msg, a.keys.Right),
+				key.Matches(msg, a.keys.SourcePicker):
 				if a.demoMode {
 					a.setMessage("Demo mode - NAD device controls disabled (try Spotify controls with 'a')", MessageInfo)
 				} else {
@@ -931,10 +1138,21 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return a, a.tickCmd()
 
+	case fadeProgressMsg:
+		if msg.progress.Zone == a.zone {
+			a.status.Volume = msg.progress.Volume
+		}
+		if msg.progress.Step == msg.progress.Steps {
+			a.setMessage(fmt.Sprintf("Volume faded to %.1f dB", msg.progress.Volume), MessageSuccess)
+		} else {
+			a.setMessage(fmt.Sprintf("Fading volume: %.1f dB (%d/%d)", msg.progress.Volume, msg.progress.Step, msg.progress.Steps), MessageInfo)
+		}
+		return a, a.listenForResults()
+
 	case volumeAdjustTimeoutMsg:
 		// Auto-commit volume adjustment after timeout
 		if a.adjustMode {
-			return a, a.commitVolumeAdjustment()
+			return a, a.commitVolumeAdjustment(false)
 		}
 		return a, nil
 
@@ -942,7 +1160,7 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.device = msg.device
 		a.connected = true
 		a.connecting = false
-		a.status.IP = msg.device.IP.String()
+		a.status.IP = deviceAddress(msg.device)
 		a.setMessage("Connected to NAD device!", MessageSuccess)
 		// Queue a status refresh after successful connection
 		a.queueCommand(CmdRefreshStatus, nil)
@@ -954,12 +1172,51 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.setMessage(fmt.Sprintf("Connection failed: %v", msg.err), MessageError)
 		return a, a.listenForResults()
 
+	case discoveryProgressMsg:
+		if !a.discovering {
+			a.discovered = nil
+		}
+		a.discovering = true
+		a.discovery = msg.progress
+		return a, a.listenForResults()
+
+	case discoveryDeviceMsg:
+		a.discovered = append(a.discovered, msg.device)
+		a.setMessage(fmt.Sprintf("Found %s at %s", msg.device.Model, net.JoinHostPort(msg.device.IP, msg.device.Port)), MessageSuccess)
+		return a, a.listenForResults()
+
+	case discoveryDoneMsg:
+		a.discovering = false
+		a.discovery = nadapi.DiscoveryProgress{}
+		return a, a.listenForResults()
+
+	case connStateMsg:
+		switch msg.state {
+		case nadapi.ConnReady:
+			a.connected = true
+			a.connecting = false
+			a.setMessage("Connection restored", MessageSuccess)
+			a.queueCommand(CmdRefreshStatus, nil)
+		case nadapi.ConnDegraded, nadapi.ConnConnecting:
+			a.connecting = true
+			a.setMessage("Connection lost, reconnecting...", MessageWarning)
+		case nadapi.ConnDown:
+			a.connected = false
+			a.connecting = false
+			a.setMessage("Device unreachable, still retrying in the background", MessageError)
+		}
+		return a, a.listenForResults()
+
 	case statusUpdateMsg:
 		a.status = msg.status
 		a.lastUpdate = time.Now()
+		// A newly connected model may lack the selected zone
+		if a.status.Known && !slices.Contains(a.status.Caps.Zones(), a.zone) {
+			a.zone = nadapi.MainZone
+		}
 		// Update progress bars
-		a.volumeBar.SetPercent((a.status.Volume + 80) / 90)          // Volume range -80 to +10
-		a.brightnessBar.SetPercent(float64(a.status.Brightness) / 3) // Brightness 0-3
+		a.volumeBar.SetPercent(a.status.volumePercent(a.status.Volume))
+		a.brightnessBar.SetPercent(a.status.brightnessPercent())
 		return a, a.listenForResults()
 
 	case spotifyUpdateMsg:
@@ -1078,7 +1335,7 @@ func (a *App) View() string {
 		volumeInputPanel := inputStyle.Render(
 			labelStyle.Render("Set Volume") + "\n\n" +
 				a.volumeInput.View() + "\n\n" +
-				mutedTextStyle.Render("Press Enter to confirm, Esc to cancel"),
+				mutedTextStyle.Render("Press Enter to confirm, f to fade to it, Esc to cancel"),
 		)
 		sections = append(sections, volumeInputPanel)
 	}
@@ -1144,6 +1401,10 @@ func (a *App) View() string {
 		sections = append(sections, adjustPanel)
 	}
 
+	if a.discovering {
+		sections = append(sections, a.renderDiscoveryProgress())
+	}
+
 	// Message area - make it responsive
 	if a.message != "" {
 		sections = append(sections, a.renderMessage())
@@ -1388,7 +1649,7 @@ func (a *App) renderDeviceTab(availableHeight int) string {
 				successTextStyle.Render(status),
 		)
 	} else {
-		status := "🔴 Disconnected\n\nPress 'd' to discover devices"
+		status := "🔴 Disconnected\n\nPress 'd' to discover devices or 'c' to choose one"
 		connectionPanel = leftErrorPanelStyle.Render(
 			labelStyle.Render("Connection Status") + "\n\n" +
 				errorTextStyle.Render(status),
@@ -1406,7 +1667,7 @@ func (a *App) renderDeviceTab(availableHeight int) string {
 		deviceInfo := leftPanelStyle.Render(
 			labelStyle.Render("Device Information") + "\n\n" +
 				fmt.Sprintf("Model: %s\n", valueStyle.Render(a.status.Model)) +
-				fmt.Sprintf("IP: %s", valueStyle.Render(a.status.IP)),
+				fmt.Sprintf("Address: %s", valueStyle.Render(a.status.IP)),
 		)
 
 		panelHeight = strings.Count(deviceInfo, "\n") + 2 // +2 for spacing
@@ -1455,14 +1716,14 @@ func (a *App) renderDeviceTab(availableHeight int) string {
 	if a.connected {
 		// Power Status Panel (highest priority)
 		var powerStatus string
-		if a.status.Power == "On" {
+		if a.status.Power == nadapi.PowerOn {
 			powerStatus = powerOnStyle.Render(" POWER ON ")
 		} else {
 			powerStatus = powerOffStyle.Render(" POWER OFF ")
 		}
 
 		powerPanel := rightPanelStyle.Render(
-			labelStyle.Render("Power Status") + "\n\n" +
+			labelStyle.Render("Power Status"+a.zoneLabel()+a.groupLabel()) + "\n\n" +
 				powerStatus + "\n\n" +
 				mutedTextStyle.Render("Press 'p' to toggle"),
 		)
@@ -1473,10 +1734,30 @@ func (a *App) renderDeviceTab(availableHeight int) string {
 			rightHeight += panelHeight
 		}
 
+		// Source Picker Panel (while choosing an input)
+		if a.sourcePickerMode {
+			pickerPanel := a.renderSourcePickerPanel(rightPanelStyle)
+			panelHeight = strings.Count(pickerPanel, "\n") + 2 // +2 for spacing
+			if rightHeight+panelHeight <= availableHeight {
+				rightPanels = append(rightPanels, pickerPanel)
+				rightHeight += panelHeight
+			}
+		}
+
+		// Device Switcher Panel (while choosing a device)
+		if a.devicePickerMode {
+			pickerPanel := a.renderDevicePickerPanel(rightPanelStyle)
+			panelHeight = strings.Count(pickerPanel, "\n") + 2 // +2 for spacing
+			if rightHeight+panelHeight <= availableHeight {
+				rightPanels = append(rightPanels, pickerPanel)
+				rightHeight += panelHeight
+			}
+		}
+
 		// Audio Controls Panel (high priority)
 		if rightHeight < availableHeight-10 {
 			var muteStatus string
-			if a.status.Mute == "On" {
+			if a.status.Muted {
 				muteStatus = errorTextStyle.Render("🔇 MUTED")
 			} else {
 				muteStatus = successTextStyle.Render("🔊 UNMUTED")
@@ -1487,17 +1768,17 @@ func (a *App) renderDeviceTab(availableHeight int) string {
 			var volumeBar string
 			if a.adjustMode {
 				volumeDisplay = fmt.Sprintf("%.1f dB (adjusting...)", a.pendingVolume)
-				volumeBar = a.volumeBar.ViewAs((a.pendingVolume + 80) / 90)
+				volumeBar = a.volumeBar.ViewAs(a.status.volumePercent(a.pendingVolume))
 			} else {
-				volumeDisplay = a.status.VolumeStr
-				volumeBar = a.volumeBar.ViewAs((a.status.Volume + 80) / 90)
+				volumeDisplay = a.status.volumeString()
+				volumeBar = a.volumeBar.ViewAs(a.status.volumePercent(a.status.Volume))
 			}
 
 			audioPanel := rightPanelStyle.Render(
-				labelStyle.Render("Audio Controls") + "\n\n" +
+				labelStyle.Render("Audio Controls"+a.zoneLabel()+a.groupLabel()) + "\n\n" +
 					fmt.Sprintf("Volume: %s\n", valueStyle.Render(volumeDisplay)) +
 					volumeBar + "\n\n" +
-					fmt.Sprintf("Source: %s\n", valueStyle.Render(a.status.Source)) +
+					fmt.Sprintf("Source: %s\n", valueStyle.Render(a.status.SourceName)) +
 					fmt.Sprintf("Mute: %s", muteStatus),
 			)
 
@@ -1510,11 +1791,11 @@ func (a *App) renderDeviceTab(availableHeight int) string {
 
 		// Display Controls Panel (medium priority)
 		if rightHeight < availableHeight-8 {
-			brightnessBar := a.brightnessBar.ViewAs(float64(a.status.Brightness) / 3)
+			brightnessBar := a.brightnessBar.ViewAs(a.status.brightnessPercent())
 
 			displayPanel := rightPanelStyle.Render(
 				labelStyle.Render("Display Controls") + "\n\n" +
-					fmt.Sprintf("Brightness: %s\n", valueStyle.Render(a.status.BrightnessStr)) +
+					fmt.Sprintf("Brightness: %s\n", valueStyle.Render(a.status.brightnessString())) +
 					brightnessBar + "\n\n" +
 					mutedTextStyle.Render("Use ↑↓ keys to adjust"),
 			)
@@ -1522,6 +1803,30 @@ func (a *App) renderDeviceTab(availableHeight int) string {
 			panelHeight = strings.Count(displayPanel, "\n") + 2 // +2 for spacing
 			if rightHeight+panelHeight <= availableHeight {
 				rightPanels = append(rightPanels, displayPanel)
+				rightHeight += panelHeight
+			}
+		}
+
+		// Tone Controls Panel (low priority, models with tone controls only)
+		if a.status.HasTone && rightHeight < availableHeight-8 {
+			tone := a.status.Tone
+			defeat := mutedTextStyle.Render("Off")
+			if tone.Defeat {
+				defeat = warningTextStyle.Render("On (bass/treble bypassed)")
+			}
+
+			tonePanel := rightPanelStyle.Render(
+				labelStyle.Render("Tone Controls") + "\n\n" +
+					fmt.Sprintf("Bass: %s\n", valueStyle.Render(fmt.Sprintf("%+d dB", tone.Bass))) +
+					fmt.Sprintf("Treble: %s\n", valueStyle.Render(fmt.Sprintf("%+d dB", tone.Treble))) +
+					fmt.Sprintf("Balance: %s\n", valueStyle.Render(balanceString(tone.Balance))) +
+					fmt.Sprintf("Tone defeat: %s\n\n", defeat) +
+					mutedTextStyle.Render("[ ] bass, { } treble, , . balance, o defeat"),
+			)
+
+			panelHeight = strings.Count(tonePanel, "\n") + 2 // +2 for spacing
+			if rightHeight+panelHeight <= availableHeight {
+				rightPanels = append(rightPanels, tonePanel)
 			}
 		}
 	} else {
@@ -1531,10 +1836,15 @@ func (a *App) renderDeviceTab(availableHeight int) string {
 				mutedTextStyle.Render("Connect to a device to see controls") + "\n\n" +
 				mutedTextStyle.Render("Available commands:") + "\n" +
 				mutedTextStyle.Render("d - Discover devices") + "\n" +
+				mutedTextStyle.Render("c - Choose a device") + "\n" +
 				mutedTextStyle.Render("r - Refresh status") + "\n" +
 				mutedTextStyle.Render("? - Toggle help"),
 		)
 		rightPanels = append(rightPanels, helpPanel)
+
+		if a.devicePickerMode {
+			rightPanels = append(rightPanels, a.renderDevicePickerPanel(rightPanelStyle))
+		}
 	}
 
 	// Combine left and right columns
@@ -1586,7 +1896,7 @@ func (a *App) renderDeviceTabVertical(availableHeight int, panelWidth int) strin
 				successTextStyle.Render(status),
 		)
 	} else {
-		status := "🔴 Disconnected\n\nPress 'd' to discover devices"
+		status := "🔴 Disconnected\n\nPress 'd' to discover devices or 'c' to choose one"
 		connectionPanel = errorPanelStyle.Render(
 			labelStyle.Render("Connection Status") + "\n\n" +
 				errorTextStyle.Render(status),
@@ -1603,30 +1913,49 @@ func (a *App) renderDeviceTabVertical(availableHeight int, panelWidth int) strin
 	if a.connected && currentHeight < availableHeight-8 {
 		// Power and audio in one combined panel for vertical layout
 		var powerStatus string
-		if a.status.Power == "On" {
+		if a.status.Power == nadapi.PowerOn {
 			powerStatus = powerOnStyle.Render(" POWER ON ")
 		} else {
 			powerStatus = powerOffStyle.Render(" POWER OFF ")
 		}
 
 		var muteStatus string
-		if a.status.Mute == "On" {
+		if a.status.Muted {
 			muteStatus = errorTextStyle.Render("🔇 MUTED")
 		} else {
 			muteStatus = successTextStyle.Render("🔊 UNMUTED")
 		}
 
 		controlPanel := panelStyle.Render(
-			labelStyle.Render("Device Controls") + "\n\n" +
+			labelStyle.Render("Device Controls"+a.zoneLabel()+a.groupLabel()) + "\n\n" +
 				fmt.Sprintf("Power: %s\n", powerStatus) +
-				fmt.Sprintf("Volume: %s\n", valueStyle.Render(a.status.VolumeStr)) +
-				fmt.Sprintf("Source: %s\n", valueStyle.Render(a.status.Source)) +
+				fmt.Sprintf("Volume: %s\n", valueStyle.Render(a.status.volumeString())) +
+				fmt.Sprintf("Source: %s\n", valueStyle.Render(a.status.SourceName)) +
 				fmt.Sprintf("Mute: %s", muteStatus),
 		)
 
 		panelHeight = strings.Count(controlPanel, "\n") + 2
 		if currentHeight+panelHeight <= availableHeight {
 			panels = append(panels, controlPanel)
+			currentHeight += panelHeight
+		}
+
+		if a.sourcePickerMode {
+			pickerPanel := a.renderSourcePickerPanel(panelStyle)
+			panelHeight = strings.Count(pickerPanel, "\n") + 2
+			if currentHeight+panelHeight <= availableHeight {
+				panels = append(panels, pickerPanel)
+				currentHeight += panelHeight
+			}
+		}
+	}
+
+	// The device switcher is available whether or not a device is connected
+	if a.devicePickerMode {
+		pickerPanel := a.renderDevicePickerPanel(panelStyle)
+		panelHeight = strings.Count(pickerPanel, "\n") + 2
+		if currentHeight+panelHeight <= availableHeight {
+			panels = append(panels, pickerPanel)
 		}
 	}
 
@@ -1780,9 +2109,9 @@ func (a *App) renderSettingsTab(availableHeight int) string {
 	if currentHeight < availableHeight-10 {
 		var deviceIP string
 		if a.connected && a.device != nil {
-			deviceIP = a.device.IP.String()
+			deviceIP = deviceAddress(a.device)
 		} else {
-			deviceIP = viper.GetString("ip")
+			deviceIP = nadapi.AddressFromSettings(viper.GetViper())
 			if deviceIP == "" {
 				deviceIP = "Auto-discovery"
 			}
@@ -1790,7 +2119,7 @@ func (a *App) renderSettingsTab(availableHeight int) string {
 
 		deviceSettings := panelStyle.Render(
 			labelStyle.Render("🎛️ Device Settings") + "\n\n" +
-				fmt.Sprintf("Device IP: %s\n", valueStyle.Render(deviceIP)) +
+				fmt.Sprintf("Device Address: %s\n", valueStyle.Render(deviceIP)) +
 				fmt.Sprintf("Discovery Enabled: %s\n", valueStyle.Render("true")) +
 				fmt.Sprintf("Cache TTL: %s", valueStyle.Render("5 minutes")),
 		)
@@ -1802,6 +2131,44 @@ func (a *App) renderSettingsTab(availableHeight int) string {
 		}
 	}
 
+	// Discovered Devices Panel (if a discovery found any and space available)
+	if len(a.discovered) > 0 && currentHeight < availableHeight-10 {
+		var lines []string
+		for _, dev := range a.discovered {
+			lines = append(lines, fmt.Sprintf("%s at %s", valueStyle.Render(dev.Model), net.JoinHostPort(dev.IP, dev.Port)))
+			var details []string
+			if dev.Firmware != "" {
+				details = append(details, "firmware "+dev.Firmware)
+			}
+			if dev.MAC != "" {
+				details = append(details, dev.MAC)
+			}
+			if dev.Hostname != "" {
+				details = append(details, dev.Hostname)
+			}
+			if dev.Latency > 0 {
+				details = append(details, dev.Latency.Round(time.Millisecond/10).String())
+			}
+			if dev.Method != "" {
+				details = append(details, "via "+dev.Method)
+			}
+			if len(details) > 0 {
+				lines = append(lines, "  "+strings.Join(details, ", "))
+			}
+		}
+
+		discovered := panelStyle.Render(
+			labelStyle.Render("📡 Discovered Devices") + "\n\n" +
+				strings.Join(lines, "\n"),
+		)
+
+		panelHeight = strings.Count(discovered, "\n") + 2 // +2 for spacing
+		if currentHeight+panelHeight <= availableHeight {
+			panels = append(panels, discovered)
+			currentHeight += panelHeight
+		}
+	}
+
 	// Spotify Settings Panel (if space available)
 	if currentHeight < availableHeight-10 {
 		var spotifyStatus string
@@ -2044,6 +2411,36 @@ func (a *App) renderHelpTab(availableHeight int) string {
 	return a.help.View(a.keys)
 }
 
+// renderDiscoveryProgress shows how far the running device discovery has come
+func (a *App) renderDiscoveryProgress() string {
+	width := a.width - 4
+	if width < 40 {
+		width = 40
+	}
+
+	p := a.discovery
+	text := fmt.Sprintf("%s Searching for NAD devices", a.spinner)
+	percent := 0.0
+	switch {
+	case p.Total > 0:
+		text = fmt.Sprintf("%s Sweeping %s: %d/%d addresses", a.spinner, p.Subnet, p.Probed, p.Total)
+		percent = float64(p.Probed) / float64(p.Total)
+	case p.Provider != "":
+		text = fmt.Sprintf("%s Listening for %s announcements", a.spinner, p.Provider)
+	}
+	text += fmt.Sprintf(", %d found", p.Found)
+
+	a.discoveryBar.Width = width - 6
+	style := lipgloss.NewStyle().
+		Foreground(primaryColor).
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(primaryColor).
+		Padding(0, 1).
+		Margin(0, 2).
+		Width(width)
+	return style.Render(text + "\n" + a.discoveryBar.ViewAs(percent))
+}
+
 func (a *App) renderMessage() string {
 	var style lipgloss.Style
 	var icon string
@@ -2118,9 +2515,6 @@ func (a *App) processCommands() {
 			a.processing = true
 			a.executeCommand(cmd)
 			a.processing = false
-
-			// Small delay to prevent overwhelming the device
-			time.Sleep(100 * time.Millisecond)
 		} else {
 			// No commands, wait a bit before checking again
 			time.Sleep(50 * time.Millisecond)
@@ -2129,6 +2523,11 @@ func (a *App) processCommands() {
 }
 
 func (a *App) executeCommand(cmd QueuedCommand) {
+	if group, ok := cmd.Params["group"].(*nadapi.GroupExecutor); ok {
+		a.executeGroupCommand(group, cmd)
+		return
+	}
+
 	// For device control commands, ensure we have a device
 	if cmd.Type != CmdDiscoverDevices && cmd.Type != CmdConnectDevice && cmd.Type != CmdRefreshStatus &&
 		cmd.Type != CmdSpotifyPlayPause && cmd.Type != CmdSpotifyNext && cmd.Type != CmdSpotifyPrev &&
@@ -2142,37 +2541,68 @@ func (a *App) executeCommand(cmd QueuedCommand) {
 
 	switch cmd.Type {
 	case CmdPowerToggle:
-		err = a.device.PowerToggle()
+		err = a.device.Zone(cmd.Zone).PowerToggleContext(a.ctx)
 
 	case CmdMuteToggle:
-		err = a.device.ToggleMute()
+		err = a.device.Zone(cmd.Zone).ToggleMuteContext(a.ctx)
 
 	case CmdVolumeSet:
 		if volume, ok := cmd.Params["volume"].(float64); ok {
-			err = a.device.SetVolume(volume)
+			err = a.device.Zone(cmd.Zone).SetVolumeContext(a.ctx, volume)
+		}
+
+	case CmdVolumeFade:
+		if volume, ok := cmd.Params["volume"].(float64); ok {
+			err = a.fadeVolume(cmd.Zone, volume)
 		}
 
 	case CmdVolumeUp:
-		err = a.device.TuneVolume(nadapi.DirectionUp)
+		err = a.device.Zone(cmd.Zone).TuneVolumeContext(a.ctx, nadapi.DirectionUp)
 
 	case CmdVolumeDown:
-		err = a.device.TuneVolume(nadapi.DirectionDown)
+		err = a.device.Zone(cmd.Zone).TuneVolumeContext(a.ctx, nadapi.DirectionDown)
 
 	case CmdSourceNext:
-		_, err = a.device.ToggleSource(nadapi.DirectionUp)
+		_, err = a.device.Zone(cmd.Zone).ToggleSourceContext(a.ctx, nadapi.DirectionUp)
 
 	case CmdSourcePrev:
-		_, err = a.device.ToggleSource(nadapi.DirectionDown)
+		_, err = a.device.Zone(cmd.Zone).ToggleSourceContext(a.ctx, nadapi.DirectionDown)
+
+	case CmdSourceSet:
+		if source, ok := cmd.Params["source"].(string); ok {
+			err = a.device.Zone(cmd.Zone).SetSourceContext(a.ctx, source)
+		}
 
 	case CmdBrightnessUp:
-		err = a.device.ToggleBrightness(nadapi.DirectionUp)
+		err = a.device.ToggleBrightnessContext(a.ctx, nadapi.DirectionUp)
 
 	case CmdBrightnessDown:
-		err = a.device.ToggleBrightness(nadapi.DirectionDown)
+		err = a.device.ToggleBrightnessContext(a.ctx, nadapi.DirectionDown)
+
+	case CmdBassUp:
+		err = a.device.TuneBassContext(a.ctx, nadapi.DirectionUp)
+
+	case CmdBassDown:
+		err = a.device.TuneBassContext(a.ctx, nadapi.DirectionDown)
+
+	case CmdTrebleUp:
+		err = a.device.TuneTrebleContext(a.ctx, nadapi.DirectionUp)
+
+	case CmdTrebleDown:
+		err = a.device.TuneTrebleContext(a.ctx, nadapi.DirectionDown)
+
+	case CmdBalanceLeft:
+		err = a.device.TuneBalanceContext(a.ctx, nadapi.DirectionDown)
+
+	case CmdBalanceRight:
+		err = a.device.TuneBalanceContext(a.ctx, nadapi.DirectionUp)
+
+	case CmdToneDefeatToggle:
+		err = a.device.ToggleToneDefeatContext(a.ctx)
 
 	case CmdRefreshStatus:
 		// Refresh status is handled differently
-		a.refreshStatusSync()
+		a.refreshStatusSync(cmd.Zone)
 		return
 
 	case CmdDiscoverDevices:
@@ -2181,7 +2611,8 @@ func (a *App) executeCommand(cmd QueuedCommand) {
 
 	case CmdConnectDevice:
 		if ip, ok := cmd.Params["ip"].(string); ok {
-			a.connectToDeviceSync(ip)
+			port, _ := cmd.Params["port"].(string)
+			a.connectToDeviceSync(ip, port)
 		}
 		return
 
@@ -2292,42 +2723,26 @@ func (a *App) executeCommand(cmd QueuedCommand) {
 		return
 	}
 
-	// Handle communication errors with retry logic
+	// Lost connections are re-established by the supervisor, which holds
+	// commands sent meanwhile
+	if errors.Is(err, nadapi.ErrSuperseded) {
+		// A newer set of the same setting took its place and refreshes
+		// the status itself
+		log.WithField("command", cmd.Type).Debug("Command superseded")
+		return
+	}
 	if err != nil {
-		if strings.Contains(err.Error(), "timeout") ||
-			strings.Contains(err.Error(), "connection") ||
-			strings.Contains(err.Error(), "EOF") {
-
-			log.WithError(err).WithField("command", cmd.Type).Debug("Communication error, attempting reconnection")
-
-			// Try to reconnect and retry
-			if newDevice, reconnectErr := nadapi.New(a.device.IP.String(), a.device.Port); reconnectErr == nil {
-				// Properly close the old connection first
-				if oldDevice := a.device; oldDevice != nil {
-					if disconnectErr := oldDevice.Disconnect(); disconnectErr != nil {
-						log.WithError(disconnectErr).Debug("Error closing old connection during reconnect")
-					} else {
-						log.Debug("Successfully closed old connection during reconnect")
-					}
-				}
-
-				a.device = newDevice
-				log.WithField("command", cmd.Type).Debug("Reconnected, retrying command")
-
-				// Retry the command once
-				a.executeCommand(cmd)
-				return
-			} else {
-				log.WithError(reconnectErr).WithField("command", cmd.Type).Debug("Failed to reconnect")
-			}
-		}
 		log.WithError(err).WithField("command", cmd.Type).Debug("Command failed")
+		if errors.Is(err, nadapi.ErrVolumeLimit) {
+			a.sendResult(messageMsg{text: fmt.Sprintf("Refused: %v", err), msgType: MessageWarning})
+		}
 	} else {
 		// Command succeeded, queue a status refresh to show the result
 		if cmd.Type != CmdRefreshStatus {
 			a.commandQueue.Add(QueuedCommand{
 				Type:      CmdRefreshStatus,
 				Params:    nil,
+				Zone:      cmd.Zone,
 				ID:        fmt.Sprintf("refresh-%d", time.Now().UnixNano()),
 				Timestamp: time.Now(),
 			})
@@ -2336,15 +2751,113 @@ func (a *App) executeCommand(cmd QueuedCommand) {
 }
 
 func (a *App) queueCommand(cmdType CommandType, params map[string]interface{}) {
+	if a.group != nil {
+		cmdType, params = a.groupCommand(cmdType, params)
+	}
 	cmd := QueuedCommand{
 		Type:      cmdType,
 		Params:    params,
+		Zone:      a.zone,
 		ID:        fmt.Sprintf("%d-%d", cmdType, time.Now().UnixNano()),
 		Timestamp: time.Now(),
 	}
 	a.commandQueue.Add(cmd)
 }
 
+// groupCommand turns a power, mute, volume or source command into its
+// group counterpart while a group is active. Toggles follow the state of the
+// connected device, so that the devices of the group end up alike.
+func (a *App) groupCommand(cmdType CommandType, params map[string]interface{}) (CommandType, map[string]interface{}) {
+	groupParams := map[string]interface{}{"group": a.group}
+	// The connected member runs the commands on the connection the TUI
+	// shows, and the displayed volume includes its offset
+	var offset float64
+	if member, ok := a.connectedMember(); ok {
+		groupParams["member"] = member
+		groupParams["device"] = a.device
+		offset = a.group.Group.Members[member].VolumeOffset
+	}
+	switch cmdType {
+	case CmdPowerToggle:
+		groupParams["on"] = a.status.Power != nadapi.PowerOn
+		return CmdGroupPower, groupParams
+	case CmdMuteToggle:
+		groupParams["muted"] = !a.status.Muted
+		return CmdGroupMute, groupParams
+	case CmdVolumeSet, CmdVolumeFade:
+		// Groups jump to the volume rather than fade
+		if volume, ok := params["volume"].(float64); ok {
+			groupParams["volume"] = volume - offset
+		}
+		return CmdGroupVolumeSet, groupParams
+	case CmdVolumeUp:
+		return CmdGroupVolumeUp, groupParams
+	case CmdVolumeDown:
+		return CmdGroupVolumeDown, groupParams
+	case CmdSourceSet:
+		groupParams["source"] = params["source"]
+		return CmdGroupSourceSet, groupParams
+	}
+	return cmdType, params
+}
+
+// executeGroupCommand runs a group command on every device of group and
+// reports the devices it failed on
+func (a *App) executeGroupCommand(group *nadapi.GroupExecutor, cmd QueuedCommand) {
+	group.SetZone(cmd.Zone)
+	if device, ok := cmd.Params["device"].(*nadapi.Device); ok {
+		group.Share(cmd.Params["member"].(int), device)
+	}
+
+	var results []nadapi.GroupResult
+	switch cmd.Type {
+	case CmdGroupPower:
+		if on, _ := cmd.Params["on"].(bool); on {
+			results = group.PowerOn(a.ctx)
+		} else {
+			results = group.PowerOff(a.ctx)
+		}
+	case CmdGroupMute:
+		muted, _ := cmd.Params["muted"].(bool)
+		results = group.SetMute(a.ctx, muted)
+	case CmdGroupVolumeSet:
+		if volume, ok := cmd.Params["volume"].(float64); ok {
+			results = group.SetVolume(a.ctx, volume)
+		}
+	case CmdGroupVolumeUp:
+		results = group.TuneVolume(a.ctx, nadapi.DirectionUp)
+	case CmdGroupVolumeDown:
+		results = group.TuneVolume(a.ctx, nadapi.DirectionDown)
+	case CmdGroupSourceSet:
+		if source, ok := cmd.Params["source"].(string); ok {
+			results = group.SetSource(a.ctx, source)
+		}
+	}
+
+	if err := nadapi.GroupError(results); err != nil {
+		a.sendResult(messageMsg{text: fmt.Sprintf("Group %s: %v", group.Group.Name, err), msgType: MessageError})
+	} else {
+		a.sendResult(messageMsg{text: fmt.Sprintf("Group %s: done on %d devices", group.Group.Name, len(results)), msgType: MessageSuccess})
+	}
+	if a.device != nil {
+		a.commandQueue.Add(QueuedCommand{
+			Type:      CmdRefreshStatus,
+			Zone:      cmd.Zone,
+			ID:        fmt.Sprintf("refresh-%d", time.Now().UnixNano()),
+			Timestamp: time.Now(),
+		})
+	}
+}
+
+// deviceAddress returns the host of a network device as configured, which
+// may be a host name, or the address URI of a serial device
+func deviceAddress(device *nadapi.Device) string {
+	if device.Host != "" {
+		return device.Host
+	}
+	return device.String()
+}
+
 // Command functions
 func (a *App) connectToDevice() tea.Cmd {
 	// In demo mode, skip device connection
@@ -2353,8 +2866,30 @@ func (a *App) connectToDevice() tea.Cmd {
 		return nil
 	}
 
-	// Try to get IP from config
-	ip := viper.GetString("ip")
+	// A group chosen with --group acts on all its devices and shows the
+	// first one
+	if ref := viper.GetString("group"); ref != "" {
+		return a.selectGroup(ref)
+	}
+
+	// Try to get the device address from config
+	ip, port := nadapi.AddressFromSettings(viper.GetViper()), ""
+
+	// A device chosen with --device is looked up in the configured and
+	// known devices
+	if ref := viper.GetString("device"); ref != "" {
+		entries, err := nadapi.DeviceEntries(viper.GetStringMapString("devices"))
+		if err == nil {
+			var entry nadapi.DeviceEntry
+			if entry, err = nadapi.SelectDevice(entries, ref); err == nil {
+				ip, port = entry.Address, entry.Port
+			}
+		}
+		if err != nil {
+			a.setMessage(fmt.Sprintf("Cannot select device: %v - press 'c' to choose one", err), MessageError)
+			return nil
+		}
+	}
 
 	if ip == "" {
 		// No IP configured, queue discovery first
@@ -2363,7 +2898,7 @@ func (a *App) connectToDevice() tea.Cmd {
 	} else {
 		// IP configured, queue direct connection
 		a.connecting = true
-		params := map[string]interface{}{"ip": ip}
+		params := map[string]interface{}{"ip": ip, "port": port}
 		a.queueCommand(CmdConnectDevice, params)
 		a.setMessage("Connecting to device...", MessageInfo)
 	}
@@ -2418,6 +2953,269 @@ func (a *App) prevSource() tea.Cmd {
 	return nil
 }
 
+// enterSourcePicker opens the input list with the current source selected
+func (a *App) enterSourcePicker() {
+	if len(a.status.Inputs) == 0 {
+		a.setMessage("Source list not loaded yet - press 'r' to refresh", MessageWarning)
+		return
+	}
+	a.sourcePickerMode = true
+	a.sourcePickerSelection = 0
+	for i, in := range a.status.Inputs {
+		if strings.EqualFold(in.Name, a.status.Source) {
+			a.sourcePickerSelection = i
+			break
+		}
+	}
+	a.setMessage("Source selection mode - Use ↑↓ to navigate, Enter to select, Esc to cancel", MessageInfo)
+}
+
+func (a *App) sourcePickerSelectionUp() {
+	if len(a.status.Inputs) == 0 {
+		return
+	}
+	a.sourcePickerSelection--
+	if a.sourcePickerSelection < 0 {
+		a.sourcePickerSelection = len(a.status.Inputs) - 1
+	}
+}
+
+func (a *App) sourcePickerSelectionDown() {
+	if len(a.status.Inputs) == 0 {
+		return
+	}
+	a.sourcePickerSelection++
+	if a.sourcePickerSelection >= len(a.status.Inputs) {
+		a.sourcePickerSelection = 0
+	}
+}
+
+// selectPickedSource queues a switch to the selected input and leaves the picker
+func (a *App) selectPickedSource() tea.Cmd {
+	if a.sourcePickerSelection < 0 || a.sourcePickerSelection >= len(a.status.Inputs) {
+		a.sourcePickerMode = false
+		return nil
+	}
+	selected := a.status.Inputs[a.sourcePickerSelection]
+	if !selected.Enabled {
+		a.setMessage(fmt.Sprintf("'%s' is disabled on the device", selected.DisplayName()), MessageWarning)
+		return nil
+	}
+
+	a.sourcePickerMode = false
+	a.queueCommand(CmdSourceSet, map[string]interface{}{"source": selected.Name})
+	a.setMessage(fmt.Sprintf("Switching to %s...", selected.DisplayName()), MessageInfo)
+	return nil
+}
+
+// enterDevicePicker opens the list of configured and known devices with the
+// connected one selected
+func (a *App) enterDevicePicker() {
+	entries, err := nadapi.DeviceEntries(viper.GetStringMapString("devices"))
+	if err != nil {
+		a.setMessage(fmt.Sprintf("Failed to load devices: %v", err), MessageError)
+		return
+	}
+	if len(entries) == 0 {
+		a.setMessage("No known devices - press 'd' to discover", MessageWarning)
+		return
+	}
+	a.devicePickerEntries = entries
+	a.devicePickerGroups = nadapi.GroupNames(viper.GetStringMapStringSlice("groups"))
+	a.devicePickerMode = true
+	a.devicePickerSelection = 0
+	for i, e := range entries {
+		if a.group == nil && a.isCurrentDevice(e) {
+			a.devicePickerSelection = i
+			break
+		}
+	}
+	for i, name := range a.devicePickerGroups {
+		if a.group != nil && a.group.Group.Name == name {
+			a.devicePickerSelection = len(entries) + i
+		}
+	}
+	a.setMessage("Device selection mode - Use ↑↓ to navigate, Enter to connect, Esc to cancel", MessageInfo)
+}
+
+// devicePickerLen returns the number of devices and groups in the switcher
+func (a *App) devicePickerLen() int {
+	return len(a.devicePickerEntries) + len(a.devicePickerGroups)
+}
+
+// isCurrentDevice reports whether e is the connected device
+func (a *App) isCurrentDevice(e nadapi.DeviceEntry) bool {
+	if !a.connected || a.device == nil {
+		return false
+	}
+	if a.device.Host == "" {
+		return a.device.String() == e.Address
+	}
+	return a.device.Host == e.Address && (e.Port == "" || a.device.Port == e.Port)
+}
+
+func (a *App) devicePickerSelectionUp() {
+	if a.devicePickerLen() == 0 {
+		return
+	}
+	a.devicePickerSelection--
+	if a.devicePickerSelection < 0 {
+		a.devicePickerSelection = a.devicePickerLen() - 1
+	}
+}
+
+func (a *App) devicePickerSelectionDown() {
+	if a.devicePickerLen() == 0 {
+		return
+	}
+	a.devicePickerSelection++
+	if a.devicePickerSelection >= a.devicePickerLen() {
+		a.devicePickerSelection = 0
+	}
+}
+
+// selectPickedDevice queues a connection to the selected device, or
+// activates the selected group, and leaves the switcher
+func (a *App) selectPickedDevice() tea.Cmd {
+	a.devicePickerMode = false
+	if a.devicePickerSelection < 0 || a.devicePickerSelection >= a.devicePickerLen() {
+		return nil
+	}
+	if i := a.devicePickerSelection - len(a.devicePickerEntries); i >= 0 {
+		return a.selectGroup(a.devicePickerGroups[i])
+	}
+
+	selected := a.devicePickerEntries[a.devicePickerSelection]
+	if a.group != nil {
+		a.setGroup(nil)
+		if a.isCurrentDevice(selected) {
+			a.setMessage(fmt.Sprintf("Controlling %s only", selected.String()), MessageInfo)
+			return nil
+		}
+	}
+	if a.isCurrentDevice(selected) {
+		a.setMessage(fmt.Sprintf("Already connected to %s", selected.String()), MessageInfo)
+		return nil
+	}
+
+	a.connecting = true
+	a.queueCommand(CmdConnectDevice, map[string]interface{}{"ip": selected.Address, "port": selected.Port})
+	a.setMessage(fmt.Sprintf("Switching to %s...", selected.String()), MessageInfo)
+	return nil
+}
+
+// selectGroup makes the power, mute, volume and source picker keys act on
+// every device of the group ref selects, and connects to its first device to
+// show its status
+func (a *App) selectGroup(ref string) tea.Cmd {
+	entries, err := nadapi.DeviceEntries(viper.GetStringMapString("devices"))
+	if err != nil {
+		a.setMessage(fmt.Sprintf("Failed to load devices: %v", err), MessageError)
+		return nil
+	}
+	group, err := nadapi.ResolveGroup(viper.GetStringMapStringSlice("groups"), entries, ref)
+	if err != nil {
+		a.setMessage(fmt.Sprintf("Cannot select group: %v", err), MessageError)
+		return nil
+	}
+	first, err := nadapi.SelectDevice(entries, group.Members[0].Device)
+	if err != nil {
+		a.setMessage(fmt.Sprintf("Cannot select group %s: %v", group.Name, err), MessageError)
+		return nil
+	}
+
+	a.setGroup(nadapi.NewGroupExecutor(group, func(ctx context.Context, m nadapi.GroupMember) (*nadapi.Device, error) {
+		entry, err := nadapi.SelectDevice(entries, m.Device)
+		if err != nil {
+			return nil, err
+		}
+		return nadapi.NewFromSettings(ctx, entry.Address, entry.Port, viper.GetViper())
+	}))
+	a.setMessage(fmt.Sprintf("Controlling group %s (%d devices)", group.Name, len(group.Members)), MessageInfo)
+	if _, ok := a.connectedMember(); !ok {
+		a.connecting = true
+		a.queueCommand(CmdConnectDevice, map[string]interface{}{"ip": first.Address, "port": first.Port})
+	}
+	return nil
+}
+
+// connectedMember returns the index of the member of the active group that
+// is the connected device, matched by address
+func (a *App) connectedMember() (int, bool) {
+	if a.group == nil {
+		return 0, false
+	}
+	entries, err := nadapi.DeviceEntries(viper.GetStringMapString("devices"))
+	if err != nil {
+		return 0, false
+	}
+	for i, m := range a.group.Group.Members {
+		if e, err := nadapi.SelectDevice(entries, m.Device); err == nil && a.isCurrentDevice(e) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// setGroup replaces the active group, disconnecting the devices of the
+// previous one
+func (a *App) setGroup(group *nadapi.GroupExecutor) {
+	if a.group != nil {
+		// Closing waits for a group command in flight
+		go a.group.Close()
+	}
+	a.group = group
+}
+
+// groupLabel names the active group in the titles of the panels its keys
+// act on
+func (a *App) groupLabel() string {
+	if a.group == nil {
+		return ""
+	}
+	return fmt.Sprintf(" [group %s]", a.group.Group.Name)
+}
+
+// switchZone selects the next zone of the model for the power, volume,
+// source and mute keys
+func (a *App) switchZone() tea.Cmd {
+	zones := a.status.Caps.Zones()
+	if !a.status.Known || len(zones) < 2 {
+		a.setMessage("This model has a single zone", MessageWarning)
+		return nil
+	}
+	next := zones[0]
+	for i, z := range zones {
+		if z == a.zone {
+			next = zones[(i+1)%len(zones)]
+			break
+		}
+	}
+	a.zone = next
+	a.queueCommand(CmdRefreshStatus, nil)
+	a.setMessage(fmt.Sprintf("Controlling %s zone", next), MessageInfo)
+	return nil
+}
+
+// zoneLabel names the selected zone in panel titles of multi-zone models
+func (a *App) zoneLabel() string {
+	if !a.status.Known || len(a.status.Caps.Zones()) < 2 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", a.zone)
+}
+
+// toneCommand queues a tone control command if the model has tone controls
+func (a *App) toneCommand(cmdType CommandType, description string) tea.Cmd {
+	if !a.status.HasTone {
+		a.setMessage("Tone controls are not available on this model", MessageWarning)
+		return nil
+	}
+	a.queueCommand(cmdType, nil)
+	a.setMessage(description+" queued", MessageInfo)
+	return nil
+}
+
 func (a *App) brightnessUp() tea.Cmd {
 	a.queueCommand(CmdBrightnessUp, nil)
 	a.setMessage("Brightness up queued", MessageInfo)
@@ -2430,8 +3228,14 @@ func (a *App) brightnessDown() tea.Cmd {
 	return nil
 }
 
-func (a *App) setSpecificVolume(volume float64) tea.Cmd {
+// setSpecificVolume queues setting the volume, or fading to it with fade
+func (a *App) setSpecificVolume(volume float64, fade bool) tea.Cmd {
 	params := map[string]interface{}{"volume": volume}
+	if fade {
+		a.queueCommand(CmdVolumeFade, params)
+		a.setMessage(fmt.Sprintf("Volume fade to %.1f dB queued", volume), MessageInfo)
+		return nil
+	}
 	a.queueCommand(CmdVolumeSet, params)
 	a.setMessage(fmt.Sprintf("Volume %.1f dB queued", volume), MessageInfo)
 	return nil
@@ -2440,14 +3244,15 @@ func (a *App) setSpecificVolume(volume float64) tea.Cmd {
 // Volume adjustment mode methods
 func (a *App) adjustPendingVolume(delta float64) {
 	a.pendingVolume += delta
-	// Clamp to valid range
-	if a.pendingVolume < -80 {
-		a.pendingVolume = -80
+	// Clamp to the range of the model
+	min, max := a.status.volumeRange()
+	if a.pendingVolume < min {
+		a.pendingVolume = min
 	}
-	if a.pendingVolume > 10 {
-		a.pendingVolume = 10
+	if a.pendingVolume > max {
+		a.pendingVolume = max
 	}
-	a.setMessage(fmt.Sprintf("Adjusting volume: %.1f dB (Press Enter to apply, Esc to cancel)", a.pendingVolume), MessageInfo)
+	a.setMessage(fmt.Sprintf("Adjusting volume: %.1f dB (Press Enter to apply, f to fade, Esc to cancel)", a.pendingVolume), MessageInfo)
 }
 
 func (a *App) resetAdjustTimer() tea.Cmd {
@@ -2465,7 +3270,7 @@ func (a *App) resetAdjustTimer() tea.Cmd {
 	}
 }
 
-func (a *App) commitVolumeAdjustment() tea.Cmd {
+func (a *App) commitVolumeAdjustment(fade bool) tea.Cmd {
 	if a.adjustTimer != nil {
 		a.adjustTimer.Stop()
 		a.adjustTimer = nil
@@ -2475,11 +3280,7 @@ func (a *App) commitVolumeAdjustment() tea.Cmd {
 	a.adjustMode = false
 	a.pendingVolume = 0
 	a.originalVolume = 0
-
-	params := map[string]interface{}{"volume": volume}
-	a.queueCommand(CmdVolumeSet, params)
-	a.setMessage(fmt.Sprintf("Volume %.1f dB queued", volume), MessageInfo)
-	return nil
+	return a.setSpecificVolume(volume, fade)
 }
 
 func (a *App) cancelVolumeAdjustment() tea.Cmd {
@@ -2524,6 +3325,29 @@ type deviceErrorMsg struct {
 	err error
 }
 
+// discoveryProgressMsg reports how far a device discovery has come
+type discoveryProgressMsg struct {
+	progress nadapi.DiscoveryProgress
+}
+
+// discoveryDeviceMsg reports a device found by a running discovery
+type discoveryDeviceMsg struct {
+	device nadapi.DiscoveredDevice
+}
+
+// discoveryDoneMsg reports that a device discovery has finished
+type discoveryDoneMsg struct{}
+
+// fadeProgressMsg reports a step of a volume fade
+type fadeProgressMsg struct {
+	progress nadapi.FadeProgress
+}
+
+// connStateMsg reports a change in the health of the device connection
+type connStateMsg struct {
+	state nadapi.ConnState
+}
+
 type statusUpdateMsg struct {
 	status DeviceStatus
 }
@@ -2545,98 +3369,61 @@ type spotifyDevicesUpdateMsg struct {
 	devices []spotify.Device
 }
 
-// refreshStatusSync synchronously updates the device status
-func (a *App) refreshStatusSync() {
+// refreshStatusSync synchronously updates the device status, reporting
+// power, volume, source and mute of zone
+func (a *App) refreshStatusSync(zone nadapi.ZoneID) {
 	if a.device == nil {
 		return
 	}
 
-	status := DeviceStatus{IP: a.device.IP.String()}
+	if zone == "" {
+		zone = nadapi.MainZone
+	}
+	status := DeviceStatus{IP: deviceAddress(a.device)}
 
-	// Helper function to handle command errors and potential reconnection
-	handleError := func(operation string, err error) bool {
-		if err == nil {
-			return false
+	// The supervisor holds the read while the connection is re-established
+	state, err := a.device.StateContext(a.ctx)
+	if err != nil {
+		log.WithError(err).Debug("Failed to read device state")
+		status.Volume = -80
+	} else {
+		status.State = state
+		status.Caps, _ = nadapi.LookupCapabilities(state.Model)
+		status.Known = true
+		// Cached by the device after the first read
+		if inputs, err := a.device.InputsContext(a.ctx); err == nil {
+			status.Inputs = inputs
+		} else {
+			log.WithError(err).Debug("Failed to read device inputs")
 		}
-
-		// Check if it's a timeout or connection error
-		if strings.Contains(err.Error(), "timeout") ||
-			strings.Contains(err.Error(), "connection") ||
-			strings.Contains(err.Error(), "EOF") {
-			log.WithError(err).WithField("operation", operation).Debug("Communication error detected, attempting reconnection")
-
-			// Try to reconnect
-			if reconnectErr := a.device.Disconnect(); reconnectErr != nil {
-				log.WithError(reconnectErr).Debug("Error during disconnect for recovery")
+		if status.Caps.HasFeature(nadapi.FeatureToneControls) {
+			if tone, err := a.device.ToneContext(a.ctx); err == nil {
+				status.Tone = tone
+				status.HasTone = true
+			} else {
+				log.WithError(err).Debug("Failed to read tone controls")
 			}
-
-			// Create new device connection
-			newDevice, reconnectErr := nadapi.New(a.device.IP.String(), a.device.Port)
-			if reconnectErr != nil {
-				log.WithError(reconnectErr).Debug("Failed to reconnect after communication error")
-				return true // Indicate error occurred
+		}
+		if zone != nadapi.MainZone {
+			if zs, err := a.device.Zone(zone).StateContext(a.ctx); err == nil {
+				status.Power = zs.Power
+				status.Volume = zs.Volume
+				status.Source = zs.Source
+				status.SourceName = zs.SourceName
+				status.Muted = zs.Muted
+			} else {
+				log.WithError(err).WithField("zone", zone).Debug("Failed to read zone state")
+				zone = nadapi.MainZone
 			}
-
-			a.device = newDevice
-			log.Debug("Successfully reconnected after communication error")
-			return false // Retry succeeded
 		}
-
-		log.WithError(err).WithField("operation", operation).Debug("Non-recoverable error")
-		return true // Indicate error occurred
-	}
-
-	// Get power state with error handling
-	if power, err := a.device.GetPowerState(); handleError("GetPowerState", err) {
-		status.Power = "Unknown"
-	} else {
-		status.Power = power
-	}
-
-	// Get volume with error handling
-	if volume, err := a.device.GetVolumeFloat(); handleError("GetVolumeFloat", err) {
-		status.Volume = -80
-		status.VolumeStr = "Unknown"
-	} else {
-		status.Volume = volume
-		status.VolumeStr = fmt.Sprintf("%.1f dB", volume)
-	}
-
-	// Get source with error handling
-	if source, err := a.device.GetSource(); handleError("GetSource", err) {
-		status.Source = "Unknown"
-	} else {
-		status.Source = source
-	}
-
-	// Get mute status with error handling
-	if mute, err := a.device.GetMuteStatus(); handleError("GetMuteStatus", err) {
-		status.Mute = "Unknown"
-	} else {
-		status.Mute = mute
-	}
-
-	// Get brightness with error handling
-	if brightness, err := a.device.GetBrightnessInt(); handleError("GetBrightnessInt", err) {
-		status.Brightness = 0
-		status.BrightnessStr = "Unknown"
-	} else {
-		status.Brightness = brightness
-		status.BrightnessStr = strconv.Itoa(brightness)
-	}
-
-	// Get model with error handling
-	if model, err := a.device.GetModel(); handleError("GetModel", err) {
-		status.Model = "Unknown"
-	} else {
-		status.Model = model
 	}
+	status.Zone = zone
 
 	// Send status update message to UI thread
 	a.sendResult(statusUpdateMsg{status: status})
 }
 
-func (a *App) connectToDeviceSync(ip string) {
+func (a *App) connectToDeviceSync(ip, port string) {
 	// Send connecting message
 	a.sendResult(messageMsg{text: "Connecting to device...", msgType: MessageInfo})
 
@@ -2651,16 +3438,79 @@ func (a *App) connectToDeviceSync(ip string) {
 		a.connected = false
 	}
 
-	device, err := nadapi.New(ip, "")
+	device, err := nadapi.NewFromSettings(a.ctx, ip, port, viper.GetViper())
 	if err != nil {
 		a.sendResult(deviceErrorMsg{err: err})
 		return
 	}
+	device.Supervise(a.ctx, nadapi.SuperviseOptions{
+		OnStateChange: func(_, state nadapi.ConnState) {
+			a.sendResult(connStateMsg{state: state})
+		},
+	})
+	a.watchDeviceEvents(device)
 	a.sendResult(deviceConnectedMsg{device: device})
 }
 
+// fadeVolume starts fading the volume of zone and reports its steps until
+// it ends, so that the command queue is free meanwhile
+func (a *App) fadeVolume(zone nadapi.ZoneID, volume float64) error {
+	fade, err := a.device.Zone(zone).FadeVolume(a.ctx, volume, volumeFadeDuration, nadapi.FadeLinear)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for p := range fade.Progress() {
+			a.sendResult(fadeProgressMsg{progress: p})
+		}
+		if err := fade.Wait(); err != nil {
+			log.WithError(err).Debug("Volume fade ended early")
+			a.sendResult(messageMsg{text: fmt.Sprintf("Volume fade stopped: %v", err), msgType: MessageWarning})
+		}
+	}()
+	return nil
+}
+
+// watchDeviceEvents refreshes the status whenever the device reports a change
+// made elsewhere, e.g. on the front panel or with the IR remote
+func (a *App) watchDeviceEvents(device *nadapi.Device) {
+	if a.stopEvents != nil {
+		a.stopEvents()
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.stopEvents = cancel
+
+	events := device.Subscribe(ctx)
+	go func() {
+		for ev := range events {
+			log.WithFields(log.Fields{
+				"type":  ev.Type,
+				"value": ev.Value,
+			}).Debug("Device reported state change")
+			a.queueCommand(CmdRefreshStatus, nil)
+		}
+	}()
+}
+
 func (a *App) discoverDevicesSync() {
-	devices, _, err := nadapi.DiscoverDevicesWithCache(30*time.Second, false, nadapi.DefaultCacheTTL)
+	opts, err := nadapi.DiscoveryOptionsFromSettings(viper.GetViper())
+	if err != nil {
+		a.sendResult(messageMsg{text: fmt.Sprintf("Invalid discovery settings: %v", err), msgType: MessageError})
+		return
+	}
+
+	opts.Events = nadapi.DiscoveryEvents{
+		OnDevice: func(device nadapi.DiscoveredDevice) {
+			a.sendResult(discoveryDeviceMsg{device: device})
+		},
+		OnProgress: func(p nadapi.DiscoveryProgress) {
+			a.sendResult(discoveryProgressMsg{progress: p})
+		},
+	}
+
+	a.sendResult(discoveryProgressMsg{})
+	devices, _, err := nadapi.DiscoverDevicesWithCacheOptions(30*time.Second, false, nadapi.DefaultCacheTTL, opts)
+	a.sendResult(discoveryDoneMsg{})
 	if err != nil {
 		a.sendResult(messageMsg{text: fmt.Sprintf("Discovery failed: %v", err), msgType: MessageError})
 		return
@@ -2677,7 +3527,7 @@ func (a *App) discoverDevicesSync() {
 	if !a.connected && len(devices) > 0 {
 		a.commandQueue.Add(QueuedCommand{
 			Type:      CmdConnectDevice,
-			Params:    map[string]interface{}{"ip": devices[0].IP},
+			Params:    map[string]interface{}{"ip": devices[0].IP, "port": devices[0].Port},
 			ID:        fmt.Sprintf("connect-%d", time.Now().UnixNano()),
 			Timestamp: time.Now(),
 		})
@@ -2830,6 +3680,98 @@ func (a *App) renderSpotifyPanel(panelStyle lipgloss.Style) string {
 	return spotifyPanel
 }
 
+// balanceString formats a balance for display, e.g. "3 dB left" or "center"
+func balanceString(db int) string {
+	switch {
+	case db < 0:
+		return fmt.Sprintf("%d dB left", -db)
+	case db > 0:
+		return fmt.Sprintf("%d dB right", db)
+	default:
+		return "center"
+	}
+}
+
+// renderSourcePickerPanel renders the input list of the source picker
+func (a *App) renderSourcePickerPanel(panelStyle lipgloss.Style) string {
+	var list strings.Builder
+	list.WriteString(labelStyle.Render("Select Source") + "\n")
+	list.WriteString(mutedTextStyle.Render("Use ↑↓ to navigate, Enter to select, Esc to cancel") + "\n\n")
+
+	for i, in := range a.status.Inputs {
+		name := in.DisplayName()
+		if in.Custom != "" {
+			name += mutedTextStyle.Render(fmt.Sprintf(" (%s)", in.Name))
+		}
+		if strings.EqualFold(in.Name, a.status.Source) {
+			name += successTextStyle.Render(" (current)")
+		}
+
+		switch {
+		case i == a.sourcePickerSelection:
+			list.WriteString(fmt.Sprintf("▶ %s", primaryTextStyle.Render(name)))
+		case !in.Enabled:
+			list.WriteString(mutedTextStyle.Render(fmt.Sprintf("  %s (disabled)", in.DisplayName())))
+		default:
+			list.WriteString(fmt.Sprintf("  %s", valueStyle.Render(name)))
+		}
+		if i < len(a.status.Inputs)-1 {
+			list.WriteString("\n")
+		}
+	}
+
+	return panelStyle.Render(list.String())
+}
+
+// renderDevicePickerPanel renders the device list of the device switcher
+func (a *App) renderDevicePickerPanel(panelStyle lipgloss.Style) string {
+	var list strings.Builder
+	list.WriteString(labelStyle.Render("Select Device") + "\n")
+	list.WriteString(mutedTextStyle.Render("Use ↑↓ to navigate, Enter to connect, Esc to cancel") + "\n\n")
+
+	for i, e := range a.devicePickerEntries {
+		name := fmt.Sprintf("%d. %s", i+1, e.String())
+		if e.Known != nil {
+			name += mutedTextStyle.Render(fmt.Sprintf(" (%s at %s)", e.Known.Model, net.JoinHostPort(e.Known.IP, e.Known.Port)))
+		}
+		if a.isCurrentDevice(e) {
+			name += successTextStyle.Render(" (current)")
+		}
+
+		if i == a.devicePickerSelection {
+			list.WriteString(fmt.Sprintf("▶ %s", primaryTextStyle.Render(name)))
+		} else {
+			list.WriteString(fmt.Sprintf("  %s", valueStyle.Render(name)))
+		}
+		if i < a.devicePickerLen()-1 {
+			list.WriteString("\n")
+		}
+	}
+
+	groups := viper.GetStringMapStringSlice("groups")
+	for i, name := range a.devicePickerGroups {
+		n := len(a.devicePickerEntries) + i
+		label := fmt.Sprintf("👥 %s", name)
+		if group, err := nadapi.ParseGroup(name, groups[name]...); err == nil {
+			label += mutedTextStyle.Render(fmt.Sprintf(" (%d devices)", len(group.Members)))
+		}
+		if a.group != nil && a.group.Group.Name == name {
+			label += successTextStyle.Render(" (current)")
+		}
+
+		if n == a.devicePickerSelection {
+			list.WriteString(fmt.Sprintf("▶ %s", primaryTextStyle.Render(label)))
+		} else {
+			list.WriteString(fmt.Sprintf("  %s", valueStyle.Render(label)))
+		}
+		if n < a.devicePickerLen()-1 {
+			list.WriteString("\n")
+		}
+	}
+
+	return panelStyle.Render(list.String())
+}
+
 func (a *App) renderSpotifyDevicesPanel(panelStyle lipgloss.Style) string {
 	if len(a.spotifyDevices) == 0 {
 		return panelStyle.Render(
@@ -3472,6 +4414,19 @@ func (a *App) Cleanup() error {
 
 	var errors []error
 
+	// Abort any device command still in flight
+	if a.cancel != nil {
+		a.cancel()
+	}
+
+	// Close the connections of the active group
+	if a.group != nil {
+		if err := a.group.Close(); err != nil {
+			errors = append(errors, fmt.Errorf("group disconnect: %w", err))
+		}
+		a.group = nil
+	}
+
 	// Close NAD device connection
 	if a.device != nil {
 		if err := a.device.Disconnect(); err != nil {
@@ -0,0 +1,122 @@
+package tui
+
+import (
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/galamiram/nadctl/internal/fakeamp"
+	"github.com/galamiram/nadctl/nadapi"
+	"github.com/spf13/viper"
+)
+
+// newFakeT758 connects to a fake T 758 that is on in both zones
+func newFakeT758(t *testing.T) (*nadapi.Device, *fakeamp.Amp) {
+	t.Helper()
+	amp := fakeamp.Start(t, fakeamp.FactoryInputs(), fakeamp.Values(map[string]string{
+		"Main.Model":      "T 758",
+		"Main.Power":      "On",
+		"Main.Volume":     "-40",
+		"Main.Source":     "HDMI1",
+		"Main.Mute":       "Off",
+		"Main.Brightness": "2",
+		"Main.Bass":       "0",
+		"Main.Treble":     "0",
+		"Main.Balance":    "0",
+		"Main.ToneDefeat": "Off",
+		"Zone2.Power":     "On",
+		"Zone2.Volume":    "-30",
+		"Zone2.Source":    "Analog1",
+		"Zone2.Mute":      "Off",
+	}))
+	d, err := nadapi.New(amp.Host, amp.Port)
+	if err != nil {
+		t.Fatalf("New(%s, %s) unexpected error: %v", amp.Host, amp.Port, err)
+	}
+	t.Cleanup(func() { d.Disconnect() })
+	return d, amp
+}
+
+func TestCommandRefreshesItsZone(t *testing.T) {
+	device, amp := newFakeT758(t)
+	a := NewApp()
+	a.device = device
+	a.zone = nadapi.Zone2
+
+	a.queueCommand(CmdMuteToggle, nil)
+	cmd, _ := a.commandQueue.Next()
+	a.executeCommand(cmd)
+
+	refresh, ok := a.commandQueue.Next()
+	if !ok || refresh.Type != CmdRefreshStatus {
+		t.Fatalf("queued %+v after the command, want a status refresh", refresh)
+	}
+	amp.TakeQueried()
+	a.executeCommand(refresh)
+
+	for _, key := range amp.TakeQueried() {
+		if strings.HasPrefix(key, ".") {
+			t.Errorf("refresh queried %q, a key without zone", key)
+		}
+	}
+	msg := (<-a.resultChan).(statusUpdateMsg)
+	if msg.status.Zone != nadapi.Zone2 || !msg.status.Muted || msg.status.Volume != -30 {
+		t.Errorf("refresh status = zone %s, muted %v, %v dB; want Zone2 muted at -30 dB",
+			msg.status.Zone, msg.status.Muted, msg.status.Volume)
+	}
+}
+
+func TestCommandQueueKeepsDeviceCommands(t *testing.T) {
+	cq := NewCommandQueue()
+	for _, cmdType := range []CommandType{CmdVolumeUp, CmdRefreshStatus, CmdVolumeUp, CmdVolumeDown, CmdRefreshStatus} {
+		cq.Add(QueuedCommand{Type: cmdType, Zone: nadapi.MainZone})
+	}
+	cq.Add(QueuedCommand{Type: CmdGroupVolumeSet, Params: map[string]interface{}{"volume": -30.0}})
+	cq.Add(QueuedCommand{Type: CmdGroupVolumeSet, Params: map[string]interface{}{"volume": -20.0}})
+
+	// The device coalesces volume commands itself, only refreshes are dropped
+	var got []CommandType
+	for cmd, ok := cq.Next(); ok; cmd, ok = cq.Next() {
+		got = append(got, cmd.Type)
+	}
+	want := []CommandType{CmdVolumeUp, CmdVolumeUp, CmdVolumeDown, CmdRefreshStatus, CmdGroupVolumeSet, CmdGroupVolumeSet}
+	if !slices.Equal(got, want) {
+		t.Errorf("queued %v, want %v", got, want)
+	}
+}
+
+func TestGroupCommandUsesConnectedMember(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), ".nadctl_cache.json")
+	original := nadapi.GetCacheFilePathFunc()
+	nadapi.SetCacheFilePathFunc(func() (string, error) { return cachePath, nil })
+	t.Cleanup(func() { nadapi.SetCacheFilePathFunc(original) })
+
+	office := fakeamp.Start(t, fakeamp.Values(map[string]string{"Main.Model": "C338", "Main.Power": "On", "Main.Volume": "-40"}))
+	kitchen, kitchenAmp := newFakeT758(t)
+	viper.Set("devices", map[string]string{"office": office.Addr(), "kitchen": kitchenAmp.Addr()})
+	t.Cleanup(viper.Reset)
+
+	// The TUI shows the second member, which keeps 3 dB below the group
+	a := NewApp()
+	a.device, a.connected = kitchen, true
+	a.selectGroup("office+kitchen@-3")
+	t.Cleanup(func() { a.group.Close() })
+	if cmd, ok := a.commandQueue.Next(); ok {
+		t.Fatalf("selecting the group queued %+v, want no reconnect to a member", cmd)
+	}
+
+	a.queueCommand(CmdVolumeSet, map[string]interface{}{"volume": -33.0})
+	cmd, _ := a.commandQueue.Next()
+	if member, _ := cmd.Params["member"].(int); member != 1 || cmd.Params["device"] != kitchen {
+		t.Errorf("group command params = %v, want the kitchen device as member 1", cmd.Params)
+	}
+	a.executeCommand(cmd)
+	if got, _ := strconv.ParseFloat(office.Value("Main.Volume"), 64); got != -30 {
+		t.Errorf("office volume = %v dB, want -30 for the group volume", got)
+	}
+	if got, _ := strconv.ParseFloat(kitchenAmp.Value("Main.Volume"), 64); got != -33 {
+		t.Errorf("kitchen volume = %v dB, want -33 as shown", got)
+	}
+}